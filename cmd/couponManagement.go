@@ -12,6 +12,7 @@ import (
 	pb "trintech/review/dto/coupon-management/coupon"
 	"trintech/review/internal/coupon-management/service"
 	"trintech/review/pkg/grpc_server"
+	"trintech/review/pkg/idempotency"
 	"trintech/review/pkg/postgres_client"
 )
 
@@ -58,11 +59,21 @@ func loadCouponManagement(_ context.Context) {
 	// Create a new PostgreSQL client using the specified address.
 	pgClient := postgres_client.NewPostgresClient(cfgs.PostgresDB.Address())
 
+	// Create a processor that purges the coupons which stayed in the trash longer than the retention.
+	purgeProcessor := service.NewCouponPurgeProcessor(pgClient, cfgs.TrashRetention)
+
+	// Create a processor that expires the coupon reservations of the purchases which never completed.
+	reservationExpiryProcessor := service.NewCouponReservationExpiryProcessor(pgClient)
+
 	// Create a new CouponService instance with the PostgreSQL client.
-	service := service.NewCouponService(pgClient)
+	service := service.NewCouponService(pgClient, cfgs.BaseCurrency)
+
+	// Create the idempotency key store and the processor that purges the expired keys.
+	idempotencyStore := idempotency.NewPostgresStore(pgClient)
+	idempotencyPurgeProcessor := idempotency.NewPurgeProcessor(idempotencyStore, idempotency.Retention)
 
-	// Create a new gRPC server using the specified configuration.
-	srv := grpc_server.NewGrpcServer(cfgs.CouponService)
+	// Create a new gRPC server using the specified configuration, the retried requests with an idempotency key are replayed.
+	srv := grpc_server.NewGrpcServer(cfgs.CouponService, idempotency.UnaryServerInterceptor(idempotencyStore))
 
 	// Register the CouponService implementation with the gRPC server.
 	pb.RegisterCouponServiceServer(srv.Server, service)
@@ -70,6 +81,6 @@ func loadCouponManagement(_ context.Context) {
 	// Append the all factory client to the list of factories.
 	factories = append(factories, pgClient)
 
-	// Append the all server to the list of processors.
-	processors = append(processors, srv)
+	// Append the all server and the purge processors to the list of processors.
+	processors = append(processors, srv, purgeProcessor, reservationExpiryProcessor, idempotencyPurgeProcessor)
 }
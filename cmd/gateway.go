@@ -12,6 +12,7 @@ import (
 
 	couponpb "trintech/review/dto/coupon-management/coupon"
 	productpb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
 	userpb "trintech/review/dto/user-management/auth"
 	"trintech/review/pkg/grpc_client"
 	"trintech/review/pkg/http_server"
@@ -57,30 +58,33 @@ func init() {
 
 // loadGateway initializes and loads the Gateway service.
 func loadGateway(ctx context.Context) {
-	// Create gRPC client connections to the user, product, and coupon services.
+	// Create gRPC client connections to the user, product, coupon, and storage services.
 	userClientConn := grpc_client.NewGrpcClient(cfgs.UserService)
 	productClientConn := grpc_client.NewGrpcClient(cfgs.ProductService)
 	couponClientConn := grpc_client.NewGrpcClient(cfgs.CouponService)
+	storageClientConn := grpc_client.NewGrpcClient(cfgs.StorageService)
 
-	// Create gRPC client instances for user, product, and coupon services.
+	// Create gRPC client instances for user, product, coupon, and storage services.
 	userClient := userpb.NewAuthServiceClient(userClientConn)
 	productClient := productpb.NewProductServiceClient(productClientConn)
 	couponClient := couponpb.NewCouponServiceClient(couponClientConn)
+	storageClient := storagepb.NewUploadServiceClient(storageClientConn)
 
 	// Create a new HTTP server for handling gRPC-to-HTTP translation.
 	httpServer := http_server.NewHttpServer(
 		func(mux *runtime.ServeMux) {
-			// Register gRPC handlers for user, product, and coupon services.
+			// Register gRPC handlers for user, product, coupon, and storage services.
 			userpb.RegisterAuthServiceHandlerClient(ctx, mux, userClient)
 			productpb.RegisterProductServiceHandlerClient(ctx, mux, productClient)
 			couponpb.RegisterCouponServiceHandlerClient(ctx, mux, couponClient)
+			storagepb.RegisterUploadServiceHandlerClient(ctx, mux, storageClient)
 		},
 		cfgs.GatewayService,
 		tokenGenerator,
 	)
 
 	// Append gRPC client connections to the list of factories.
-	factories = append(factories, userClientConn, productClientConn, couponClientConn)
+	factories = append(factories, userClientConn, productClientConn, couponClientConn, storageClientConn)
 
 	// Append the HTTP server to the list of processors.
 	processors = append(processors, httpServer)
@@ -0,0 +1,104 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/transfer"
+	"trintech/review/pkg/money"
+)
+
+// productExportCmd represents the productExport command
+var productExportCmd = &cobra.Command{
+	Use:   "productExport",
+	Short: "Export the product catalogue to a CSV or JSON Lines file",
+	Long: `Export the product catalogue of the product service to a CSV or JSON Lines file.
+
+Products are written while they are streamed from the service. For example:
+
+SERVICE=product-management ENV=dev go run main.go productExport --file=catalogue.jsonl --token=<admin token>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loadDefault()
+
+		filePath, _ := cmd.Flags().GetString("file")
+		formatName, _ := cmd.Flags().GetString("format")
+		token, _ := cmd.Flags().GetString("token")
+
+		format, err := transfer.ParseFormat(formatName, filePath)
+		if err != nil {
+			return err
+		}
+
+		// Write to stdout unless a file is given.
+		var out io.Writer = cmd.OutOrStdout()
+		if filePath != "" {
+			f, err := os.Create(filePath)
+			if err != nil {
+				return fmt.Errorf("unable to create catalogue file: %w", err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		writer, err := transfer.NewWriter(format, out)
+		if err != nil {
+			return err
+		}
+
+		ctx, client, closeFn, err := connectProductService(cmd.Context(), token)
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		stream, err := client.ExportProduct(ctx, &pb.ExportProductRequest{})
+		if err != nil {
+			return fmt.Errorf("unable to start export: %w", err)
+		}
+
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("unable to export products: %w", err)
+			}
+
+			row := resp.GetData()
+			price := money.New(row.GetPrice().GetAmount(), row.GetPrice().GetCurrency())
+			if err := writer.Write(&transfer.Row{
+				SKU:         row.GetSku(),
+				ExternalID:  row.GetExternalId(),
+				Name:        row.GetName(),
+				Type:        row.GetType(),
+				ImageURLs:   row.GetImageUrls(),
+				Description: row.GetDescription(),
+				Price:       json.Number(price.Decimal()),
+				Currency:    price.Currency,
+			}); err != nil {
+				return fmt.Errorf("unable to write product: %w", err)
+			}
+		}
+
+		return writer.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(productExportCmd)
+
+	productExportCmd.Flags().String("file", "", "path of the catalogue file (default is stdout)")
+	productExportCmd.Flags().String("format", "", "format of the catalogue file: csv or jsonl (default is the file extension)")
+	productExportCmd.Flags().String("token", "", "access token of an admin user")
+	productExportCmd.MarkFlagRequired("token")
+}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/metadata"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/transfer"
+	"trintech/review/pkg/grpc_client"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+)
+
+// productImportCmd represents the productImport command
+var productImportCmd = &cobra.Command{
+	Use:   "productImport",
+	Short: "Import products from a CSV or JSON Lines file",
+	Long: `Import products from a CSV or JSON Lines file into the product service.
+
+Every row is upserted by its sku or external_id and invalid rows are reported
+with their line number without stopping the import. For example:
+
+SERVICE=product-management ENV=dev go run main.go productImport --file=catalogue.csv --token=<admin token> --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loadDefault()
+
+		filePath, _ := cmd.Flags().GetString("file")
+		formatName, _ := cmd.Flags().GetString("format")
+		token, _ := cmd.Flags().GetString("token")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		format, err := transfer.ParseFormat(formatName, filePath)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("unable to open catalogue file: %w", err)
+		}
+		defer f.Close()
+
+		reader, err := transfer.NewReader(format, f)
+		if err != nil {
+			return err
+		}
+
+		ctx, client, closeFn, err := connectProductService(cmd.Context(), token)
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		return importProducts(ctx, client, reader, dryRun, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(productImportCmd)
+
+	productImportCmd.Flags().String("file", "", "path of the catalogue file")
+	productImportCmd.Flags().String("format", "", "format of the catalogue file: csv or jsonl (default is the file extension)")
+	productImportCmd.Flags().String("token", "", "access token of an admin user")
+	productImportCmd.Flags().Bool("dry-run", false, "validate the rows without writing them")
+	productImportCmd.MarkFlagRequired("file")
+	productImportCmd.MarkFlagRequired("token")
+}
+
+// connectProductService connects to the Product service and returns a context authenticated by the given token.
+func connectProductService(ctx context.Context, token string) (context.Context, pb.ProductServiceClient, func(), error) {
+	// Verify the token the same way the gateway does and forward the user information.
+	payload, err := tokenGenerator.Verify(token)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to verify token: %w", err)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, http_server.ImportUserInfoToMD(payload))
+
+	// Create a gRPC client connection to the Product service.
+	productClientConn := grpc_client.NewGrpcClient(cfgs.ProductService)
+	if err := productClientConn.Connect(ctx); err != nil {
+		return nil, nil, nil, fmt.Errorf("unable to connect product service: %w", err)
+	}
+
+	return ctx, pb.NewProductServiceClient(productClientConn), func() {
+		productClientConn.Close(ctx)
+	}, nil
+}
+
+// importProducts streams the rows of reader to the Product service and prints the import report to out.
+func importProducts(ctx context.Context, client pb.ProductServiceClient, reader transfer.Reader, dryRun bool, out io.Writer) error {
+	stream, err := client.ImportProduct(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start import: %w", err)
+	}
+
+	if err := stream.Send(&pb.ImportProductRequest{
+		Data: &pb.ImportProductRequest_Options_{
+			Options: &pb.ImportProductRequest_Options{DryRun: dryRun},
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to send import options: %w", err)
+	}
+
+	// Send the rows which could be parsed, the others are reported right away.
+	var parseErrors int
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *transfer.RowError
+		if errors.As(err, &rowErr) {
+			parseErrors++
+			fmt.Fprintf(out, "line %d: %v\n", rowErr.Line, rowErr.Err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read catalogue file: %w", err)
+		}
+
+		// Prices are decimals in the file and minor units in the service, in the base currency by default.
+		price := money.New(0, row.Currency)
+		if price.Currency == "" {
+			price.Currency = cfgs.BaseCurrency
+		}
+		if row.Price != "" {
+			if price, err = money.Parse(row.Price.String(), price.Currency); err != nil {
+				parseErrors++
+				fmt.Fprintf(out, "line %d: %v\n", row.Line, err)
+				continue
+			}
+		}
+
+		if err := stream.Send(&pb.ImportProductRequest{
+			Data: &pb.ImportProductRequest_Row{
+				Row: &pb.ProductRow{
+					Line:        row.Line,
+					Columns:     row.Columns,
+					Sku:         row.SKU,
+					ExternalId:  row.ExternalID,
+					Name:        row.Name,
+					Type:        row.Type,
+					ImageUrls:   row.ImageURLs,
+					Description: row.Description,
+					Price:       &pb.Money{Amount: price.Amount, Currency: price.Currency},
+				},
+			},
+		}); err != nil {
+			return fmt.Errorf("unable to send row of line %d: %w", row.Line, err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("unable to import products: %w", err)
+	}
+
+	for _, rowErr := range resp.GetErrors() {
+		fmt.Fprintf(out, "line %d (sku=%q external_id=%q): %s\n", rowErr.GetLine(), rowErr.GetSku(), rowErr.GetExternalId(), rowErr.GetMessage())
+	}
+
+	mode := "imported"
+	if resp.GetDryRun() {
+		mode = "validated (dry run)"
+	}
+	fmt.Fprintf(out, "%s %d rows: %d created, %d updated, %d failed\n",
+		mode,
+		resp.GetTotal()+int64(parseErrors),
+		resp.GetCreated(),
+		resp.GetUpdated(),
+		len(resp.GetErrors())+parseErrors,
+	)
+
+	if parseErrors > 0 || len(resp.GetErrors()) > 0 {
+		return fmt.Errorf("some rows could not be imported")
+	}
+
+	return nil
+}
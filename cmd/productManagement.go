@@ -12,10 +12,14 @@ import (
 
 	couponpb "trintech/review/dto/coupon-management/coupon"
 	pb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
 	"trintech/review/internal/product-management/service"
 	"trintech/review/pkg/grpc_client"
 	"trintech/review/pkg/grpc_server"
+	"trintech/review/pkg/idempotency"
+	"trintech/review/pkg/payment"
 	"trintech/review/pkg/postgres_client"
+	"trintech/review/pkg/pubsub"
 )
 
 // productManagementCmd represents the productManagement command
@@ -70,18 +74,46 @@ func loadProductManagement(_ context.Context) {
 	// Log the address of the Coupon service.
 	log.Println(cfgs.CouponService.Address())
 
+	// Create a gRPC client connection and instance for the Storage service, which resolves the product images.
+	storageClientConn := grpc_client.NewGrpcClient(cfgs.StorageService)
+	storageClient := storagepb.NewUploadServiceClient(storageClientConn)
+
+	// Create a processor that purges the products which stayed in the trash longer than the retention.
+	purgeProcessor := service.NewProductPurgeProcessor(pgClient, cfgs.TrashRetention)
+
+	// Create a processor that refreshes the sales rollups of the analytics with the updated purchases.
+	salesRollupProcessor := service.NewSalesRollupProcessor(pgClient)
+
+	// Create a processor that relays the coupon redemption saga steps of the committed purchases to the Coupon service.
+	couponSagaRelay := service.NewCouponSagaRelay(pgClient, couponClient)
+
+	// Create the payment provider charging the purchases, the fake provider runs the payments offline.
+	paymentProvider := payment.NewFakeProvider(cfgs.PaymentWebhookSecret, nil)
+
+	// Create a processor that relays the committed refunds of the purchases and orders to the payment provider.
+	paymentRefundRelay := service.NewPaymentRefundRelay(pgClient, paymentProvider)
+
+	// Create the broker of the product and order events, the changes of products invalidate the product cache
+	// of every replica through the notifications of the database.
+	broker := pubsub.NewPostgresBroker(pgClient, cfgs.PostgresDB.Address())
+
 	// Create a new ProductService instance with the PostgreSQL client and Coupon client.
-	service := service.NewProductService(pgClient, couponClient)
+	service := service.NewProductService(pgClient, couponClient, storageClient, broker, paymentProvider, cfgs.BaseCurrency, cfgs.TaxRates)
+
+	// Create the idempotency key store and the processor that purges the expired keys.
+	idempotencyStore := idempotency.NewPostgresStore(pgClient)
+	idempotencyPurgeProcessor := idempotency.NewPurgeProcessor(idempotencyStore, idempotency.Retention)
 
-	// Create a new gRPC server using the specified configuration.
-	srv := grpc_server.NewGrpcServer(cfgs.ProductService)
+	// Create a new gRPC server using the specified configuration, the retried requests with an idempotency key are replayed.
+	srv := grpc_server.NewGrpcServer(cfgs.ProductService, idempotency.UnaryServerInterceptor(idempotencyStore))
 
 	// Register the ProductService implementation with the gRPC server.
 	pb.RegisterProductServiceServer(srv.Server, service)
 
-	// Append the PostgreSQL client and Coupon gRPC client connection to the list of factories.
-	factories = append(factories, pgClient, couponClientConn)
+	// Append the PostgreSQL client and the Coupon and Storage gRPC client connections to the list of factories.
+	factories = append(factories, pgClient, couponClientConn, storageClientConn)
 
-	// Append the gRPC server to the list of processors.
-	processors = append(processors, srv)
+	// Append the gRPC server, the purge processors, the sales rollup processor, the coupon saga and payment refund relays
+	// and the broker to the list of processors.
+	processors = append(processors, srv, purgeProcessor, salesRollupProcessor, couponSagaRelay, paymentRefundRelay, idempotencyPurgeProcessor, broker)
 }
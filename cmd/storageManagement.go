@@ -1,13 +1,19 @@
 /*
 Copyright © 2024 NAME HERE <EMAIL ADDRESS>
-
 */
 package cmd
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
 
 	"github.com/spf13/cobra"
+
+	pb "trintech/review/dto/storage-management/upload"
+	"trintech/review/internal/storage-management/service"
+	"trintech/review/pkg/grpc_server"
+	"trintech/review/pkg/postgres_client"
+	"trintech/review/pkg/storage"
 )
 
 // storageManagementCmd represents the storageManagement command
@@ -21,7 +27,16 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("storageManagement called")
+		ctx := cmd.Context()
+		loadDefault()
+		loadStorageManagement(ctx)
+		errChan := make(chan error)
+		start(ctx, errChan)
+		err := <-errChan
+		if err != nil {
+			slog.Error(err.Error())
+			stop(ctx)
+		}
 	},
 }
 
@@ -38,3 +53,24 @@ func init() {
 	// is called directly, e.g.:
 	// storageManagementCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// loadStorageManagement initializes and loads the Storage Management service.
+func loadStorageManagement(_ context.Context) {
+	// Create a new PostgreSQL client using the specified address.
+	pgClient := postgres_client.NewPostgresClient(cfgs.PostgresDB.Address())
+
+	// Create a new StorageService instance storing the uploaded files in the local directory.
+	service := service.NewStorageService(pgClient, storage.NewLocalStorage(cfgs.StorageDir, cfgs.StorageBaseURL))
+
+	// Create a new gRPC server using the specified configuration.
+	srv := grpc_server.NewGrpcServer(cfgs.StorageService)
+
+	// Register the UploadService implementation with the gRPC server.
+	pb.RegisterUploadServiceServer(srv.Server, service)
+
+	// Append the PostgreSQL client to the list of factories.
+	factories = append(factories, pgClient)
+
+	// Append the gRPC server to the list of processors.
+	processors = append(processors, srv)
+}
@@ -13,6 +13,7 @@ import (
 	"trintech/review/internal/user-management/service"
 	"trintech/review/mocks"
 	"trintech/review/pkg/grpc_server"
+	"trintech/review/pkg/idempotency"
 	"trintech/review/pkg/postgres_client"
 )
 
@@ -62,8 +63,12 @@ func loadUserManagement(_ context.Context) {
 	// Create a new AuthService instance with the PostgreSQL client, mock publisher, and token generator.
 	service := service.NewAuthService(pgClient, &mocks.Publisher{}, tokenGenerator)
 
-	// Create a new gRPC server using the specified configuration.
-	srv := grpc_server.NewGrpcServer(cfgs.UserService)
+	// Create the idempotency key store and the processor that purges the expired keys.
+	idempotencyStore := idempotency.NewPostgresStore(pgClient)
+	idempotencyPurgeProcessor := idempotency.NewPurgeProcessor(idempotencyStore, idempotency.Retention)
+
+	// Create a new gRPC server using the specified configuration, the retried requests with an idempotency key are replayed.
+	srv := grpc_server.NewGrpcServer(cfgs.UserService, idempotency.UnaryServerInterceptor(idempotencyStore))
 
 	// Register the AuthService implementation with the gRPC server.
 	pb.RegisterAuthServiceServer(srv.Server, service)
@@ -71,6 +76,6 @@ func loadUserManagement(_ context.Context) {
 	// Append the PostgreSQL client to the list of factories.
 	factories = append(factories, pgClient)
 
-	// Append the gRPC server to the list of processors.
-	processors = append(processors, srv)
+	// Append the gRPC server and the idempotency key purge processor to the list of processors.
+	processors = append(processors, srv, idempotencyPurgeProcessor)
 }
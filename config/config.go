@@ -2,39 +2,60 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"trintech/review/pkg/tax"
 )
 
+// defaultBaseCurrency is the base currency when BASE_CURRENCY is not set.
+const defaultBaseCurrency = "USD"
+
 // Config represents the overall configuration structure.
 type Config struct {
-	PostgresDB     *Database
-	HTTP           *Endpoint
-	UserService    *Endpoint
-	ProductService *Endpoint
-	CouponService  *Endpoint
-	GatewayService *Endpoint
-	SymetricKey    string
-	FileLogOutPut  string
+	PostgresDB           *Database
+	HTTP                 *Endpoint
+	UserService          *Endpoint
+	ProductService       *Endpoint
+	CouponService        *Endpoint
+	StorageService       *Endpoint
+	GatewayService       *Endpoint
+	SymetricKey          string
+	FileLogOutPut        string
+	TrashRetention       time.Duration
+	BaseCurrency         string
+	PaymentWebhookSecret string
+	TaxRates             []tax.Rate
+	StorageDir           string
+	StorageBaseURL       string
 }
 
 // config is a private structure used for unmarshaling the configuration from Viper.
 type config struct {
-	DBHost          string `mapstructure:"DB_HOST"`
-	DBPort          string `mapstructure:"DB_PORT"`
-	DBUser          string `mapstructure:"DB_USER"`
-	DBPassword      string `mapstructure:"DB_PASSWORD"`
-	DBDatabase      string `mapstructure:"DB_NAME"`
-	UserGRPCHost    string `mapstructure:"USER_GRPC_HOST"`
-	UserGRPCPort    string `mapstructure:"USER_GRPC_PORT"`
-	ProductGRPCHost string `mapstructure:"PRODUCT_GRPC_HOST"`
-	ProductGRPCPort string `mapstructure:"PRODUCT_GRPC_PORT"`
-	CouponGRPCHost  string `mapstructure:"COUPON_GRPC_HOST"`
-	CouponGRPCPort  string `mapstructure:"COUPON_GRPC_PORT"`
-	GatewayGRPCHost string `mapstructure:"GATEWAY_GRPC_HOST"`
-	GatewayGRPCPort string `mapstructure:"GATEWAY_GRPC_PORT"`
-	SymetricKey     string `mapstructure:"SYMETRIC_KEY"`
-	FileLogOutPut   string `mapstructure:"FILE_LOG_OUTPUT"`
+	DBHost               string        `mapstructure:"DB_HOST"`
+	DBPort               string        `mapstructure:"DB_PORT"`
+	DBUser               string        `mapstructure:"DB_USER"`
+	DBPassword           string        `mapstructure:"DB_PASSWORD"`
+	DBDatabase           string        `mapstructure:"DB_NAME"`
+	UserGRPCHost         string        `mapstructure:"USER_GRPC_HOST"`
+	UserGRPCPort         string        `mapstructure:"USER_GRPC_PORT"`
+	ProductGRPCHost      string        `mapstructure:"PRODUCT_GRPC_HOST"`
+	ProductGRPCPort      string        `mapstructure:"PRODUCT_GRPC_PORT"`
+	CouponGRPCHost       string        `mapstructure:"COUPON_GRPC_HOST"`
+	CouponGRPCPort       string        `mapstructure:"COUPON_GRPC_PORT"`
+	StorageGRPCHost      string        `mapstructure:"STORAGE_GRPC_HOST"`
+	StorageGRPCPort      string        `mapstructure:"STORAGE_GRPC_PORT"`
+	GatewayGRPCHost      string        `mapstructure:"GATEWAY_GRPC_HOST"`
+	GatewayGRPCPort      string        `mapstructure:"GATEWAY_GRPC_PORT"`
+	SymetricKey          string        `mapstructure:"SYMETRIC_KEY"`
+	FileLogOutPut        string        `mapstructure:"FILE_LOG_OUTPUT"`
+	TrashRetention       time.Duration `mapstructure:"TRASH_RETENTION"`
+	BaseCurrency         string        `mapstructure:"BASE_CURRENCY"`
+	PaymentWebhookSecret string        `mapstructure:"PAYMENT_WEBHOOK_SECRET"`
+	TaxRates             string        `mapstructure:"TAX_RATES"`
+	StorageDir           string        `mapstructure:"STORAGE_DIR"`
+	StorageBaseURL       string        `mapstructure:"STORAGE_BASE_URL"`
 }
 
 // LoadConfig loads the configuration from the specified file path and environment.
@@ -60,6 +81,18 @@ func LoadConfig(path string, env string) (*Config, error) {
 		return nil, fmt.Errorf("unable to unmarshal config file: %w", err)
 	}
 
+	// Default the base currency, the currency in which prices are stored and orders are paid.
+	baseCurrency := cfg.BaseCurrency
+	if baseCurrency == "" {
+		baseCurrency = defaultBaseCurrency
+	}
+
+	// Parse the default tax rates, the rates set by the admins win over them.
+	taxRates, err := tax.ParseRates(cfg.TaxRates)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse tax rates: %w", err)
+	}
+
 	// Create and return the public Config structure based on the private config.
 	return &Config{
 		PostgresDB: &Database{
@@ -81,11 +114,21 @@ func LoadConfig(path string, env string) (*Config, error) {
 			Host: cfg.CouponGRPCHost,
 			Port: cfg.CouponGRPCPort,
 		},
+		StorageService: &Endpoint{
+			Host: cfg.StorageGRPCHost,
+			Port: cfg.StorageGRPCPort,
+		},
 		GatewayService: &Endpoint{
 			Host: cfg.GatewayGRPCHost,
 			Port: cfg.GatewayGRPCPort,
 		},
-		SymetricKey:   cfg.SymetricKey,
-		FileLogOutPut: cfg.FileLogOutPut,
+		SymetricKey:          cfg.SymetricKey,
+		FileLogOutPut:        cfg.FileLogOutPut,
+		TrashRetention:       cfg.TrashRetention,
+		BaseCurrency:         baseCurrency,
+		PaymentWebhookSecret: cfg.PaymentWebhookSecret,
+		TaxRates:             taxRates,
+		StorageDir:           cfg.StorageDir,
+		StorageBaseURL:       cfg.StorageBaseURL,
 	}, nil
 }
@@ -1,6 +1,6 @@
 #!/bin/bash
 
-svcs=("user-management" "product-management" "coupon-management")
+svcs=("user-management" "product-management" "coupon-management" "storage-management")
 
 install_migrate() {
   go install -tags 'postgres' github.com/golang-migrate/migrate/v4/cmd/migrate@latest
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+// The actions of the coupon audit trail.
+const (
+	CouponAuditAction_Created  = "CREATED"
+	CouponAuditAction_Updated  = "UPDATED"
+	CouponAuditAction_Paused   = "PAUSED"
+	CouponAuditAction_Resumed  = "RESUMED"
+	CouponAuditAction_Deleted  = "DELETED"
+	CouponAuditAction_Restored = "RESTORED"
+)
+
+// CouponAudit represents an entry of the audit trail of a coupon, a change made by an admin.
+type CouponAudit struct {
+	ID        sql.NullInt64  `db:"id"`         // Audit entry ID
+	CouponID  sql.NullInt64  `db:"coupon_id"`  // ID of the changed coupon
+	Action    sql.NullString `db:"action"`     // Action of the change
+	Changes   sql.NullString `db:"changes"`    // JSON of the changed columns with their previous and new values, NULL without
+	Version   sql.NullInt64  `db:"version"`    // Version of the coupon after the change
+	ActorID   sql.NullInt64  `db:"actor_id"`   // User ID who made the change
+	CreatedAt sql.NullTime   `db:"created_at"` // Change timestamp
+}
+
+// TableName returns the table name for the CouponAudit entity.
+func (t *CouponAudit) TableName() string {
+	return "coupon_audits"
+}
+
+// CouponAuditChange is the change of a column of a coupon in the audit trail.
+type CouponAuditChange struct {
+	From any `json:"from"` // Previous value of the column
+	To   any `json:"to"`   // New value of the column
+}
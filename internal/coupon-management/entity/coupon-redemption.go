@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+// The statuses of a coupon redemption. A reservation is confirmed once the purchase is committed,
+// or released when the purchase fails. A reservation which is neither is expired, it can still be confirmed.
+// A confirmed redemption is restored when its purchase is refunded.
+const (
+	CouponRedemptionStatus_Reserved  = "RESERVED"
+	CouponRedemptionStatus_Confirmed = "CONFIRMED"
+	CouponRedemptionStatus_Released  = "RELEASED"
+	CouponRedemptionStatus_Expired   = "EXPIRED"
+	CouponRedemptionStatus_Restored  = "RESTORED"
+)
+
+// CouponRedemption represents an entity for tracking the redemption saga of a coupon in the database.
+type CouponRedemption struct {
+	ID         sql.NullString `db:"id"`          // ID of the redemption, chosen by the caller
+	CouponID   sql.NullInt64  `db:"coupon_id"`   // ID of the redeemed coupon, NULL for a redemption released before its reservation
+	CouponType sql.NullString `db:"coupon_type"` // Type of the coupon whose usage the reservation took, NULL when it took none
+	UserID     sql.NullInt64  `db:"user_id"`     // ID of the user redeeming the coupon
+	Status     sql.NullString `db:"status"`      // Status of the redemption
+	CreatedAt  sql.NullTime   `db:"created_at"`  // Redemption creation timestamp
+	UpdatedAt  sql.NullTime   `db:"updated_at"`  // Redemption last status change timestamp
+}
+
+// TableName returns the table name for the CouponRedemption entity.
+func (t *CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
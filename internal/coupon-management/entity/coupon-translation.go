@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// CouponTranslation represents the description of a coupon in a locale other than the default locale.
+type CouponTranslation struct {
+	CouponID    sql.NullInt64  `db:"coupon_id"`   // ID of the translated coupon
+	Locale      sql.NullString `db:"locale"`      // Locale of the translation
+	Description sql.NullString `db:"description"` // Description of the coupon in the locale
+	UpdatedBy   sql.NullInt64  `db:"updated_by"`  // User ID who last set the translation
+	CreatedAt   sql.NullTime   `db:"created_at"`  // Translation creation timestamp
+	UpdatedAt   sql.NullTime   `db:"updated_at"`  // Translation last update timestamp
+}
+
+// TableName returns the table name for the CouponTranslation entity.
+func (t *CouponTranslation) TableName() string {
+	return "coupon_translations"
+}
+
+// MissingCouponTranslation lists the locales a coupon is not translated in.
+type MissingCouponTranslation struct {
+	CouponID sql.NullInt64  // ID of the coupon
+	Code     sql.NullString // Code of the coupon
+	Locales  pq.StringArray // Locales the coupon is not translated in
+}
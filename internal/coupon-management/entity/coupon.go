@@ -3,27 +3,60 @@ package entity
 
 import (
 	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrCouponTotalBelowUsed is returned by an update which would set the total of a coupon below its usage,
+// the usages taken by the pending reservations included.
+var ErrCouponTotalBelowUsed = errors.New("coupon total below its usage")
+
+// The states of a coupon by its validity window at a time, a scheduled coupon is not valid yet.
+const (
+	CouponState_Scheduled = "SCHEDULED"
+	CouponState_Active    = "ACTIVE"
+	CouponState_Expired   = "EXPIRED"
 )
 
 // Coupon represents the database entity for coupons.
 type Coupon struct {
-	ID           sql.NullInt64   `db:"id"`            // Coupon ID
-	Code         sql.NullString  `db:"code"`          // Coupon code
-	From         sql.NullTime    `db:"from"`          // Validity start time
-	To           sql.NullTime    `db:"to"`            // Validity end time
-	Used         sql.NullInt64   `db:"used"`          // Number of times the coupon has been used
-	Total        sql.NullInt64   `db:"total"`         // Total available coupons
-	Type         sql.NullString  `db:"coupon_type"`   // Type of coupon
-	Value        sql.NullFloat64 `db:"value"`         // Value or percentage of the coupon discount
-	ImageURL     sql.NullString  `db:"image_url"`     // URL of the coupon image
-	Description  sql.NullString  `db:"description"`   // Coupon description
-	DiscountType sql.NullString  `db:"discount_type"` // Type of discount (e.g., fixed value or percentage)
-	CreatedBy    sql.NullInt64   `db:"created_by"`    // User ID who created the coupon
-	CreatedAt    sql.NullTime    `db:"created_at"`    // Coupon creation timestamp
-	UpdatedAt    sql.NullTime    `db:"updated_at"`    // Coupon last update timestamp
+	ID                sql.NullInt64  `db:"id"`                  // Coupon ID
+	Code              sql.NullString `db:"code"`                // Coupon code
+	From              sql.NullTime   `db:"from"`                // Validity start time
+	To                sql.NullTime   `db:"to"`                  // Validity end time
+	Used              sql.NullInt64  `db:"used"`                // Number of times the coupon has been used
+	Total             sql.NullInt64  `db:"total"`               // Total available coupons
+	Type              sql.NullString `db:"coupon_type"`         // Type of coupon
+	Value             sql.NullInt64  `db:"value"`               // Minor units of a value discount or basis points of a percent discount
+	Currency          sql.NullString `db:"currency"`            // Currency of a value discount
+	ImageURL          sql.NullString `db:"image_url"`           // URL of the coupon image
+	Description       sql.NullString `db:"description"`         // Coupon description
+	DiscountType      sql.NullString `db:"discount_type"`       // Type of discount (e.g., fixed value or percentage)
+	CreatedBy         sql.NullInt64  `db:"created_by"`          // User ID who created the coupon
+	CreatedAt         sql.NullTime   `db:"created_at"`          // Coupon creation timestamp
+	UpdatedAt         sql.NullTime   `db:"updated_at"`          // Coupon last update timestamp
+	DeletedAt         sql.NullTime   `db:"deleted_at"`          // Coupon soft deletion timestamp
+	DeletedBy         sql.NullInt64  `db:"deleted_by"`          // User ID who moved the coupon to the trash
+	AppliesToShipping sql.NullBool   `db:"applies_to_shipping"` // Whether the coupon discounts the shipping cost too
+	Version           sql.NullInt64  `db:"version"`             // Incremented by every update, the ETag of the coupon
+	Rules             sql.NullString `db:"rules"`               // JSON of the eligibility rules, NULL without rules
+	PausedAt          sql.NullTime   `db:"paused_at"`           // Pause timestamp, NULL when the coupon is not paused
+	PausedBy          sql.NullInt64  `db:"paused_by"`           // User ID who paused the coupon
 }
 
 // TableName returns the table name for the Coupon entity.
 func (t *Coupon) TableName() string {
 	return "coupons"
 }
+
+// CouponFilter filters the coupons which are not in the trash, the invalid fields don't filter.
+type CouponFilter struct {
+	Code         sql.NullString // Part of the code, case insensitive
+	Type         sql.NullString // Type of coupon
+	DiscountType sql.NullString // Type of discount
+	State        sql.NullString // State of the coupon at Now
+	Now          time.Time      // Time the state is evaluated at
+	UserID       sql.NullInt64  // User of a user coupon
+	ProductID    sql.NullInt64  // Product of a product coupon
+	CreatedBy    sql.NullInt64  // User ID who created the coupon
+}
@@ -18,11 +18,15 @@ const (
 
 // UsedCoupon represents an entity for tracking used coupons in the database.
 type UsedCoupon struct {
-	CouponID  sql.NullInt64 `db:"coupon_id"`  // ID of the associated coupon
-	UserID    sql.NullInt64 `db:"user_id"`    // ID of the user associated with the used coupon
-	CreatedBy sql.NullInt64 `db:"created_by"` // User ID who created the used coupon entry
-	CreatedAt sql.NullTime  `db:"created_at"` // Used coupon creation timestamp
-	UpdatedAt sql.NullTime  `db:"updated_at"` // Used coupon last update timestamp
+	CouponID     sql.NullInt64  `db:"coupon_id"`     // ID of the associated coupon
+	UserID       sql.NullInt64  `db:"user_id"`       // ID of the user associated with the used coupon
+	Type         sql.NullString `db:"type"`          // Type of the coupon when it was used
+	CreatedBy    sql.NullInt64  `db:"created_by"`    // User ID who created the used coupon entry
+	CreatedAt    sql.NullTime   `db:"created_at"`    // Used coupon creation timestamp
+	UpdatedAt    sql.NullTime   `db:"updated_at"`    // Used coupon last update timestamp
+	RedemptionID sql.NullString `db:"redemption_id"` // ID of the redemption which used the coupon
+	Discount     sql.NullInt64  `db:"discount"`      // Minor units of the discount given, NULL when it is not recorded
+	Currency     sql.NullString `db:"currency"`      // Currency of the discount given
 }
 
 // TableName returns the table name for the UsedCoupon entity.
@@ -36,3 +40,17 @@ type CouponUsedCoupon struct {
 	UsedCoupon *UsedCoupon // Information about the used coupon
 	Coupon     *Coupon     // Information about the associated coupon
 }
+
+// UsedCouponStats are the redemption statistics of a coupon computed from its used coupons.
+type UsedCouponStats struct {
+	CouponID    int64 // ID of the coupon
+	Used        int64 // Number of used coupons
+	UniqueUsers int64 // Number of distinct users who used the coupon
+}
+
+// UsedCouponDiscount is the total discount given by a coupon in a currency.
+type UsedCouponDiscount struct {
+	CouponID int64  // ID of the coupon
+	Currency string // Currency of the discount
+	Amount   int64  // Minor units of the total discount
+}
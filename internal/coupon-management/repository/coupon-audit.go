@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+)
+
+// CouponAuditRepository defines the interface for coupon audit trail related database operations.
+type CouponAuditRepository interface {
+	// Create appends an entry to the audit trail of a coupon.
+	Create(ctx context.Context, db database.Executor, data *entity.CouponAudit) error
+
+	// ListByCouponID retrieves the audit trail of a coupon, the latest change first.
+	ListByCouponID(ctx context.Context, db database.Executor, couponID, offset, limit int64) ([]*entity.CouponAudit, error)
+
+	// CountByCouponID counts the entries of the audit trail of a coupon.
+	CountByCouponID(ctx context.Context, db database.Executor, couponID int64) (int64, error)
+}
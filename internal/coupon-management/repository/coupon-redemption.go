@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+)
+
+// CouponRedemptionRepository defines the interface for coupon redemption related database operations.
+type CouponRedemptionRepository interface {
+	// Create creates a redemption unless its ID exists, it returns false when the ID exists.
+	Create(ctx context.Context, db database.Executor, data *entity.CouponRedemption) (bool, error)
+
+	// RetrieveByID retrieves a redemption by its ID.
+	RetrieveByID(ctx context.Context, db database.Executor, id string) (*entity.CouponRedemption, error)
+
+	// UpdateStatus changes the status of a redemption which is in one of the from statuses.
+	// It returns sql.ErrNoRows when the redemption is in none of them.
+	UpdateStatus(ctx context.Context, db database.Executor, id string, from []string, to string) error
+
+	// ExpireReservedBefore expires the reservations created before a time and returns the expired redemptions.
+	ExpireReservedBefore(ctx context.Context, db database.Executor, before time.Time) ([]*entity.CouponRedemption, error)
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+)
+
+// CouponTranslationRepository defines the interface for coupon translation related database operations.
+type CouponTranslationRepository interface {
+	// Upsert sets the translation of a coupon in a locale.
+	Upsert(ctx context.Context, db database.Executor, data *entity.CouponTranslation) error
+
+	// Delete deletes the translation of a coupon in a locale.
+	Delete(ctx context.Context, db database.Executor, couponID int64, locale string) error
+
+	// ListByCouponID retrieves the translations of a coupon in the given locales, in every locale without locales.
+	ListByCouponID(ctx context.Context, db database.Executor, couponID int64, locales []string) ([]*entity.CouponTranslation, error)
+
+	// ListMissing retrieves the coupons which are not in the trash with the locales they are not translated in.
+	ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCouponTranslation, error)
+
+	// CountMissing counts the coupons which are not in the trash and not translated in one of the locales.
+	CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+}
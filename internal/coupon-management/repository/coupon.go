@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"trintech/review/internal/coupon-management/entity"
 	"trintech/review/pkg/database"
@@ -12,9 +13,46 @@ type CouponRepository interface {
 	// Create creates a new coupon in the database and returns its ID.
 	Create(ctx context.Context, db database.Executor, data *entity.Coupon) (int64, error)
 
-	// DeleteByID deletes a coupon with the specified ID from the database.
-	DeleteByID(ctx context.Context, db database.Executor, id int64) error
+	// DeleteByID moves a coupon with the specified ID to the trash.
+	DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error
+
+	// RetrieveByID retrieves a coupon which is not in the trash by its ID.
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Coupon, error)
+
+	// UpdateByID writes the given columns of a coupon which is not in the trash and increments its version. With a
+	// version in data, it only updates that version and returns [database.ErrVersionMismatch] on another version.
+	// A total below the current usage is not written and returns [entity.ErrCouponTotalBelowUsed].
+	UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Coupon, columns []string) error
 
 	// RetrieveByCode retrieves a coupon from the database based on its unique code.
 	RetrieveByCode(ctx context.Context, db database.Executor, code string) (*entity.Coupon, error)
+
+	// List retrieves the coupons which are not in the trash matching a filter, the latest first.
+	List(ctx context.Context, db database.Executor, filter *entity.CouponFilter, offset, limit int64) ([]*entity.Coupon, error)
+
+	// Count counts the coupons which are not in the trash matching a filter.
+	Count(ctx context.Context, db database.Executor, filter *entity.CouponFilter) (int64, error)
+
+	// ListDeleted retrieves the coupons in the trash, most recently deleted first.
+	ListDeleted(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Coupon, error)
+
+	// CountDeleted counts the coupons in the trash.
+	CountDeleted(ctx context.Context, db database.Executor) (int64, error)
+
+	// RestoreByID moves a coupon with the specified ID out of the trash.
+	RestoreByID(ctx context.Context, db database.Executor, id int64) error
+
+	// IncrementUsed takes a usage of a coupon. With withinTotal, the usage of a LIMITED coupon is only taken below its
+	// total, it returns sql.ErrNoRows when the coupon is used up or not found.
+	IncrementUsed(ctx context.Context, db database.Executor, id int64, withinTotal bool) error
+
+	// DecrementUsed gives back a usage of a coupon, the usage count never goes below zero.
+	DecrementUsed(ctx context.Context, db database.Executor, id int64) error
+
+	// IncrementVersionByID increments the version of a coupon which is not in the trash, when a content it is read
+	// with changes.
+	IncrementVersionByID(ctx context.Context, db database.Executor, id int64) error
+
+	// PurgeDeletedBefore hard-deletes the coupons trashed before the given time and returns how many were removed.
+	PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error)
 }
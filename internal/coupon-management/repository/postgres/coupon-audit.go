@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// couponAuditRepository is an implementation of the CouponAuditRepository interface for PostgreSQL.
+type couponAuditRepository struct{}
+
+// NewCouponAuditRepository creates a new instance of the couponAuditRepository.
+func NewCouponAuditRepository() repository.CouponAuditRepository {
+	return &couponAuditRepository{}
+}
+
+// Create inserts an audit entry into the database.
+func (r *couponAuditRepository) Create(ctx context.Context, db database.Executor, data *entity.CouponAudit) error {
+	// An audit entry is dated at its creation by default.
+	if !data.CreatedAt.Valid {
+		data.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	// Get field names and values excluding the "id" field.
+	fieldNames, values := database.FieldMap(data)
+	fieldNames = fieldNames[1:]
+	values = values[1:]
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListByCouponID retrieves the audit entries of a coupon from the database, the latest first.
+func (r *couponAuditRepository) ListByCouponID(ctx context.Context, db database.Executor, couponID, offset, limit int64) ([]*entity.CouponAudit, error) {
+	e := &entity.CouponAudit{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE coupon_id = $1
+		ORDER BY id DESC
+		OFFSET $2
+		LIMIT $3
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &couponID, &offset, &limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.CouponAudit
+	for rows.Next() {
+		var val entity.CouponAudit
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// CountByCouponID counts the audit entries of a coupon in the database.
+func (r *couponAuditRepository) CountByCouponID(ctx context.Context, db database.Executor, couponID int64) (int64, error) {
+	e := &entity.CouponAudit{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE coupon_id = $1
+	`, e.TableName())
+
+	var total int64
+	if err := db.QueryRowContext(ctx, stmt, &couponID).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
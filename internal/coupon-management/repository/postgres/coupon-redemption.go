@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// couponRedemptionCreateColumns are the columns written when a redemption is created, the others keep their default.
+var couponRedemptionCreateColumns = []string{"id", "coupon_id", "coupon_type", "user_id", "status"}
+
+// couponRedemptionRepository is an implementation of the CouponRedemptionRepository interface for PostgreSQL.
+type couponRedemptionRepository struct{}
+
+// NewCouponRedemptionRepository creates a new instance of the couponRedemptionRepository.
+func NewCouponRedemptionRepository() repository.CouponRedemptionRepository {
+	return &couponRedemptionRepository{}
+}
+
+// Create inserts a redemption in the database unless its ID exists.
+func (r *couponRedemptionRepository) Create(ctx context.Context, db database.Executor, data *entity.CouponRedemption) (bool, error) {
+	fieldNames, values := database.SelectFieldMap(data, couponRedemptionCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		ON CONFLICT (id) DO NOTHING
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	result, err := db.ExecContext(ctx, stmt, values...)
+	if err != nil {
+		return false, err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowEffected == 1, nil
+}
+
+// RetrieveByID retrieves a redemption by its ID from the database.
+func (r *couponRedemptionRepository) RetrieveByID(ctx context.Context, db database.Executor, id string) (*entity.CouponRedemption, error) {
+	var result entity.CouponRedemption
+	fieldNames, values := database.FieldMap(&result)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), result.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UpdateStatus changes the status of a redemption in the database.
+func (r *couponRedemptionRepository) UpdateStatus(ctx context.Context, db database.Executor, id string, from []string, to string) error {
+	e := &entity.CouponRedemption{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET status = $3, updated_at = now()
+		WHERE id = $1
+		AND status = ANY($2)
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, pq.Array(from), &to)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ExpireReservedBefore expires the reservations created before a time in the database and returns them.
+func (r *couponRedemptionRepository) ExpireReservedBefore(ctx context.Context, db database.Executor, before time.Time) ([]*entity.CouponRedemption, error) {
+	e := &entity.CouponRedemption{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET status = $2, updated_at = now()
+		WHERE status = $1
+		AND created_at < $3
+		RETURNING "%s"
+	`, e.TableName(), strings.Join(fieldNames, "\",\""))
+
+	rows, err := db.QueryContext(ctx, stmt, entity.CouponRedemptionStatus_Reserved, entity.CouponRedemptionStatus_Expired, &before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.CouponRedemption
+	for rows.Next() {
+		var val entity.CouponRedemption
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
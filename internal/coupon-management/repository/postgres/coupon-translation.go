@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// couponTranslationRepository is an implementation of the CouponTranslationRepository interface for PostgreSQL.
+type couponTranslationRepository struct{}
+
+// NewCouponTranslationRepository creates a new instance of the couponTranslationRepository.
+func NewCouponTranslationRepository() repository.CouponTranslationRepository {
+	return &couponTranslationRepository{}
+}
+
+// Upsert inserts the translation of a coupon in a locale, or updates it when it exists.
+func (r *couponTranslationRepository) Upsert(ctx context.Context, db database.Executor, data *entity.CouponTranslation) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(coupon_id, locale, description, updated_by)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (coupon_id, locale) DO UPDATE
+		SET
+		description = EXCLUDED.description,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.CouponID, &data.Locale, &data.Description, &data.UpdatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the translation of a coupon in a locale.
+func (r *couponTranslationRepository) Delete(ctx context.Context, db database.Executor, couponID int64, locale string) error {
+	e := &entity.CouponTranslation{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE coupon_id = $1
+		AND locale = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &couponID, &locale)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByCouponID retrieves the translations of a coupon in the given locales by locale.
+func (r *couponTranslationRepository) ListByCouponID(ctx context.Context, db database.Executor, couponID int64, locales []string) ([]*entity.CouponTranslation, error) {
+	e := &entity.CouponTranslation{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE coupon_id = $1
+		AND ($2::text[] IS NULL OR locale = ANY($2))
+		ORDER BY locale
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &couponID, pq.StringArray(locales))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.CouponTranslation
+	for rows.Next() {
+		var val entity.CouponTranslation
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// ListMissing retrieves the coupons which are not in the trash with the locales they are not translated in, by coupon.
+func (r *couponTranslationRepository) ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCouponTranslation, error) {
+	e := &entity.CouponTranslation{}
+	c := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		SELECT c.id, c.code, array_agg(l.locale ORDER BY l.locale)
+		FROM %s c
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.coupon_id = c.id AND t.locale = l.locale
+		WHERE c.deleted_at IS NULL
+		AND t.coupon_id IS NULL
+		GROUP BY c.id, c.code
+		ORDER BY c.id
+		OFFSET $2
+		LIMIT $3
+	`, c.TableName(), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.StringArray(locales), &offset, &limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.MissingCouponTranslation
+	for rows.Next() {
+		var val entity.MissingCouponTranslation
+		if err := rows.Scan(&val.CouponID, &val.Code, &val.Locales); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// CountMissing counts the coupons which are not in the trash and not translated in one of the locales.
+func (r *couponTranslationRepository) CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error) {
+	e := &entity.CouponTranslation{}
+	c := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT c.id)
+		FROM %s c
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.coupon_id = c.id AND t.locale = l.locale
+		WHERE c.deleted_at IS NULL
+		AND t.coupon_id IS NULL
+	`, c.TableName(), e.TableName())
+
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt, pq.StringArray(locales)).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total.Int64, nil
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"time"
 
 	"trintech/review/internal/coupon-management/entity"
 	repository "trintech/review/internal/coupon-management/repository"
@@ -22,6 +24,11 @@ func NewCouponRepository() repository.CouponRepository {
 
 // Create inserts a new coupon record into the database and returns the generated ID.
 func (r *couponRepository) Create(ctx context.Context, db database.Executor, data *entity.Coupon) (int64, error) {
+	// A new coupon starts at the first version.
+	if !data.Version.Valid {
+		data.Version = sql.NullInt64{Int64: 1, Valid: true}
+	}
+
 	// Get field names and values excluding the "id" field.
 	fieldNames, values := database.FieldMap(data)
 	fieldNames = fieldNames[1:]
@@ -42,15 +49,19 @@ func (r *couponRepository) Create(ctx context.Context, db database.Executor, dat
 	return id, nil
 }
 
-// DeleteByID deletes a coupon record from the database based on its ID.
-func (r *couponRepository) DeleteByID(ctx context.Context, db database.Executor, id int64) error {
+// DeleteByID moves a coupon record to the trash by setting its deletion timestamp.
+func (r *couponRepository) DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error {
 	e := &entity.Coupon{}
 	stmt := fmt.Sprintf(`
-		DELETE FROM %s
+		UPDATE %s
+		SET
+		deleted_at = NOW(),
+		deleted_by = $2
 		WHERE id = $1
+		AND deleted_at IS NULL
 	`, e.TableName())
 
-	result, err := db.ExecContext(ctx, stmt, &id)
+	result, err := db.ExecContext(ctx, stmt, &id, &deletedBy)
 	if err != nil {
 		return err
 	}
@@ -74,6 +85,7 @@ func (r *couponRepository) RetrieveByCode(ctx context.Context, db database.Execu
 		SELECT "%s"
 		FROM %s
 		WHERE code = $1
+		AND deleted_at IS NULL
 	`, strings.Join(fieldNames, "\",\""), e.TableName())
 
 	if err := db.QueryRowContext(ctx, stmt, &code).Scan(values...); err != nil {
@@ -82,3 +94,379 @@ func (r *couponRepository) RetrieveByCode(ctx context.Context, db database.Execu
 
 	return e, nil
 }
+
+// List retrieves the coupon records which are not in the trash matching a filter, the latest first.
+func (r *couponRepository) List(ctx context.Context, db database.Executor, filter *entity.CouponFilter, offset, limit int64) ([]*entity.Coupon, error) {
+	e := &entity.Coupon{}
+	fieldNames, _ := database.FieldMap(e)
+	where, args := couponConditions(filter)
+	stmt := fmt.Sprintf(`
+		SELECT "%s"
+		FROM %s
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		OFFSET $%d
+		LIMIT $%d
+	`, strings.Join(fieldNames, "\",\""), e.TableName(), where, len(args)+1, len(args)+2)
+
+	rows, err := db.QueryContext(ctx, stmt, append(args, &offset, &limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.Coupon
+	for rows.Next() {
+		var val entity.Coupon
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// Count counts the coupon records which are not in the trash matching a filter.
+func (r *couponRepository) Count(ctx context.Context, db database.Executor, filter *entity.CouponFilter) (int64, error) {
+	e := &entity.Coupon{}
+	where, args := couponConditions(filter)
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE %s
+	`, e.TableName(), where)
+	var total int64
+
+	if err := db.QueryRowContext(ctx, stmt, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// couponLikeEscaper escapes the wildcards of a LIKE pattern.
+var couponLikeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// couponConditions returns the WHERE conditions of a filter with their arguments.
+// A coupon is scheduled before its validity window, active within it and expired after it.
+func couponConditions(filter *entity.CouponFilter) (string, []any) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter == nil {
+		return strings.Join(conditions, " AND "), args
+	}
+
+	if filter.Code.Valid {
+		add("code ILIKE $%d", "%"+couponLikeEscaper.Replace(filter.Code.String)+"%")
+	}
+	if filter.Type.Valid {
+		add("coupon_type = $%d", filter.Type)
+	}
+	if filter.DiscountType.Valid {
+		add("discount_type = $%d", filter.DiscountType)
+	}
+	switch filter.State.String {
+	case entity.CouponState_Scheduled:
+		add(`"from" > $%d`, filter.Now)
+	case entity.CouponState_Active:
+		add(`"from" <= $%[1]d AND "to" >= $%[1]d`, filter.Now)
+	case entity.CouponState_Expired:
+		add(`"to" < $%d`, filter.Now)
+	}
+	c, uc, pc := &entity.Coupon{}, &entity.UserCoupon{}, &entity.ProductCoupon{}
+	if filter.UserID.Valid {
+		add(fmt.Sprintf("EXISTS (SELECT 1 FROM %s uc WHERE uc.coupon_id = %s.id AND uc.user_id = $%%d)", uc.TableName(), c.TableName()), filter.UserID)
+	}
+	if filter.ProductID.Valid {
+		add(fmt.Sprintf("EXISTS (SELECT 1 FROM %s pc WHERE pc.coupon_id = %s.id AND pc.product_id = $%%d)", pc.TableName(), c.TableName()), filter.ProductID)
+	}
+	if filter.CreatedBy.Valid {
+		add("created_by = $%d", filter.CreatedBy)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// RetrieveByID retrieves a coupon record which is not in the trash from the database based on its ID.
+func (r *couponRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Coupon, error) {
+	e := &entity.Coupon{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT "%s"
+		FROM %s
+		WHERE id = $1
+		AND deleted_at IS NULL
+	`, strings.Join(fieldNames, "\",\""), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// UpdateByID writes only the given columns of data, so a column can also be set to an empty value, and increments
+// the version of the coupon. With a version in data, it only updates that version of the coupon and returns
+// [database.ErrVersionMismatch] when the coupon has another version. A total below the current usage of the coupon
+// is not written and returns [entity.ErrCouponTotalBelowUsed].
+func (r *couponRepository) UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Coupon, columns []string) error {
+	fieldNames, values := database.SelectFieldMap(data, columns)
+	if len(fieldNames) == 0 {
+		return fmt.Errorf("no column to update")
+	}
+
+	// A total is never set below the current usage, which counts the usages of the pending reservations
+	var totalClause string
+	totalIdx := slices.Index(fieldNames, "total")
+	if totalIdx >= 0 {
+		totalClause = fmt.Sprintf("AND COALESCE(used, 0) <= $%d", totalIdx+3)
+	}
+
+	setClauses := []string{database.GetSetClauses(fieldNames, 2), "version = version + 1", "updated_at = NOW()"}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		%s
+		WHERE id = $1
+		AND deleted_at IS NULL
+		AND ($2::bigint IS NULL OR version = $2)
+		%s
+	`, data.TableName(), strings.Join(setClauses, ",\n\t\t"), totalClause)
+
+	result, err := db.ExecContext(ctx, stmt, append([]any{&id, &data.Version}, values...)...)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		if data.Version.Valid || totalIdx >= 0 {
+			return r.updateMismatch(ctx, db, id, data, totalIdx >= 0)
+		}
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// updateMismatch returns the error of an update which did not apply, sql.ErrNoRows when the coupon is not found,
+// [entity.ErrCouponTotalBelowUsed] when the updated total is below the usage of the coupon
+// and [database.ErrVersionMismatch] otherwise.
+func (r *couponRepository) updateMismatch(ctx context.Context, db database.Executor, id int64, data *entity.Coupon, withTotal bool) error {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		SELECT COALESCE(used, 0)
+		FROM %s
+		WHERE id = $1
+		AND deleted_at IS NULL
+	`, e.TableName())
+
+	var used int64
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(&used); err != nil {
+		return err
+	}
+	if withTotal && data.Total.Int64 < used {
+		return entity.ErrCouponTotalBelowUsed
+	}
+	if !data.Version.Valid {
+		return sql.ErrNoRows
+	}
+
+	return database.ErrVersionMismatch
+}
+
+// ListDeleted retrieves the coupon records in the trash, most recently deleted first.
+func (r *couponRepository) ListDeleted(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Coupon, error) {
+	e := &entity.Coupon{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT "%s"
+		FROM %s
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $1
+		OFFSET $2
+	`, strings.Join(fieldNames, "\",\""), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &limit, &offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var result []*entity.Coupon
+	for rows.Next() {
+		var val entity.Coupon
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, nil
+}
+
+// CountDeleted counts the coupon records in the trash.
+func (r *couponRepository) CountDeleted(ctx context.Context, db database.Executor) (int64, error) {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(1)
+		FROM %s
+		WHERE deleted_at IS NOT NULL
+	`, e.TableName())
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt).Scan(&total); err != nil {
+		return total.Int64, err
+	}
+
+	return total.Int64, nil
+}
+
+// RestoreByID clears the deletion timestamp of a coupon record in the trash.
+func (r *couponRepository) RestoreByID(ctx context.Context, db database.Executor, id int64) error {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		deleted_at = NULL,
+		deleted_by = NULL
+		WHERE id = $1
+		AND deleted_at IS NOT NULL
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// IncrementUsed increments the usage count of a coupon record in the database. The usage is not admin state, so the
+// version of the coupon is kept and the ETags taken by the admins stay valid across redemptions.
+// The condition is evaluated on the row it updates, so concurrent increments wait on each other and never go past the total.
+func (r *couponRepository) IncrementUsed(ctx context.Context, db database.Executor, id int64, withinTotal bool) error {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		used = COALESCE(used, 0) + 1
+		WHERE id = $1
+		AND (NOT $2 OR coupon_type <> 'CouponType_LIMITED' OR COALESCE(used, 0) < COALESCE(total, 0))
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &withinTotal)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DecrementUsed decrements the usage count of a coupon record in the database, keeping its version like [couponRepository.IncrementUsed].
+func (r *couponRepository) DecrementUsed(ctx context.Context, db database.Executor, id int64) error {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		used = GREATEST(COALESCE(used, 0) - 1, 0)
+		WHERE id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncrementVersionByID increments the version of a coupon record which is not in the trash.
+func (r *couponRepository) IncrementVersionByID(ctx context.Context, db database.Executor, id int64) error {
+	e := &entity.Coupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		version = version + 1,
+		updated_at = NOW()
+		WHERE id = $1
+		AND deleted_at IS NULL
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore hard-deletes the coupon records trashed before the given time, with their used coupon history
+// and their redemptions once they are settled: a coupon with a reserved redemption, or with a redemption or a use
+// changed since the given time, is kept in the trash until the retention has passed for them as well.
+func (r *couponRepository) PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error) {
+	e := &entity.Coupon{}
+	uE := &entity.UsedCoupon{}
+	rE := &entity.CouponRedemption{}
+	stmt := fmt.Sprintf(`
+		WITH purged AS (
+			SELECT c.id FROM %[1]s c
+			WHERE c.deleted_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM %[2]s uc WHERE uc.coupon_id = c.id AND uc.updated_at >= $1
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM %[3]s cr WHERE cr.coupon_id = c.id AND (cr.status = '%[4]s' OR cr.updated_at >= $1)
+			)
+			FOR UPDATE
+		), used AS (
+			DELETE FROM %[2]s uc USING purged p WHERE uc.coupon_id = p.id
+		), redemptions AS (
+			DELETE FROM %[3]s cr USING purged p WHERE cr.coupon_id = p.id
+		)
+		DELETE FROM %[1]s c USING purged p WHERE c.id = p.id
+	`, e.TableName(), uE.TableName(), rE.TableName(), entity.CouponRedemptionStatus_Reserved)
+
+	result, err := db.ExecContext(ctx, stmt, &before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -77,3 +77,62 @@ func (r *productCouponRepository) RetrieveByCouponID(ctx context.Context, db dat
 
 	return e, nil
 }
+
+// IncrementUsed increments the usage count of a product coupon record in the database.
+// The condition is evaluated on the row it updates, so concurrent increments wait on each other and never go past the total.
+func (r *productCouponRepository) IncrementUsed(ctx context.Context, db database.Executor, couponID int64, withinTotal bool) error {
+	e := &entity.ProductCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET used = COALESCE(used, 0) + 1
+		WHERE coupon_id = $1
+		AND (NOT $2 OR COALESCE(used, 0) < COALESCE(total, 0))
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &couponID, &withinTotal)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateTotalByCouponID updates the total of the product coupon records of a coupon in the database.
+func (r *productCouponRepository) UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error {
+	e := &entity.ProductCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET total = $2, updated_at = now()
+		WHERE coupon_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &couponID, &total); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DecrementUsed decrements the usage count of a product coupon record in the database.
+func (r *productCouponRepository) DecrementUsed(ctx context.Context, db database.Executor, couponID int64) error {
+	e := &entity.ProductCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET used = GREATEST(COALESCE(used, 0) - 1, 0)
+		WHERE coupon_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &couponID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lib/pq"
+
 	"trintech/review/internal/coupon-management/entity"
 	"trintech/review/internal/coupon-management/repository"
 	"trintech/review/pkg/database"
@@ -23,15 +25,16 @@ func (r *usedCouponRepository) ListUsedCouponByUserID(ctx context.Context, db da
 	e := &entity.UsedCoupon{}
 	cE := &entity.Coupon{}
 	fieldNames, _ := database.FieldMap(e)
+	cFieldNames, _ := database.FieldMap(cE)
 	stmt := fmt.Sprintf(`
-		SELECT uc.%s, c.%s
+		SELECT uc."%s", c."%s"
 		FROM %s uc
 		JOIN %s c
 		ON uc.coupon_id = c.id
-		WHERE user_id = $1
+		WHERE uc.user_id = $1
 	`,
-		strings.Join(fieldNames, ",uc."),
-		strings.Join(fieldNames, ",c."),
+		strings.Join(fieldNames, "\",uc.\""),
+		strings.Join(cFieldNames, "\",c.\""),
 		e.TableName(),
 		cE.TableName(),
 	)
@@ -85,3 +88,77 @@ func (r *usedCouponRepository) Create(ctx context.Context, db database.Executor,
 
 	return nil
 }
+
+// ListStatsByCouponIDs computes the number of used coupon records and of their distinct users by coupon.
+func (r *usedCouponRepository) ListStatsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponStats, error) {
+	e := &entity.UsedCoupon{}
+	stmt := fmt.Sprintf(`
+		SELECT coupon_id, COUNT(*), COUNT(DISTINCT user_id)
+		FROM %s
+		WHERE coupon_id = ANY($1)
+		GROUP BY coupon_id
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(couponIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.UsedCouponStats
+	for rows.Next() {
+		var val entity.UsedCouponStats
+		if err := rows.Scan(&val.CouponID, &val.Used, &val.UniqueUsers); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// ListDiscountsByCouponIDs sums the discounts of the used coupon records by coupon and currency.
+func (r *usedCouponRepository) ListDiscountsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponDiscount, error) {
+	e := &entity.UsedCoupon{}
+	stmt := fmt.Sprintf(`
+		SELECT coupon_id, currency, SUM(discount)
+		FROM %s
+		WHERE coupon_id = ANY($1)
+		AND discount IS NOT NULL
+		AND currency IS NOT NULL
+		GROUP BY coupon_id, currency
+		ORDER BY coupon_id, currency
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(couponIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.UsedCouponDiscount
+	for rows.Next() {
+		var val entity.UsedCouponDiscount
+		if err := rows.Scan(&val.CouponID, &val.Currency, &val.Amount); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteByRedemptionID deletes the used coupon record of a redemption from the database.
+func (r *usedCouponRepository) DeleteByRedemptionID(ctx context.Context, db database.Executor, redemptionID string) error {
+	e := &entity.UsedCoupon{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE redemption_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &redemptionID); err != nil {
+		return err
+	}
+
+	return nil
+}
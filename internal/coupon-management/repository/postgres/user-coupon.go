@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -55,3 +56,62 @@ func (r *userCouponRepository) RetrieveByCouponIDUserID(ctx context.Context, db
 
 	return e, nil
 }
+
+// IncrementUsed increments the usage count of a user coupon record in the database.
+// The condition is evaluated on the row it updates, so concurrent increments wait on each other and never go past the total.
+func (r *userCouponRepository) IncrementUsed(ctx context.Context, db database.Executor, couponID, userID int64, withinTotal bool) error {
+	e := &entity.UserCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET used = COALESCE(used, 0) + 1
+		WHERE coupon_id = $1 AND user_id = $2
+		AND (NOT $3 OR COALESCE(used, 0) < COALESCE(total, 0))
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &couponID, &userID, &withinTotal)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateTotalByCouponID updates the total of the user coupon records of a coupon in the database.
+func (r *userCouponRepository) UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error {
+	e := &entity.UserCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET total = $2, updated_at = now()
+		WHERE coupon_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &couponID, &total); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DecrementUsed decrements the usage count of a user coupon record in the database.
+func (r *userCouponRepository) DecrementUsed(ctx context.Context, db database.Executor, couponID, userID int64) error {
+	e := &entity.UserCoupon{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET used = GREATEST(COALESCE(used, 0) - 1, 0)
+		WHERE coupon_id = $1 AND user_id = $2
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &couponID, &userID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -17,4 +17,14 @@ type ProductCouponRepository interface {
 
 	// RetrieveByCouponID retrieves product-coupon associations by the specified coupon ID.
 	RetrieveByCouponID(ctx context.Context, db database.Executor, couponID int64) (*entity.ProductCoupon, error)
+
+	// IncrementUsed takes a usage of a product coupon. With withinTotal, the usage is only taken below its total,
+	// it returns sql.ErrNoRows when the product coupon is used up or not found.
+	IncrementUsed(ctx context.Context, db database.Executor, couponID int64, withinTotal bool) error
+
+	// UpdateTotalByCouponID changes the total of the product coupons of a coupon.
+	UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error
+
+	// DecrementUsed gives back a usage of a product coupon, the usage count never goes below zero.
+	DecrementUsed(ctx context.Context, db database.Executor, couponID int64) error
 }
@@ -14,4 +14,13 @@ type UsedCouponRepository interface {
 
 	// Create creates a new entry for a used coupon in the database.
 	Create(ctx context.Context, db database.Executor, data *entity.UsedCoupon) error
+
+	// ListStatsByCouponIDs computes the redemption statistics of coupons, the coupons without used coupons have none.
+	ListStatsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponStats, error)
+
+	// ListDiscountsByCouponIDs computes the total discount given by coupons by currency.
+	ListDiscountsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponDiscount, error)
+
+	// DeleteByRedemptionID deletes the used coupon entry of a redemption.
+	DeleteByRedemptionID(ctx context.Context, db database.Executor, redemptionID string) error
 }
@@ -17,4 +17,14 @@ type UserCouponRepository interface {
 
 	// RetrieveByCouponIDUserID retrieves a user coupon based on coupon ID and user ID.
 	RetrieveByCouponIDUserID(ctx context.Context, db database.Executor, couponID, userID int64) (*entity.UserCoupon, error)
+
+	// IncrementUsed takes a usage of a user coupon. With withinTotal, the usage is only taken below its total,
+	// it returns sql.ErrNoRows when the user coupon is used up or not found.
+	IncrementUsed(ctx context.Context, db database.Executor, couponID, userID int64, withinTotal bool) error
+
+	// UpdateTotalByCouponID changes the total of the user coupons of a coupon.
+	UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error
+
+	// DecrementUsed gives back a usage of a user coupon, the usage count never goes below zero.
+	DecrementUsed(ctx context.Context, db database.Executor, couponID, userID int64) error
 }
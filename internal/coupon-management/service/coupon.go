@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"slices"
 	"time"
 
@@ -19,48 +20,99 @@ import (
 	userEntity "trintech/review/internal/user-management/entity"
 	"trintech/review/pkg/crypto_util"
 	"trintech/review/pkg/database"
+	"trintech/review/pkg/eligibility"
 	"trintech/review/pkg/http_server"
 	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/money"
 	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/processor"
 )
 
 // couponService provides coupon handling operations.
 type couponService struct {
 	couponRepo interface {
 		Create(ctx context.Context, db database.Executor, data *entity.Coupon) (int64, error)
-		DeleteByID(ctx context.Context, db database.Executor, id int64) error
+		DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error
 		RetrieveByCode(ctx context.Context, db database.Executor, code string) (*entity.Coupon, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Coupon, error)
+		UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Coupon, columns []string) error
+		List(ctx context.Context, db database.Executor, filter *entity.CouponFilter, offset, limit int64) ([]*entity.Coupon, error)
+		Count(ctx context.Context, db database.Executor, filter *entity.CouponFilter) (int64, error)
+		ListDeleted(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Coupon, error)
+		CountDeleted(ctx context.Context, db database.Executor) (int64, error)
+		RestoreByID(ctx context.Context, db database.Executor, id int64) error
+		IncrementUsed(ctx context.Context, db database.Executor, id int64, withinTotal bool) error
+		DecrementUsed(ctx context.Context, db database.Executor, id int64) error
+		IncrementVersionByID(ctx context.Context, db database.Executor, id int64) error
 	}
 
 	userCouponRepo interface {
 		Create(ctx context.Context, db database.Executor, data *entity.UserCoupon) error
 		DeleteByCouponID(ctx context.Context, db database.Executor, id int64) error
 		RetrieveByCouponIDUserID(ctx context.Context, db database.Executor, couponID, userID int64) (*entity.UserCoupon, error)
+		IncrementUsed(ctx context.Context, db database.Executor, couponID, userID int64, withinTotal bool) error
+		DecrementUsed(ctx context.Context, db database.Executor, couponID, userID int64) error
+		UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error
 	}
 
 	productCouponRepo interface {
 		Create(ctx context.Context, db database.Executor, data *entity.ProductCoupon) error
 		DeleteByCouponID(ctx context.Context, db database.Executor, id int64) error
 		RetrieveByCouponID(ctx context.Context, db database.Executor, couponID int64) (*entity.ProductCoupon, error)
+		IncrementUsed(ctx context.Context, db database.Executor, couponID int64, withinTotal bool) error
+		DecrementUsed(ctx context.Context, db database.Executor, couponID int64) error
+		UpdateTotalByCouponID(ctx context.Context, db database.Executor, couponID, total int64) error
 	}
 
 	usedCouponRepo interface {
 		ListUsedCouponByUserID(ctx context.Context, db database.Executor, userID int64) ([]*entity.CouponUsedCoupon, error)
 		Create(ctx context.Context, db database.Executor, data *entity.UsedCoupon) error
+		ListStatsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponStats, error)
+		ListDiscountsByCouponIDs(ctx context.Context, db database.Executor, couponIDs []int64) ([]*entity.UsedCouponDiscount, error)
+		DeleteByRedemptionID(ctx context.Context, db database.Executor, redemptionID string) error
+	}
+
+	couponRedemptionRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.CouponRedemption) (bool, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id string) (*entity.CouponRedemption, error)
+		UpdateStatus(ctx context.Context, db database.Executor, id string, from []string, to string) error
+		ExpireReservedBefore(ctx context.Context, db database.Executor, before time.Time) ([]*entity.CouponRedemption, error)
+	}
+
+	couponTranslationRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.CouponTranslation) error
+		Delete(ctx context.Context, db database.Executor, couponID int64, locale string) error
+		ListByCouponID(ctx context.Context, db database.Executor, couponID int64, locales []string) ([]*entity.CouponTranslation, error)
+		ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCouponTranslation, error)
+		CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+	}
+
+	couponAuditRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.CouponAudit) error
+		ListByCouponID(ctx context.Context, db database.Executor, couponID, offset, limit int64) ([]*entity.CouponAudit, error)
+		CountByCouponID(ctx context.Context, db database.Executor, couponID int64) (int64, error)
 	}
 
 	db database.Database
 	pb.UnimplementedCouponServiceServer
+
+	// baseCurrency is the currency of the value discounts created without currency.
+	baseCurrency string
 }
 
 // NewCouponService returns coupon service that implements coupon handling operations.
-func NewCouponService(db database.Database) pb.CouponServiceServer {
+func NewCouponService(db database.Database, baseCurrency string) pb.CouponServiceServer {
 	return &couponService{
-		db:                db,
-		couponRepo:        postgres.NewCouponRepository(),
-		productCouponRepo: postgres.NewProductCouponRepository(),
-		userCouponRepo:    postgres.NewUserCouponRepository(),
-		usedCouponRepo:    postgres.NewUsedCouponRepository(),
+		db:                   db,
+		baseCurrency:         baseCurrency,
+		couponRepo:           postgres.NewCouponRepository(),
+		productCouponRepo:    postgres.NewProductCouponRepository(),
+		userCouponRepo:       postgres.NewUserCouponRepository(),
+		usedCouponRepo:       postgres.NewUsedCouponRepository(),
+		couponRedemptionRepo: postgres.NewCouponRedemptionRepository(),
+
+		couponTranslationRepo: postgres.NewCouponTranslationRepository(),
+		couponAuditRepo:       postgres.NewCouponAuditRepository(),
 	}
 }
 
@@ -94,6 +146,18 @@ func (s *couponService) CreateCoupon(ctx context.Context, req *pb.CreateCouponRe
 		return nil, err
 	}
 
+	// Validate the value of the discount
+	value, currency, err := s.couponValue(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the eligibility rules
+	rules, err := s.validCouponRules(req.GetRules())
+	if err != nil {
+		return nil, err
+	}
+
 	var id int64
 
 	// Start a database transaction
@@ -106,22 +170,27 @@ func (s *couponService) CreateCoupon(ctx context.Context, req *pb.CreateCouponRe
 			Description:  pg_util.NullString(req.GetDescription()),
 			ImageURL:     pg_util.NullString(req.GetImageUrl()),
 			CreatedBy:    pg_util.NullInt64(userCtx.UserID),
-			Value:        pg_util.NullFloat64(req.GetValue()),
+			Value:        value,
+			Currency:     currency,
 			Total:        pg_util.NullInt64(req.GetTotal()),
 			CreatedAt:    pg_util.NullTime(time.Now()),
 			Type:         pg_util.NullString(req.GetType().String()),
 			DiscountType: pg_util.NullString(req.GetDiscountType().String()),
+
+			AppliesToShipping: sql.NullBool{Bool: req.GetAppliesToShipping(), Valid: true},
+			Rules:             rules,
 		})
 		if err != nil {
 			return fmt.Errorf("unable to create coupon: %w", err)
 		}
 
-		// Depending on the ApplyId type, associate the coupon with a user or a product
+		// Depending on the ApplyId type, associate the coupon with a user or a product, which counts its usage within the total
 		switch req.GetApplyId().(type) {
 		case *pb.CreateCouponRequest_UserId:
 			if err := s.userCouponRepo.Create(ctx, tx, &entity.UserCoupon{
 				CouponID: pg_util.NullInt64(id),
 				UserID:   pg_util.NullInt64(req.GetUserId().GetValue()),
+				Total:    pg_util.NullInt64(req.GetTotal()),
 			}); err != nil {
 				return fmt.Errorf("unable to create user coupon: %w", err)
 			}
@@ -129,12 +198,14 @@ func (s *couponService) CreateCoupon(ctx context.Context, req *pb.CreateCouponRe
 			if err := s.productCouponRepo.Create(ctx, tx, &entity.ProductCoupon{
 				CouponID:  pg_util.NullInt64(id),
 				ProductID: pg_util.NullInt64(req.GetProductId().GetValue()),
+				Total:     pg_util.NullInt64(req.GetTotal()),
 			}); err != nil {
 				return fmt.Errorf("unable to create product coupon: %w", err)
 			}
 		}
 
-		return nil
+		// Record the creation in the audit trail of the coupon
+		return s.recordCouponAudit(ctx, tx, id, entity.CouponAuditAction_Created, sql.NullString{}, 1, userCtx.UserID)
 	}); err != nil {
 		// If there's an error during the transaction, return an internal server error
 		return nil, status.Errorf(codes.Internal, "unable to create coupon: %v", err)
@@ -147,16 +218,29 @@ func (s *couponService) CreateCoupon(ctx context.Context, req *pb.CreateCouponRe
 }
 
 // DeleteCouponByID is a method of the couponService that deletes a coupon based on the provided ID.
-// It checks if the user has admin privileges, and if so, it proceeds to delete the coupon.
+// It checks if the user has admin privileges, and if so, it moves the coupon to the trash.
+// The targeting rows of the coupon are kept so that a restored coupon behaves as before.
 func (s *couponService) DeleteCouponByID(ctx context.Context, req *pb.DeleteCouponByIDRequest) (*pb.DeleteCouponByIDResponse, error) {
 	// Check if the user is an admin
-	if _, err := validAdmin(ctx); err != nil {
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
 		// If not an admin, return the permission error
 		return nil, err
 	}
 
-	// Attempt to delete the coupon by ID using the coupon repository
-	if err := s.couponRepo.DeleteByID(ctx, s.db, req.GetId()); err != nil {
+	// Attempt to move the coupon to the trash by ID using the coupon repository, and record it in the audit trail
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.couponRepo.DeleteByID(ctx, tx, req.GetId(), userCtx.UserID); err != nil {
+			return err
+		}
+
+		return s.recordCouponAudit(ctx, tx, req.GetId(), entity.CouponAuditAction_Deleted, sql.NullString{}, 0, userCtx.UserID)
+	}); err != nil {
+		// If the coupon is not found, return a not found error
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "coupon not found")
+		}
+
 		// If there is an error during deletion, return an internal server error
 		return nil, status.Errorf(codes.Internal, "unable to delete coupon by id: %v", err.Error())
 	}
@@ -175,15 +259,27 @@ func (s *couponService) RetrieveCouponByCode(ctx context.Context, req *pb.Retrie
 		return nil, status.Errorf(codes.Internal, "unable to retrieve coupon by code: %v", err.Error())
 	}
 
+	// Decode the eligibility rules of the coupon
+	rules, err := couponRules(coupon)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err.Error())
+	}
+
 	// If CheckUse is requested, perform additional checks on the usability of the coupon
 	if req.GetCheckUse() {
 		now := time.Now()
 
+		// Check if the coupon is paused by an admin
+		if coupon.PausedAt.Valid {
+			return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_PAUSED, "coupon is paused"), nil
+		}
+
 		// Check if the current time is within the valid range of the coupon's From and To times
-		if coupon.From.Time.After(now) || coupon.To.Time.Before(now) {
-			return &pb.RetrieveCouponByCodeResponse{
-				CanUse: false,
-			}, nil
+		if coupon.From.Time.After(now) {
+			return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_NOT_STARTED, "coupon is not valid yet"), nil
+		}
+		if coupon.To.Time.Before(now) {
+			return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_EXPIRED, "coupon has expired"), nil
 		}
 
 		// Perform additional checks based on the coupon type
@@ -191,21 +287,17 @@ func (s *couponService) RetrieveCouponByCode(ctx context.Context, req *pb.Retrie
 		case pb.CouponType_CouponType_LIMITED.String():
 			// If the coupon type is LIMITED, check if it has reached its usage limit
 			if coupon.Used.Int64 >= coupon.Total.Int64 {
-				return &pb.RetrieveCouponByCodeResponse{
-					CanUse: false,
-				}, nil
+				return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED, "coupon has reached its usage limit"), nil
 			}
 		case pb.CouponType_CouponType_USER.String():
 			// If the coupon type is USER, retrieve user-specific coupon information
-			userCoupon, err := s.userCouponRepo.RetrieveByCouponIDUserID(ctx, s.db, coupon.ID.Int64, req.UserId.Value)
+			userCoupon, err := s.userCouponRepo.RetrieveByCouponIDUserID(ctx, s.db, coupon.ID.Int64, req.GetUserId().GetValue())
 			if err != nil {
 				return nil, status.Errorf(codes.Internal, "unable to retrieve user coupon: %v", err.Error())
 			}
 			// Check if the user-specific coupon has reached its usage limit
 			if userCoupon.Used.Int64 >= userCoupon.Total.Int64 {
-				return &pb.RetrieveCouponByCodeResponse{
-					CanUse: false,
-				}, nil
+				return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED, "coupon has reached its usage limit"), nil
 			}
 		case pb.CouponType_CouponType_PRODUCT.String():
 			// If the coupon type is PRODUCT, retrieve product-specific coupon information
@@ -215,15 +307,27 @@ func (s *couponService) RetrieveCouponByCode(ctx context.Context, req *pb.Retrie
 			}
 			// Check if the product-specific coupon has reached its usage limit
 			if productCoupon.Used.Int64 >= productCoupon.Total.Int64 {
-				return &pb.RetrieveCouponByCodeResponse{
-					CanUse: false,
-				}, nil
+				return cannotUseCoupon(pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED, "coupon has reached its usage limit"), nil
 			}
 		}
+
+		// Check if the order of the request satisfies the eligibility rules, the role is the role of the caller
+		order := eligibility.Order{
+			Time:          now,
+			OrderValue:    money.New(req.GetOrderValue().GetAmount(), req.GetOrderValue().GetCurrency()),
+			ProductTypes:  req.GetProductTypes(),
+			FirstPurchase: req.GetFirstPurchase(),
+		}
+		if userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx); ok {
+			order.UserRole = userCtx.Role
+		}
+		if failure := rules.Evaluate(order); failure != nil {
+			return cannotUseCoupon(couponUseReasons[failure.Reason], failure.Message), nil
+		}
 	}
 
 	// Return the retrieved coupon information in the response
-	return &pb.RetrieveCouponByCodeResponse{
+	resp := &pb.RetrieveCouponByCodeResponse{
 		Type:         new(pb.CouponType).FromString(coupon.Type.String),
 		Total:        coupon.Total.Int64,
 		From:         timestamppb.New(coupon.From.Time),
@@ -231,10 +335,68 @@ func (s *couponService) RetrieveCouponByCode(ctx context.Context, req *pb.Retrie
 		ImageUrl:     coupon.ImageURL.String,
 		Description:  coupon.Description.String,
 		DiscountType: new(pb.DiscountType).FromString(coupon.DiscountType.String),
-		Value:        coupon.Value.Float64,
 		Used:         coupon.Used.Int64,
 		CanUse:       true,
-	}, nil
+		Paused:       coupon.PausedAt.Valid,
+
+		AppliesToShipping: coupon.AppliesToShipping.Bool,
+		Version:           coupon.Version.Int64,
+		Rules:             toPbCouponRules(coupon, rules),
+	}
+	switch resp.DiscountType {
+	case pb.DiscountType_DiscountType_PERCENT:
+		resp.PercentBasisPoints = coupon.Value.Int64
+	case pb.DiscountType_DiscountType_VALUE:
+		resp.Value = &pb.Money{Amount: coupon.Value.Int64, Currency: coupon.Currency.String}
+	}
+
+	// Translate the description in the locale of the request
+	if err := s.localiseCoupon(ctx, coupon.ID.Int64, resp); err != nil {
+		return nil, err
+	}
+
+	// Send the version of the coupon as its ETag, unless the response depends on the usage of the user too
+	if !req.GetCheckUse() {
+		http_server.SetETag(ctx, coupon.Version.Int64)
+	}
+	return resp, nil
+}
+
+// cannotUseCoupon returns the response of a coupon which cannot be used with the reason.
+func cannotUseCoupon(reason pb.CouponUseReason, message string) *pb.RetrieveCouponByCodeResponse {
+	return &pb.RetrieveCouponByCodeResponse{
+		CanUse:        false,
+		Reason:        reason,
+		ReasonMessage: message,
+	}
+}
+
+// couponValue returns the value and currency to store for the discount of a coupon request:
+// the basis points of a percent discount, or the minor units and currency of a value discount.
+func (s *couponService) couponValue(req *pb.CreateCouponRequest) (sql.NullInt64, sql.NullString, error) {
+	switch req.GetDiscountType() {
+	case pb.DiscountType_DiscountType_PERCENT:
+		if bp := req.GetPercentBasisPoints(); bp <= 0 || bp > 10000 {
+			return sql.NullInt64{}, sql.NullString{}, status.Errorf(codes.InvalidArgument, "percent must be above 0 and at most 100%%")
+		}
+
+		return pg_util.NullInt64(req.GetPercentBasisPoints()), sql.NullString{}, nil
+	case pb.DiscountType_DiscountType_VALUE:
+		currency := req.GetValue().GetCurrency()
+		if currency == "" {
+			currency = s.baseCurrency
+		}
+		if !money.IsCurrency(currency) {
+			return sql.NullInt64{}, sql.NullString{}, status.Errorf(codes.InvalidArgument, "invalid currency")
+		}
+		if req.GetValue().GetAmount() <= 0 {
+			return sql.NullInt64{}, sql.NullString{}, status.Errorf(codes.InvalidArgument, "value must be positive")
+		}
+
+		return pg_util.NullInt64(req.GetValue().GetAmount()), pg_util.NullString(currency), nil
+	}
+
+	return sql.NullInt64{}, sql.NullString{}, nil
 }
 
 // ListUsedCoupon is a method of the couponService that retrieves a list of coupons used by the current user.
@@ -270,7 +432,7 @@ func (s *couponService) ListUsedCoupon(ctx context.Context, _ *pb.ListUsedCoupon
 
 // ApplyCoupon is a method of the couponService that applies a coupon for the current user.
 // It checks if the user has the necessary permissions and retrieves the coupon by its code.
-// If the coupon is found, it creates a record in the usedCoupon repository for the applied coupon.
+// If the coupon is found, it takes a usage of the coupon and creates a record in the usedCoupon repository for the applied coupon.
 func (s *couponService) ApplyCoupon(ctx context.Context, req *pb.ApplyCouponRequest) (*pb.ApplyCouponResponse, error) {
 	// Extract user information from the context
 	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
@@ -291,16 +453,130 @@ func (s *couponService) ApplyCoupon(ctx context.Context, req *pb.ApplyCouponRequ
 		return nil, status.Errorf(codes.Internal, "unable to retrieve coupon by code: %v", err.Error())
 	}
 
-	// Create a record in the usedCoupon repository for the applied coupon
-	if err := s.usedCouponRepo.Create(ctx, s.db, &entity.UsedCoupon{
-		CouponID:  coupon.ID,
-		UserID:    pg_util.NullInt64(userCtx.UserID),
-		CreatedAt: pg_util.NullTime(time.Now()),
+	// A paused coupon cannot be applied until it is resumed
+	if coupon.PausedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "coupon is paused")
+	}
+
+	// Take a usage of the coupon and create a record in the usedCoupon repository in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.takeCouponUsage(ctx, tx, coupon.ID.Int64, coupon.Type.String, userCtx.UserID, true); err != nil {
+			return err
+		}
+
+		if err := s.usedCouponRepo.Create(ctx, tx, &entity.UsedCoupon{
+			CouponID:  coupon.ID,
+			UserID:    pg_util.NullInt64(userCtx.UserID),
+			Type:      coupon.Type,
+			CreatedAt: pg_util.NullTime(time.Now()),
+		}); err != nil {
+			return fmt.Errorf("unable to create used coupon: %w", err)
+		}
+
+		return nil
 	}); err != nil {
-		// If there is an internal error during usedCoupon creation, return an internal server error
-		return nil, status.Errorf(codes.Internal, "unable to create used coupon: %v", err.Error())
+		// A coupon which is used up is a failed precondition, any other error is an internal server error
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to apply coupon: %v", err.Error())
 	}
 
 	// Return an empty response indicating successful coupon application
 	return &pb.ApplyCouponResponse{}, nil
 }
+
+// ListDeletedCoupon is a method of the couponService that retrieves the coupons in the trash.
+func (s *couponService) ListDeletedCoupon(ctx context.Context, req *pb.ListDeletedCouponRequest) (*pb.ListDeletedCouponResponse, error) {
+	// Check if the user is an admin
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the list of deleted coupons from the coupon repository
+	coupons, err := s.couponRepo.ListDeleted(ctx, s.db, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve deleted coupons: %v", err.Error())
+	}
+
+	// Prepare the response data with details of deleted coupons
+	respData := make([]*pb.ListDeletedCouponResponse_Coupon, 0, len(coupons))
+	for _, coupon := range coupons {
+		respData = append(respData, &pb.ListDeletedCouponResponse_Coupon{
+			Id:          coupon.ID.Int64,
+			Code:        coupon.Code.String,
+			Type:        new(pb.CouponType).FromString(coupon.Type.String),
+			Description: coupon.Description.String,
+			DeletedAt:   timestamppb.New(coupon.DeletedAt.Time),
+			DeletedBy:   coupon.DeletedBy.Int64,
+		})
+	}
+
+	// Get the total count of deleted coupons
+	total, err := s.couponRepo.CountDeleted(ctx, s.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count deleted coupons: %v", err.Error())
+	}
+
+	// Return the response with the list of deleted coupons
+	return &pb.ListDeletedCouponResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// RestoreCouponByID is a method of the couponService that restores a coupon from the trash.
+func (s *couponService) RestoreCouponByID(ctx context.Context, req *pb.RestoreCouponByIDRequest) (*pb.RestoreCouponByIDResponse, error) {
+	// Check if the user is an admin
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the coupon by ID using the coupon repository, and record it in the audit trail
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.couponRepo.RestoreByID(ctx, tx, req.GetId()); err != nil {
+			return err
+		}
+
+		return s.recordCouponAudit(ctx, tx, req.GetId(), entity.CouponAuditAction_Restored, sql.NullString{}, 0, userCtx.UserID)
+	}); err != nil {
+		// If the coupon is not in the trash, return a not found error
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "deleted coupon not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to restore coupon: %v", err.Error())
+	}
+
+	// Return an empty response indicating successful restoration
+	return &pb.RestoreCouponByIDResponse{}, nil
+}
+
+// NewCouponPurgeProcessor returns a processor which periodically hard-deletes
+// the coupons that stayed in the trash longer than the retention period.
+func NewCouponPurgeProcessor(db database.Database, retention time.Duration) processor.Processor {
+	couponRepo := postgres.NewCouponRepository()
+
+	return processor.NewIntervalProcessor("coupon-purge", time.Hour, func(ctx context.Context) error {
+		return purgeDeletedCoupons(ctx, db, couponRepo, retention)
+	})
+}
+
+// couponPurgeRepository is the repository of the coupons purged from the trash.
+type couponPurgeRepository interface {
+	PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error)
+}
+
+// purgeDeletedCoupons hard-deletes the coupons trashed before the retention period.
+func purgeDeletedCoupons(ctx context.Context, db database.Executor, couponRepo couponPurgeRepository, retention time.Duration) error {
+	purged, err := couponRepo.PurgeDeletedBefore(ctx, db, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("unable to purge deleted coupons: %w", err)
+	}
+
+	slog.Info("purged deleted coupons", "total", purged)
+
+	return nil
+}
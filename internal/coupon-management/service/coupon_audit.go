@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/pg_util"
+)
+
+// couponAuditActions maps the actions of the coupon audit trail to the actions of the API.
+var couponAuditActions = map[string]pb.CouponAuditAction{
+	entity.CouponAuditAction_Created:  pb.CouponAuditAction_CouponAuditAction_CREATED,
+	entity.CouponAuditAction_Updated:  pb.CouponAuditAction_CouponAuditAction_UPDATED,
+	entity.CouponAuditAction_Paused:   pb.CouponAuditAction_CouponAuditAction_PAUSED,
+	entity.CouponAuditAction_Resumed:  pb.CouponAuditAction_CouponAuditAction_RESUMED,
+	entity.CouponAuditAction_Deleted:  pb.CouponAuditAction_CouponAuditAction_DELETED,
+	entity.CouponAuditAction_Restored: pb.CouponAuditAction_CouponAuditAction_RESTORED,
+}
+
+// recordCouponAudit appends a change of a coupon by an admin to its audit trail, in the transaction of the change.
+// The version is the version of the coupon after the change, zero when the change keeps the version.
+func (s *couponService) recordCouponAudit(ctx context.Context, db database.Executor, couponID int64, action string, changes sql.NullString, version, actorID int64) error {
+	audit := &entity.CouponAudit{
+		CouponID: pg_util.NullInt64(couponID),
+		Action:   pg_util.NullString(action),
+		Changes:  changes,
+		ActorID:  pg_util.NullInt64(actorID),
+	}
+	if version > 0 {
+		audit.Version = pg_util.NullInt64(version)
+	}
+
+	if err := s.couponAuditRepo.Create(ctx, db, audit); err != nil {
+		return fmt.Errorf("unable to record coupon audit: %w", err)
+	}
+
+	return nil
+}
+
+// couponAuditChanges returns the JSON of the changes of the given columns from a coupon to its update,
+// the columns whose value is the same are left out and there is no JSON without change.
+func couponAuditChanges(before, after *entity.Coupon, columns []string) (sql.NullString, error) {
+	names, from := database.SelectFieldMap(before, columns)
+	_, to := database.SelectFieldMap(after, columns)
+
+	changes := make(map[string]entity.CouponAuditChange, len(names))
+	for i, name := range names {
+		f, err := from[i].(driver.Valuer).Value()
+		if err != nil {
+			return sql.NullString{}, err
+		}
+		t, err := to[i].(driver.Valuer).Value()
+		if err != nil {
+			return sql.NullString{}, err
+		}
+
+		if ft, ok := f.(time.Time); ok {
+			if tt, ok := t.(time.Time); ok && ft.Equal(tt) {
+				continue
+			}
+		}
+		if reflect.DeepEqual(f, t) {
+			continue
+		}
+
+		changes[name] = entity.CouponAuditChange{From: f, To: t}
+	}
+	if len(changes) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return pg_util.NullString(string(b)), nil
+}
+
+// ListCouponAudit is a method of the couponService that retrieves the audit trail of a coupon, the latest change first.
+func (s *couponService) ListCouponAudit(ctx context.Context, req *pb.ListCouponAuditRequest) (*pb.ListCouponAuditResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// List the audit trail with its total count
+	audits, err := s.couponAuditRepo.ListByCouponID(ctx, s.db, req.GetCouponId(), req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list coupon audit: %v", err.Error())
+	}
+	total, err := s.couponAuditRepo.CountByCouponID(ctx, s.db, req.GetCouponId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count coupon audit: %v", err.Error())
+	}
+
+	data := make([]*pb.CouponAudit, 0, len(audits))
+	for _, audit := range audits {
+		item, err := toPbCouponAudit(audit)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%v", err.Error())
+		}
+		data = append(data, item)
+	}
+
+	return &pb.ListCouponAuditResponse{
+		Data:  data,
+		Total: total,
+	}, nil
+}
+
+// toPbCouponAudit converts an audit entry to the API, its changes are sorted by field.
+func toPbCouponAudit(audit *entity.CouponAudit) (*pb.CouponAudit, error) {
+	result := &pb.CouponAudit{
+		Id:        audit.ID.Int64,
+		Action:    couponAuditActions[audit.Action.String],
+		Version:   audit.Version.Int64,
+		ActorId:   audit.ActorID.Int64,
+		CreatedAt: timestamppb.New(audit.CreatedAt.Time),
+	}
+	if !audit.Changes.Valid {
+		return result, nil
+	}
+
+	var changes map[string]struct {
+		From json.RawMessage `json:"from"`
+		To   json.RawMessage `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(audit.Changes.String), &changes); err != nil {
+		return nil, fmt.Errorf("invalid coupon audit changes: %w", err)
+	}
+	for field, change := range changes {
+		result.Changes = append(result.Changes, &pb.CouponAudit_Change{
+			Field: field,
+			From:  string(change.From),
+			To:    string(change.To),
+		})
+	}
+	slices.SortFunc(result.Changes, func(a, b *pb.CouponAudit_Change) int { return strings.Compare(a.Field, b.Field) })
+
+	return result, nil
+}
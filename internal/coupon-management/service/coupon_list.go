@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/pg_util"
+)
+
+// couponStates maps the coupon states of the API to the states of the coupon filter.
+var couponStates = map[pb.CouponState]string{
+	pb.CouponState_CouponState_SCHEDULED: entity.CouponState_Scheduled,
+	pb.CouponState_CouponState_ACTIVE:    entity.CouponState_Active,
+	pb.CouponState_CouponState_EXPIRED:   entity.CouponState_Expired,
+}
+
+// ListCoupons is a method of the couponService that lists and searches the coupons which are not in the trash,
+// with their redemption statistics computed from the used coupons.
+func (s *couponService) ListCoupons(ctx context.Context, req *pb.ListCouponsRequest) (*pb.ListCouponsResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Build the filter of the request, the zero values don't filter
+	filter := &entity.CouponFilter{Now: time.Now()}
+	if req.GetUserId() != nil {
+		filter.UserID = pg_util.NullInt64(req.GetUserId().GetValue())
+	}
+	if req.GetProductId() != nil {
+		filter.ProductID = pg_util.NullInt64(req.GetProductId().GetValue())
+	}
+	if req.GetCreatedBy() != nil {
+		filter.CreatedBy = pg_util.NullInt64(req.GetCreatedBy().GetValue())
+	}
+	if code := strings.TrimSpace(req.GetCode()); code != "" {
+		filter.Code = pg_util.NullString(code)
+	}
+	if req.GetType() != pb.CouponType_CouponType_NONE {
+		filter.Type = pg_util.NullString(req.GetType().String())
+	}
+	if req.GetDiscountType() != pb.DiscountType_DiscountType_NONE {
+		filter.DiscountType = pg_util.NullString(req.GetDiscountType().String())
+	}
+	if req.GetState() != pb.CouponState_CouponState_NONE {
+		state, ok := couponStates[req.GetState()]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid state")
+		}
+		filter.State = pg_util.NullString(state)
+	}
+
+	// List the coupons with their total count
+	coupons, err := s.couponRepo.List(ctx, s.db, filter, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list coupons: %v", err.Error())
+	}
+	total, err := s.couponRepo.Count(ctx, s.db, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count coupons: %v", err.Error())
+	}
+
+	// Compute the redemption statistics of the listed coupons
+	data := make([]*pb.ListCouponsResponse_Coupon, 0, len(coupons))
+	byID := make(map[int64]*pb.ListCouponsResponse_Coupon, len(coupons))
+	ids := make([]int64, 0, len(coupons))
+	for _, coupon := range coupons {
+		c := toPbListedCoupon(coupon, filter.Now)
+		data = append(data, c)
+		byID[coupon.ID.Int64] = c
+		ids = append(ids, coupon.ID.Int64)
+	}
+	if len(ids) > 0 {
+		stats, err := s.usedCouponRepo.ListStatsByCouponIDs(ctx, s.db, ids)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to compute coupon stats: %v", err.Error())
+		}
+		for _, st := range stats {
+			if c, ok := byID[st.CouponID]; ok {
+				c.Stats.Used = st.Used
+				c.Stats.Remaining = max(c.GetTotal()-st.Used, 0)
+				c.Stats.UniqueUsers = st.UniqueUsers
+			}
+		}
+
+		discounts, err := s.usedCouponRepo.ListDiscountsByCouponIDs(ctx, s.db, ids)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to compute coupon discounts: %v", err.Error())
+		}
+		for _, d := range discounts {
+			if c, ok := byID[d.CouponID]; ok {
+				c.Stats.TotalDiscount = append(c.Stats.TotalDiscount, &pb.Money{Amount: d.Amount, Currency: d.Currency})
+			}
+		}
+	}
+
+	return &pb.ListCouponsResponse{
+		Data:  data,
+		Total: total,
+	}, nil
+}
+
+// toPbListedCoupon converts a coupon to a listed coupon in its state at a time, its statistics are those of an unused coupon.
+func toPbListedCoupon(coupon *entity.Coupon, now time.Time) *pb.ListCouponsResponse_Coupon {
+	c := &pb.ListCouponsResponse_Coupon{
+		Id:           coupon.ID.Int64,
+		Code:         coupon.Code.String,
+		Type:         new(pb.CouponType).FromString(coupon.Type.String),
+		DiscountType: new(pb.DiscountType).FromString(coupon.DiscountType.String),
+		Total:        coupon.Total.Int64,
+		From:         timestamppb.New(coupon.From.Time),
+		To:           timestamppb.New(coupon.To.Time),
+		State:        pb.CouponState_CouponState_ACTIVE,
+		Paused:       coupon.PausedAt.Valid,
+		Description:  coupon.Description.String,
+		CreatedBy:    coupon.CreatedBy.Int64,
+		CreatedAt:    timestamppb.New(coupon.CreatedAt.Time),
+		Stats:        &pb.CouponStats{Remaining: coupon.Total.Int64},
+	}
+	switch {
+	case coupon.From.Time.After(now):
+		c.State = pb.CouponState_CouponState_SCHEDULED
+	case coupon.To.Time.Before(now):
+		c.State = pb.CouponState_CouponState_EXPIRED
+	}
+	switch c.DiscountType {
+	case pb.DiscountType_DiscountType_PERCENT:
+		c.PercentBasisPoints = coupon.Value.Int64
+	case pb.DiscountType_DiscountType_VALUE:
+		c.Value = &pb.Money{Amount: coupon.Value.Int64, Currency: coupon.Currency.String}
+	}
+
+	return c
+}
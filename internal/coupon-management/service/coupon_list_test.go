@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_couponService_ListCoupons(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 9, Role: userEntity.UserRole_Admin}))
+	now := time.Now()
+	limited := &entity.Coupon{
+		ID:           pg_util.NullInt64(3),
+		Code:         pg_util.NullString("COUPON-ABC"),
+		Type:         pg_util.NullString(pb.CouponType_CouponType_LIMITED.String()),
+		DiscountType: pg_util.NullString(pb.DiscountType_DiscountType_PERCENT.String()),
+		Value:        pg_util.NullInt64(1050),
+		Total:        pg_util.NullInt64(10),
+		From:         pg_util.NullTime(now.Add(-time.Hour)),
+		To:           pg_util.NullTime(now.Add(time.Hour)),
+		CreatedBy:    pg_util.NullInt64(9),
+	}
+	scheduled := &entity.Coupon{
+		ID:           pg_util.NullInt64(4),
+		Code:         pg_util.NullString("COUPON-XYZ"),
+		Type:         pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+		DiscountType: pg_util.NullString(pb.DiscountType_DiscountType_VALUE.String()),
+		Value:        pg_util.NullInt64(500),
+		Currency:     pg_util.NullString("USD"),
+		Total:        pg_util.NullInt64(1),
+		From:         pg_util.NullTime(now.Add(time.Hour)),
+		To:           pg_util.NullTime(now.Add(2 * time.Hour)),
+		CreatedBy:    pg_util.NullInt64(9),
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.ListCouponsRequest
+		want    []*pb.ListCouponsResponse_Coupon
+		wantErr error
+		setup   func(couponRepo *mocks.CouponRepository, usedCouponRepo *mocks.UsedCouponRepository)
+	}{
+		{
+			name: "happy case with stats",
+			ctx:  adminCtx,
+			req:  &pb.ListCouponsRequest{Code: " coupon ", Limit: 10},
+			want: []*pb.ListCouponsResponse_Coupon{
+				{
+					Id:                 3,
+					Code:               "COUPON-ABC",
+					Type:               pb.CouponType_CouponType_LIMITED,
+					DiscountType:       pb.DiscountType_DiscountType_PERCENT,
+					PercentBasisPoints: 1050,
+					Total:              10,
+					State:              pb.CouponState_CouponState_ACTIVE,
+					CreatedBy:          9,
+					Stats: &pb.CouponStats{
+						Used:          4,
+						Remaining:     6,
+						UniqueUsers:   3,
+						TotalDiscount: []*pb.Money{{Amount: 900, Currency: "EUR"}, {Amount: 1200, Currency: "USD"}},
+					},
+				},
+				{
+					Id:           4,
+					Code:         "COUPON-XYZ",
+					Type:         pb.CouponType_CouponType_USER,
+					DiscountType: pb.DiscountType_DiscountType_VALUE,
+					Value:        &pb.Money{Amount: 500, Currency: "USD"},
+					Total:        1,
+					State:        pb.CouponState_CouponState_SCHEDULED,
+					CreatedBy:    9,
+					Stats:        &pb.CouponStats{Remaining: 1},
+				},
+			},
+			setup: func(couponRepo *mocks.CouponRepository, usedCouponRepo *mocks.UsedCouponRepository) {
+				filter := mock.MatchedBy(func(f *entity.CouponFilter) bool {
+					return f.Code == pg_util.NullString("coupon") && !f.Type.Valid && !f.State.Valid
+				})
+				couponRepo.On("List", mock.Anything, mock.Anything, filter, int64(0), int64(10)).Return([]*entity.Coupon{limited, scheduled}, nil)
+				couponRepo.On("Count", mock.Anything, mock.Anything, filter).Return(int64(2), nil)
+				usedCouponRepo.On("ListStatsByCouponIDs", mock.Anything, mock.Anything, []int64{3, 4}).Return([]*entity.UsedCouponStats{
+					{CouponID: 3, Used: 4, UniqueUsers: 3},
+				}, nil)
+				usedCouponRepo.On("ListDiscountsByCouponIDs", mock.Anything, mock.Anything, []int64{3, 4}).Return([]*entity.UsedCouponDiscount{
+					{CouponID: 3, Currency: "EUR", Amount: 900},
+					{CouponID: 3, Currency: "USD", Amount: 1200},
+				}, nil)
+			},
+		},
+		{
+			name: "happy case filters",
+			ctx:  adminCtx,
+			req: &pb.ListCouponsRequest{
+				Type:         pb.CouponType_CouponType_USER,
+				DiscountType: pb.DiscountType_DiscountType_VALUE,
+				State:        pb.CouponState_CouponState_EXPIRED,
+				UserId:       wrapperspb.Int64(1),
+				CreatedBy:    wrapperspb.Int64(9),
+			},
+			want: []*pb.ListCouponsResponse_Coupon{},
+			setup: func(couponRepo *mocks.CouponRepository, usedCouponRepo *mocks.UsedCouponRepository) {
+				filter := mock.MatchedBy(func(f *entity.CouponFilter) bool {
+					return f.Type == pg_util.NullString(pb.CouponType_CouponType_USER.String()) &&
+						f.DiscountType == pg_util.NullString(pb.DiscountType_DiscountType_VALUE.String()) &&
+						f.State == pg_util.NullString(entity.CouponState_Expired) &&
+						f.UserID == pg_util.NullInt64(1) && !f.ProductID.Valid && f.CreatedBy == pg_util.NullInt64(9)
+				})
+				couponRepo.On("List", mock.Anything, mock.Anything, filter, int64(0), int64(0)).Return(nil, nil)
+				couponRepo.On("Count", mock.Anything, mock.Anything, filter).Return(int64(0), nil)
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.ListCouponsRequest{},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(couponRepo *mocks.CouponRepository, usedCouponRepo *mocks.UsedCouponRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			couponRepo := &mocks.CouponRepository{}
+			usedCouponRepo := &mocks.UsedCouponRepository{}
+			tt.setup(couponRepo, usedCouponRepo)
+			s := &couponService{
+				couponRepo:     couponRepo,
+				usedCouponRepo: usedCouponRepo,
+			}
+			got, err := s.ListCoupons(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got.GetData(), len(tt.want))
+			for i, want := range tt.want {
+				c := got.GetData()[i]
+				c.From, c.To, c.CreatedAt = nil, nil, nil
+				require.Equal(t, want, c)
+			}
+			require.Equal(t, int64(len(tt.want)), got.GetTotal())
+			couponRepo.AssertExpectations(t)
+			usedCouponRepo.AssertExpectations(t)
+		})
+	}
+}
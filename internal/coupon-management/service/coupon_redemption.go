@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository/postgres"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/processor"
+)
+
+// couponReservationTTL is how long a reservation waits for its confirmation or release before it expires.
+const couponReservationTTL = 15 * time.Minute
+
+// ReserveCoupon is a method of the couponService that reserves a coupon for a redemption, the first step of the redemption saga.
+// The reservation takes a usage of the coupon, so that a used up coupon fails before the purchase is committed.
+// A retry of the reservation succeeds, unless the redemption is released.
+func (s *couponService) ReserveCoupon(ctx context.Context, req *pb.ReserveCouponRequest) (*pb.ReserveCouponResponse, error) {
+	// Validate the redemption
+	if req.GetRedemptionId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "redemption_id is required")
+	}
+
+	// Retrieve the coupon by its code
+	coupon, err := s.couponRepo.RetrieveByCode(ctx, s.db, req.GetCode())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "coupon not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve coupon by code: %v", err.Error())
+	}
+
+	// Reserve the coupon and take its usage in a database transaction, unless the redemption exists
+	var created bool
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		created, err = s.couponRedemptionRepo.Create(ctx, tx, &entity.CouponRedemption{
+			ID:         pg_util.NullString(req.GetRedemptionId()),
+			CouponID:   coupon.ID,
+			CouponType: coupon.Type,
+			UserID:     pg_util.NullInt64(req.GetUserId()),
+			Status:     pg_util.NullString(entity.CouponRedemptionStatus_Reserved),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create coupon redemption: %w", err)
+		}
+		if !created {
+			return nil
+		}
+
+		// A paused coupon cannot be reserved until it is resumed
+		if coupon.PausedAt.Valid {
+			return status.Errorf(codes.FailedPrecondition, "coupon is paused")
+		}
+
+		return s.takeCouponUsage(ctx, tx, coupon.ID.Int64, coupon.Type.String, req.GetUserId(), true)
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to reserve coupon: %v", err.Error())
+	}
+	if created {
+		return &pb.ReserveCouponResponse{}, nil
+	}
+
+	// Check the existing redemption is the same reservation
+	redemption, err := s.couponRedemptionRepo.RetrieveByID(ctx, s.db, req.GetRedemptionId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve coupon redemption: %v", err.Error())
+	}
+	switch {
+	case redemption.Status.String == entity.CouponRedemptionStatus_Released:
+		return nil, status.Errorf(codes.FailedPrecondition, "coupon redemption is released")
+	case redemption.CouponID.Int64 != coupon.ID.Int64:
+		return nil, status.Errorf(codes.AlreadyExists, "coupon redemption is used by another coupon")
+	}
+
+	return &pb.ReserveCouponResponse{}, nil
+}
+
+// ConfirmCoupon is a method of the couponService that records the usage of a reserved coupon and the discount it gave,
+// once its purchase is committed.
+// A retry of the confirmation succeeds. An expired reservation can still be confirmed, it takes back the usage
+// it gave back, even past the total of the coupon since the purchase is committed already.
+func (s *couponService) ConfirmCoupon(ctx context.Context, req *pb.ConfirmCouponRequest) (*pb.ConfirmCouponResponse, error) {
+	// Record the discount given in a currency, an invalid discount is not recorded rather than failing the saga step
+	var (
+		discount sql.NullInt64
+		currency sql.NullString
+	)
+	if d := req.GetDiscount(); money.IsCurrency(d.GetCurrency()) && d.GetAmount() >= 0 {
+		discount, currency = pg_util.NullInt64(d.GetAmount()), pg_util.NullString(d.GetCurrency())
+	}
+
+	// Confirm the reservation and count the usage in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		redemption, err := s.couponRedemptionRepo.RetrieveByID(ctx, tx, req.GetRedemptionId())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "coupon redemption not found")
+		case err != nil:
+			return fmt.Errorf("unable to retrieve coupon redemption: %w", err)
+		}
+
+		switch redemption.Status.String {
+		case entity.CouponRedemptionStatus_Confirmed, entity.CouponRedemptionStatus_Restored:
+			return nil
+		case entity.CouponRedemptionStatus_Released:
+			return status.Errorf(codes.FailedPrecondition, "coupon redemption is released")
+		}
+
+		// The update fails when the status changed since it was read, by the expiry of the reservation
+		if err := s.couponRedemptionRepo.UpdateStatus(ctx, tx, req.GetRedemptionId(), []string{
+			redemption.Status.String,
+		}, entity.CouponRedemptionStatus_Confirmed); err != nil {
+			return fmt.Errorf("unable to confirm coupon redemption: %w", err)
+		}
+
+		if redemption.Status.String == entity.CouponRedemptionStatus_Expired && redemption.CouponType.Valid {
+			if err := s.takeCouponUsage(ctx, tx, redemption.CouponID.Int64, redemption.CouponType.String, redemption.UserID.Int64, false); err != nil {
+				return err
+			}
+		}
+
+		if err := s.usedCouponRepo.Create(ctx, tx, &entity.UsedCoupon{
+			CouponID:     redemption.CouponID,
+			UserID:       redemption.UserID,
+			Type:         redemption.CouponType,
+			CreatedAt:    pg_util.NullTime(time.Now()),
+			RedemptionID: redemption.ID,
+			Discount:     discount,
+			Currency:     currency,
+		}); err != nil {
+			return fmt.Errorf("unable to create used coupon: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to confirm coupon: %v", err.Error())
+	}
+
+	return &pb.ConfirmCouponResponse{}, nil
+}
+
+// ReleaseCoupon is a method of the couponService that releases the reservation of a failed purchase, the compensation of the saga.
+// The release gives back the usage of the reservation, unless it expired and gave it back already.
+// A redemption released before its reservation is recorded, so that the late reservation fails.
+func (s *couponService) ReleaseCoupon(ctx context.Context, req *pb.ReleaseCouponRequest) (*pb.ReleaseCouponResponse, error) {
+	// Validate the redemption
+	if req.GetRedemptionId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "redemption_id is required")
+	}
+
+	// Record the release of a redemption which is not reserved yet
+	created, err := s.couponRedemptionRepo.Create(ctx, s.db, &entity.CouponRedemption{
+		ID:     pg_util.NullString(req.GetRedemptionId()),
+		Status: pg_util.NullString(entity.CouponRedemptionStatus_Released),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to release coupon: %v", err.Error())
+	}
+	if created {
+		return &pb.ReleaseCouponResponse{}, nil
+	}
+
+	// Release the reservation and give back its usage in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		redemption, err := s.couponRedemptionRepo.RetrieveByID(ctx, tx, req.GetRedemptionId())
+		if err != nil {
+			return fmt.Errorf("unable to retrieve coupon redemption: %w", err)
+		}
+
+		switch redemption.Status.String {
+		case entity.CouponRedemptionStatus_Released, entity.CouponRedemptionStatus_Restored:
+			return nil
+		case entity.CouponRedemptionStatus_Confirmed:
+			return status.Errorf(codes.FailedPrecondition, "coupon redemption is confirmed")
+		}
+
+		// The update fails when the status changed since it was read, by the expiry of the reservation
+		if err := s.couponRedemptionRepo.UpdateStatus(ctx, tx, req.GetRedemptionId(), []string{
+			redemption.Status.String,
+		}, entity.CouponRedemptionStatus_Released); err != nil {
+			return fmt.Errorf("unable to release coupon redemption: %w", err)
+		}
+
+		if redemption.Status.String == entity.CouponRedemptionStatus_Reserved {
+			return s.giveBackCouponUsage(ctx, tx, redemption)
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to release coupon: %v", err.Error())
+	}
+
+	return &pb.ReleaseCouponResponse{}, nil
+}
+
+// RestoreCoupon is a method of the couponService that gives back the usage of a confirmed redemption whose purchase is refunded.
+// A retry of the restoration succeeds.
+func (s *couponService) RestoreCoupon(ctx context.Context, req *pb.RestoreCouponRequest) (*pb.RestoreCouponResponse, error) {
+	// Validate the redemption
+	if req.GetRedemptionId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "redemption_id is required")
+	}
+
+	// Restore the redemption and give back the usage in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		redemption, err := s.couponRedemptionRepo.RetrieveByID(ctx, tx, req.GetRedemptionId())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "coupon redemption not found")
+		case err != nil:
+			return fmt.Errorf("unable to retrieve coupon redemption: %w", err)
+		}
+
+		switch redemption.Status.String {
+		case entity.CouponRedemptionStatus_Restored:
+			return nil
+		case entity.CouponRedemptionStatus_Confirmed:
+		default:
+			return status.Errorf(codes.FailedPrecondition, "coupon redemption is not confirmed")
+		}
+
+		if err := s.couponRedemptionRepo.UpdateStatus(ctx, tx, req.GetRedemptionId(), []string{
+			entity.CouponRedemptionStatus_Confirmed,
+		}, entity.CouponRedemptionStatus_Restored); err != nil {
+			return fmt.Errorf("unable to restore coupon redemption: %w", err)
+		}
+
+		if err := s.usedCouponRepo.DeleteByRedemptionID(ctx, tx, req.GetRedemptionId()); err != nil {
+			return fmt.Errorf("unable to delete used coupon: %w", err)
+		}
+
+		return s.giveBackCouponUsage(ctx, tx, redemption)
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to restore coupon: %v", err.Error())
+	}
+
+	return &pb.RestoreCouponResponse{}, nil
+}
+
+// NewCouponReservationExpiryProcessor returns a processor which periodically expires
+// the reservations that were neither confirmed nor released, after a crash of their purchase.
+// The usage of the expired reservations is given back.
+func NewCouponReservationExpiryProcessor(db database.Database) processor.Processor {
+	s := &couponService{
+		couponRepo:           postgres.NewCouponRepository(),
+		userCouponRepo:       postgres.NewUserCouponRepository(),
+		productCouponRepo:    postgres.NewProductCouponRepository(),
+		couponRedemptionRepo: postgres.NewCouponRedemptionRepository(),
+		db:                   db,
+	}
+
+	return processor.NewIntervalProcessor("coupon-reservation-expiry", time.Minute, func(ctx context.Context) error {
+		expired, err := s.expireCouponReservations(ctx, time.Now().Add(-couponReservationTTL))
+		if err != nil {
+			return err
+		}
+
+		slog.Info("expired coupon reservations", "total", expired)
+
+		return nil
+	})
+}
+
+// expireCouponReservations expires the reservations created before a time and gives back their usage
+// in a database transaction, it returns the number of expired reservations.
+func (s *couponService) expireCouponReservations(ctx context.Context, before time.Time) (int, error) {
+	var expired []*entity.CouponRedemption
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		expired, err = s.couponRedemptionRepo.ExpireReservedBefore(ctx, tx, before)
+		if err != nil {
+			return fmt.Errorf("unable to expire coupon reservations: %w", err)
+		}
+
+		for _, redemption := range expired {
+			if err := s.giveBackCouponUsage(ctx, tx, redemption); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return len(expired), nil
+}
+
+// takeCouponUsage takes a usage of a coupon, and of the user or product coupon of its type, with conditional updates.
+// The updates lock the counters until the end of the transaction, so that concurrent redemptions never take more
+// usages than the totals. Without withinTotal, the usage is taken past the totals.
+func (s *couponService) takeCouponUsage(ctx context.Context, db database.Executor, couponID int64, couponType string, userID int64, withinTotal bool) error {
+	err := s.couponRepo.IncrementUsed(ctx, db, couponID, withinTotal)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Errorf(codes.FailedPrecondition, "coupon has reached its usage limit")
+	case err != nil:
+		return fmt.Errorf("unable to take coupon usage: %w", err)
+	}
+
+	switch couponType {
+	case pb.CouponType_CouponType_USER.String():
+		err = s.userCouponRepo.IncrementUsed(ctx, db, couponID, userID, withinTotal)
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.Errorf(codes.FailedPrecondition, "coupon has reached its usage limit for the user")
+		}
+	case pb.CouponType_CouponType_PRODUCT.String():
+		err = s.productCouponRepo.IncrementUsed(ctx, db, couponID, withinTotal)
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.Errorf(codes.FailedPrecondition, "coupon has reached its usage limit")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to take coupon usage: %w", err)
+	}
+
+	return nil
+}
+
+// giveBackCouponUsage gives back the usage taken by a redemption. The redemptions reserved before the usage
+// was taken have no coupon type and nothing to give back.
+func (s *couponService) giveBackCouponUsage(ctx context.Context, db database.Executor, redemption *entity.CouponRedemption) error {
+	if !redemption.CouponType.Valid {
+		return nil
+	}
+
+	couponID := redemption.CouponID.Int64
+	if err := s.couponRepo.DecrementUsed(ctx, db, couponID); err != nil {
+		return fmt.Errorf("unable to give back coupon usage: %w", err)
+	}
+
+	switch redemption.CouponType.String {
+	case pb.CouponType_CouponType_USER.String():
+		if err := s.userCouponRepo.DecrementUsed(ctx, db, couponID, redemption.UserID.Int64); err != nil {
+			return fmt.Errorf("unable to give back user coupon usage: %w", err)
+		}
+	case pb.CouponType_CouponType_PRODUCT.String():
+		if err := s.productCouponRepo.DecrementUsed(ctx, db, couponID); err != nil {
+			return fmt.Errorf("unable to give back product coupon usage: %w", err)
+		}
+	}
+
+	return nil
+}
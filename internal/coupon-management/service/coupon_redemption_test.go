@@ -0,0 +1,456 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository/postgres"
+	"trintech/review/mocks"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_couponService_ReserveCoupon(t *testing.T) {
+	coupon := &entity.Coupon{
+		ID:    pg_util.NullInt64(3),
+		Code:  pg_util.NullString("ABC"),
+		Type:  pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+		Total: pg_util.NullInt64(1),
+	}
+	tests := []struct {
+		name    string
+		req     *pb.ReserveCouponRequest
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case",
+			req:  &pb.ReserveCouponRequest{RedemptionId: "r1", Code: "ABC", UserId: 1},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByCode", mock.Anything, mock.Anything, "ABC").Return(coupon, nil)
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("Create", mock.Anything, mock.Anything, &entity.CouponRedemption{
+					ID:         pg_util.NullString("r1"),
+					CouponID:   pg_util.NullInt64(3),
+					CouponType: pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+					UserID:     pg_util.NullInt64(1),
+					Status:     pg_util.NullString(entity.CouponRedemptionStatus_Reserved),
+				}).Return(true, nil)
+				s.couponRepo.(*mocks.CouponRepository).On("IncrementUsed", mock.Anything, mock.Anything, int64(3), true).Return(nil)
+				s.userCouponRepo.(*mocks.UserCouponRepository).On("IncrementUsed", mock.Anything, mock.Anything, int64(3), int64(1), true).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case retry",
+			req:  &pb.ReserveCouponRequest{RedemptionId: "r1", Code: "ABC", UserId: 1},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByCode", mock.Anything, mock.Anything, "ABC").Return(coupon, nil)
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectCommit()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(&entity.CouponRedemption{
+					ID:       pg_util.NullString("r1"),
+					CouponID: pg_util.NullInt64(3),
+					Status:   pg_util.NullString(entity.CouponRedemptionStatus_Reserved),
+				}, nil)
+			},
+		},
+		{
+			name:    "err usage limit reached for the user",
+			req:     &pb.ReserveCouponRequest{RedemptionId: "r1", Code: "ABC", UserId: 1},
+			wantErr: status.Errorf(codes.FailedPrecondition, "coupon has reached its usage limit for the user"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByCode", mock.Anything, mock.Anything, "ABC").Return(coupon, nil)
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("Create", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+				s.couponRepo.(*mocks.CouponRepository).On("IncrementUsed", mock.Anything, mock.Anything, int64(3), true).Return(nil)
+				s.userCouponRepo.(*mocks.UserCouponRepository).On("IncrementUsed", mock.Anything, mock.Anything, int64(3), int64(1), true).Return(sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err released before reservation",
+			req:     &pb.ReserveCouponRequest{RedemptionId: "r1", Code: "ABC", UserId: 1},
+			wantErr: status.Errorf(codes.FailedPrecondition, "coupon redemption is released"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByCode", mock.Anything, mock.Anything, "ABC").Return(coupon, nil)
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectCommit()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(&entity.CouponRedemption{
+					ID:     pg_util.NullString("r1"),
+					Status: pg_util.NullString(entity.CouponRedemptionStatus_Released),
+				}, nil)
+			},
+		},
+		{
+			name:    "err coupon not found",
+			req:     &pb.ReserveCouponRequest{RedemptionId: "r1", Code: "XYZ", UserId: 1},
+			wantErr: status.Errorf(codes.NotFound, "coupon not found"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByCode", mock.Anything, mock.Anything, "XYZ").Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err missing redemption id",
+			req:     &pb.ReserveCouponRequest{Code: "ABC", UserId: 1},
+			wantErr: status.Errorf(codes.InvalidArgument, "redemption_id is required"),
+			setup:   func(smock sqlmock.Sqlmock, s *couponService) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:                   &postgres_client.PostgresClient{DB: db},
+				couponRepo:           &mocks.CouponRepository{},
+				userCouponRepo:       &mocks.UserCouponRepository{},
+				couponRedemptionRepo: &mocks.CouponRedemptionRepository{},
+			}
+			tt.setup(smock, s)
+			_, err = s.ReserveCoupon(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).AssertExpectations(t)
+			s.userCouponRepo.(*mocks.UserCouponRepository).AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponService_ConfirmCoupon(t *testing.T) {
+	redemption := func(status string) *entity.CouponRedemption {
+		return &entity.CouponRedemption{
+			ID:         pg_util.NullString("r1"),
+			CouponID:   pg_util.NullInt64(3),
+			CouponType: pg_util.NullString(pb.CouponType_CouponType_LIMITED.String()),
+			UserID:     pg_util.NullInt64(1),
+			Status:     pg_util.NullString(status),
+		}
+	}
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case reserved",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Reserved), nil)
+				couponRedemptionRepo.On("UpdateStatus", mock.Anything, mock.Anything, "r1",
+					[]string{entity.CouponRedemptionStatus_Reserved}, entity.CouponRedemptionStatus_Confirmed).Return(nil)
+				s.usedCouponRepo.(*mocks.UsedCouponRepository).On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(e *entity.UsedCoupon) bool {
+					return e.RedemptionID.String == "r1" && e.Type.String == pb.CouponType_CouponType_LIMITED.String() &&
+						e.Discount == pg_util.NullInt64(250) && e.Currency == pg_util.NullString("USD")
+				})).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case expired takes back its usage",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Expired), nil)
+				couponRedemptionRepo.On("UpdateStatus", mock.Anything, mock.Anything, "r1",
+					[]string{entity.CouponRedemptionStatus_Expired}, entity.CouponRedemptionStatus_Confirmed).Return(nil)
+				s.couponRepo.(*mocks.CouponRepository).On("IncrementUsed", mock.Anything, mock.Anything, int64(3), false).Return(nil)
+				s.usedCouponRepo.(*mocks.UsedCouponRepository).On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err released",
+			wantErr: status.Errorf(codes.FailedPrecondition, "coupon redemption is released"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Released), nil)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:                   &postgres_client.PostgresClient{DB: db},
+				couponRepo:           &mocks.CouponRepository{},
+				usedCouponRepo:       &mocks.UsedCouponRepository{},
+				couponRedemptionRepo: &mocks.CouponRedemptionRepository{},
+			}
+			tt.setup(smock, s)
+			_, err = s.ConfirmCoupon(context.Background(), &pb.ConfirmCouponRequest{
+				RedemptionId: "r1",
+				Discount:     &pb.Money{Amount: 250, Currency: "USD"},
+			})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+			s.usedCouponRepo.(*mocks.UsedCouponRepository).AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponService_ReleaseCoupon(t *testing.T) {
+	redemption := func(status string) *entity.CouponRedemption {
+		return &entity.CouponRedemption{
+			ID:         pg_util.NullString("r1"),
+			CouponID:   pg_util.NullInt64(3),
+			CouponType: pg_util.NullString(pb.CouponType_CouponType_PRODUCT.String()),
+			UserID:     pg_util.NullInt64(1),
+			Status:     pg_util.NullString(status),
+		}
+	}
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case before reservation",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("Create", mock.Anything, mock.Anything, &entity.CouponRedemption{
+					ID:     pg_util.NullString("r1"),
+					Status: pg_util.NullString(entity.CouponRedemptionStatus_Released),
+				}).Return(true, nil)
+			},
+		},
+		{
+			name: "happy case reserved gives back its usage",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectBegin()
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Reserved), nil)
+				couponRedemptionRepo.On("UpdateStatus", mock.Anything, mock.Anything, "r1",
+					[]string{entity.CouponRedemptionStatus_Reserved}, entity.CouponRedemptionStatus_Released).Return(nil)
+				s.couponRepo.(*mocks.CouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				s.productCouponRepo.(*mocks.ProductCouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case expired gave back its usage already",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectBegin()
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Expired), nil)
+				couponRedemptionRepo.On("UpdateStatus", mock.Anything, mock.Anything, "r1",
+					[]string{entity.CouponRedemptionStatus_Expired}, entity.CouponRedemptionStatus_Released).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case released already",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectBegin()
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Released), nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err confirmed",
+			wantErr: status.Errorf(codes.FailedPrecondition, "coupon redemption is confirmed"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+				smock.ExpectBegin()
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(redemption(entity.CouponRedemptionStatus_Confirmed), nil)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:                   &postgres_client.PostgresClient{DB: db},
+				couponRepo:           &mocks.CouponRepository{},
+				productCouponRepo:    &mocks.ProductCouponRepository{},
+				couponRedemptionRepo: &mocks.CouponRedemptionRepository{},
+			}
+			tt.setup(smock, s)
+			_, err = s.ReleaseCoupon(context.Background(), &pb.ReleaseCouponRequest{RedemptionId: "r1"})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).AssertExpectations(t)
+			s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+			s.productCouponRepo.(*mocks.ProductCouponRepository).AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponService_RestoreCoupon(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case confirmed",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				couponRedemptionRepo := s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository)
+				couponRedemptionRepo.On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(&entity.CouponRedemption{
+					ID:         pg_util.NullString("r1"),
+					CouponID:   pg_util.NullInt64(3),
+					CouponType: pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+					UserID:     pg_util.NullInt64(1),
+					Status:     pg_util.NullString(entity.CouponRedemptionStatus_Confirmed),
+				}, nil)
+				couponRedemptionRepo.On("UpdateStatus", mock.Anything, mock.Anything, "r1",
+					[]string{entity.CouponRedemptionStatus_Confirmed}, entity.CouponRedemptionStatus_Restored).Return(nil)
+				s.usedCouponRepo.(*mocks.UsedCouponRepository).On("DeleteByRedemptionID", mock.Anything, mock.Anything, "r1").Return(nil)
+				s.couponRepo.(*mocks.CouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				s.userCouponRepo.(*mocks.UserCouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3), int64(1)).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case restored already",
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(&entity.CouponRedemption{
+					Status: pg_util.NullString(entity.CouponRedemptionStatus_Restored),
+				}, nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err not confirmed",
+			wantErr: status.Errorf(codes.FailedPrecondition, "coupon redemption is not confirmed"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("RetrieveByID", mock.Anything, mock.Anything, "r1").Return(&entity.CouponRedemption{
+					Status: pg_util.NullString(entity.CouponRedemptionStatus_Reserved),
+				}, nil)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:                   &postgres_client.PostgresClient{DB: db},
+				couponRepo:           &mocks.CouponRepository{},
+				userCouponRepo:       &mocks.UserCouponRepository{},
+				productCouponRepo:    &mocks.ProductCouponRepository{},
+				usedCouponRepo:       &mocks.UsedCouponRepository{},
+				couponRedemptionRepo: &mocks.CouponRedemptionRepository{},
+			}
+			tt.setup(smock, s)
+			_, err = s.RestoreCoupon(context.Background(), &pb.RestoreCouponRequest{RedemptionId: "r1"})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.usedCouponRepo.(*mocks.UsedCouponRepository).AssertExpectations(t)
+			s.userCouponRepo.(*mocks.UserCouponRepository).AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponService_expireCouponReservations(t *testing.T) {
+	db, smock, err := sqlmock.New()
+	require.NoError(t, err)
+	s := &couponService{
+		db:                   &postgres_client.PostgresClient{DB: db},
+		couponRepo:           &mocks.CouponRepository{},
+		userCouponRepo:       &mocks.UserCouponRepository{},
+		couponRedemptionRepo: &mocks.CouponRedemptionRepository{},
+	}
+	before := time.Now()
+
+	smock.ExpectBegin()
+	s.couponRedemptionRepo.(*mocks.CouponRedemptionRepository).On("ExpireReservedBefore", mock.Anything, mock.Anything, before).Return([]*entity.CouponRedemption{
+		{
+			ID:         pg_util.NullString("r1"),
+			CouponID:   pg_util.NullInt64(3),
+			CouponType: pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+			UserID:     pg_util.NullInt64(1),
+		},
+		{
+			ID:       pg_util.NullString("r2"),
+			CouponID: pg_util.NullInt64(4),
+			UserID:   pg_util.NullInt64(2),
+		},
+	}, nil)
+	s.couponRepo.(*mocks.CouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3)).Return(nil).Once()
+	s.userCouponRepo.(*mocks.UserCouponRepository).On("DecrementUsed", mock.Anything, mock.Anything, int64(3), int64(1)).Return(nil).Once()
+	smock.ExpectCommit()
+
+	expired, err := s.expireCouponReservations(context.Background(), before)
+	require.NoError(t, err)
+	require.Equal(t, 2, expired)
+	require.NoError(t, smock.ExpectationsWereMet())
+	s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+	s.userCouponRepo.(*mocks.UserCouponRepository).AssertExpectations(t)
+}
+
+func Test_couponRepository_IncrementUsed(t *testing.T) {
+	db, smock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// The usage is taken without a new version of the coupon
+	smock.ExpectExec(`UPDATE coupons SET used = COALESCE\(used, 0\) \+ 1 WHERE id = \$1 AND`).
+		WithArgs(int64(3), true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, postgres.NewCouponRepository().IncrementUsed(context.Background(), &postgres_client.PostgresClient{DB: db}, 3, true))
+
+	// A used up coupon is not found
+	smock.ExpectExec(`UPDATE coupons SET used = COALESCE\(used, 0\) \+ 1 WHERE id = \$1 AND`).
+		WithArgs(int64(3), true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.ErrorIs(t, postgres.NewCouponRepository().IncrementUsed(context.Background(), &postgres_client.PostgresClient{DB: db}, 3, true), sql.ErrNoRows)
+	require.NoError(t, smock.ExpectationsWereMet())
+}
+
+func Test_couponRepository_DecrementUsed(t *testing.T) {
+	db, smock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	// The usage is given back without a new version of the coupon
+	smock.ExpectExec(`UPDATE coupons SET used = GREATEST\(COALESCE\(used, 0\) - 1, 0\) WHERE id = \$1`).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, postgres.NewCouponRepository().DecrementUsed(context.Background(), &postgres_client.PostgresClient{DB: db}, 3))
+	require.NoError(t, smock.ExpectationsWereMet())
+}
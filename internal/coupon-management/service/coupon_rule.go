@@ -0,0 +1,107 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/pkg/eligibility"
+	"trintech/review/pkg/pg_util"
+)
+
+// couponUseReasons maps the reasons of the rules to the reasons of the responses.
+var couponUseReasons = map[eligibility.Reason]pb.CouponUseReason{
+	eligibility.ReasonMinOrderValue:     pb.CouponUseReason_CouponUseReason_MIN_ORDER_VALUE,
+	eligibility.ReasonProductType:       pb.CouponUseReason_CouponUseReason_PRODUCT_TYPE,
+	eligibility.ReasonFirstPurchaseOnly: pb.CouponUseReason_CouponUseReason_FIRST_PURCHASE_ONLY,
+	eligibility.ReasonWeekday:           pb.CouponUseReason_CouponUseReason_WEEKDAY,
+	eligibility.ReasonHour:              pb.CouponUseReason_CouponUseReason_HOUR,
+	eligibility.ReasonUserRole:          pb.CouponUseReason_CouponUseReason_USER_ROLE,
+}
+
+// validCouponRules validates the rules of a coupon request and returns their JSON to store, NULL without rules.
+// The amounts of the rules are in the base currency by default.
+func (s *couponService) validCouponRules(req *pb.CouponRules) (sql.NullString, error) {
+	if req == nil {
+		return sql.NullString{}, nil
+	}
+
+	rules := eligibility.Rules{
+		Currency:          req.GetCurrency(),
+		MinOrderValue:     req.GetMinOrderValue(),
+		MaxDiscount:       req.GetMaxDiscount(),
+		ProductTypes:      req.GetProductTypes(),
+		FirstPurchaseOnly: req.GetFirstPurchaseOnly(),
+		FromHour:          int(req.GetFromHour()),
+		ToHour:            int(req.GetToHour()),
+		TimeZone:          req.GetTimeZone(),
+		UserRoles:         req.GetUserRoles(),
+	}
+	for _, weekday := range req.GetWeekdays() {
+		rules.Weekdays = append(rules.Weekdays, time.Weekday(weekday))
+	}
+	if rules.Currency == "" && (rules.MinOrderValue > 0 || rules.MaxDiscount > 0) {
+		rules.Currency = s.baseCurrency
+	}
+
+	if err := rules.Validate(); err != nil {
+		return sql.NullString{}, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	for _, role := range rules.UserRoles {
+		if !slices.Contains([]string{userEntity.UserRole_User, userEntity.UserRole_Admin, userEntity.UserRole_SuperAdmin}, role) {
+			return sql.NullString{}, status.Errorf(codes.InvalidArgument, "invalid user role %s", role)
+		}
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return sql.NullString{}, status.Errorf(codes.Internal, "unable to encode coupon rules: %v", err.Error())
+	}
+
+	return pg_util.NullString(string(data)), nil
+}
+
+// couponRules returns the rules of a coupon, the zero rules without rules.
+func couponRules(coupon *entity.Coupon) (eligibility.Rules, error) {
+	var rules eligibility.Rules
+	if !coupon.Rules.Valid {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(coupon.Rules.String), &rules); err != nil {
+		return rules, fmt.Errorf("unable to decode coupon rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// toPbCouponRules transforms the rules of a coupon to the response format, nil without rules.
+func toPbCouponRules(coupon *entity.Coupon, rules eligibility.Rules) *pb.CouponRules {
+	if !coupon.Rules.Valid {
+		return nil
+	}
+
+	data := &pb.CouponRules{
+		Currency:          rules.Currency,
+		MinOrderValue:     rules.MinOrderValue,
+		MaxDiscount:       rules.MaxDiscount,
+		ProductTypes:      rules.ProductTypes,
+		FirstPurchaseOnly: rules.FirstPurchaseOnly,
+		FromHour:          int32(rules.FromHour),
+		ToHour:            int32(rules.ToHour),
+		TimeZone:          rules.TimeZone,
+		UserRoles:         rules.UserRoles,
+	}
+	for _, weekday := range rules.Weekdays {
+		data.Weekdays = append(data.Weekdays, int32(weekday))
+	}
+
+	return data
+}
@@ -3,18 +3,34 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	pb "trintech/review/dto/coupon-management/coupon"
 	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository/postgres"
+	userEntity "trintech/review/internal/user-management/entity"
 	"trintech/review/mocks"
 	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
 	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
 )
 
 func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
@@ -176,6 +192,7 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 			want: &pb.RetrieveCouponByCodeResponse{
 				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_NOT_STARTED,
 			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
@@ -188,6 +205,37 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 		},
 
+		{
+			name: "err coupon is paused",
+			fields: fields{
+				couponRepo:        &mocks.CouponRepository{},
+				userCouponRepo:    &mocks.UserCouponRepository{},
+				productCouponRepo: &mocks.ProductCouponRepository{},
+				usedCouponRepo:    &mocks.UsedCouponRepository{},
+			},
+			args: args{
+				ctx: context.Background(),
+				req: &pb.RetrieveCouponByCodeRequest{
+					Code:     "ABC",
+					CheckUse: true,
+				},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{
+				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_PAUSED,
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
+					Type:     pg_util.NullString(pb.CouponType_CouponType_LIMITED.String()),
+					Used:     pg_util.NullInt64(1),
+					Total:    pg_util.NullInt64(10),
+					From:     pg_util.NullTime(time.Now().AddDate(0, 0, -1)),
+					To:       pg_util.NullTime(time.Now().AddDate(0, 1, 0)),
+					PausedAt: pg_util.NullTime(time.Now()),
+				}, nil)
+			},
+		},
+
 		{
 			name: "err coupon is outdate",
 			fields: fields{
@@ -205,6 +253,7 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 			want: &pb.RetrieveCouponByCodeResponse{
 				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_EXPIRED,
 			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
@@ -233,6 +282,7 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 			want: &pb.RetrieveCouponByCodeResponse{
 				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED,
 			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
@@ -262,6 +312,7 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 			want: &pb.RetrieveCouponByCodeResponse{
 				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED,
 			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
@@ -293,6 +344,7 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			},
 			want: &pb.RetrieveCouponByCodeResponse{
 				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_USAGE_LIMIT_REACHED,
 			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
@@ -308,6 +360,88 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 				}, nil)
 			},
 		},
+		{
+			name: "happy case check use rules",
+			fields: fields{
+				couponRepo: &mocks.CouponRepository{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.RetrieveCouponByCodeRequest{
+					Code:          "ABC",
+					CheckUse:      true,
+					OrderValue:    &pb.Money{Amount: 5000, Currency: "USD"},
+					ProductTypes:  []string{"book"},
+					FirstPurchase: true,
+				},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{
+				CanUse: true,
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
+					Type:  pg_util.NullString(pb.CouponType_CouponType_NONE.String()),
+					From:  pg_util.NullTime(time.Now().AddDate(0, -1, 0)),
+					To:    pg_util.NullTime(time.Now().AddDate(0, 1, 0)),
+					Rules: pg_util.NullString(`{"currency":"USD","min_order_value":5000,"product_types":["book"],"first_purchase_only":true,"user_roles":["USER"]}`),
+				}, nil)
+			},
+		},
+		{
+			name: "err check use min order value",
+			fields: fields{
+				couponRepo: &mocks.CouponRepository{},
+			},
+			args: args{
+				ctx: context.Background(),
+				req: &pb.RetrieveCouponByCodeRequest{
+					Code:       "ABC",
+					CheckUse:   true,
+					OrderValue: &pb.Money{Amount: 4999, Currency: "USD"},
+				},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{
+				CanUse:        false,
+				Reason:        pb.CouponUseReason_CouponUseReason_MIN_ORDER_VALUE,
+				ReasonMessage: "order value must be at least 50.00 USD",
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
+					Type:  pg_util.NullString(pb.CouponType_CouponType_NONE.String()),
+					From:  pg_util.NullTime(time.Now().AddDate(0, -1, 0)),
+					To:    pg_util.NullTime(time.Now().AddDate(0, 1, 0)),
+					Rules: pg_util.NullString(`{"currency":"USD","min_order_value":5000}`),
+				}, nil)
+			},
+		},
+		{
+			name: "err check use user role",
+			fields: fields{
+				couponRepo: &mocks.CouponRepository{},
+			},
+			args: args{
+				ctx: context.Background(),
+				req: &pb.RetrieveCouponByCodeRequest{
+					Code:     "ABC",
+					CheckUse: true,
+				},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{
+				CanUse: false,
+				Reason: pb.CouponUseReason_CouponUseReason_USER_ROLE,
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Coupon{
+					Type:  pg_util.NullString(pb.CouponType_CouponType_NONE.String()),
+					From:  pg_util.NullTime(time.Now().AddDate(0, -1, 0)),
+					To:    pg_util.NullTime(time.Now().AddDate(0, 1, 0)),
+					Rules: pg_util.NullString(`{"user_roles":["USER"]}`),
+				}, nil)
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt.setup(tt.args.ctx, tt.fields)
@@ -326,7 +460,299 @@ func Test_couponService_RetrieveCouponByCode_CanUse(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 				require.Equal(t, tt.want.CanUse, got.CanUse)
+				require.Equal(t, tt.want.Reason, got.Reason)
+				if tt.want.ReasonMessage != "" {
+					require.Equal(t, tt.want.ReasonMessage, got.ReasonMessage)
+				}
+			}
+		})
+	}
+}
+
+func Test_couponService_ApplyCoupon_Concurrent(t *testing.T) {
+	const (
+		total    = 5
+		requests = 20
+	)
+	db, smock, err := sqlmock.New()
+	require.NoError(t, err)
+	smock.MatchExpectationsInOrder(false)
+	for i := 0; i < requests; i++ {
+		smock.ExpectBegin()
+	}
+	for i := 0; i < total; i++ {
+		smock.ExpectCommit()
+	}
+	for i := 0; i < requests-total; i++ {
+		smock.ExpectRollback()
+	}
+
+	// The conditional update of the counter, serialised like the row lock of the database
+	var (
+		mu   sync.Mutex
+		used int64
+	)
+	couponRepo := &mocks.CouponRepository{}
+	couponRepo.On("RetrieveByCode", mock.Anything, mock.Anything, "ABC").Return(&entity.Coupon{
+		ID:    pg_util.NullInt64(3),
+		Type:  pg_util.NullString(pb.CouponType_CouponType_LIMITED.String()),
+		Total: pg_util.NullInt64(total),
+	}, nil)
+	couponRepo.On("IncrementUsed", mock.Anything, mock.Anything, int64(3), true).Return(func(context.Context, database.Executor, int64, bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if used >= total {
+			return sql.ErrNoRows
+		}
+		used++
+
+		return nil
+	})
+	usedCouponRepo := &mocks.UsedCouponRepository{}
+	usedCouponRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(e *entity.UsedCoupon) bool {
+		return e.Type.String == pb.CouponType_CouponType_LIMITED.String()
+	})).Return(nil)
+	s := &couponService{
+		db:             &postgres_client.PostgresClient{DB: db},
+		couponRepo:     couponRepo,
+		usedCouponRepo: usedCouponRepo,
+	}
+
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int64
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(userID int64) {
+			defer wg.Done()
+			ctx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: userID}))
+			_, err := s.ApplyCoupon(ctx, &pb.ApplyCouponRequest{Code: "ABC"})
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		}(int64(i + 1))
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(total), succeeded.Load())
+	require.Equal(t, int64(total), used)
+	usedCouponRepo.AssertNumberOfCalls(t, "Create", total)
+	require.NoError(t, smock.ExpectationsWereMet())
+}
+
+func Test_couponService_ListDeletedCoupon(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 9, Role: userEntity.UserRole_Admin}))
+	deletedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.ListDeletedCouponRequest
+		want    *pb.ListDeletedCouponResponse
+		wantErr error
+		setup   func(couponRepo *mocks.CouponRepository)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req:  &pb.ListDeletedCouponRequest{Offset: 0, Limit: 10},
+			want: &pb.ListDeletedCouponResponse{
+				Data: []*pb.ListDeletedCouponResponse_Coupon{
+					{Id: 3, Code: "SALE", Type: pb.CouponType_CouponType_USER, DeletedAt: timestamppb.New(deletedAt), DeletedBy: 9},
+				},
+				Total: 1,
+			},
+			setup: func(couponRepo *mocks.CouponRepository) {
+				couponRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return([]*entity.Coupon{
+					{
+						ID:        pg_util.NullInt64(3),
+						Code:      pg_util.NullString("SALE"),
+						Type:      pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+						DeletedAt: pg_util.NullTime(deletedAt),
+						DeletedBy: pg_util.NullInt64(9),
+					},
+				}, nil)
+				couponRepo.On("CountDeleted", mock.Anything, mock.Anything).Return(int64(1), nil)
+			},
+		},
+		{
+			name:    "err list deleted coupons",
+			ctx:     adminCtx,
+			req:     &pb.ListDeletedCouponRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.Internal, "unable to retrieve deleted coupons: conn closed"),
+			setup: func(couponRepo *mocks.CouponRepository) {
+				couponRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return(nil, errors.New("conn closed"))
+			},
+		},
+		{
+			name:    "err count deleted coupons",
+			ctx:     adminCtx,
+			req:     &pb.ListDeletedCouponRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.Internal, "unable to count deleted coupons: conn closed"),
+			setup: func(couponRepo *mocks.CouponRepository) {
+				couponRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return(nil, nil)
+				couponRepo.On("CountDeleted", mock.Anything, mock.Anything).Return(int64(0), errors.New("conn closed"))
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.ListDeletedCouponRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(couponRepo *mocks.CouponRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			couponRepo := &mocks.CouponRepository{}
+			tt.setup(couponRepo)
+			s := &couponService{couponRepo: couponRepo}
+			got, err := s.ListDeletedCoupon(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			couponRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponService_RestoreCouponByID(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 9, Role: userEntity.UserRole_Admin}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.RestoreCouponByIDRequest
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req:  &pb.RestoreCouponByIDRequest{Id: 3},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RestoreByID", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, &entity.CouponAudit{
+					CouponID: pg_util.NullInt64(3),
+					Action:   pg_util.NullString(entity.CouponAuditAction_Restored),
+					ActorID:  pg_util.NullInt64(9),
+				}).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err deleted coupon not found",
+			ctx:     adminCtx,
+			req:     &pb.RestoreCouponByIDRequest{Id: 3},
+			wantErr: status.Errorf(codes.NotFound, "deleted coupon not found"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RestoreByID", mock.Anything, mock.Anything, int64(3)).Return(sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err record audit",
+			ctx:     adminCtx,
+			req:     &pb.RestoreCouponByIDRequest{Id: 3},
+			wantErr: status.Errorf(codes.Internal, "unable to restore coupon: unable to record coupon audit: conn closed"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RestoreByID", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("conn closed"))
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.RestoreCouponByIDRequest{Id: 3},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(smock sqlmock.Sqlmock, s *couponService) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:              &postgres_client.PostgresClient{DB: db},
+				couponRepo:      &mocks.CouponRepository{},
+				couponAuditRepo: &mocks.CouponAuditRepository{},
+			}
+			tt.setup(smock, s)
+			_, err = s.RestoreCouponByID(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+			s.couponAuditRepo.(*mocks.CouponAuditRepository).AssertExpectations(t)
+		})
+	}
+}
+
+// purgedBefore matches the trash deadline of a purge with a retention.
+type purgedBefore time.Duration
+
+// Match implements sqlmock.Argument.
+func (retention purgedBefore) Match(v driver.Value) bool {
+	before, ok := v.(time.Time)
+	deadline := time.Now().Add(-time.Duration(retention))
+
+	return ok && before.Before(deadline) && before.After(deadline.Add(-time.Minute))
+}
+
+func Test_purgeDeletedCoupons(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock)
+	}{
+		{
+			name: "happy case purges the settled coupons with their uses and redemptions",
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(`WITH purged AS \( SELECT c.id FROM coupons c WHERE c.deleted_at < \$1 ` +
+					`AND NOT EXISTS \( SELECT 1 FROM used_coupons uc WHERE uc.coupon_id = c.id AND uc.updated_at >= \$1 \) ` +
+					`AND NOT EXISTS \( SELECT 1 FROM coupon_redemptions cr WHERE cr.coupon_id = c.id AND \(cr.status = 'RESERVED' OR cr.updated_at >= \$1\) \) ` +
+					`FOR UPDATE \), ` +
+					`used AS \( DELETE FROM used_coupons uc USING purged p WHERE uc.coupon_id = p.id \), ` +
+					`redemptions AS \( DELETE FROM coupon_redemptions cr USING purged p WHERE cr.coupon_id = p.id \) ` +
+					`DELETE FROM coupons c USING purged p WHERE c.id = p.id`).
+					WithArgs(purgedBefore(30 * 24 * time.Hour)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name:    "err purge deleted coupons",
+			wantErr: errors.New("unable to purge deleted coupons: conn closed"),
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(`WITH purged AS`).WillReturnError(errors.New("conn closed"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			tt.setup(smock)
+			err = purgeDeletedCoupons(context.Background(), &postgres_client.PostgresClient{DB: db}, postgres.NewCouponRepository(), 30*24*time.Hour)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
 			}
+			require.NoError(t, smock.ExpectationsWereMet())
 		})
 	}
 }
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/locale"
+	"trintech/review/pkg/pg_util"
+)
+
+// validTranslationLocale validates the locale of a translation, the content in the default locale is not a translation.
+func validTranslationLocale(tag string) (string, error) {
+	l, err := locale.Parse(tag)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid locale %s", tag)
+	}
+	if l == locale.Default {
+		return "", status.Errorf(codes.InvalidArgument, "%s is the default locale, it is not translated", l)
+	}
+
+	return l, nil
+}
+
+// localiseCoupon replaces the description of a coupon by its translation in the first locale of the fallback chain
+// of the request it is translated in.
+func (s *couponService) localiseCoupon(ctx context.Context, couponID int64, resp *pb.RetrieveCouponByCodeResponse) error {
+	resp.Locale = locale.Default
+
+	// The coupon is in the default locale, the last locale of the chain
+	chain := http_server.ExtractLocalesFromCtx(ctx)
+	locales := chain[:len(chain)-1]
+	if len(locales) == 0 {
+		return nil
+	}
+
+	translations, err := s.couponTranslationRepo.ListByCouponID(ctx, s.db, couponID, locales)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to retrieve coupon translations: %v", err.Error())
+	}
+
+	// Keep the translation of the preferred locale
+	var best *entity.CouponTranslation
+	for _, translation := range translations {
+		if best == nil || slices.Index(locales, translation.Locale.String) < slices.Index(locales, best.Locale.String) {
+			best = translation
+		}
+	}
+	if best != nil {
+		resp.Locale = best.Locale.String
+		resp.Description = best.Description.String
+	}
+
+	return nil
+}
+
+// SetCouponTranslation is a method of the couponService that sets the description of a coupon in a locale.
+func (s *couponService) SetCouponTranslation(ctx context.Context, req *pb.SetCouponTranslationRequest) (*pb.SetCouponTranslationResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the translation
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+	description := strings.TrimSpace(req.GetDescription())
+	if description == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "description is required")
+	}
+
+	// Set the translation and increment the version of the coupon in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.couponRepo.IncrementVersionByID(ctx, tx, req.GetCouponId()); err != nil {
+			return err
+		}
+
+		return s.couponTranslationRepo.Upsert(ctx, tx, &entity.CouponTranslation{
+			CouponID:    pg_util.NullInt64(req.GetCouponId()),
+			Locale:      pg_util.NullString(l),
+			Description: pg_util.NullString(description),
+			UpdatedBy:   pg_util.NullInt64(userCtx.UserID),
+		})
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "coupon not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to set coupon translation: %v", err.Error())
+	}
+
+	return &pb.SetCouponTranslationResponse{}, nil
+}
+
+// RemoveCouponTranslation is a method of the couponService that removes the translation of a coupon in a locale,
+// the coupon falls back to the next locale of the chain in this locale.
+func (s *couponService) RemoveCouponTranslation(ctx context.Context, req *pb.RemoveCouponTranslationRequest) (*pb.RemoveCouponTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the locale
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the translation and increment the version of the coupon in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		err := s.couponTranslationRepo.Delete(ctx, tx, req.GetCouponId(), l)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "coupon translation not found")
+		case err != nil:
+			return err
+		}
+
+		return s.couponRepo.IncrementVersionByID(ctx, tx, req.GetCouponId())
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "coupon not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to remove coupon translation: %v", err.Error())
+	}
+
+	return &pb.RemoveCouponTranslationResponse{}, nil
+}
+
+// ListCouponTranslation is a method of the couponService that lists the translations of a coupon by locale.
+func (s *couponService) ListCouponTranslation(ctx context.Context, req *pb.ListCouponTranslationRequest) (*pb.ListCouponTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// List the translations of the coupon
+	translations, err := s.couponTranslationRepo.ListByCouponID(ctx, s.db, req.GetCouponId(), nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list coupon translations: %v", err.Error())
+	}
+
+	data := make([]*pb.CouponTranslation, 0, len(translations))
+	for _, translation := range translations {
+		data = append(data, &pb.CouponTranslation{
+			Locale:      translation.Locale.String,
+			Description: translation.Description.String,
+			UpdatedAt:   timestamppb.New(translation.UpdatedAt.Time),
+		})
+	}
+
+	return &pb.ListCouponTranslationResponse{
+		Data: data,
+	}, nil
+}
+
+// ListMissingCouponTranslation is a method of the couponService that reports the coupons which are not translated
+// in one of the locales, with the locales they are missing.
+func (s *couponService) ListMissingCouponTranslation(ctx context.Context, req *pb.ListMissingCouponTranslationRequest) (*pb.ListMissingCouponTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the locales
+	if len(req.GetLocales()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "locales are required")
+	}
+	locales := make([]string, 0, len(req.GetLocales()))
+	for _, tag := range req.GetLocales() {
+		l, err := validTranslationLocale(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(locales, l) {
+			locales = append(locales, l)
+		}
+	}
+
+	// List the missing translations with their total count
+	missing, err := s.couponTranslationRepo.ListMissing(ctx, s.db, locales, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list missing coupon translations: %v", err.Error())
+	}
+	total, err := s.couponTranslationRepo.CountMissing(ctx, s.db, locales)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count missing coupon translations: %v", err.Error())
+	}
+
+	data := make([]*pb.ListMissingCouponTranslationResponse_MissingTranslation, 0, len(missing))
+	for _, m := range missing {
+		data = append(data, &pb.ListMissingCouponTranslationResponse_MissingTranslation{
+			CouponId: m.CouponID.Int64,
+			Code:     m.Code.String,
+			Locales:  m.Locales,
+		})
+	}
+
+	return &pb.ListMissingCouponTranslationResponse{
+		Data:  data,
+		Total: total,
+	}, nil
+}
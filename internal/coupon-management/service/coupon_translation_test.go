@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_couponService_localiseCoupon(t *testing.T) {
+	tests := []struct {
+		name         string
+		locales      []string
+		translations []*entity.CouponTranslation
+		want         *pb.RetrieveCouponByCodeResponse
+	}{
+		{
+			name: "happy case default locale",
+			want: &pb.RetrieveCouponByCodeResponse{Description: "Summer sale", Locale: "en"},
+		},
+		{
+			name:    "happy case falls back to the parent locale",
+			locales: []string{"vi-VN"},
+			translations: []*entity.CouponTranslation{
+				{CouponID: pg_util.NullInt64(1), Locale: pg_util.NullString("vi"), Description: pg_util.NullString("Giảm giá mùa hè")},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{Description: "Giảm giá mùa hè", Locale: "vi"},
+		},
+		{
+			name:    "happy case preferred locale",
+			locales: []string{"vi-VN", "fr"},
+			translations: []*entity.CouponTranslation{
+				{CouponID: pg_util.NullInt64(1), Locale: pg_util.NullString("fr"), Description: pg_util.NullString("Soldes d'été")},
+				{CouponID: pg_util.NullInt64(1), Locale: pg_util.NullString("vi-VN"), Description: pg_util.NullString("Giảm giá mùa hè")},
+			},
+			want: &pb.RetrieveCouponByCodeResponse{Description: "Giảm giá mùa hè", Locale: "vi-VN"},
+		},
+		{
+			name:    "happy case not translated",
+			locales: []string{"fr"},
+			want:    &pb.RetrieveCouponByCodeResponse{Description: "Summer sale", Locale: "en"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{http_server.MDLocale: tt.locales})
+			couponTranslationRepo := &mocks.CouponTranslationRepository{}
+			couponTranslationRepo.On("ListByCouponID", mock.Anything, mock.Anything, int64(1), mock.Anything).Return(tt.translations, nil)
+			s := &couponService{
+				couponTranslationRepo: couponTranslationRepo,
+			}
+			resp := &pb.RetrieveCouponByCodeResponse{Description: "Summer sale"}
+			require.NoError(t, s.localiseCoupon(ctx, 1, resp))
+			require.Equal(t, tt.want, resp)
+			if len(tt.locales) == 0 {
+				couponTranslationRepo.AssertNotCalled(t, "ListByCouponID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func Test_couponService_SetCouponTranslation(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		req     *pb.SetCouponTranslationRequest
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case",
+			req:  &pb.SetCouponTranslationRequest{CouponId: 1, Locale: "vi_vn", Description: " Giảm giá "},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("IncrementVersionByID", mock.Anything, mock.Anything, int64(1)).Return(nil)
+				s.couponTranslationRepo.(*mocks.CouponTranslationRepository).On("Upsert", mock.Anything, mock.Anything, &entity.CouponTranslation{
+					CouponID:    pg_util.NullInt64(1),
+					Locale:      pg_util.NullString("vi-VN"),
+					Description: pg_util.NullString("Giảm giá"),
+					UpdatedBy:   pg_util.NullInt64(9),
+				}).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err default locale",
+			req:     &pb.SetCouponTranslationRequest{CouponId: 1, Locale: "en", Description: "Sale"},
+			wantErr: status.Errorf(codes.InvalidArgument, "en is the default locale, it is not translated"),
+		},
+		{
+			name:    "err description is required",
+			req:     &pb.SetCouponTranslationRequest{CouponId: 1, Locale: "vi"},
+			wantErr: status.Errorf(codes.InvalidArgument, "description is required"),
+		},
+		{
+			name:    "err coupon not found",
+			req:     &pb.SetCouponTranslationRequest{CouponId: 1, Locale: "vi", Description: "Giảm giá"},
+			wantErr: status.Errorf(codes.NotFound, "coupon not found"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("IncrementVersionByID", mock.Anything, mock.Anything, int64(1)).Return(sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:                    &postgres_client.PostgresClient{DB: db},
+				couponRepo:            &mocks.CouponRepository{},
+				couponTranslationRepo: &mocks.CouponTranslationRepository{},
+			}
+			if tt.setup != nil {
+				tt.setup(smock, s)
+			}
+			_, err = s.SetCouponTranslation(adminCtx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponTranslationRepo.(*mocks.CouponTranslationRepository).AssertExpectations(t)
+		})
+	}
+}
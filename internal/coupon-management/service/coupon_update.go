@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/pg_util"
+)
+
+// couponFieldPaths maps the update mask paths of a coupon to its columns.
+// Both discount paths write the value, the one of the discount type of the coupon is allowed.
+var couponFieldPaths = database.FieldPaths{
+	"from":                 "from",
+	"to":                   "to",
+	"total":                "total",
+	"percent_basis_points": "value",
+	"value":                "value",
+	"description":          "description",
+	"image_url":            "image_url",
+	"applies_to_shipping":  "applies_to_shipping",
+	"rules":                "rules",
+}
+
+// UpdateCoupon is a method of the couponService that updates the masked fields of a coupon.
+// It validates the fields against the current coupon, such as a total not below its usage, updates the coupon
+// only at its current version, so that a concurrent update or redemption aborts the update, and records the
+// changes in the audit trail of the coupon.
+func (s *couponService) UpdateCoupon(ctx context.Context, req *pb.UpdateCouponRequest) (*pb.UpdateCouponResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the fields to update, falling back to the non-empty fields without an update mask
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = populatedCouponPaths(req)
+	}
+	columns, err := couponFieldPaths.Columns(paths)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid update mask: %v", err.Error())
+	}
+	if len(columns) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "nothing to update")
+	}
+	if slices.Contains(columns, "from") && req.GetFrom() == nil || slices.Contains(columns, "to") && req.GetTo() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "the validity window of the coupon can't be removed")
+	}
+	if slices.Contains(columns, "total") && req.GetTotal() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "total must not be negative")
+	}
+
+	// Validate the eligibility rules
+	var rules sql.NullString
+	if slices.Contains(columns, "rules") {
+		if rules, err = s.validCouponRules(req.GetRules()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the version to update, from the If-Match header without expected version
+	expectedVersion, err := couponExpectedVersion(ctx, req.GetExpectedVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the coupon at its current version, its user or product coupon total and record the changes in a database transaction
+	var version int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		before, err := s.couponRepo.RetrieveByID(ctx, tx, req.GetId())
+		if err != nil {
+			return err
+		}
+		if expectedVersion > 0 && expectedVersion != before.Version.Int64 {
+			return database.ErrVersionMismatch
+		}
+
+		// Merge the fields to update into the current coupon
+		after := *before
+		for _, column := range columns {
+			switch column {
+			case "from":
+				after.From = pg_util.NullTime(req.GetFrom().AsTime())
+			case "to":
+				after.To = pg_util.NullTime(req.GetTo().AsTime())
+			case "total":
+				after.Total = pg_util.NullInt64(req.GetTotal())
+			case "description":
+				after.Description = pg_util.NullString(req.GetDescription())
+			case "image_url":
+				after.ImageURL = pg_util.NullString(req.GetImageUrl())
+			case "applies_to_shipping":
+				after.AppliesToShipping = sql.NullBool{Bool: req.GetAppliesToShipping(), Valid: true}
+			case "rules":
+				after.Rules = rules
+			case "value":
+				// The discount type of a coupon is kept, only the value of its type can be updated
+				discountType := new(pb.DiscountType).FromString(before.DiscountType.String)
+				if discountType == pb.DiscountType_DiscountType_PERCENT && slices.Contains(paths, "value") ||
+					discountType == pb.DiscountType_DiscountType_VALUE && slices.Contains(paths, "percent_basis_points") {
+					return status.Errorf(codes.InvalidArgument, "the discount type of the coupon can't be changed")
+				}
+
+				if after.Value, after.Currency, err = s.couponValue(&pb.CreateCouponRequest{
+					DiscountType:       discountType,
+					PercentBasisPoints: req.GetPercentBasisPoints(),
+					Value:              req.GetValue(),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		if slices.Contains(columns, "value") && !slices.Contains(columns, "currency") {
+			columns = append(columns, "currency")
+		}
+
+		// Validate the updated coupon against its usage
+		if !after.From.Time.Before(after.To.Time) {
+			return status.Errorf(codes.InvalidArgument, "from must be before to")
+		}
+		// The usage counts the pending reservations, the update re-checks it against the usage at the time of the write
+		if after.Total.Int64 < before.Used.Int64 {
+			return status.Errorf(codes.FailedPrecondition, "total must not be below the usage of the coupon (%d)", before.Used.Int64)
+		}
+
+		// Update the coupon only at the version it was validated against
+		after.Version = before.Version
+		if err := s.couponRepo.UpdateByID(ctx, tx, req.GetId(), &after, columns); err != nil {
+			return err
+		}
+		version = before.Version.Int64 + 1
+
+		// Keep the total of the user or product coupon, which counts the usage of its type, in line with the coupon
+		if after.Total.Int64 != before.Total.Int64 {
+			switch before.Type.String {
+			case pb.CouponType_CouponType_USER.String():
+				if err := s.userCouponRepo.UpdateTotalByCouponID(ctx, tx, req.GetId(), after.Total.Int64); err != nil {
+					return err
+				}
+			case pb.CouponType_CouponType_PRODUCT.String():
+				if err := s.productCouponRepo.UpdateTotalByCouponID(ctx, tx, req.GetId(), after.Total.Int64); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Record the changes in the audit trail of the coupon
+		changes, err := couponAuditChanges(before, &after, columns)
+		if err != nil {
+			return err
+		}
+		return s.recordCouponAudit(ctx, tx, req.GetId(), entity.CouponAuditAction_Updated, changes, version, userCtx.UserID)
+	}); err != nil {
+		return nil, couponUpdateError(err)
+	}
+
+	// Return the version of the updated coupon as its ETag
+	http_server.SetETag(ctx, version)
+	return &pb.UpdateCouponResponse{
+		Version: version,
+	}, nil
+}
+
+// PauseCoupon is a method of the couponService that pauses a coupon, which can't be used until it is resumed.
+// Pausing a paused coupon keeps it as it is.
+func (s *couponService) PauseCoupon(ctx context.Context, req *pb.PauseCouponRequest) (*pb.PauseCouponResponse, error) {
+	version, err := s.setCouponPaused(ctx, req.GetId(), req.GetExpectedVersion(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PauseCouponResponse{
+		Version: version,
+	}, nil
+}
+
+// ResumeCoupon is a method of the couponService that resumes a paused coupon.
+// Resuming a coupon which is not paused keeps it as it is.
+func (s *couponService) ResumeCoupon(ctx context.Context, req *pb.ResumeCouponRequest) (*pb.ResumeCouponResponse, error) {
+	version, err := s.setCouponPaused(ctx, req.GetId(), req.GetExpectedVersion(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ResumeCouponResponse{
+		Version: version,
+	}, nil
+}
+
+// setCouponPaused pauses or resumes a coupon at its current version and records it in the audit trail of the coupon.
+// It returns the version of the coupon.
+func (s *couponService) setCouponPaused(ctx context.Context, id, expectedVersion int64, paused bool) (int64, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Resolve the version to update, from the If-Match header without expected version
+	if expectedVersion, err = couponExpectedVersion(ctx, expectedVersion); err != nil {
+		return 0, err
+	}
+
+	// Pause or resume the coupon and record it in a database transaction
+	var version int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		before, err := s.couponRepo.RetrieveByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion > 0 && expectedVersion != before.Version.Int64 {
+			return database.ErrVersionMismatch
+		}
+
+		// The coupon is already in the state
+		version = before.Version.Int64
+		if before.PausedAt.Valid == paused {
+			return nil
+		}
+
+		after := *before
+		after.PausedAt, after.PausedBy = sql.NullTime{}, sql.NullInt64{}
+		action := entity.CouponAuditAction_Resumed
+		if paused {
+			after.PausedAt, after.PausedBy = pg_util.NullTime(time.Now()), pg_util.NullInt64(userCtx.UserID)
+			action = entity.CouponAuditAction_Paused
+		}
+
+		columns := []string{"paused_at", "paused_by"}
+		if err := s.couponRepo.UpdateByID(ctx, tx, id, &after, columns); err != nil {
+			return err
+		}
+		version++
+
+		changes, err := couponAuditChanges(before, &after, columns)
+		if err != nil {
+			return err
+		}
+		return s.recordCouponAudit(ctx, tx, id, action, changes, version, userCtx.UserID)
+	}); err != nil {
+		return 0, couponUpdateError(err)
+	}
+
+	// Send the version of the coupon as its ETag
+	http_server.SetETag(ctx, version)
+	return version, nil
+}
+
+// couponExpectedVersion returns the version of a coupon to update, from the If-Match header without expected version.
+// Zero updates any version.
+func couponExpectedVersion(ctx context.Context, expectedVersion int64) (int64, error) {
+	if expectedVersion == 0 {
+		var err error
+		if expectedVersion, err = http_server.ExtractIfMatchFromCtx(ctx); err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "invalid If-Match header")
+		}
+	}
+	if expectedVersion < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "expected_version must not be negative")
+	}
+
+	return expectedVersion, nil
+}
+
+// couponUpdateError returns the status error of a failed update of a coupon.
+func couponUpdateError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		// If the coupon is not found, return a not found error
+		return status.Errorf(codes.NotFound, "coupon not found")
+	}
+	if errors.Is(err, entity.ErrCouponTotalBelowUsed) {
+		// If the coupon has been reserved or redeemed beyond the total since it was read, return a failed precondition error
+		return status.Errorf(codes.FailedPrecondition, "total must not be below the usage of the coupon")
+	}
+	if errors.Is(err, database.ErrVersionMismatch) {
+		// If the coupon has been updated since the expected version, return an aborted error
+		return status.Errorf(codes.Aborted, "coupon has been modified, version mismatch")
+	}
+	if _, ok := status.FromError(err); ok {
+		// A field which is invalid for the coupon is returned as it is
+		return err
+	}
+
+	// If there is an error during coupon update, return an internal server error
+	return status.Errorf(codes.Internal, "unable to update coupon: %v", err.Error())
+}
+
+// populatedCouponPaths returns the field paths of the non-empty fields in an update request.
+func populatedCouponPaths(req *pb.UpdateCouponRequest) []string {
+	var paths []string
+	if req.GetFrom() != nil {
+		paths = append(paths, "from")
+	}
+	if req.GetTo() != nil {
+		paths = append(paths, "to")
+	}
+	if req.GetTotal() != 0 {
+		paths = append(paths, "total")
+	}
+	if req.GetPercentBasisPoints() != 0 {
+		paths = append(paths, "percent_basis_points")
+	}
+	if req.GetValue() != nil {
+		paths = append(paths, "value")
+	}
+	if req.GetDescription() != "" {
+		paths = append(paths, "description")
+	}
+	if req.GetImageUrl() != "" {
+		paths = append(paths, "image_url")
+	}
+	if req.GetAppliesToShipping() {
+		paths = append(paths, "applies_to_shipping")
+	}
+	if req.GetRules() != nil {
+		paths = append(paths, "rules")
+	}
+
+	return paths
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/internal/coupon-management/entity"
+	"trintech/review/internal/coupon-management/repository/postgres"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_couponService_UpdateCoupon(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 9, Role: userEntity.UserRole_Admin}))
+	now := time.Now()
+	coupon := func() *entity.Coupon {
+		return &entity.Coupon{
+			ID:           pg_util.NullInt64(3),
+			Type:         pg_util.NullString(pb.CouponType_CouponType_USER.String()),
+			DiscountType: pg_util.NullString(pb.DiscountType_DiscountType_PERCENT.String()),
+			Value:        pg_util.NullInt64(1000),
+			Total:        pg_util.NullInt64(5),
+			Used:         pg_util.NullInt64(2),
+			From:         pg_util.NullTime(now.Add(-time.Hour)),
+			To:           pg_util.NullTime(now.Add(time.Hour)),
+			Version:      pg_util.NullInt64(4),
+		}
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.UpdateCouponRequest
+		want    *pb.UpdateCouponResponse
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req:  &pb.UpdateCouponRequest{Id: 3, Total: 8, PercentBasisPoints: 1500},
+			want: &pb.UpdateCouponResponse{Version: 5},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.MatchedBy(func(c *entity.Coupon) bool {
+					return c.Total == pg_util.NullInt64(8) && c.Value == pg_util.NullInt64(1500) && c.Version == pg_util.NullInt64(4)
+				}), []string{"total", "value", "currency"}).Return(nil)
+				s.userCouponRepo.(*mocks.UserCouponRepository).On("UpdateTotalByCouponID", mock.Anything, mock.Anything, int64(3), int64(8)).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, &entity.CouponAudit{
+					CouponID: pg_util.NullInt64(3),
+					Action:   pg_util.NullString(entity.CouponAuditAction_Updated),
+					Changes:  pg_util.NullString(`{"total":{"from":5,"to":8},"value":{"from":1000,"to":1500}}`),
+					Version:  pg_util.NullInt64(5),
+					ActorID:  pg_util.NullInt64(9),
+				}).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case update mask",
+			ctx:  adminCtx,
+			req:  &pb.UpdateCouponRequest{Id: 3, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description"}}, ExpectedVersion: 4},
+			want: &pb.UpdateCouponResponse{Version: 5},
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.Anything, []string{"description"}).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(a *entity.CouponAudit) bool {
+					return a.Changes == pg_util.NullString(`{"description":{"from":null,"to":""}}`)
+				})).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err total below usage",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3, Total: 1},
+			wantErr: status.Errorf(codes.FailedPrecondition, "total must not be below the usage of the coupon (2)"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err total below a usage reserved since the read",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3, Total: 3},
+			wantErr: status.Errorf(codes.FailedPrecondition, "total must not be below the usage of the coupon"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.MatchedBy(func(c *entity.Coupon) bool {
+					return c.Total == pg_util.NullInt64(3)
+				}), []string{"total"}).Return(entity.ErrCouponTotalBelowUsed)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err discount type change",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3, Value: &pb.Money{Amount: 500}},
+			wantErr: status.Errorf(codes.InvalidArgument, "the discount type of the coupon can't be changed"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err version mismatch",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3, Total: 8, ExpectedVersion: 3},
+			wantErr: status.Errorf(codes.Aborted, "coupon has been modified, version mismatch"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(), nil)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err not found",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3, Total: 8},
+			wantErr: status.Errorf(codes.NotFound, "coupon not found"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(nil, sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err nothing to update",
+			ctx:     adminCtx,
+			req:     &pb.UpdateCouponRequest{Id: 3},
+			wantErr: status.Errorf(codes.InvalidArgument, "nothing to update"),
+			setup:   func(smock sqlmock.Sqlmock, s *couponService) {},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.UpdateCouponRequest{Id: 3, Total: 8},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(smock sqlmock.Sqlmock, s *couponService) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:              &postgres_client.PostgresClient{DB: db},
+				couponRepo:      &mocks.CouponRepository{},
+				userCouponRepo:  &mocks.UserCouponRepository{},
+				couponAuditRepo: &mocks.CouponAuditRepository{},
+			}
+			tt.setup(smock, s)
+			got, err := s.UpdateCoupon(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+			s.userCouponRepo.(*mocks.UserCouponRepository).AssertExpectations(t)
+			s.couponAuditRepo.(*mocks.CouponAuditRepository).AssertExpectations(t)
+		})
+	}
+}
+
+func Test_couponRepository_UpdateByID_total(t *testing.T) {
+	updateStmt := `UPDATE coupons SET "total" = \$3, version = version \+ 1, updated_at = NOW\(\) ` +
+		`WHERE id = \$1 AND deleted_at IS NULL AND \(\$2::bigint IS NULL OR version = \$2\) AND COALESCE\(used, 0\) <= \$3`
+	usedStmt := `SELECT COALESCE\(used, 0\) FROM coupons WHERE id = \$1 AND deleted_at IS NULL`
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock)
+	}{
+		{
+			name: "happy case total within the usage",
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(updateStmt).WithArgs(int64(3), int64(4), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:    "err total below the usage of the pending reservations",
+			wantErr: entity.ErrCouponTotalBelowUsed,
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(updateStmt).WithArgs(int64(3), int64(4), int64(3)).WillReturnResult(sqlmock.NewResult(0, 0))
+				smock.ExpectQuery(usedStmt).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(int64(4)))
+			},
+		},
+		{
+			name:    "err version mismatch",
+			wantErr: database.ErrVersionMismatch,
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(updateStmt).WithArgs(int64(3), int64(4), int64(3)).WillReturnResult(sqlmock.NewResult(0, 0))
+				smock.ExpectQuery(usedStmt).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(int64(2)))
+			},
+		},
+		{
+			name:    "err not found",
+			wantErr: sql.ErrNoRows,
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(updateStmt).WithArgs(int64(3), int64(4), int64(3)).WillReturnResult(sqlmock.NewResult(0, 0))
+				smock.ExpectQuery(usedStmt).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			tt.setup(smock)
+			err = postgres.NewCouponRepository().UpdateByID(context.Background(), &postgres_client.PostgresClient{DB: db}, 3, &entity.Coupon{
+				Total:   pg_util.NullInt64(3),
+				Version: pg_util.NullInt64(4),
+			}, []string{"total"})
+			require.ErrorIs(t, err, tt.wantErr)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_couponService_PauseCoupon(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 9, Role: userEntity.UserRole_Admin}))
+	coupon := func(paused bool) *entity.Coupon {
+		c := &entity.Coupon{
+			ID:      pg_util.NullInt64(3),
+			Version: pg_util.NullInt64(4),
+		}
+		if paused {
+			c.PausedAt, c.PausedBy = pg_util.NullTime(time.Now()), pg_util.NullInt64(9)
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		paused  bool
+		want    int64
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, s *couponService)
+	}{
+		{
+			name:   "happy case pause",
+			paused: true,
+			want:   5,
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(false), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.MatchedBy(func(c *entity.Coupon) bool {
+					return c.PausedAt.Valid && c.PausedBy == pg_util.NullInt64(9) && c.Version == pg_util.NullInt64(4)
+				}), []string{"paused_at", "paused_by"}).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(a *entity.CouponAudit) bool {
+					return a.Action == pg_util.NullString(entity.CouponAuditAction_Paused) && a.Version == pg_util.NullInt64(5) && a.Changes.Valid
+				})).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:   "happy case resume",
+			paused: false,
+			want:   5,
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(true), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.MatchedBy(func(c *entity.Coupon) bool {
+					return !c.PausedAt.Valid && !c.PausedBy.Valid
+				}), []string{"paused_at", "paused_by"}).Return(nil)
+				s.couponAuditRepo.(*mocks.CouponAuditRepository).On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(a *entity.CouponAudit) bool {
+					return a.Action == pg_util.NullString(entity.CouponAuditAction_Resumed)
+				})).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:   "happy case already paused",
+			paused: true,
+			want:   4,
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(true), nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err version mismatch",
+			paused:  true,
+			wantErr: status.Errorf(codes.Aborted, "coupon has been modified, version mismatch"),
+			setup: func(smock sqlmock.Sqlmock, s *couponService) {
+				smock.ExpectBegin()
+				s.couponRepo.(*mocks.CouponRepository).On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(coupon(false), nil)
+				s.couponRepo.(*mocks.CouponRepository).On("UpdateByID", mock.Anything, mock.Anything, int64(3), mock.Anything, mock.Anything).Return(database.ErrVersionMismatch)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			s := &couponService{
+				db:              &postgres_client.PostgresClient{DB: db},
+				couponRepo:      &mocks.CouponRepository{},
+				couponAuditRepo: &mocks.CouponAuditRepository{},
+			}
+			tt.setup(smock, s)
+			var got int64
+			if tt.paused {
+				resp, err := s.PauseCoupon(adminCtx, &pb.PauseCouponRequest{Id: 3})
+				got = resp.GetVersion()
+				if tt.wantErr != nil {
+					require.Error(t, err)
+					require.Equal(t, tt.wantErr.Error(), err.Error())
+				} else {
+					require.NoError(t, err)
+				}
+			} else {
+				resp, err := s.ResumeCoupon(adminCtx, &pb.ResumeCouponRequest{Id: 3})
+				got = resp.GetVersion()
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, got)
+			require.NoError(t, smock.ExpectationsWereMet())
+			s.couponRepo.(*mocks.CouponRepository).AssertExpectations(t)
+			s.couponAuditRepo.(*mocks.CouponAuditRepository).AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package entity
+
+import "database/sql"
+
+// Address is an address of the address book of a user.
+type Address struct {
+	ID         sql.NullInt64  `db:"id"`
+	UserID     sql.NullInt64  `db:"user_id"`
+	Recipient  sql.NullString `db:"recipient"`
+	Line1      sql.NullString `db:"line1"`
+	Line2      sql.NullString `db:"line2"`
+	City       sql.NullString `db:"city"`
+	PostalCode sql.NullString `db:"postal_code"`
+	// Region is the ISO 3166 code of the country or subdivision, e.g. "DE" or "US-CA".
+	Region    sql.NullString `db:"region"`
+	Phone     sql.NullString `db:"phone"`
+	IsDefault sql.NullBool   `db:"is_default"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the Address entity.
+func (u *Address) TableName() string {
+	return "addresses"
+}
+
+// ShippingAddress is the copy of an address stored with a purchase, it is kept when the address is edited.
+type ShippingAddress struct {
+	Recipient  string `json:"recipient"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Region     string `json:"region"`
+	Phone      string `json:"phone,omitempty"`
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "database/sql"
+
+// Cart represents the structure of a shopping cart entity in the database.
+// A cart has a user, or a token when it is anonymous.
+type Cart struct {
+	ID        sql.NullInt64  `db:"id"`
+	UserID    sql.NullInt64  `db:"user_id"`
+	Token     sql.NullString `db:"token"`
+	Coupon    sql.NullString `db:"coupon"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the Cart entity.
+func (u *Cart) TableName() string {
+	return "carts"
+}
+
+// CartItem represents a product line of a cart.
+type CartItem struct {
+	CartID    sql.NullInt64 `db:"cart_id"`
+	ProductID sql.NullInt64 `db:"product_id"`
+	Quantity  sql.NullInt64 `db:"quantity"`
+	CreatedAt sql.NullTime  `db:"created_at"`
+	UpdatedAt sql.NullTime  `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the CartItem entity.
+func (u *CartItem) TableName() string {
+	return "cart_items"
+}
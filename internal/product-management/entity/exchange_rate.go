@@ -0,0 +1,17 @@
+package entity
+
+import "database/sql"
+
+// ExchangeRate represents the amount of a currency for a unit of the base currency.
+type ExchangeRate struct {
+	Currency  sql.NullString  `db:"currency"`
+	Rate      sql.NullFloat64 `db:"rate"`
+	UpdatedBy sql.NullInt64   `db:"updated_by"`
+	CreatedAt sql.NullTime    `db:"created_at"`
+	UpdatedAt sql.NullTime    `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the ExchangeRate entity.
+func (u *ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
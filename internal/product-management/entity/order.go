@@ -0,0 +1,106 @@
+package entity
+
+import "database/sql"
+
+// Order statuses, see the order state machine of the service for the allowed transitions.
+const (
+	OrderStatus_Pending   = "PENDING"
+	OrderStatus_Paid      = "PAID"
+	OrderStatus_Fulfilled = "FULFILLED"
+	OrderStatus_Delivered = "DELIVERED"
+	OrderStatus_Cancelled = "CANCELLED"
+	OrderStatus_Refunded  = "REFUNDED"
+)
+
+// Order represents the structure of an order entity in the database.
+type Order struct {
+	ID        sql.NullInt64  `db:"id"`
+	UserID    sql.NullInt64  `db:"user_id"`
+	Status    sql.NullString `db:"status"`
+	Subtotal  sql.NullInt64  `db:"subtotal"`
+	Discount  sql.NullInt64  `db:"discount"`
+	Total     sql.NullInt64  `db:"total"`
+	Currency  sql.NullString `db:"currency"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+	// Tax and Shipping are included in the total.
+	Tax      sql.NullInt64 `db:"tax"`
+	Shipping sql.NullInt64 `db:"shipping"`
+	// CouponRedemptionID is the redemption of the coupon of the order, reserved when the order is placed.
+	CouponRedemptionID sql.NullString `db:"coupon_redemption_id"`
+	// ShippingAddress is the JSON of the [ShippingAddress] the order is shipped to, they are unset for an order which is not shipped.
+	ShippingMethodID sql.NullInt64  `db:"shipping_method_id"`
+	ShippingAddress  sql.NullString `db:"shipping_address"`
+	// PaymentID is the id of the payment at the payment provider, authorized when the order is placed and captured
+	// once it is paid. The orders without total and the orders placed before payments don't have one.
+	PaymentID     sql.NullString `db:"payment_id"`
+	PaymentStatus sql.NullString `db:"payment_status"`
+}
+
+// TableName returns the name of the database table associated with the Order entity.
+func (u *Order) TableName() string {
+	return "orders"
+}
+
+// OrderItem represents a product line of an order, with the product name and price when it was ordered.
+// The amounts are in the currency of the order.
+type OrderItem struct {
+	ID        sql.NullInt64  `db:"id"`
+	OrderID   sql.NullInt64  `db:"order_id"`
+	ProductID sql.NullInt64  `db:"product_id"`
+	Name      sql.NullString `db:"name"`
+	UnitPrice sql.NullInt64  `db:"unit_price"`
+	Quantity  sql.NullInt64  `db:"quantity"`
+	Subtotal  sql.NullInt64  `db:"subtotal"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	// Discount is the share of the order discount of the item, Total is the amount paid for the item with its shipping
+	// after its discount.
+	Discount sql.NullInt64 `db:"discount"`
+	Total    sql.NullInt64 `db:"total"`
+	// Shipping is the share of the order shipping of the item.
+	Shipping sql.NullInt64 `db:"shipping"`
+	// Tax is included in the total, and in the subtotal too for an inclusive rate. The rate is unset for an untaxed item.
+	Tax            sql.NullInt64  `db:"tax"`
+	TaxRegion      sql.NullString `db:"tax_region"`
+	TaxCategory    sql.NullString `db:"tax_category"`
+	TaxBasisPoints sql.NullInt64  `db:"tax_basis_points"`
+	TaxInclusive   sql.NullBool   `db:"tax_inclusive"`
+}
+
+// TableName returns the name of the database table associated with the OrderItem entity.
+func (u *OrderItem) TableName() string {
+	return "order_items"
+}
+
+// OrderDiscount represents a discount applied to an order. Value is the coupon value, in basis points
+// for a percent discount, and Amount is the discounted amount in the currency of the order.
+type OrderDiscount struct {
+	ID           sql.NullInt64  `db:"id"`
+	OrderID      sql.NullInt64  `db:"order_id"`
+	Coupon       sql.NullString `db:"coupon"`
+	DiscountType sql.NullString `db:"discount_type"`
+	Value        sql.NullInt64  `db:"value"`
+	Amount       sql.NullInt64  `db:"amount"`
+	CreatedAt    sql.NullTime   `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the OrderDiscount entity.
+func (u *OrderDiscount) TableName() string {
+	return "order_discounts"
+}
+
+// OrderHistory represents a status transition of an order. FromStatus is NULL when the order is created.
+type OrderHistory struct {
+	ID         sql.NullInt64  `db:"id"`
+	OrderID    sql.NullInt64  `db:"order_id"`
+	FromStatus sql.NullString `db:"from_status"`
+	ToStatus   sql.NullString `db:"to_status"`
+	ChangedBy  sql.NullInt64  `db:"changed_by"`
+	Reason     sql.NullString `db:"reason"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the OrderHistory entity.
+func (u *OrderHistory) TableName() string {
+	return "order_histories"
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "database/sql"
+
+// The reasons of a price change.
+const (
+	PriceChangeReason_Created  = "CREATED"
+	PriceChangeReason_Updated  = "UPDATED"
+	PriceChangeReason_Imported = "IMPORTED"
+)
+
+// PriceHistory represents a change of the regular price of a product.
+type PriceHistory struct {
+	ID        sql.NullInt64  `db:"id"`
+	ProductID sql.NullInt64  `db:"product_id"`
+	Price     sql.NullInt64  `db:"price"`
+	Currency  sql.NullString `db:"currency"`
+	ChangedBy sql.NullInt64  `db:"changed_by"`
+	Reason    sql.NullString `db:"reason"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the PriceHistory entity.
+func (u *PriceHistory) TableName() string {
+	return "price_histories"
+}
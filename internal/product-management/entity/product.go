@@ -2,21 +2,55 @@ package entity
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 // Product represents the entity structure for products in the database.
 type Product struct {
-	ID          sql.NullInt64   `db:"id"`
-	Name        sql.NullString  `db:"name"`
-	Type        sql.NullString  `db:"type"`
-	ImageURLs   pq.StringArray  `db:"image_urls"`
-	Description sql.NullString  `db:"description"`
-	Price       sql.NullFloat64 `db:"price"`
-	CreatedBy   sql.NullInt64   `db:"created_by"`
-	CreatedAt   sql.NullTime    `db:"created_at"`
-	UpdatedAt   sql.NullTime    `db:"updated_at"`
+	ID            sql.NullInt64   `db:"id"`
+	Name          sql.NullString  `db:"name"`
+	Type          sql.NullString  `db:"type"`
+	ImageURLs     pq.StringArray  `db:"image_urls"`
+	Description   sql.NullString  `db:"description"`
+	Price         sql.NullInt64   `db:"price"`
+	Currency      sql.NullString  `db:"currency"`
+	CreatedBy     sql.NullInt64   `db:"created_by"`
+	CreatedAt     sql.NullTime    `db:"created_at"`
+	UpdatedAt     sql.NullTime    `db:"updated_at"`
+	DeletedAt     sql.NullTime    `db:"deleted_at"`
+	DeletedBy     sql.NullInt64   `db:"deleted_by"`
+	SKU           sql.NullString  `db:"sku"`
+	ExternalID    sql.NullString  `db:"external_id"`
+	RatingAverage sql.NullFloat64 `db:"rating_average"`
+	RatingCount   sql.NullInt64   `db:"rating_count"`
+	WeightGrams   sql.NullInt64   `db:"weight_grams"`
+	// Version is incremented by every update, an update with a version only applies to that version.
+	Version sql.NullInt64 `db:"version"`
+}
+
+// ProductOrder is the order of a product listing.
+type ProductOrder string
+
+const (
+	// ProductOrder_Default lists the products in storage order.
+	ProductOrder_Default ProductOrder = ""
+	// ProductOrder_Rating lists the best rated products first.
+	ProductOrder_Rating ProductOrder = "RATING"
+)
+
+// ProductPage is a page of a product listing with the total number of products.
+type ProductPage struct {
+	Products []*Product
+	Total    int64
+}
+
+// ProductView is the serialised response of a product priced in a currency and localised.
+// It is valid until ExpiresAt, when the price of the product changes, or without expiry when it is zero.
+type ProductView struct {
+	Data      []byte
+	ExpiresAt time.Time
 }
 
 // TableName returns the name of the database table associated with the Product entity.
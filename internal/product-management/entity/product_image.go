@@ -0,0 +1,18 @@
+package entity
+
+import "database/sql"
+
+// ProductImage is an image of a product, FileID is the id of a file of storage-management.
+type ProductImage struct {
+	ProductID sql.NullInt64  `db:"product_id"`
+	FileID    sql.NullString `db:"file_id"`
+	Position  sql.NullInt64  `db:"position"`
+	AltText   sql.NullString `db:"alt_text"`
+	IsPrimary sql.NullBool   `db:"is_primary"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the ProductImage entity.
+func (u *ProductImage) TableName() string {
+	return "product_images"
+}
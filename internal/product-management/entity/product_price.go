@@ -0,0 +1,17 @@
+package entity
+
+import "database/sql"
+
+// ProductPrice represents the price of a product in another currency than the base currency.
+type ProductPrice struct {
+	ProductID sql.NullInt64  `db:"product_id"`
+	Currency  sql.NullString `db:"currency"`
+	Amount    sql.NullInt64  `db:"amount"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the ProductPrice entity.
+func (u *ProductPrice) TableName() string {
+	return "product_prices"
+}
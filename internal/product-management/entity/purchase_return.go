@@ -0,0 +1,39 @@
+package entity
+
+import "database/sql"
+
+// Purchase return statuses, a requested return is refunded once an admin approves it.
+const (
+	PurchaseReturnStatus_Requested = "REQUESTED"
+	PurchaseReturnStatus_Refunded  = "REFUNDED"
+)
+
+// Purchase return reasons.
+const (
+	PurchaseReturnReason_Damaged        = "DAMAGED"
+	PurchaseReturnReason_NotAsDescribed = "NOT_AS_DESCRIBED"
+	PurchaseReturnReason_WrongItem      = "WRONG_ITEM"
+	PurchaseReturnReason_NoLongerNeeded = "NO_LONGER_NEEDED"
+	PurchaseReturnReason_Other          = "OTHER"
+)
+
+// PurchaseReturn represents a return of a purchase requested by its user.
+// The amount is the refunded part of the purchase, in the currency of the purchase.
+type PurchaseReturn struct {
+	ID         sql.NullInt64  `db:"id"`
+	PurchaseID sql.NullInt64  `db:"purchase_id"`
+	UserID     sql.NullInt64  `db:"user_id"`
+	Amount     sql.NullInt64  `db:"amount"`
+	Currency   sql.NullString `db:"currency"`
+	Reason     sql.NullString `db:"reason"`
+	Note       sql.NullString `db:"note"`
+	Status     sql.NullString `db:"status"`
+	ApprovedBy sql.NullInt64  `db:"approved_by"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the PurchaseReturn entity.
+func (u *PurchaseReturn) TableName() string {
+	return "purchase_returns"
+}
@@ -4,17 +4,50 @@ import "database/sql"
 
 // PurchasedProduct represents the structure of a purchased product entity in the database.
 type PurchasedProduct struct {
-	ProductID sql.NullInt64   `db:"product_id"`
-	UserID    sql.NullInt64   `db:"user_id"`
-	Price     sql.NullFloat64 `db:"price"`
-	Discount  sql.NullFloat64 `db:"discount"`
-	Total     sql.NullFloat64 `db:"purchase_total"`
-	Coupon    sql.NullString  `db:"apply_coupon"`
-	CreatedAt sql.NullTime    `db:"created_at"`
-	UpdatedAt sql.NullTime    `db:"updated_at"`
+	ID        sql.NullInt64  `db:"id"`
+	ProductID sql.NullInt64  `db:"product_id"`
+	UserID    sql.NullInt64  `db:"user_id"`
+	Price     sql.NullInt64  `db:"price"`
+	Discount  sql.NullInt64  `db:"discount"`
+	Total     sql.NullInt64  `db:"purchase_total"`
+	Currency  sql.NullString `db:"currency"`
+	Coupon    sql.NullString `db:"apply_coupon"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+	OrderID   sql.NullInt64  `db:"order_id"`
+	// PaymentID is the id of the payment at the payment provider, purchases recorded before payments don't have one.
+	PaymentID     sql.NullString `db:"payment_id"`
+	PaymentStatus sql.NullString `db:"payment_status"`
+	// RefundedAmount is the refunded part of the total.
+	RefundedAmount sql.NullInt64 `db:"refunded_amount"`
+	// CouponRedemptionID is the redemption of the coupon, restored when the purchase is fully refunded.
+	CouponRedemptionID sql.NullString `db:"coupon_redemption_id"`
+	// Tax is included in the total, and in the price too for an inclusive rate.
+	// The tax fields are NULL for an untaxed purchase.
+	Tax            sql.NullInt64  `db:"tax"`
+	TaxRegion      sql.NullString `db:"tax_region"`
+	TaxCategory    sql.NullString `db:"tax_category"`
+	TaxBasisPoints sql.NullInt64  `db:"tax_basis_points"`
+	TaxInclusive   sql.NullBool   `db:"tax_inclusive"`
+	// Shipping is included in the total, ShippingAddress is the JSON of the [ShippingAddress] it was shipped to.
+	// The shipping fields are NULL for a purchase which is not shipped.
+	ShippingMethodID sql.NullInt64  `db:"shipping_method_id"`
+	Shipping         sql.NullInt64  `db:"shipping"`
+	ShippingAddress  sql.NullString `db:"shipping_address"`
+	// Quantity is the number of units of the purchase, Price is the price of a unit.
+	Quantity sql.NullInt64 `db:"quantity"`
 }
 
 // TableName returns the name of the database table associated with the PurchasedProduct entity.
 func (u *PurchasedProduct) TableName() string {
 	return "purchased_products"
 }
+
+// PurchasedProductFilter filters the purchased products, the invalid fields don't filter.
+type PurchasedProductFilter struct {
+	UserID    sql.NullInt64
+	ProductID sql.NullInt64
+	Coupon    sql.NullString
+	From      sql.NullTime
+	To        sql.NullTime
+}
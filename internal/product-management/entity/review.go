@@ -0,0 +1,42 @@
+package entity
+
+import "database/sql"
+
+// Review statuses, only approved reviews are public and counted in the product rating.
+const (
+	ReviewStatus_Pending  = "PENDING"
+	ReviewStatus_Approved = "APPROVED"
+	ReviewStatus_Hidden   = "HIDDEN"
+)
+
+// Review represents the structure of a product review entity in the database.
+type Review struct {
+	ID           sql.NullInt64  `db:"id"`
+	ProductID    sql.NullInt64  `db:"product_id"`
+	UserID       sql.NullInt64  `db:"user_id"`
+	Rating       sql.NullInt64  `db:"rating"`
+	Content      sql.NullString `db:"content"`
+	Status       sql.NullString `db:"status"`
+	HelpfulCount sql.NullInt64  `db:"helpful_count"`
+	ModeratedBy  sql.NullInt64  `db:"moderated_by"`
+	ModeratedAt  sql.NullTime   `db:"moderated_at"`
+	CreatedAt    sql.NullTime   `db:"created_at"`
+	UpdatedAt    sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the Review entity.
+func (u *Review) TableName() string {
+	return "reviews"
+}
+
+// ReviewHelpfulVote represents a user marking a review helpful.
+type ReviewHelpfulVote struct {
+	ReviewID  sql.NullInt64 `db:"review_id"`
+	UserID    sql.NullInt64 `db:"user_id"`
+	CreatedAt sql.NullTime  `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the ReviewHelpfulVote entity.
+func (u *ReviewHelpfulVote) TableName() string {
+	return "review_helpful_votes"
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// The dimensions of the sales rollups, the sales of a day are aggregated in total and by product, type and coupon.
+const (
+	SalesDimension_Total   = "TOTAL"
+	SalesDimension_Product = "PRODUCT"
+	SalesDimension_Type    = "TYPE"
+	SalesDimension_Coupon  = "COUPON"
+)
+
+// The intervals the sales rollups are grouped by, the weeks start on Monday.
+const (
+	SalesInterval_Day   = "day"
+	SalesInterval_Week  = "week"
+	SalesInterval_Month = "month"
+)
+
+// SalesRollup is the sales of the purchases of a UTC day in a currency, in total or for a key of a dimension.
+// Revenue is net of the refunds, Orders counts the distinct orders of the purchases.
+type SalesRollup struct {
+	Day          sql.NullTime   `db:"day"`
+	Dimension    sql.NullString `db:"dimension"`
+	DimensionKey sql.NullString `db:"dimension_key"`
+	Currency     sql.NullString `db:"currency"`
+	Units        sql.NullInt64  `db:"units"`
+	Orders       sql.NullInt64  `db:"orders"`
+	Revenue      sql.NullInt64  `db:"revenue"`
+	Discount     sql.NullInt64  `db:"discount"`
+	Refunded     sql.NullInt64  `db:"refunded"`
+	RefreshedAt  sql.NullTime   `db:"refreshed_at"`
+}
+
+// TableName returns the name of the database table associated with the SalesRollup entity.
+func (u *SalesRollup) TableName() string {
+	return "sales_rollups"
+}
+
+// SalesRollupFilter selects the sales rollups of the days in [From, To) of a dimension, grouped by Interval.
+type SalesRollupFilter struct {
+	From      time.Time
+	To        time.Time
+	Interval  string
+	Dimension string
+	// DimensionKeys restricts the keys of the dimension, every key without DimensionKeys.
+	DimensionKeys []string
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "database/sql"
+
+// ScheduledPrice represents a price which replaces the regular price of a product from StartsAt until EndsAt,
+// forever without EndsAt.
+type ScheduledPrice struct {
+	ID        sql.NullInt64  `db:"id"`
+	ProductID sql.NullInt64  `db:"product_id"`
+	Price     sql.NullInt64  `db:"price"`
+	Currency  sql.NullString `db:"currency"`
+	StartsAt  sql.NullTime   `db:"starts_at"`
+	EndsAt    sql.NullTime   `db:"ends_at"`
+	CreatedBy sql.NullInt64  `db:"created_by"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the ScheduledPrice entity.
+func (u *ScheduledPrice) TableName() string {
+	return "scheduled_prices"
+}
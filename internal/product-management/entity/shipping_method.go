@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// ShippingMethod is a shipping method, its amounts are in its currency.
+// FreeThreshold is the order value from which the shipping is free, zero when it is never free.
+type ShippingMethod struct {
+	ID            sql.NullInt64  `db:"id"`
+	Name          sql.NullString `db:"name"`
+	Currency      sql.NullString `db:"currency"`
+	FreeThreshold sql.NullInt64  `db:"free_threshold"`
+	CreatedBy     sql.NullInt64  `db:"created_by"`
+	CreatedAt     sql.NullTime   `db:"created_at"`
+	UpdatedAt     sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the ShippingMethod entity.
+func (u *ShippingMethod) TableName() string {
+	return "shipping_methods"
+}
+
+// ShippingRate is a rate rule of a shipping method for the parcels of the regions within bounds of weight
+// and order value. Empty regions are everywhere and zero upper bounds are unbounded.
+type ShippingRate struct {
+	ID               sql.NullInt64  `db:"id"`
+	ShippingMethodID sql.NullInt64  `db:"shipping_method_id"`
+	Regions          pq.StringArray `db:"regions"`
+	MinWeightGrams   sql.NullInt64  `db:"min_weight_grams"`
+	MaxWeightGrams   sql.NullInt64  `db:"max_weight_grams"`
+	MinOrderValue    sql.NullInt64  `db:"min_order_value"`
+	MaxOrderValue    sql.NullInt64  `db:"max_order_value"`
+	Amount           sql.NullInt64  `db:"amount"`
+}
+
+// TableName returns the name of the database table associated with the ShippingRate entity.
+func (u *ShippingRate) TableName() string {
+	return "shipping_rates"
+}
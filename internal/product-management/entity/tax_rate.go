@@ -0,0 +1,20 @@
+package entity
+
+import "database/sql"
+
+// TaxRate is the tax rate of a region for a product category set by an admin, an empty category is the
+// default rate of the region.
+type TaxRate struct {
+	Region      sql.NullString `db:"region"`
+	Category    sql.NullString `db:"category"`
+	BasisPoints sql.NullInt64  `db:"basis_points"`
+	Inclusive   sql.NullBool   `db:"inclusive"`
+	UpdatedBy   sql.NullInt64  `db:"updated_by"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the TaxRate entity.
+func (u *TaxRate) TableName() string {
+	return "tax_rates"
+}
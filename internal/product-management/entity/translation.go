@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// ProductTranslation is the name and description of a product in a locale other than the default locale.
+type ProductTranslation struct {
+	ProductID   sql.NullInt64  `db:"product_id"`
+	Locale      sql.NullString `db:"locale"`
+	Name        sql.NullString `db:"name"`
+	Description sql.NullString `db:"description"`
+	UpdatedBy   sql.NullInt64  `db:"updated_by"`
+	CreatedAt   sql.NullTime   `db:"created_at"`
+	UpdatedAt   sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the ProductTranslation entity.
+func (u *ProductTranslation) TableName() string {
+	return "product_translations"
+}
+
+// CategoryTranslation is the name of a product category, a product type, in a locale other than the default locale.
+type CategoryTranslation struct {
+	Category  sql.NullString `db:"category"`
+	Locale    sql.NullString `db:"locale"`
+	Name      sql.NullString `db:"name"`
+	UpdatedBy sql.NullInt64  `db:"updated_by"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the CategoryTranslation entity.
+func (u *CategoryTranslation) TableName() string {
+	return "category_translations"
+}
+
+// MissingProductTranslation lists the locales a product is not translated in.
+type MissingProductTranslation struct {
+	ProductID sql.NullInt64  `db:"product_id"`
+	Name      sql.NullString `db:"name"`
+	Locales   pq.StringArray `db:"locales"`
+}
+
+// MissingCategoryTranslation lists the locales a product category is not translated in.
+type MissingCategoryTranslation struct {
+	Category sql.NullString `db:"category"`
+	Locales  pq.StringArray `db:"locales"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "database/sql"
+
+// Wishlist represents the structure of a user's wishlist entity in the database.
+type Wishlist struct {
+	UserID     sql.NullInt64  `db:"user_id"`
+	ShareToken sql.NullString `db:"share_token"`
+	CreatedAt  sql.NullTime   `db:"created_at"`
+	UpdatedAt  sql.NullTime   `db:"updated_at"`
+}
+
+// TableName returns the name of the database table associated with the Wishlist entity.
+func (u *Wishlist) TableName() string {
+	return "wishlists"
+}
+
+// WishlistItem represents a product saved in a user's wishlist.
+type WishlistItem struct {
+	UserID    sql.NullInt64 `db:"user_id"`
+	ProductID sql.NullInt64 `db:"product_id"`
+	CreatedAt sql.NullTime  `db:"created_at"`
+}
+
+// TableName returns the name of the database table associated with the WishlistItem entity.
+func (u *WishlistItem) TableName() string {
+	return "wishlist_items"
+}
+
+// ProductWishlistCount is the number of wishlists containing a product.
+type ProductWishlistCount struct {
+	ProductID sql.NullInt64  `db:"product_id"`
+	Name      sql.NullString `db:"name"`
+	Total     sql.NullInt64  `db:"total"`
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type AddressRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.Address) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, userID, id int64) (*entity.Address, error)
+	RetrieveDefault(ctx context.Context, db database.Executor, userID int64) (*entity.Address, error)
+	ListByUserID(ctx context.Context, db database.Executor, userID int64) ([]*entity.Address, error)
+	UpdateByID(ctx context.Context, db database.Executor, userID, id int64, data *entity.Address) error
+	DeleteByID(ctx context.Context, db database.Executor, userID, id int64) error
+	ClearDefault(ctx context.Context, db database.Executor, userID int64) error
+	SetDefault(ctx context.Context, db database.Executor, userID, id int64) error
+}
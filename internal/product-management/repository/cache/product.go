@@ -0,0 +1,138 @@
+package memcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/cache"
+	"trintech/review/pkg/lru"
+)
+
+// productCacheRepository is an implementation of the repository.ProductCacheRepository interface.
+type productCacheRepository struct {
+	products cache.Cache[int64, *entity.Product]      // Cache for storing products by ID
+	pages    cache.Cache[string, *entity.ProductPage] // Cache for storing pages of the product listings
+	views    cache.Cache[string, *entity.ProductView] // Cache for storing the priced and localised views of the products
+	// generation is part of the keys of the pages, so incrementing it removes every page at once.
+	generation atomic.Int64
+	// viewGeneration is part of the keys of the views, so incrementing it removes every view at once.
+	viewGeneration atomic.Int64
+
+	// mu orders the stores after the removals, so that a value loaded before a removal is never stored after it.
+	mu sync.Mutex
+	// removals is the generation of the cache, incremented by every removal.
+	removals int64
+}
+
+// NewProductCacheRepository creates a new instance of productCacheRepository.
+func NewProductCacheRepository() repository.ProductCacheRepository {
+	return &productCacheRepository{
+		products: lru.NewLRU[int64, *entity.Product](10000, 10*time.Minute),
+		pages:    lru.NewLRU[string, *entity.ProductPage](1000, time.Minute),
+		views:    lru.NewLRU[string, *entity.ProductView](10000, time.Minute),
+	}
+}
+
+// RetrieveByID retrieves a product from the cache based on its ID.
+func (r *productCacheRepository) RetrieveByID(ctx context.Context, id int64) (*entity.Product, error) {
+	return r.products.Get(ctx, id)
+}
+
+// Generation returns the generation of the cache, which changes with every removal.
+func (r *productCacheRepository) Generation(_ context.Context) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.removals
+}
+
+// StoreByID stores a product loaded in a generation in the cache based on its ID,
+// the product is skipped when the cache has had a removal since.
+func (r *productCacheRepository) StoreByID(ctx context.Context, id int64, product *entity.Product, generation int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.removals != generation {
+		return nil
+	}
+
+	return r.products.Add(ctx, id, product)
+}
+
+// RemoveByID removes a product from the cache based on its ID, a product which is not cached is not an error.
+func (r *productCacheRepository) RemoveByID(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removals++
+	_ = r.products.Remove(ctx, id)
+
+	return nil
+}
+
+// RetrievePage retrieves a page of a product listing from the cache based on its key.
+func (r *productCacheRepository) RetrievePage(ctx context.Context, key string) (*entity.ProductPage, error) {
+	return r.pages.Get(ctx, r.pageKey(key))
+}
+
+// StorePage stores a page of a product listing loaded in a generation in the cache based on its key,
+// the page is skipped when the cache has had a removal since.
+func (r *productCacheRepository) StorePage(ctx context.Context, key string, page *entity.ProductPage, generation int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.removals != generation {
+		return nil
+	}
+
+	return r.pages.Add(ctx, r.pageKey(key), page)
+}
+
+// RemovePages removes the pages of every product listing, the removed pages are evicted by the LRU.
+func (r *productCacheRepository) RemovePages(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removals++
+	r.generation.Add(1)
+
+	return nil
+}
+
+// pageKey returns the key of a page in the current generation.
+func (r *productCacheRepository) pageKey(key string) string {
+	return fmt.Sprintf("%d|%s", r.generation.Load(), key)
+}
+
+// RetrieveView retrieves the view of a product from the cache based on its key.
+func (r *productCacheRepository) RetrieveView(ctx context.Context, key string) (*entity.ProductView, error) {
+	return r.views.Get(ctx, r.viewKey(key))
+}
+
+// StoreView stores the view of a product assembled in a generation in the cache based on its key,
+// the view is skipped when the cache has had a removal since.
+func (r *productCacheRepository) StoreView(ctx context.Context, key string, view *entity.ProductView, generation int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.removals != generation {
+		return nil
+	}
+
+	return r.views.Add(ctx, r.viewKey(key), view)
+}
+
+// RemoveViews removes the views of every product, the removed views are evicted by the LRU.
+func (r *productCacheRepository) RemoveViews(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removals++
+	r.viewGeneration.Add(1)
+
+	return nil
+}
+
+// viewKey returns the key of a view in the current generation.
+func (r *productCacheRepository) viewKey(key string) string {
+	return fmt.Sprintf("%d|%s", r.viewGeneration.Load(), key)
+}
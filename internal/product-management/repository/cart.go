@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type CartRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.Cart) (int64, error)
+	RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Cart, error)
+	RetrieveByToken(ctx context.Context, db database.Executor, token string) (*entity.Cart, error)
+	UpdateCouponByID(ctx context.Context, db database.Executor, id int64, coupon sql.NullString) error
+	MergeInto(ctx context.Context, db database.Executor, fromID, toID int64) error
+	ListItems(ctx context.Context, db database.Executor, cartID int64) ([]*entity.CartItem, error)
+	AddItem(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error
+	SetItemQuantity(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error
+	RemoveItem(ctx context.Context, db database.Executor, cartID, productID int64) error
+	ClearItems(ctx context.Context, db database.Executor, cartID int64) error
+}
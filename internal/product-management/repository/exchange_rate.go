@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ExchangeRateRepository interface {
+	Upsert(ctx context.Context, db database.Executor, data *entity.ExchangeRate) error
+	RetrieveByCurrency(ctx context.Context, db database.Executor, currency string) (*entity.ExchangeRate, error)
+	List(ctx context.Context, db database.Executor) ([]*entity.ExchangeRate, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type OrderRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.Order) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Order, error)
+	UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string) error
+	UpdatePaymentStatusByID(ctx context.Context, db database.Executor, id int64, paymentStatus string) error
+	UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error
+	CreateItem(ctx context.Context, db database.Executor, data *entity.OrderItem) error
+	ListItems(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderItem, error)
+	CreateDiscount(ctx context.Context, db database.Executor, data *entity.OrderDiscount) error
+	ListDiscounts(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderDiscount, error)
+	CreateHistory(ctx context.Context, db database.Executor, data *entity.OrderHistory) error
+	ListHistories(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderHistory, error)
+}
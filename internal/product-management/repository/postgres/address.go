@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+var (
+	// addressCreateColumns are the columns written when an address is created, the others keep their default.
+	addressCreateColumns = []string{"user_id", "recipient", "line1", "line2", "city", "postal_code", "region", "phone", "is_default"}
+	// addressUpdateColumns are the columns of an address the user can edit.
+	addressUpdateColumns = []string{"recipient", "line1", "line2", "city", "postal_code", "region", "phone"}
+)
+
+type addressRepository struct{}
+
+func NewAddressRepository() repository.AddressRepository {
+	return &addressRepository{}
+}
+
+func (r *addressRepository) Create(ctx context.Context, db database.Executor, data *entity.Address) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, addressCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// RetrieveByID retrieves an address of a user, the addresses of other users are not found.
+func (r *addressRepository) RetrieveByID(ctx context.Context, db database.Executor, userID, id int64) (*entity.Address, error) {
+	return r.retrieve(ctx, db, "WHERE user_id = $1 AND id = $2", &userID, &id)
+}
+
+// RetrieveDefault retrieves the default address of a user, it returns sql.ErrNoRows when the user has none.
+func (r *addressRepository) RetrieveDefault(ctx context.Context, db database.Executor, userID int64) (*entity.Address, error) {
+	return r.retrieve(ctx, db, "WHERE user_id = $1 AND is_default", &userID)
+}
+
+func (r *addressRepository) retrieve(ctx context.Context, db database.Executor, where string, args ...any) (*entity.Address, error) {
+	e := &entity.Address{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+	`, strings.Join(fieldNames, ","), e.TableName(), where)
+
+	if err := db.QueryRowContext(ctx, stmt, args...).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// ListByUserID lists the addresses of a user, the default address first.
+func (r *addressRepository) ListByUserID(ctx context.Context, db database.Executor, userID int64) ([]*entity.Address, error) {
+	e := &entity.Address{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE user_id = $1
+		ORDER BY is_default DESC, id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.Address
+	for rows.Next() {
+		var val entity.Address
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// UpdateByID updates the editable columns of an address of a user, it returns sql.ErrNoRows when the user has no such address.
+func (r *addressRepository) UpdateByID(ctx context.Context, db database.Executor, userID, id int64, data *entity.Address) error {
+	fieldNames, values := database.SelectFieldMap(data, addressUpdateColumns)
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		%s,
+		updated_at = NOW()
+		WHERE user_id = $1
+		AND id = $2
+	`, data.TableName(), database.GetSetClauses(fieldNames, 2))
+
+	result, err := db.ExecContext(ctx, stmt, append([]any{&userID, &id}, values...)...)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteByID deletes an address of a user, it returns sql.ErrNoRows when the user has no such address.
+func (r *addressRepository) DeleteByID(ctx context.Context, db database.Executor, userID, id int64) error {
+	e := &entity.Address{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE user_id = $1
+		AND id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &userID, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ClearDefault unsets the default address of a user.
+func (r *addressRepository) ClearDefault(ctx context.Context, db database.Executor, userID int64) error {
+	e := &entity.Address{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET is_default = false, updated_at = NOW()
+		WHERE user_id = $1
+		AND is_default
+	`, e.TableName())
+
+	_, err := db.ExecContext(ctx, stmt, &userID)
+
+	return err
+}
+
+// SetDefault sets the default address of a user, the previous default address must be cleared first.
+// It returns sql.ErrNoRows when the user has no such address.
+func (r *addressRepository) SetDefault(ctx context.Context, db database.Executor, userID, id int64) error {
+	e := &entity.Address{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET is_default = true, updated_at = NOW()
+		WHERE user_id = $1
+		AND id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &userID, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// cartCreateColumns are the columns written when a cart is created, the others keep their default.
+var cartCreateColumns = []string{"user_id", "token", "coupon"}
+
+type cartRepository struct{}
+
+func NewCartRepository() repository.CartRepository {
+	return &cartRepository{}
+}
+
+func (r *cartRepository) Create(ctx context.Context, db database.Executor, data *entity.Cart) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, cartCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *cartRepository) RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Cart, error) {
+	e := &entity.Cart{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE user_id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &userID).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// RetrieveByToken retrieves an anonymous cart by its token.
+func (r *cartRepository) RetrieveByToken(ctx context.Context, db database.Executor, token string) (*entity.Cart, error) {
+	e := &entity.Cart{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE token = $1
+		AND user_id IS NULL
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &token).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *cartRepository) UpdateCouponByID(ctx context.Context, db database.Executor, id int64, coupon sql.NullString) error {
+	e := &entity.Cart{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		coupon = $2,
+		updated_at = NOW()
+		WHERE id = $1
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &coupon)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MergeInto moves the items of a cart into another cart, adding up the quantities of the same product,
+// and deletes the emptied cart. The coupon of the target cart wins over the merged one.
+func (r *cartRepository) MergeInto(ctx context.Context, db database.Executor, fromID, toID int64) error {
+	e := &entity.Cart{}
+	iE := &entity.CartItem{}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(cart_id, product_id, quantity)
+		SELECT $2, product_id, quantity
+		FROM %s
+		WHERE cart_id = $1
+		ON CONFLICT (cart_id, product_id) DO UPDATE
+		SET
+		quantity = %s.quantity + EXCLUDED.quantity,
+		updated_at = NOW()
+	`, iE.TableName(), iE.TableName(), iE.TableName())
+	if _, err := db.ExecContext(ctx, stmt, &fromID, &toID); err != nil {
+		return err
+	}
+
+	stmt = fmt.Sprintf(`
+		UPDATE %s t
+		SET
+		coupon = COALESCE(t.coupon, f.coupon),
+		updated_at = NOW()
+		FROM %s f
+		WHERE t.id = $2
+		AND f.id = $1
+	`, e.TableName(), e.TableName())
+	if _, err := db.ExecContext(ctx, stmt, &fromID, &toID); err != nil {
+		return err
+	}
+
+	stmt = fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE id = $1
+	`, e.TableName())
+	if _, err := db.ExecContext(ctx, stmt, &fromID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListItems lists the items of a cart, the first added first.
+func (r *cartRepository) ListItems(ctx context.Context, db database.Executor, cartID int64) ([]*entity.CartItem, error) {
+	e := &entity.CartItem{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE cart_id = $1
+		ORDER BY created_at, product_id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.CartItem
+	for rows.Next() {
+		var val entity.CartItem
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// AddItem adds a quantity of a product to a cart, on top of the quantity already in the cart.
+func (r *cartRepository) AddItem(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error {
+	e := &entity.CartItem{}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(cart_id, product_id, quantity)
+		VALUES($1, $2, $3)
+		ON CONFLICT (cart_id, product_id) DO UPDATE
+		SET
+		quantity = %s.quantity + EXCLUDED.quantity,
+		updated_at = NOW()
+	`, e.TableName(), e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &cartID, &productID, &quantity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *cartRepository) SetItemQuantity(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error {
+	e := &entity.CartItem{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		quantity = $3,
+		updated_at = NOW()
+		WHERE cart_id = $1
+		AND product_id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &cartID, &productID, &quantity)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *cartRepository) RemoveItem(ctx context.Context, db database.Executor, cartID, productID int64) error {
+	e := &entity.CartItem{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE cart_id = $1
+		AND product_id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &cartID, &productID)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ClearItems removes all the items of a cart.
+func (r *cartRepository) ClearItems(ctx context.Context, db database.Executor, cartID int64) error {
+	e := &entity.CartItem{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE cart_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &cartID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type categoryTranslationRepository struct{}
+
+func NewCategoryTranslationRepository() repository.CategoryTranslationRepository {
+	return &categoryTranslationRepository{}
+}
+
+// Upsert sets the translation of a category in a locale.
+func (r *categoryTranslationRepository) Upsert(ctx context.Context, db database.Executor, data *entity.CategoryTranslation) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(category, locale, name, updated_by)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (category, locale) DO UPDATE
+		SET
+		name = EXCLUDED.name,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.Category, &data.Locale, &data.Name, &data.UpdatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *categoryTranslationRepository) Delete(ctx context.Context, db database.Executor, category, locale string) error {
+	e := &entity.CategoryTranslation{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE category = $1
+		AND locale = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &category, &locale)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByCategories lists the translations of the given categories in the given locales, in every locale without
+// locales, by category and locale.
+func (r *categoryTranslationRepository) ListByCategories(ctx context.Context, db database.Executor, categories []string, locales []string) ([]*entity.CategoryTranslation, error) {
+	e := &entity.CategoryTranslation{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE category = ANY($1)
+		AND ($2::text[] IS NULL OR locale = ANY($2))
+		ORDER BY category, locale
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.StringArray(categories), pq.StringArray(locales))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.CategoryTranslation
+	for rows.Next() {
+		var val entity.CategoryTranslation
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// ListMissing lists the categories of the products which are not in the trash with the locales they are not
+// translated in, by category.
+func (r *categoryTranslationRepository) ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCategoryTranslation, error) {
+	e := &entity.CategoryTranslation{}
+	stmt := fmt.Sprintf(`
+		SELECT c.category, array_agg(l.locale ORDER BY l.locale)
+		FROM (
+			SELECT DISTINCT type AS category
+			FROM products
+			WHERE deleted_at IS NULL
+			AND COALESCE(type, '') <> ''
+		) c
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.category = c.category AND t.locale = l.locale
+		WHERE t.category IS NULL
+		GROUP BY c.category
+		ORDER BY c.category
+		OFFSET $2
+		LIMIT $3
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.StringArray(locales), &offset, &limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.MissingCategoryTranslation
+	for rows.Next() {
+		var val entity.MissingCategoryTranslation
+		if err := rows.Scan(&val.Category, &val.Locales); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// CountMissing counts the categories of the products which are not in the trash and not translated in one of the locales.
+func (r *categoryTranslationRepository) CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error) {
+	e := &entity.CategoryTranslation{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT c.category)
+		FROM (
+			SELECT DISTINCT type AS category
+			FROM products
+			WHERE deleted_at IS NULL
+			AND COALESCE(type, '') <> ''
+		) c
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.category = c.category AND t.locale = l.locale
+		WHERE t.category IS NULL
+	`, e.TableName())
+
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt, pq.StringArray(locales)).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total.Int64, nil
+}
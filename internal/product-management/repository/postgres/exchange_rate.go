@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type exchangeRateRepository struct{}
+
+func NewExchangeRateRepository() repository.ExchangeRateRepository {
+	return &exchangeRateRepository{}
+}
+
+// Upsert sets the rate of a currency.
+func (r *exchangeRateRepository) Upsert(ctx context.Context, db database.Executor, data *entity.ExchangeRate) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(currency, rate, updated_by)
+		VALUES($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE
+		SET
+		rate = EXCLUDED.rate,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.Currency, &data.Rate, &data.UpdatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *exchangeRateRepository) RetrieveByCurrency(ctx context.Context, db database.Executor, currency string) (*entity.ExchangeRate, error) {
+	e := &entity.ExchangeRate{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE currency = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &currency).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *exchangeRateRepository) List(ctx context.Context, db database.Executor) ([]*entity.ExchangeRate, error) {
+	e := &entity.ExchangeRate{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY currency
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ExchangeRate
+	for rows.Next() {
+		var val entity.ExchangeRate
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// The columns written when the order rows are created, the others keep their default.
+var (
+	orderCreateColumns         = []string{"user_id", "status", "subtotal", "discount", "total", "currency", "tax", "shipping", "coupon_redemption_id", "shipping_method_id", "shipping_address", "payment_id", "payment_status"}
+	orderItemCreateColumns     = []string{"order_id", "product_id", "name", "unit_price", "quantity", "subtotal", "discount", "total", "shipping", "tax", "tax_region", "tax_category", "tax_basis_points", "tax_inclusive"}
+	orderDiscountCreateColumns = []string{"order_id", "coupon", "discount_type", "value", "amount"}
+	orderHistoryCreateColumns  = []string{"order_id", "from_status", "to_status", "changed_by", "reason"}
+)
+
+type orderRepository struct{}
+
+func NewOrderRepository() repository.OrderRepository {
+	return &orderRepository{}
+}
+
+func (r *orderRepository) Create(ctx context.Context, db database.Executor, data *entity.Order) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, orderCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *orderRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Order, error) {
+	e := &entity.Order{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// UpdateStatusByID moves an order from a status to another.
+// It returns [database/sql.ErrNoRows] when the order is no longer in the from status.
+func (r *orderRepository) UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string) error {
+	e := &entity.Order{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		status = $3,
+		updated_at = NOW()
+		WHERE id = $1
+		AND status = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &fromStatus, &toStatus)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdatePaymentStatusByID updates the payment status of an order.
+// It returns [database/sql.ErrNoRows] when the order is not found.
+func (r *orderRepository) UpdatePaymentStatusByID(ctx context.Context, db database.Executor, id int64, paymentStatus string) error {
+	e := &entity.Order{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET payment_status = $2, updated_at = NOW()
+		WHERE id = $1
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &paymentStatus)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdatePaymentStatusByPaymentID updates the payment status of the order of a payment.
+// It returns [database/sql.ErrNoRows] when no order has the payment.
+func (r *orderRepository) UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error {
+	e := &entity.Order{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET payment_status = $2, updated_at = NOW()
+		WHERE payment_id = $1
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &paymentID, &paymentStatus)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *orderRepository) CreateItem(ctx context.Context, db database.Executor, data *entity.OrderItem) error {
+	fieldNames, values := database.SelectFieldMap(data, orderItemCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderRepository) ListItems(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderItem, error) {
+	e := &entity.OrderItem{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE order_id = $1
+		ORDER BY id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.OrderItem
+	for rows.Next() {
+		var val entity.OrderItem
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *orderRepository) CreateDiscount(ctx context.Context, db database.Executor, data *entity.OrderDiscount) error {
+	fieldNames, values := database.SelectFieldMap(data, orderDiscountCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderRepository) ListDiscounts(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderDiscount, error) {
+	e := &entity.OrderDiscount{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE order_id = $1
+		ORDER BY id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.OrderDiscount
+	for rows.Next() {
+		var val entity.OrderDiscount
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *orderRepository) CreateHistory(ctx context.Context, db database.Executor, data *entity.OrderHistory) error {
+	fieldNames, values := database.SelectFieldMap(data, orderHistoryCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListHistories lists the status transitions of an order, the oldest first.
+func (r *orderRepository) ListHistories(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderHistory, error) {
+	e := &entity.OrderHistory{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.OrderHistory
+	for rows.Next() {
+		var val entity.OrderHistory
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
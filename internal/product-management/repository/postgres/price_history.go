@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// priceHistoryCreateColumns are the columns written when a price change is recorded, the others keep their default.
+var priceHistoryCreateColumns = []string{"product_id", "price", "currency", "changed_by", "reason"}
+
+type priceHistoryRepository struct{}
+
+func NewPriceHistoryRepository() repository.PriceHistoryRepository {
+	return &priceHistoryRepository{}
+}
+
+func (r *priceHistoryRepository) Create(ctx context.Context, db database.Executor, data *entity.PriceHistory) error {
+	fieldNames, values := database.SelectFieldMap(data, priceHistoryCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListByProductID lists the price changes of a product, the latest first.
+func (r *priceHistoryRepository) ListByProductID(ctx context.Context, db database.Executor, productID, offset, limit int64) ([]*entity.PriceHistory, error) {
+	e := &entity.PriceHistory{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC
+		OFFSET $2
+		LIMIT $3
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, &productID, &offset, &limit)
+}
+
+func (r *priceHistoryRepository) CountByProductID(ctx context.Context, db database.Executor, productID int64) (int64, error) {
+	e := &entity.PriceHistory{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE product_id = $1
+	`, e.TableName())
+	var total int64
+
+	if err := db.QueryRowContext(ctx, stmt, &productID).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// ListHighestSince lists the highest price of the given products which was in effect since a time,
+// the price in effect at that time included.
+func (r *priceHistoryRepository) ListHighestSince(ctx context.Context, db database.Executor, productIDs []int64, since time.Time) ([]*entity.PriceHistory, error) {
+	e := &entity.PriceHistory{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT DISTINCT ON (product_id) %s
+		FROM %s h
+		WHERE product_id = ANY($1)
+		AND (
+			created_at >= $2
+			OR id = (
+				SELECT id
+				FROM %s
+				WHERE product_id = h.product_id
+				AND created_at < $2
+				ORDER BY created_at DESC, id DESC
+				LIMIT 1
+			)
+		)
+		ORDER BY product_id, price DESC, created_at DESC
+	`, strings.Join(fieldNames, ","), e.TableName(), e.TableName())
+
+	return r.list(ctx, db, stmt, pq.Int64Array(productIDs), &since)
+}
+
+func (r *priceHistoryRepository) list(ctx context.Context, db database.Executor, stmt string, args ...any) ([]*entity.PriceHistory, error) {
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.PriceHistory
+	for rows.Next() {
+		var val entity.PriceHistory
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
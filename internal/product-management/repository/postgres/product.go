@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 
@@ -19,20 +20,38 @@ func NewProductRepository() repository.ProductRepository {
 	return &productRepository{}
 }
 
-func (r *productRepository) List(ctx context.Context, db database.Executor, offset int64, limit int64) ([]*entity.Product, error) {
+// productOrderClauses maps the product orders to their ORDER BY clause.
+var productOrderClauses = map[entity.ProductOrder]string{
+	entity.ProductOrder_Default: "id",
+	entity.ProductOrder_Rating:  "rating_average DESC NULLS LAST, rating_count DESC NULLS LAST, id",
+}
+
+func (r *productRepository) List(ctx context.Context, db database.Executor, offset int64, limit int64, order entity.ProductOrder) ([]*entity.Product, error) {
+	orderClause, ok := productOrderClauses[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown product order %q", order)
+	}
+
 	e := &entity.Product{}
 	fieldNames, _ := database.FieldMap(e)
 	stmt := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
+		WHERE deleted_at IS NULL
+		ORDER BY %s
 		LIMIT $1
 		OFFSET $2
-	`, strings.Join(fieldNames, ","), e.TableName())
+	`, strings.Join(fieldNames, ","), e.TableName(), orderClause)
 
-	rows, err := db.QueryContext(ctx, stmt, &limit, &offset)
+	return r.list(ctx, db, stmt, &limit, &offset)
+}
+
+func (r *productRepository) list(ctx context.Context, db database.Executor, stmt string, args ...any) ([]*entity.Product, error) {
+	rows, err := db.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -57,6 +76,7 @@ func (r *productRepository) RetrieveByID(ctx context.Context, db database.Execut
 		SELECT %s
 		FROM %s
 		WHERE id = $1
+		AND deleted_at IS NULL
 	`, strings.Join(fieldNames, ","), e.TableName())
 
 	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
@@ -67,6 +87,10 @@ func (r *productRepository) RetrieveByID(ctx context.Context, db database.Execut
 }
 
 func (r *productRepository) Create(ctx context.Context, db database.Executor, data *entity.Product) (int64, error) {
+	// A new product starts at the first version
+	if !data.Version.Valid {
+		data.Version = sql.NullInt64{Int64: 1, Valid: true}
+	}
 	fieldNames, values := database.FieldMap(data)
 	fieldNames = fieldNames[1:]
 	values = values[1:]
@@ -86,19 +110,29 @@ func (r *productRepository) Create(ctx context.Context, db database.Executor, da
 	return id, nil
 }
 
-func (r *productRepository) UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product) error {
-	e := &entity.Product{}
+// UpdateByID writes only the given columns of data, so a column can also be set to an empty value, and increments
+// the version of the product, without columns it only increments the version. With a version in data, it only
+// updates that version of the product and returns [database.ErrVersionMismatch] when the product has another version.
+func (r *productRepository) UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product, columns []string) error {
+	fieldNames, values := database.SelectFieldMap(data, columns)
+	if len(fieldNames) == 0 && len(columns) > 0 {
+		return fmt.Errorf("no column to update")
+	}
+
+	setClauses := []string{"version = version + 1", "updated_at = NOW()"}
+	if len(fieldNames) > 0 {
+		setClauses = append([]string{database.GetSetClauses(fieldNames, 2)}, setClauses...)
+	}
 	stmt := fmt.Sprintf(`
 		UPDATE %s
 		SET
-		name = COALESCE($2, name),
-		type = COALESCE($3, type),
-		description = COALESCE($4, description),
-		price = COALESCE($5, price)
+		%s
 		WHERE id = $1
-	`, e.TableName())
+		AND deleted_at IS NULL
+		AND ($2::bigint IS NULL OR version = $2)
+	`, data.TableName(), strings.Join(setClauses, ",\n\t\t"))
 
-	result, err := db.ExecContext(ctx, stmt, &id, &data.Name, &data.Type, &data.Description, &data.Price)
+	result, err := db.ExecContext(ctx, stmt, append([]any{&id, &data.Version}, values...)...)
 	if err != nil {
 		return err
 	}
@@ -108,20 +142,51 @@ func (r *productRepository) UpdateByID(ctx context.Context, db database.Executor
 	}
 
 	if rowEffected == 0 {
+		if data.Version.Valid {
+			return r.versionMismatch(ctx, db, id)
+		}
 		return sql.ErrNoRows
 	}
 
 	return nil
 }
 
-func (r *productRepository) DeleteByID(ctx context.Context, db database.Executor, id int64) error {
+// versionMismatch returns the error of an update of a version which did not apply, sql.ErrNoRows when the
+// product is not found and [database.ErrVersionMismatch] otherwise.
+func (r *productRepository) versionMismatch(ctx context.Context, db database.Executor, id int64) error {
 	e := &entity.Product{}
 	stmt := fmt.Sprintf(`
-		DELETE FROM %s
+		SELECT EXISTS(
+			SELECT 1
+			FROM %s
+			WHERE id = $1
+			AND deleted_at IS NULL
+		)
+	`, e.TableName())
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	return database.ErrVersionMismatch
+}
+
+func (r *productRepository) DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error {
+	e := &entity.Product{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		deleted_at = NOW(),
+		deleted_by = $2
 		WHERE id = $1
+		AND deleted_at IS NULL
 	`, e.TableName())
 
-	result, err := db.ExecContext(ctx, stmt, &id)
+	result, err := db.ExecContext(ctx, stmt, &id, &deletedBy)
 	if err != nil {
 		return err
 	}
@@ -137,14 +202,18 @@ func (r *productRepository) DeleteByID(ctx context.Context, db database.Executor
 	return nil
 }
 
-func (r *productRepository) DeleteByIDs(ctx context.Context, db database.Executor, ids []int64) error {
+func (r *productRepository) DeleteByIDs(ctx context.Context, db database.Executor, ids []int64, deletedBy int64) error {
 	e := &entity.Product{}
 	stmt := fmt.Sprintf(`
-		DELETE FROM %s
+		UPDATE %s
+		SET
+		deleted_at = NOW(),
+		deleted_by = $2
 		WHERE id = ANY($1)
+		AND deleted_at IS NULL
 	`, e.TableName())
 
-	result, err := db.ExecContext(ctx, stmt, pq.Int64Array(ids))
+	result, err := db.ExecContext(ctx, stmt, pq.Int64Array(ids), &deletedBy)
 	if err != nil {
 		return err
 	}
@@ -165,6 +234,37 @@ func (r *productRepository) Count(ctx context.Context, db database.Executor) (in
 	stmt := fmt.Sprintf(`
 		SELECT COUNT(1)
 		FROM %s
+		WHERE deleted_at IS NULL
+	`, e.TableName())
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt).Scan(&total); err != nil {
+		return total.Int64, err
+	}
+
+	return total.Int64, nil
+}
+
+func (r *productRepository) ListDeleted(ctx context.Context, db database.Executor, offset int64, limit int64) ([]*entity.Product, error) {
+	e := &entity.Product{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $1
+		OFFSET $2
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, &limit, &offset)
+}
+
+func (r *productRepository) CountDeleted(ctx context.Context, db database.Executor) (int64, error) {
+	e := &entity.Product{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(1)
+		FROM %s
+		WHERE deleted_at IS NOT NULL
 	`, e.TableName())
 	var total sql.NullInt64
 	if err := db.QueryRowContext(ctx, stmt).Scan(&total); err != nil {
@@ -173,3 +273,182 @@ func (r *productRepository) Count(ctx context.Context, db database.Executor) (in
 
 	return total.Int64, nil
 }
+
+func (r *productRepository) RestoreByID(ctx context.Context, db database.Executor, id int64) error {
+	e := &entity.Product{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		deleted_at = NULL,
+		deleted_by = NULL
+		WHERE id = $1
+		AND deleted_at IS NOT NULL
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore hard-deletes the products trashed before the given time.
+// Products which are still referenced by a purchase or an order are kept in the trash.
+func (r *productRepository) PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error) {
+	e := &entity.Product{}
+	pE := &entity.PurchasedProduct{}
+	oiE := &entity.OrderItem{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s p
+		WHERE p.deleted_at < $1
+		AND NOT EXISTS (
+			SELECT 1 FROM %s pp WHERE pp.product_id = p.id
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM %s oi WHERE oi.product_id = p.id
+		)
+	`, e.TableName(), pE.TableName(), oiE.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ListByKeys retrieves the products, trashed ones included, which match the given sku or external id.
+// Empty keys are ignored.
+func (r *productRepository) ListByKeys(ctx context.Context, db database.Executor, sku, externalID string) ([]*entity.Product, error) {
+	e := &entity.Product{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE sku = NULLIF($1, '')
+		OR external_id = NULLIF($2, '')
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, &sku, &externalID)
+}
+
+// Iterate calls fn for every product which is not in the trash, ordered by id.
+// Rows are scanned one at a time so the catalogue is never fully loaded in memory.
+func (r *productRepository) Iterate(ctx context.Context, db database.Executor, fn func(*entity.Product) error) error {
+	e := &entity.Product{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE deleted_at IS NULL
+		ORDER BY id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var val entity.Product
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+
+		if err := fn(&val); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// IncrementVersionByType increments the version of the products of a type which are not in the trash, when a
+// content they are read with changes, and returns their IDs.
+func (r *productRepository) IncrementVersionByType(ctx context.Context, db database.Executor, productType string) ([]int64, error) {
+	e := &entity.Product{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		version = version + 1,
+		updated_at = NOW()
+		WHERE type = $1
+		AND deleted_at IS NULL
+		RETURNING id
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &productType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, rows.Err()
+}
+
+// RefreshRating recomputes the rating aggregates of a product from its approved reviews.
+func (r *productRepository) RefreshRating(ctx context.Context, db database.Executor, id int64) error {
+	e := &entity.Product{}
+	rE := &entity.Review{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s p
+		SET
+		rating_average = COALESCE(agg.average, 0),
+		rating_count = agg.total
+		FROM (
+			SELECT AVG(rating) AS average, COUNT(1) AS total
+			FROM %s
+			WHERE product_id = $1
+			AND status = $2
+		) agg
+		WHERE p.id = $1
+	`, e.TableName(), rE.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, entity.ReviewStatus_Approved)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByIDs retrieves the products with the given ids which are not in the trash.
+func (r *productRepository) ListByIDs(ctx context.Context, db database.Executor, ids []int64) ([]*entity.Product, error) {
+	e := &entity.Product{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = ANY($1)
+		AND deleted_at IS NULL
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, pq.Int64Array(ids))
+}
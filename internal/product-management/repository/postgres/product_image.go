@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// productImageCreateColumns are the columns written when a product image is created, the others keep their default.
+var productImageCreateColumns = []string{"product_id", "file_id", "position", "alt_text", "is_primary"}
+
+type productImageRepository struct{}
+
+func NewProductImageRepository() repository.ProductImageRepository {
+	return &productImageRepository{}
+}
+
+func (r *productImageRepository) Create(ctx context.Context, db database.Executor, data *entity.ProductImage) error {
+	fieldNames, values := database.SelectFieldMap(data, productImageCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteByProductID deletes the images of a product, a product without images is not an error.
+func (r *productImageRepository) DeleteByProductID(ctx context.Context, db database.Executor, productID int64) error {
+	e := &entity.ProductImage{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE product_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &productID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListByProductIDs lists the images of the given products, by product and position.
+func (r *productImageRepository) ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductImage, error) {
+	e := &entity.ProductImage{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ProductImage
+	for rows.Next() {
+		var val entity.ProductImage
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type productPriceRepository struct{}
+
+func NewProductPriceRepository() repository.ProductPriceRepository {
+	return &productPriceRepository{}
+}
+
+// Upsert sets the price of a product in a currency.
+func (r *productPriceRepository) Upsert(ctx context.Context, db database.Executor, data *entity.ProductPrice) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(product_id, currency, amount)
+		VALUES($1, $2, $3)
+		ON CONFLICT (product_id, currency) DO UPDATE
+		SET
+		amount = EXCLUDED.amount,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.ProductID, &data.Currency, &data.Amount); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *productPriceRepository) Delete(ctx context.Context, db database.Executor, productID int64, currency string) error {
+	e := &entity.ProductPrice{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE product_id = $1
+		AND currency = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &productID, &currency)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByProductIDs lists the prices of the given products, by product and currency.
+func (r *productPriceRepository) ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductPrice, error) {
+	e := &entity.ProductPrice{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, currency
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ProductPrice
+	for rows.Next() {
+		var val entity.ProductPrice
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type productTranslationRepository struct{}
+
+func NewProductTranslationRepository() repository.ProductTranslationRepository {
+	return &productTranslationRepository{}
+}
+
+// Upsert sets the translation of a product in a locale.
+func (r *productTranslationRepository) Upsert(ctx context.Context, db database.Executor, data *entity.ProductTranslation) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(product_id, locale, name, description, updated_by)
+		VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (product_id, locale) DO UPDATE
+		SET
+		name = EXCLUDED.name,
+		description = EXCLUDED.description,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.ProductID, &data.Locale, &data.Name, &data.Description, &data.UpdatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *productTranslationRepository) Delete(ctx context.Context, db database.Executor, productID int64, locale string) error {
+	e := &entity.ProductTranslation{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE product_id = $1
+		AND locale = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &productID, &locale)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByProductIDs lists the translations of the given products in the given locales, in every locale without
+// locales, by product and locale.
+func (r *productTranslationRepository) ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64, locales []string) ([]*entity.ProductTranslation, error) {
+	e := &entity.ProductTranslation{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = ANY($1)
+		AND ($2::text[] IS NULL OR locale = ANY($2))
+		ORDER BY product_id, locale
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(productIDs), pq.StringArray(locales))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ProductTranslation
+	for rows.Next() {
+		var val entity.ProductTranslation
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// ListMissing lists the products which are not in the trash with the locales they are not translated in, by product.
+func (r *productTranslationRepository) ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingProductTranslation, error) {
+	e := &entity.ProductTranslation{}
+	stmt := fmt.Sprintf(`
+		SELECT p.id, p.name, array_agg(l.locale ORDER BY l.locale)
+		FROM products p
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.product_id = p.id AND t.locale = l.locale
+		WHERE p.deleted_at IS NULL
+		AND t.product_id IS NULL
+		GROUP BY p.id, p.name
+		ORDER BY p.id
+		OFFSET $2
+		LIMIT $3
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.StringArray(locales), &offset, &limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.MissingProductTranslation
+	for rows.Next() {
+		var val entity.MissingProductTranslation
+		if err := rows.Scan(&val.ProductID, &val.Name, &val.Locales); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// CountMissing counts the products which are not in the trash and not translated in one of the locales.
+func (r *productTranslationRepository) CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error) {
+	e := &entity.ProductTranslation{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT p.id)
+		FROM products p
+		CROSS JOIN unnest($1::text[]) AS l(locale)
+		LEFT JOIN %s t ON t.product_id = p.id AND t.locale = l.locale
+		WHERE p.deleted_at IS NULL
+		AND t.product_id IS NULL
+	`, e.TableName())
+
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt, pq.StringArray(locales)).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total.Int64, nil
+}
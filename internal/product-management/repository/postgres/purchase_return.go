@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// purchaseReturnCreateColumns are the columns written when a return is requested, the others keep their default.
+var purchaseReturnCreateColumns = []string{"purchase_id", "user_id", "amount", "currency", "reason", "note", "status"}
+
+type purchaseReturnRepository struct{}
+
+func NewPurchaseReturnRepository() repository.PurchaseReturnRepository {
+	return &purchaseReturnRepository{}
+}
+
+// Create creates a purchase return and returns its id, the creation time is set on data.
+func (r *purchaseReturnRepository) Create(ctx context.Context, db database.Executor, data *entity.PurchaseReturn) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, purchaseReturnCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id, created_at
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id, &data.CreatedAt); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *purchaseReturnRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchaseReturn, error) {
+	e := &entity.PurchaseReturn{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// UpdateStatusByID updates the status of a purchase return, it returns sql.ErrNoRows when the return is not in fromStatus.
+func (r *purchaseReturnRepository) UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string, approvedBy int64) error {
+	e := &entity.PurchaseReturn{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET status = $3, approved_by = $4, updated_at = now()
+		WHERE id = $1
+		AND status = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &fromStatus, &toStatus, &approvedBy)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 
@@ -10,6 +11,9 @@ import (
 	"trintech/review/pkg/database"
 )
 
+// purchasedProductCreateColumns are the columns written when a purchase is created, the others keep their default.
+var purchasedProductCreateColumns = []string{"product_id", "user_id", "price", "discount", "purchase_total", "currency", "apply_coupon", "order_id", "payment_id", "payment_status", "coupon_redemption_id", "tax", "tax_region", "tax_category", "tax_basis_points", "tax_inclusive", "shipping_method_id", "shipping", "shipping_address", "quantity"}
+
 type purchasedProductRepository struct {
 }
 
@@ -17,18 +21,215 @@ func NewPurchasedProductRepository() repository.PurchasedProductRepository {
 	return &purchasedProductRepository{}
 }
 
-func (r *purchasedProductRepository) Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) error {
-	fieldNames, values := database.FieldMap(data)
+// Create creates a purchased product and returns its id, the creation time is set on data.
+func (r *purchasedProductRepository) Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, purchasedProductCreateColumns)
 	placeHolders := database.GetPlaceholders(len(fieldNames))
 
 	stmt := fmt.Sprintf(`
 		INSERT INTO %s(%s)
 		VALUES(%s)
+		RETURNING id, created_at
 	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id, &data.CreatedAt); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *purchasedProductRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchasedProduct, error) {
+	e := &entity.PurchasedProduct{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// List lists the purchased products matching the filter, the latest first.
+func (r *purchasedProductRepository) List(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter, offset, limit int64) ([]*entity.PurchasedProduct, error) {
+	e := &entity.PurchasedProduct{}
+	fieldNames, _ := database.FieldMap(e)
+	where, args := purchasedProductConditions(filter)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		OFFSET $%d
+		LIMIT $%d
+	`, strings.Join(fieldNames, ","), e.TableName(), where, len(args)+1, len(args)+2)
+
+	rows, err := db.QueryContext(ctx, stmt, append(args, &offset, &limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.PurchasedProduct
+	for rows.Next() {
+		var val entity.PurchasedProduct
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// ListByOrderID lists the purchased products of an order, in the order of their creation.
+func (r *purchasedProductRepository) ListByOrderID(ctx context.Context, db database.Executor, orderID int64) ([]*entity.PurchasedProduct, error) {
+	e := &entity.PurchasedProduct{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE order_id = $1
+		ORDER BY id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.PurchasedProduct
+	for rows.Next() {
+		var val entity.PurchasedProduct
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// Count counts the purchased products matching the filter.
+func (r *purchasedProductRepository) Count(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter) (int64, error) {
+	e := &entity.PurchasedProduct{}
+	where, args := purchasedProductConditions(filter)
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s
+		WHERE %s
+	`, e.TableName(), where)
+	var total int64
+
+	if err := db.QueryRowContext(ctx, stmt, args...).Scan(&total); err != nil {
+		return 0, err
+	}
 
-	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+	return total, nil
+}
+
+// purchasedProductConditions returns the WHERE conditions of a filter with their arguments.
+// The created_at range includes From and excludes To.
+func purchasedProductConditions(filter *entity.PurchasedProductFilter) (string, []any) {
+	conditions := []string{"TRUE"}
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter == nil {
+		return strings.Join(conditions, " AND "), args
+	}
+
+	if filter.UserID.Valid {
+		add("user_id = $%d", filter.UserID)
+	}
+	if filter.ProductID.Valid {
+		add("product_id = $%d", filter.ProductID)
+	}
+	if filter.Coupon.Valid {
+		add("apply_coupon = $%d", filter.Coupon)
+	}
+	if filter.From.Valid {
+		add("created_at >= $%d", filter.From)
+	}
+	if filter.To.Valid {
+		add("created_at < $%d", filter.To)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// UpdatePaymentStatusByPaymentID updates the payment status of the purchases of a payment,
+// it returns sql.ErrNoRows when no purchase has the payment.
+func (r *purchasedProductRepository) UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error {
+	e := &entity.PurchasedProduct{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET payment_status = $2, updated_at = now()
+		WHERE payment_id = $1
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &paymentID, &paymentStatus)
+	if err != nil {
 		return err
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
 
 	return nil
 }
+
+// AddRefundByID adds a refunded amount to a purchase and returns its refunded amount,
+// it returns sql.ErrNoRows when the refunded amount would exceed the total.
+func (r *purchasedProductRepository) AddRefundByID(ctx context.Context, db database.Executor, id, amount int64) (int64, error) {
+	e := &entity.PurchasedProduct{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET refunded_amount = refunded_amount + $2, updated_at = now()
+		WHERE id = $1
+		AND refunded_amount + $2 <= purchase_total
+		RETURNING refunded_amount
+	`, e.TableName())
+	var refunded int64
+
+	if err := db.QueryRowContext(ctx, stmt, &id, &amount).Scan(&refunded); err != nil {
+		return 0, err
+	}
+
+	return refunded, nil
+}
+
+// ExistsByUserIDAndProductID reports whether the user has purchased the product.
+func (r *purchasedProductRepository) ExistsByUserIDAndProductID(ctx context.Context, db database.Executor, userID, productID int64) (bool, error) {
+	e := &entity.PurchasedProduct{}
+	stmt := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE user_id = $1
+			AND product_id = $2
+		)
+	`, e.TableName())
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, stmt, &userID, &productID).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// reviewCreateColumns are the columns written when a review is created, the others keep their default.
+var reviewCreateColumns = []string{"product_id", "user_id", "rating", "content", "status"}
+
+type reviewRepository struct{}
+
+func NewReviewRepository() repository.ReviewRepository {
+	return &reviewRepository{}
+}
+
+func (r *reviewRepository) Create(ctx context.Context, db database.Executor, data *entity.Review) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, reviewCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *reviewRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Review, error) {
+	e := &entity.Review{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// ListByProductID lists the reviews of a product with one of the given statuses, the most helpful first.
+func (r *reviewRepository) ListByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string, offset, limit int64) ([]*entity.Review, error) {
+	e := &entity.Review{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = $1
+		AND status = ANY($2)
+		ORDER BY helpful_count DESC, created_at DESC
+		LIMIT $3
+		OFFSET $4
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &productID, pq.StringArray(statuses), &limit, &offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.Review
+	for rows.Next() {
+		var val entity.Review
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *reviewRepository) CountByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string) (int64, error) {
+	e := &entity.Review{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(1)
+		FROM %s
+		WHERE product_id = $1
+		AND status = ANY($2)
+	`, e.TableName())
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt, &productID, pq.StringArray(statuses)).Scan(&total); err != nil {
+		return total.Int64, err
+	}
+
+	return total.Int64, nil
+}
+
+func (r *reviewRepository) UpdateStatusByID(ctx context.Context, db database.Executor, id int64, status string, moderatedBy int64) error {
+	e := &entity.Review{}
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		status = $2,
+		moderated_by = $3,
+		moderated_at = NOW(),
+		updated_at = NOW()
+		WHERE id = $1
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &id, &status, &moderatedBy)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CreateHelpfulVote records that the user found the review helpful and increases its helpful count.
+// It reports false when the user already voted for the review.
+func (r *reviewRepository) CreateHelpfulVote(ctx context.Context, db database.Executor, reviewID, userID int64) (bool, error) {
+	e := &entity.Review{}
+	vE := &entity.ReviewHelpfulVote{}
+	stmt := fmt.Sprintf(`
+		WITH vote AS (
+			INSERT INTO %s(review_id, user_id)
+			VALUES($1, $2)
+			ON CONFLICT DO NOTHING
+			RETURNING review_id
+		)
+		UPDATE %s
+		SET helpful_count = helpful_count + 1
+		WHERE id IN (SELECT review_id FROM vote)
+	`, vE.TableName(), e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &reviewID, &userID)
+	if err != nil {
+		return false, err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowEffected > 0, nil
+}
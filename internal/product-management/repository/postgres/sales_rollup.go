@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// salesWatermark is the name of the watermark of the sales rollups.
+const salesWatermark = "sales"
+
+// dateLayout formats the UTC days of the rollups as SQL dates, independently of the time zone of the session.
+const dateLayout = "2006-01-02"
+
+type salesRollupRepository struct{}
+
+func NewSalesRollupRepository() repository.SalesRollupRepository {
+	return &salesRollupRepository{}
+}
+
+func (r *salesRollupRepository) RetrieveWatermark(ctx context.Context, db database.Executor) (time.Time, error) {
+	return r.retrieveWatermark(ctx, db, "")
+}
+
+// LockWatermark locks the watermark row so a single refresh runs at a time.
+func (r *salesRollupRepository) LockWatermark(ctx context.Context, db database.Executor) (time.Time, error) {
+	return r.retrieveWatermark(ctx, db, "FOR UPDATE")
+}
+
+func (r *salesRollupRepository) retrieveWatermark(ctx context.Context, db database.Executor, lock string) (time.Time, error) {
+	stmt := fmt.Sprintf(`
+		SELECT refreshed_until
+		FROM sales_rollup_watermarks
+		WHERE name = $1
+		%s
+	`, lock)
+
+	var refreshedUntil time.Time
+	if err := db.QueryRowContext(ctx, stmt, salesWatermark).Scan(&refreshedUntil); err != nil {
+		return time.Time{}, err
+	}
+
+	return refreshedUntil, nil
+}
+
+func (r *salesRollupRepository) UpdateWatermark(ctx context.Context, db database.Executor, refreshedUntil time.Time) error {
+	stmt := `
+		UPDATE sales_rollup_watermarks
+		SET refreshed_until = $2
+		WHERE name = $1
+	`
+
+	if _, err := db.ExecContext(ctx, stmt, salesWatermark, &refreshedUntil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *salesRollupRepository) ListStaleDays(ctx context.Context, db database.Executor, since time.Time) ([]time.Time, error) {
+	e := &entity.PurchasedProduct{}
+	stmt := fmt.Sprintf(`
+		SELECT DISTINCT (created_at AT TIME ZONE 'UTC')::date AS day
+		FROM %s
+		WHERE updated_at >= $1
+		ORDER BY day
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// RefreshDays replaces the rollups of the days by the aggregates of their purchases, in total and by product,
+// type and coupon. The purchases which were not paid, the voided or failed payments, are not sales.
+func (r *salesRollupRepository) RefreshDays(ctx context.Context, db database.Executor, days []time.Time) error {
+	e := &entity.SalesRollup{}
+	dates := make([]string, 0, len(days))
+	for _, day := range days {
+		dates = append(dates, day.UTC().Format(dateLayout))
+	}
+
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE day = ANY($1::date[])
+	`, e.TableName())
+	if _, err := db.ExecContext(ctx, stmt, pq.StringArray(dates)); err != nil {
+		return err
+	}
+
+	pE := &entity.PurchasedProduct{}
+	prE := &entity.Product{}
+	stmt = fmt.Sprintf(`
+		INSERT INTO %s(day, dimension, dimension_key, currency, units, orders, revenue, discount, refunded)
+		SELECT
+			s.day,
+			CASE
+				WHEN GROUPING(s.product_id) = 0 THEN '%s'
+				WHEN GROUPING(s.type) = 0 THEN '%s'
+				WHEN GROUPING(s.coupon) = 0 THEN '%s'
+				ELSE '%s'
+			END,
+			COALESCE(s.product_id::text, s.type, s.coupon, ''),
+			s.currency,
+			SUM(s.quantity),
+			COUNT(DISTINCT s.order_key),
+			SUM(s.revenue),
+			SUM(s.discount),
+			SUM(s.refunded)
+		FROM (
+			SELECT
+				d.day,
+				p.product_id,
+				COALESCE(pr.type, '') AS type,
+				p.apply_coupon AS coupon,
+				COALESCE(p.currency, '') AS currency,
+				p.quantity,
+				COALESCE(p.order_id, -p.id) AS order_key,
+				COALESCE(p.purchase_total, 0) - COALESCE(p.refunded_amount, 0) AS revenue,
+				COALESCE(p.discount, 0) AS discount,
+				COALESCE(p.refunded_amount, 0) AS refunded
+			FROM unnest($1::date[]) AS d(day)
+			JOIN %s p ON p.created_at >= d.day AT TIME ZONE 'UTC' AND p.created_at < (d.day + 1) AT TIME ZONE 'UTC'
+			LEFT JOIN %s pr ON pr.id = p.product_id
+			WHERE p.payment_status IS NULL
+			OR p.payment_status IN ('CAPTURED', 'PARTIALLY_REFUNDED', 'REFUNDED')
+		) s
+		GROUP BY s.day, s.currency, GROUPING SETS ((), (s.product_id), (s.type), (s.coupon))
+		HAVING GROUPING(s.coupon) = 1 OR s.coupon IS NOT NULL
+	`, e.TableName(), entity.SalesDimension_Product, entity.SalesDimension_Type, entity.SalesDimension_Coupon,
+		entity.SalesDimension_Total, pE.TableName(), prE.TableName())
+	if _, err := db.ExecContext(ctx, stmt, pq.StringArray(dates)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// List sums the rollups of the days of the filter by the start of their interval.
+func (r *salesRollupRepository) List(ctx context.Context, db database.Executor, filter *entity.SalesRollupFilter) ([]*entity.SalesRollup, error) {
+	e := &entity.SalesRollup{}
+	var keys pq.StringArray
+	if filter.DimensionKeys != nil {
+		keys = pq.StringArray(filter.DimensionKeys)
+	}
+	stmt := fmt.Sprintf(`
+		SELECT
+			date_trunc($1, day::timestamp)::date AS bucket,
+			dimension,
+			dimension_key,
+			currency,
+			SUM(units),
+			SUM(orders),
+			SUM(revenue),
+			SUM(discount),
+			SUM(refunded),
+			MAX(refreshed_at)
+		FROM %s
+		WHERE dimension = $2
+		AND day >= $3::date
+		AND day < $4::date
+		AND ($5::text[] IS NULL OR dimension_key = ANY($5))
+		GROUP BY bucket, dimension, dimension_key, currency
+		ORDER BY bucket, currency, dimension_key
+	`, e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, filter.Interval, filter.Dimension,
+		filter.From.UTC().Format(dateLayout), filter.To.UTC().Format(dateLayout), keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.SalesRollup
+	for rows.Next() {
+		var val entity.SalesRollup
+		if err := rows.Scan(&val.Day, &val.Dimension, &val.DimensionKey, &val.Currency, &val.Units, &val.Orders,
+			&val.Revenue, &val.Discount, &val.Refunded, &val.RefreshedAt); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
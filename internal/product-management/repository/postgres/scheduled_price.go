@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// scheduledPriceCreateColumns are the columns written when a price is scheduled, the others keep their default.
+var scheduledPriceCreateColumns = []string{"product_id", "price", "currency", "starts_at", "ends_at", "created_by"}
+
+type scheduledPriceRepository struct{}
+
+func NewScheduledPriceRepository() repository.ScheduledPriceRepository {
+	return &scheduledPriceRepository{}
+}
+
+func (r *scheduledPriceRepository) Create(ctx context.Context, db database.Executor, data *entity.ScheduledPrice) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, scheduledPriceCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *scheduledPriceRepository) DeleteByID(ctx context.Context, db database.Executor, productID, id int64) error {
+	e := &entity.ScheduledPrice{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE product_id = $1
+		AND id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &productID, &id)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByProductID lists the scheduled prices of a product which have not ended at a time, by start.
+func (r *scheduledPriceRepository) ListByProductID(ctx context.Context, db database.Executor, productID int64, at time.Time) ([]*entity.ScheduledPrice, error) {
+	e := &entity.ScheduledPrice{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE product_id = $1
+		AND (ends_at IS NULL OR ends_at > $2)
+		ORDER BY starts_at, id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, &productID, &at)
+}
+
+// ListActive lists the scheduled price in effect at a time of the given products, at most one by product.
+// When scheduled prices overlap, the one which started last wins.
+func (r *scheduledPriceRepository) ListActive(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) ([]*entity.ScheduledPrice, error) {
+	e := &entity.ScheduledPrice{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT DISTINCT ON (product_id) %s
+		FROM %s
+		WHERE product_id = ANY($1)
+		AND starts_at <= $2
+		AND (ends_at IS NULL OR ends_at > $2)
+		ORDER BY product_id, starts_at DESC, id DESC
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	return r.list(ctx, db, stmt, pq.Int64Array(productIDs), &at)
+}
+
+// NextChangeAfter returns the first time after at when a scheduled price of the products starts or ends,
+// it is NULL when no scheduled price starts or ends after at.
+func (r *scheduledPriceRepository) NextChangeAfter(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) (sql.NullTime, error) {
+	e := &entity.ScheduledPrice{}
+	stmt := fmt.Sprintf(`
+		SELECT MIN(LEAST(
+			CASE WHEN starts_at > $2 THEN starts_at END,
+			CASE WHEN ends_at > $2 THEN ends_at END
+		))
+		FROM %s
+		WHERE product_id = ANY($1)
+		AND (starts_at > $2 OR ends_at > $2)
+	`, e.TableName())
+
+	var nextChange sql.NullTime
+	if err := db.QueryRowContext(ctx, stmt, pq.Int64Array(productIDs), &at).Scan(&nextChange); err != nil {
+		return sql.NullTime{}, err
+	}
+
+	return nextChange, nil
+}
+
+func (r *scheduledPriceRepository) list(ctx context.Context, db database.Executor, stmt string, args ...any) ([]*entity.ScheduledPrice, error) {
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ScheduledPrice
+	for rows.Next() {
+		var val entity.ScheduledPrice
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+var (
+	// shippingMethodCreateColumns are the columns written when a shipping method is created, the others keep their default.
+	shippingMethodCreateColumns = []string{"name", "currency", "free_threshold", "created_by"}
+	// shippingMethodUpdateColumns are the columns of a shipping method the admins can edit.
+	shippingMethodUpdateColumns = []string{"name", "free_threshold"}
+	// shippingRateCreateColumns are the columns written when a shipping rate is created.
+	shippingRateCreateColumns = []string{"shipping_method_id", "regions", "min_weight_grams", "max_weight_grams", "min_order_value", "max_order_value", "amount"}
+)
+
+type shippingMethodRepository struct{}
+
+func NewShippingMethodRepository() repository.ShippingMethodRepository {
+	return &shippingMethodRepository{}
+}
+
+func (r *shippingMethodRepository) Create(ctx context.Context, db database.Executor, data *entity.ShippingMethod) (int64, error) {
+	fieldNames, values := database.SelectFieldMap(data, shippingMethodCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+		RETURNING id
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+	var id int64
+
+	if err := db.QueryRowContext(ctx, stmt, values...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *shippingMethodRepository) RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.ShippingMethod, error) {
+	e := &entity.ShippingMethod{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &id).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *shippingMethodRepository) List(ctx context.Context, db database.Executor) ([]*entity.ShippingMethod, error) {
+	e := &entity.ShippingMethod{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		ORDER BY id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ShippingMethod
+	for rows.Next() {
+		var val entity.ShippingMethod
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// UpdateByID updates the editable columns of a shipping method, it returns sql.ErrNoRows when it doesn't exist.
+func (r *shippingMethodRepository) UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.ShippingMethod) error {
+	fieldNames, values := database.SelectFieldMap(data, shippingMethodUpdateColumns)
+	stmt := fmt.Sprintf(`
+		UPDATE %s
+		SET
+		%s,
+		updated_at = NOW()
+		WHERE id = $1
+	`, data.TableName(), database.GetSetClauses(fieldNames, 1))
+
+	result, err := db.ExecContext(ctx, stmt, append([]any{&id}, values...)...)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *shippingMethodRepository) CreateRate(ctx context.Context, db database.Executor, data *entity.ShippingRate) error {
+	fieldNames, values := database.SelectFieldMap(data, shippingRateCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRates deletes the rates of a shipping method, before they are replaced.
+func (r *shippingMethodRepository) DeleteRates(ctx context.Context, db database.Executor, shippingMethodID int64) error {
+	e := &entity.ShippingRate{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE shipping_method_id = $1
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &shippingMethodID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListRates lists the rates of the given shipping methods, by shipping method in creation order.
+func (r *shippingMethodRepository) ListRates(ctx context.Context, db database.Executor, shippingMethodIDs []int64) ([]*entity.ShippingRate, error) {
+	e := &entity.ShippingRate{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE shipping_method_id = ANY($1)
+		ORDER BY shipping_method_id, id
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.Int64Array(shippingMethodIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ShippingRate
+	for rows.Next() {
+		var val entity.ShippingRate
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type taxRateRepository struct{}
+
+func NewTaxRateRepository() repository.TaxRateRepository {
+	return &taxRateRepository{}
+}
+
+// Upsert sets the rate of a region for a category.
+func (r *taxRateRepository) Upsert(ctx context.Context, db database.Executor, data *entity.TaxRate) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(region, category, basis_points, inclusive, updated_by)
+		VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (region, category) DO UPDATE
+		SET
+		basis_points = EXCLUDED.basis_points,
+		inclusive = EXCLUDED.inclusive,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = NOW()
+	`, data.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &data.Region, &data.Category, &data.BasisPoints, &data.Inclusive, &data.UpdatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *taxRateRepository) Delete(ctx context.Context, db database.Executor, region, category string) error {
+	e := &entity.TaxRate{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE region = $1
+		AND category = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &region, &category)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListByRegions lists the rates of the given regions, by region and category.
+func (r *taxRateRepository) ListByRegions(ctx context.Context, db database.Executor, regions []string) ([]*entity.TaxRate, error) {
+	return r.list(ctx, db, "WHERE region = ANY($1)", pq.StringArray(regions))
+}
+
+func (r *taxRateRepository) List(ctx context.Context, db database.Executor) ([]*entity.TaxRate, error) {
+	return r.list(ctx, db, "")
+}
+
+func (r *taxRateRepository) list(ctx context.Context, db database.Executor, where string, args ...any) ([]*entity.TaxRate, error) {
+	e := &entity.TaxRate{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY region, category
+	`, strings.Join(fieldNames, ","), e.TableName(), where)
+
+	rows, err := db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.TaxRate
+	for rows.Next() {
+		var val entity.TaxRate
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository"
+	"trintech/review/pkg/database"
+)
+
+type wishlistRepository struct{}
+
+func NewWishlistRepository() repository.WishlistRepository {
+	return &wishlistRepository{}
+}
+
+// AddItem saves the product in the user's wishlist, adding it twice is a no-op.
+func (r *wishlistRepository) AddItem(ctx context.Context, db database.Executor, userID, productID int64) error {
+	e := &entity.WishlistItem{}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(user_id, product_id)
+		VALUES($1, $2)
+		ON CONFLICT DO NOTHING
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &userID, &productID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *wishlistRepository) RemoveItem(ctx context.Context, db database.Executor, userID, productID int64) error {
+	e := &entity.WishlistItem{}
+	stmt := fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE user_id = $1
+		AND product_id = $2
+	`, e.TableName())
+
+	result, err := db.ExecContext(ctx, stmt, &userID, &productID)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListProducts lists the products of the user's wishlist which are not in the trash, the latest saved first.
+func (r *wishlistRepository) ListProducts(ctx context.Context, db database.Executor, userID, offset, limit int64) ([]*entity.Product, error) {
+	e := &entity.Product{}
+	iE := &entity.WishlistItem{}
+	fieldNames, _ := database.FieldMap(e)
+	for i := range fieldNames {
+		fieldNames[i] = "p." + fieldNames[i]
+	}
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s p
+		JOIN %s wi ON wi.product_id = p.id
+		WHERE wi.user_id = $1
+		AND p.deleted_at IS NULL
+		ORDER BY wi.created_at DESC, p.id
+		LIMIT $2
+		OFFSET $3
+	`, strings.Join(fieldNames, ","), e.TableName(), iE.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &userID, &limit, &offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.Product
+	for rows.Next() {
+		var val entity.Product
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *wishlistRepository) CountProducts(ctx context.Context, db database.Executor, userID int64) (int64, error) {
+	e := &entity.Product{}
+	iE := &entity.WishlistItem{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(1)
+		FROM %s p
+		JOIN %s wi ON wi.product_id = p.id
+		WHERE wi.user_id = $1
+		AND p.deleted_at IS NULL
+	`, e.TableName(), iE.TableName())
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt, &userID).Scan(&total); err != nil {
+		return total.Int64, err
+	}
+
+	return total.Int64, nil
+}
+
+func (r *wishlistRepository) RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Wishlist, error) {
+	e := &entity.Wishlist{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE user_id = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &userID).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *wishlistRepository) RetrieveByShareToken(ctx context.Context, db database.Executor, shareToken string) (*entity.Wishlist, error) {
+	e := &entity.Wishlist{}
+	fieldNames, values := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE share_token = $1
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	if err := db.QueryRowContext(ctx, stmt, &shareToken).Scan(values...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// UpsertShareToken sets the share token of the user's wishlist, the previous link stops working.
+func (r *wishlistRepository) UpsertShareToken(ctx context.Context, db database.Executor, userID int64, shareToken string) error {
+	e := &entity.Wishlist{}
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(user_id, share_token)
+		VALUES($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+		share_token = EXCLUDED.share_token,
+		updated_at = NOW()
+	`, e.TableName())
+
+	if _, err := db.ExecContext(ctx, stmt, &userID, &shareToken); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListProductCounts lists the products which are not in the trash by the number of wishlists containing them, the most wished first.
+func (r *wishlistRepository) ListProductCounts(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.ProductWishlistCount, error) {
+	e := &entity.Product{}
+	iE := &entity.WishlistItem{}
+	stmt := fmt.Sprintf(`
+		SELECT p.id, p.name, COUNT(1) AS total
+		FROM %s wi
+		JOIN %s p ON p.id = wi.product_id
+		WHERE p.deleted_at IS NULL
+		GROUP BY p.id, p.name
+		ORDER BY total DESC, p.id
+		LIMIT $1
+		OFFSET $2
+	`, iE.TableName(), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, &limit, &offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.ProductWishlistCount
+	for rows.Next() {
+		var val entity.ProductWishlistCount
+		if err := rows.Scan(&val.ProductID, &val.Name, &val.Total); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
+
+// CountWishedProducts counts the products which are not in the trash and are in at least one wishlist.
+func (r *wishlistRepository) CountWishedProducts(ctx context.Context, db database.Executor) (int64, error) {
+	e := &entity.Product{}
+	iE := &entity.WishlistItem{}
+	stmt := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT wi.product_id)
+		FROM %s wi
+		JOIN %s p ON p.id = wi.product_id
+		WHERE p.deleted_at IS NULL
+	`, iE.TableName(), e.TableName())
+	var total sql.NullInt64
+	if err := db.QueryRowContext(ctx, stmt).Scan(&total); err != nil {
+		return total.Int64, err
+	}
+
+	return total.Int64, nil
+}
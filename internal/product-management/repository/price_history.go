@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type PriceHistoryRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.PriceHistory) error
+	ListByProductID(ctx context.Context, db database.Executor, productID, offset, limit int64) ([]*entity.PriceHistory, error)
+	CountByProductID(ctx context.Context, db database.Executor, productID int64) (int64, error)
+	ListHighestSince(ctx context.Context, db database.Executor, productIDs []int64, since time.Time) ([]*entity.PriceHistory, error)
+}
@@ -2,17 +2,45 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"trintech/review/internal/product-management/entity"
 	"trintech/review/pkg/database"
 )
 
 type ProductRepository interface {
-	List(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Product, error)
+	List(ctx context.Context, db database.Executor, offset, limit int64, order entity.ProductOrder) ([]*entity.Product, error)
 	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Product, error)
 	Create(ctx context.Context, db database.Executor, data *entity.Product) (int64, error)
-	UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product) error
-	DeleteByID(ctx context.Context, db database.Executor, id int64) error
-	DeleteByIDs(ctx context.Context, db database.Executor, ids []int64) error
+	UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product, columns []string) error
+	DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error
+	DeleteByIDs(ctx context.Context, db database.Executor, ids []int64, deletedBy int64) error
 	Count(ctx context.Context, db database.Executor) (int64, error)
+	ListDeleted(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Product, error)
+	CountDeleted(ctx context.Context, db database.Executor) (int64, error)
+	RestoreByID(ctx context.Context, db database.Executor, id int64) error
+	PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error)
+	ListByKeys(ctx context.Context, db database.Executor, sku, externalID string) ([]*entity.Product, error)
+	Iterate(ctx context.Context, db database.Executor, fn func(*entity.Product) error) error
+	RefreshRating(ctx context.Context, db database.Executor, id int64) error
+	IncrementVersionByType(ctx context.Context, db database.Executor, productType string) ([]int64, error)
+	ListByIDs(ctx context.Context, db database.Executor, ids []int64) ([]*entity.Product, error)
+}
+
+// ProductCacheRepository caches the products and the pages of the product listings.
+type ProductCacheRepository interface {
+	RetrieveByID(ctx context.Context, id int64) (*entity.Product, error)
+	// Generation returns the generation of the cache, which changes with every removal. The stores are given
+	// the generation read before their value is loaded, and skip it when it changed meanwhile.
+	Generation(ctx context.Context) int64
+	StoreByID(ctx context.Context, id int64, product *entity.Product, generation int64) error
+	RemoveByID(ctx context.Context, id int64) error
+	RetrievePage(ctx context.Context, key string) (*entity.ProductPage, error)
+	StorePage(ctx context.Context, key string, page *entity.ProductPage, generation int64) error
+	// RemovePages removes the pages of every product listing.
+	RemovePages(ctx context.Context) error
+	RetrieveView(ctx context.Context, key string) (*entity.ProductView, error)
+	StoreView(ctx context.Context, key string, view *entity.ProductView, generation int64) error
+	// RemoveViews removes the views of every product.
+	RemoveViews(ctx context.Context) error
 }
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ProductImageRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.ProductImage) error
+	DeleteByProductID(ctx context.Context, db database.Executor, productID int64) error
+	ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductImage, error)
+}
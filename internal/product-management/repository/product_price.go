@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ProductPriceRepository interface {
+	Upsert(ctx context.Context, db database.Executor, data *entity.ProductPrice) error
+	Delete(ctx context.Context, db database.Executor, productID int64, currency string) error
+	ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductPrice, error)
+}
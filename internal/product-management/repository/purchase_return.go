@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type PurchaseReturnRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.PurchaseReturn) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchaseReturn, error)
+	UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string, approvedBy int64) error
+}
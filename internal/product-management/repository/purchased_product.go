@@ -8,5 +8,12 @@ import (
 )
 
 type PurchasedProductRepository interface {
-	Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) error
+	Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchasedProduct, error)
+	List(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter, offset, limit int64) ([]*entity.PurchasedProduct, error)
+	ListByOrderID(ctx context.Context, db database.Executor, orderID int64) ([]*entity.PurchasedProduct, error)
+	Count(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter) (int64, error)
+	ExistsByUserIDAndProductID(ctx context.Context, db database.Executor, userID, productID int64) (bool, error)
+	UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error
+	AddRefundByID(ctx context.Context, db database.Executor, id, amount int64) (int64, error)
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ReviewRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.Review) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Review, error)
+	ListByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string, offset, limit int64) ([]*entity.Review, error)
+	CountByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string) (int64, error)
+	UpdateStatusByID(ctx context.Context, db database.Executor, id int64, status string, moderatedBy int64) error
+	CreateHelpfulVote(ctx context.Context, db database.Executor, reviewID, userID int64) (bool, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type SalesRollupRepository interface {
+	// RetrieveWatermark returns the watermark of the rollups, the purchases updated before it are in the rollups.
+	RetrieveWatermark(ctx context.Context, db database.Executor) (time.Time, error)
+	// LockWatermark locks the watermark of the rollups until the end of the transaction and returns it.
+	LockWatermark(ctx context.Context, db database.Executor) (time.Time, error)
+	UpdateWatermark(ctx context.Context, db database.Executor, refreshedUntil time.Time) error
+	// ListStaleDays lists the UTC days of the purchases updated since a time.
+	ListStaleDays(ctx context.Context, db database.Executor, since time.Time) ([]time.Time, error)
+	// RefreshDays recomputes the rollups of the days from the purchases.
+	RefreshDays(ctx context.Context, db database.Executor, days []time.Time) error
+	// List lists the rollups of the filter summed by interval, by interval start, currency and dimension key.
+	List(ctx context.Context, db database.Executor, filter *entity.SalesRollupFilter) ([]*entity.SalesRollup, error)
+}
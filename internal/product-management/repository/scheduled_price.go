@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ScheduledPriceRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.ScheduledPrice) (int64, error)
+	DeleteByID(ctx context.Context, db database.Executor, productID, id int64) error
+	ListByProductID(ctx context.Context, db database.Executor, productID int64, at time.Time) ([]*entity.ScheduledPrice, error)
+	ListActive(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) ([]*entity.ScheduledPrice, error)
+	NextChangeAfter(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) (sql.NullTime, error)
+}
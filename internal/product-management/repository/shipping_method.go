@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ShippingMethodRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.ShippingMethod) (int64, error)
+	RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.ShippingMethod, error)
+	List(ctx context.Context, db database.Executor) ([]*entity.ShippingMethod, error)
+	UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.ShippingMethod) error
+	CreateRate(ctx context.Context, db database.Executor, data *entity.ShippingRate) error
+	DeleteRates(ctx context.Context, db database.Executor, shippingMethodID int64) error
+	ListRates(ctx context.Context, db database.Executor, shippingMethodIDs []int64) ([]*entity.ShippingRate, error)
+}
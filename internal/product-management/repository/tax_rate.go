@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type TaxRateRepository interface {
+	Upsert(ctx context.Context, db database.Executor, data *entity.TaxRate) error
+	Delete(ctx context.Context, db database.Executor, region, category string) error
+	ListByRegions(ctx context.Context, db database.Executor, regions []string) ([]*entity.TaxRate, error)
+	List(ctx context.Context, db database.Executor) ([]*entity.TaxRate, error)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type ProductTranslationRepository interface {
+	Upsert(ctx context.Context, db database.Executor, data *entity.ProductTranslation) error
+	Delete(ctx context.Context, db database.Executor, productID int64, locale string) error
+	ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64, locales []string) ([]*entity.ProductTranslation, error)
+	ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingProductTranslation, error)
+	CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+}
+
+type CategoryTranslationRepository interface {
+	Upsert(ctx context.Context, db database.Executor, data *entity.CategoryTranslation) error
+	Delete(ctx context.Context, db database.Executor, category, locale string) error
+	ListByCategories(ctx context.Context, db database.Executor, categories []string, locales []string) ([]*entity.CategoryTranslation, error)
+	ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCategoryTranslation, error)
+	CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type WishlistRepository interface {
+	AddItem(ctx context.Context, db database.Executor, userID, productID int64) error
+	RemoveItem(ctx context.Context, db database.Executor, userID, productID int64) error
+	ListProducts(ctx context.Context, db database.Executor, userID, offset, limit int64) ([]*entity.Product, error)
+	CountProducts(ctx context.Context, db database.Executor, userID int64) (int64, error)
+	RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Wishlist, error)
+	RetrieveByShareToken(ctx context.Context, db database.Executor, shareToken string) (*entity.Wishlist, error)
+	UpsertShareToken(ctx context.Context, db database.Executor, userID int64, shareToken string) error
+	ListProductCounts(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.ProductWishlistCount, error)
+	CountWishedProducts(ctx context.Context, db database.Executor) (int64, error)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/pg_util"
+)
+
+// maxAddressLineLength is the maximum length of the lines of an address.
+const maxAddressLineLength = 200
+
+var (
+	// postalCodePatterns are the formats of the postal codes of the countries which are checked,
+	// the postal codes of the other countries are checked against defaultPostalCodePattern.
+	postalCodePatterns = map[string]*regexp.Regexp{
+		"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+		"DE": regexp.MustCompile(`^\d{5}$`),
+		"FR": regexp.MustCompile(`^\d{5}$`),
+	}
+	defaultPostalCodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+	phonePattern             = regexp.MustCompile(`^\+?[0-9 ()-]{6,20}$`)
+)
+
+// addressRequest is the address of a request creating or updating an address.
+type addressRequest interface {
+	GetRecipient() string
+	GetLine1() string
+	GetLine2() string
+	GetCity() string
+	GetPostalCode() string
+	GetRegion() string
+	GetPhone() string
+}
+
+// validAddress validates the address of a request and returns it without owner.
+func validAddress(req addressRequest) (*entity.Address, error) {
+	// Validate the required lines and their length
+	for _, line := range []struct {
+		name     string
+		value    string
+		required bool
+	}{
+		{name: "recipient", value: req.GetRecipient(), required: true},
+		{name: "line1", value: req.GetLine1(), required: true},
+		{name: "line2", value: req.GetLine2()},
+		{name: "city", value: req.GetCity(), required: true},
+	} {
+		if line.required && strings.TrimSpace(line.value) == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is required", line.name)
+		}
+		if len(line.value) > maxAddressLineLength {
+			return nil, status.Errorf(codes.InvalidArgument, "%s is too long", line.name)
+		}
+	}
+
+	// Validate the region, and the postal code in the format of the country
+	region, err := validRegion(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+	if postalCode := req.GetPostalCode(); postalCode != "" {
+		country, _, _ := strings.Cut(region, "-")
+		pattern, ok := postalCodePatterns[country]
+		if !ok {
+			pattern = defaultPostalCodePattern
+		}
+		if !pattern.MatchString(postalCode) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid postal code")
+		}
+	}
+	if phone := req.GetPhone(); phone != "" && !phonePattern.MatchString(phone) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid phone")
+	}
+
+	return &entity.Address{
+		Recipient:  pg_util.NullString(strings.TrimSpace(req.GetRecipient())),
+		Line1:      pg_util.NullString(strings.TrimSpace(req.GetLine1())),
+		Line2:      pg_util.NullEmptyString(strings.TrimSpace(req.GetLine2())),
+		City:       pg_util.NullString(strings.TrimSpace(req.GetCity())),
+		PostalCode: pg_util.NullEmptyString(req.GetPostalCode()),
+		Region:     pg_util.NullString(region),
+		Phone:      pg_util.NullEmptyString(req.GetPhone()),
+	}, nil
+}
+
+// toPbAddress transforms an address entity to the response format.
+func toPbAddress(address *entity.Address) *pb.Address {
+	data := &pb.Address{
+		Id:         address.ID.Int64,
+		Recipient:  address.Recipient.String,
+		Line1:      address.Line1.String,
+		Line2:      address.Line2.String,
+		City:       address.City.String,
+		PostalCode: address.PostalCode.String,
+		Region:     address.Region.String,
+		Phone:      address.Phone.String,
+		IsDefault:  address.IsDefault.Bool,
+	}
+	if address.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(address.CreatedAt.Time)
+	}
+
+	return data
+}
+
+// toShippingAddress returns the copy of an address stored with a purchase.
+func toShippingAddress(address *entity.Address) sql.NullString {
+	data, _ := json.Marshal(entity.ShippingAddress{
+		Recipient:  address.Recipient.String,
+		Line1:      address.Line1.String,
+		Line2:      address.Line2.String,
+		City:       address.City.String,
+		PostalCode: address.PostalCode.String,
+		Region:     address.Region.String,
+		Phone:      address.Phone.String,
+	})
+
+	return pg_util.NullString(string(data))
+}
+
+// toPbShippingAddress transforms the copy of an address stored with a purchase to the response format,
+// it is nil for the purchases which are not shipped.
+func toPbShippingAddress(shippingAddress sql.NullString) *pb.Address {
+	var address entity.ShippingAddress
+	if !shippingAddress.Valid || json.Unmarshal([]byte(shippingAddress.String), &address) != nil {
+		return nil
+	}
+
+	return &pb.Address{
+		Recipient:  address.Recipient,
+		Line1:      address.Line1,
+		Line2:      address.Line2,
+		City:       address.City,
+		PostalCode: address.PostalCode,
+		Region:     address.Region,
+		Phone:      address.Phone,
+	}
+}
+
+// shippingAddress retrieves the address of the user a purchase is shipped to, the default address without id.
+func (s *productService) shippingAddress(ctx context.Context, userID, id int64) (*entity.Address, error) {
+	if id == 0 {
+		address, err := s.addressRepo.RetrieveDefault(ctx, s.db, userID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, status.Errorf(codes.FailedPrecondition, "shipping address is required")
+		case err != nil:
+			return nil, status.Errorf(codes.Internal, "unable to retrieve default address: %v", err.Error())
+		}
+
+		return address, nil
+	}
+
+	address, err := s.addressRepo.RetrieveByID(ctx, s.db, userID, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "address not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve address: %v", err.Error())
+	}
+
+	return address, nil
+}
+
+// CreateAddress is a method of the productService that adds an address to the address book of the user.
+// The first address of the user becomes the default address.
+func (s *productService) CreateAddress(ctx context.Context, req *pb.CreateAddressRequest) (*pb.CreateAddressResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Validate the address
+	address, err := validAddress(req)
+	if err != nil {
+		return nil, err
+	}
+	address.UserID = pg_util.NullInt64(userCtx.UserID)
+
+	// Create the address in a database transaction, replacing the default address
+	var id int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		isDefault := req.GetIsDefault()
+		if isDefault {
+			if err := s.addressRepo.ClearDefault(ctx, tx, userCtx.UserID); err != nil {
+				return fmt.Errorf("unable to clear default address: %v", err)
+			}
+		} else {
+			_, err := s.addressRepo.RetrieveDefault(ctx, tx, userCtx.UserID)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				isDefault = true
+			case err != nil:
+				return fmt.Errorf("unable to retrieve default address: %v", err)
+			}
+		}
+		address.IsDefault = sql.NullBool{Bool: isDefault, Valid: true}
+
+		var err error
+		if id, err = s.addressRepo.Create(ctx, tx, address); err != nil {
+			return fmt.Errorf("unable to create address: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to create address: %v", err.Error())
+	}
+
+	return &pb.CreateAddressResponse{Id: id}, nil
+}
+
+// ListAddress is a method of the productService that lists the address book of the user, the default address first.
+func (s *productService) ListAddress(ctx context.Context, _ *pb.ListAddressRequest) (*pb.ListAddressResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the addresses of the user
+	addresses, err := s.addressRepo.ListByUserID(ctx, s.db, userCtx.UserID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve addresses: %v", err.Error())
+	}
+
+	// Transform the addresses to the response format
+	respData := make([]*pb.Address, 0, len(addresses))
+	for _, address := range addresses {
+		respData = append(respData, toPbAddress(address))
+	}
+
+	return &pb.ListAddressResponse{Data: respData}, nil
+}
+
+// UpdateAddress is a method of the productService that replaces an address of the user.
+// The purchases keep the address they were shipped to.
+func (s *productService) UpdateAddress(ctx context.Context, req *pb.UpdateAddressRequest) (*pb.UpdateAddressResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Validate the address
+	address, err := validAddress(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the address
+	err = s.addressRepo.UpdateByID(ctx, s.db, userCtx.UserID, req.GetId(), address)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "address not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to update address: %v", err.Error())
+	}
+
+	return &pb.UpdateAddressResponse{}, nil
+}
+
+// DeleteAddress is a method of the productService that removes an address from the address book of the user.
+func (s *productService) DeleteAddress(ctx context.Context, req *pb.DeleteAddressRequest) (*pb.DeleteAddressResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Delete the address
+	err := s.addressRepo.DeleteByID(ctx, s.db, userCtx.UserID, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "address not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to delete address: %v", err.Error())
+	}
+
+	return &pb.DeleteAddressResponse{}, nil
+}
+
+// SetDefaultAddress is a method of the productService that sets the default address of the user.
+func (s *productService) SetDefaultAddress(ctx context.Context, req *pb.SetDefaultAddressRequest) (*pb.SetDefaultAddressResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Replace the default address in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.addressRepo.ClearDefault(ctx, tx, userCtx.UserID); err != nil {
+			return fmt.Errorf("unable to clear default address: %v", err)
+		}
+
+		err := s.addressRepo.SetDefault(ctx, tx, userCtx.UserID, req.GetId())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "address not found")
+		case err != nil:
+			return fmt.Errorf("unable to set default address: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to set default address: %v", err.Error())
+	}
+
+	return &pb.SetDefaultAddressResponse{}, nil
+}
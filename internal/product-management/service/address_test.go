@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_productService_CreateAddress(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	address := func() *pb.CreateAddressRequest {
+		return &pb.CreateAddressRequest{
+			Recipient:  "Jane Doe",
+			Line1:      "1 Main St",
+			City:       "San Francisco",
+			PostalCode: "94105",
+			Region:     "us-ca",
+		}
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     func() *pb.CreateAddressRequest
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository)
+	}{
+		{
+			name: "happy case first address is default",
+			ctx:  userCtx,
+			req:  address,
+			setup: func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository) {
+				smock.ExpectBegin()
+				addressRepo.On("RetrieveDefault", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+				addressRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(address *entity.Address) bool {
+					return address.UserID.Int64 == 1 && address.Region.String == "US-CA" && address.IsDefault.Bool
+				})).Return(int64(2), nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case default replaces default",
+			ctx:  userCtx,
+			req: func() *pb.CreateAddressRequest {
+				req := address()
+				req.IsDefault = true
+				return req
+			},
+			setup: func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository) {
+				smock.ExpectBegin()
+				addressRepo.On("ClearDefault", mock.Anything, mock.Anything, int64(1)).Return(nil)
+				addressRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(address *entity.Address) bool {
+					return address.IsDefault.Bool
+				})).Return(int64(2), nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "err invalid postal code",
+			ctx:  userCtx,
+			req: func() *pb.CreateAddressRequest {
+				req := address()
+				req.PostalCode = "9410"
+				return req
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid postal code"),
+			setup:   func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository) {},
+		},
+		{
+			name: "err missing recipient",
+			ctx:  userCtx,
+			req: func() *pb.CreateAddressRequest {
+				req := address()
+				req.Recipient = " "
+				return req
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "recipient is required"),
+			setup:   func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository) {},
+		},
+		{
+			name:    "err invalid user",
+			ctx:     context.Background(),
+			req:     address,
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(smock sqlmock.Sqlmock, addressRepo *mocks.AddressRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			addressRepo := &mocks.AddressRepository{}
+			tt.setup(smock, addressRepo)
+			s := &productService{
+				addressRepo: addressRepo,
+				db:          &postgres_client.PostgresClient{DB: db},
+			}
+			got, err := s.CreateAddress(tt.ctx, tt.req())
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(2), got.GetId())
+			addressRepo.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository/postgres"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/processor"
+)
+
+const (
+	// salesRollupInterval is how often the sales rollups are refreshed by the processor.
+	salesRollupInterval = 5 * time.Minute
+	// salesRollupOverlap is how far before the watermark the purchases are checked again on a refresh, a purchase
+	// updated just before a refresh may only be committed after it.
+	salesRollupOverlap = 10 * time.Minute
+	// maxSalesAnalyticsRange is the longest range of days the sales can be listed for.
+	maxSalesAnalyticsRange = 3 * 366 * 24 * time.Hour
+)
+
+// salesIntervals maps the sales intervals of the requests to the intervals of the rollups.
+var salesIntervals = map[pb.SalesInterval]string{
+	pb.SalesInterval_SalesInterval_DAY:   entity.SalesInterval_Day,
+	pb.SalesInterval_SalesInterval_WEEK:  entity.SalesInterval_Week,
+	pb.SalesInterval_SalesInterval_MONTH: entity.SalesInterval_Month,
+}
+
+// salesDimensions maps the sales breakdowns of the requests to the dimensions of the rollups.
+var salesDimensions = map[pb.SalesBreakdown]string{
+	pb.SalesBreakdown_SalesBreakdown_NONE:    entity.SalesDimension_Total,
+	pb.SalesBreakdown_SalesBreakdown_PRODUCT: entity.SalesDimension_Product,
+	pb.SalesBreakdown_SalesBreakdown_TYPE:    entity.SalesDimension_Type,
+	pb.SalesBreakdown_SalesBreakdown_COUPON:  entity.SalesDimension_Coupon,
+}
+
+// refreshSalesRollups recomputes the rollups of the days of the purchases updated since the last refresh,
+// and returns the number of refreshed days with the new watermark.
+func (s *productService) refreshSalesRollups(ctx context.Context) (int64, time.Time, error) {
+	var (
+		days           []time.Time
+		refreshedUntil time.Time
+	)
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Lock the watermark, the concurrent refreshes wait for this one
+		watermark, err := s.salesRollupRepo.LockWatermark(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		// Recompute the days of the purchases updated since the watermark
+		refreshedUntil = time.Now()
+		if days, err = s.salesRollupRepo.ListStaleDays(ctx, tx, watermark.Add(-salesRollupOverlap)); err != nil {
+			return err
+		}
+		if len(days) > 0 {
+			if err := s.salesRollupRepo.RefreshDays(ctx, tx, days); err != nil {
+				return err
+			}
+		}
+
+		return s.salesRollupRepo.UpdateWatermark(ctx, tx, refreshedUntil)
+	}); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return int64(len(days)), refreshedUntil, nil
+}
+
+// NewSalesRollupProcessor returns a processor which periodically refreshes the sales rollups
+// with the purchases updated since the last refresh.
+func NewSalesRollupProcessor(db database.Database) processor.Processor {
+	s := &productService{
+		db:              db,
+		salesRollupRepo: postgres.NewSalesRollupRepository(),
+	}
+
+	return processor.NewIntervalProcessor("sales-rollup", salesRollupInterval, func(ctx context.Context) error {
+		days, refreshedUntil, err := s.refreshSalesRollups(ctx)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("refreshed sales rollups", "days", days, "refreshed_until", refreshedUntil)
+
+		return nil
+	})
+}
+
+// ListSalesAnalytics is a method of the productService that lists the revenue, units, orders, average order value
+// and discount of the sales by day, week or month, in total or broken down by product, type or coupon.
+// The sales are read from the rollups, the purchases updated after their last refresh are not counted yet.
+func (s *productService) ListSalesAnalytics(ctx context.Context, req *pb.ListSalesAnalyticsRequest) (*pb.ListSalesAnalyticsResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the range, the interval and the breakdown
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "from and to are required")
+	}
+	from, to := req.GetFrom().AsTime(), req.GetTo().AsTime()
+	if !from.Before(to) {
+		return nil, status.Errorf(codes.InvalidArgument, "from must be before to")
+	}
+	if to.Sub(from) > maxSalesAnalyticsRange {
+		return nil, status.Errorf(codes.InvalidArgument, "range must not exceed %d days", maxSalesAnalyticsRange/(24*time.Hour))
+	}
+	interval, ok := salesIntervals[req.GetInterval()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid interval")
+	}
+	dimension, ok := salesDimensions[req.GetBreakdown()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid breakdown")
+	}
+	if dimension == entity.SalesDimension_Total && len(req.GetKeys()) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "keys require a breakdown")
+	}
+
+	// Retrieve the rollups with their freshness
+	rollups, err := s.salesRollupRepo.List(ctx, s.db, &entity.SalesRollupFilter{
+		From:          from,
+		To:            to,
+		Interval:      interval,
+		Dimension:     dimension,
+		DimensionKeys: req.GetKeys(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list sales rollups: %v", err.Error())
+	}
+	refreshedUntil, err := s.salesRollupRepo.RetrieveWatermark(ctx, s.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve sales rollup watermark: %v", err.Error())
+	}
+
+	data := make([]*pb.SalesPoint, 0, len(rollups))
+	for _, rollup := range rollups {
+		data = append(data, toPbSalesPoint(rollup))
+	}
+
+	return &pb.ListSalesAnalyticsResponse{
+		Data:           data,
+		RefreshedUntil: timestamppb.New(refreshedUntil),
+	}, nil
+}
+
+// RefreshSalesAnalytics is a method of the productService that refreshes the sales rollups without waiting
+// for the processor.
+func (s *productService) RefreshSalesAnalytics(ctx context.Context, _ *pb.RefreshSalesAnalyticsRequest) (*pb.RefreshSalesAnalyticsResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Refresh the rollups
+	days, refreshedUntil, err := s.refreshSalesRollups(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to refresh sales rollups: %v", err.Error())
+	}
+
+	return &pb.RefreshSalesAnalyticsResponse{
+		Days:           days,
+		RefreshedUntil: timestamppb.New(refreshedUntil),
+	}, nil
+}
+
+// toPbSalesPoint transforms a sales rollup entity to the response format, the average order value is rounded down.
+func toPbSalesPoint(rollup *entity.SalesRollup) *pb.SalesPoint {
+	currency := rollup.Currency.String
+	data := &pb.SalesPoint{
+		PeriodStart: timestamppb.New(rollup.Day.Time),
+		Units:       rollup.Units.Int64,
+		Orders:      rollup.Orders.Int64,
+		Revenue:     toPbMoney(money.New(rollup.Revenue.Int64, currency)),
+		Discount:    toPbMoney(money.New(rollup.Discount.Int64, currency)),
+		Refunded:    toPbMoney(money.New(rollup.Refunded.Int64, currency)),
+	}
+	var averageOrderValue int64
+	if rollup.Orders.Int64 > 0 {
+		averageOrderValue = rollup.Revenue.Int64 / rollup.Orders.Int64
+	}
+	data.AverageOrderValue = toPbMoney(money.New(averageOrderValue, currency))
+
+	switch rollup.Dimension.String {
+	case entity.SalesDimension_Product:
+		data.ProductId, _ = strconv.ParseInt(rollup.DimensionKey.String, 10, 64)
+	case entity.SalesDimension_Type:
+		data.Type = rollup.DimensionKey.String
+	case entity.SalesDimension_Coupon:
+		data.Coupon = rollup.DimensionKey.String
+	}
+
+	return data
+}
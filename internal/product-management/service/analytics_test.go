@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_productService_refreshSalesRollups(t *testing.T) {
+	watermark := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	days := []time.Time{time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name     string
+		days     []time.Time
+		wantDays int64
+	}{
+		{
+			name:     "happy case stale days",
+			days:     days,
+			wantDays: 2,
+		},
+		{
+			name: "happy case no stale day",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			smock.ExpectBegin()
+			smock.ExpectCommit()
+			salesRollupRepo := &mocks.SalesRollupRepository{}
+			salesRollupRepo.On("LockWatermark", mock.Anything, mock.Anything).Return(watermark, nil)
+			salesRollupRepo.On("ListStaleDays", mock.Anything, mock.Anything, watermark.Add(-salesRollupOverlap)).Return(tt.days, nil)
+			if len(tt.days) > 0 {
+				salesRollupRepo.On("RefreshDays", mock.Anything, mock.Anything, tt.days).Return(nil)
+			}
+			salesRollupRepo.On("UpdateWatermark", mock.Anything, mock.Anything, mock.MatchedBy(func(refreshedUntil time.Time) bool {
+				return refreshedUntil.After(watermark)
+			})).Return(nil)
+			s := &productService{
+				db:              &postgres_client.PostgresClient{DB: db},
+				salesRollupRepo: salesRollupRepo,
+			}
+
+			gotDays, _, err := s.refreshSalesRollups(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDays, gotDays)
+			require.NoError(t, smock.ExpectationsWereMet())
+			salesRollupRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_productService_ListSalesAnalytics(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	refreshedUntil := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		req     *pb.ListSalesAnalyticsRequest
+		want    *pb.ListSalesAnalyticsResponse
+		wantErr error
+		setup   func(salesRollupRepo *mocks.SalesRollupRepository)
+	}{
+		{
+			name: "happy case by week and product",
+			req: &pb.ListSalesAnalyticsRequest{
+				From:      timestamppb.New(from),
+				To:        timestamppb.New(to),
+				Interval:  pb.SalesInterval_SalesInterval_WEEK,
+				Breakdown: pb.SalesBreakdown_SalesBreakdown_PRODUCT,
+				Keys:      []string{"7"},
+			},
+			setup: func(salesRollupRepo *mocks.SalesRollupRepository) {
+				salesRollupRepo.On("List", mock.Anything, mock.Anything, &entity.SalesRollupFilter{
+					From:          from,
+					To:            to,
+					Interval:      entity.SalesInterval_Week,
+					Dimension:     entity.SalesDimension_Product,
+					DimensionKeys: []string{"7"},
+				}).Return([]*entity.SalesRollup{
+					{
+						Day:          pg_util.NullTime(from),
+						Dimension:    pg_util.NullString(entity.SalesDimension_Product),
+						DimensionKey: pg_util.NullString("7"),
+						Currency:     pg_util.NullString("USD"),
+						Units:        pg_util.NullInt64(4),
+						Orders:       pg_util.NullInt64(3),
+						Revenue:      pg_util.NullInt64(1000),
+						Discount:     pg_util.NullInt64(200),
+						Refunded:     pg_util.NullInt64(0),
+					},
+				}, nil)
+				salesRollupRepo.On("RetrieveWatermark", mock.Anything, mock.Anything).Return(refreshedUntil, nil)
+			},
+			want: &pb.ListSalesAnalyticsResponse{
+				Data: []*pb.SalesPoint{
+					{
+						PeriodStart:       timestamppb.New(from),
+						ProductId:         7,
+						Units:             4,
+						Orders:            3,
+						Revenue:           &pb.Money{Amount: 1000, Currency: "USD"},
+						Discount:          &pb.Money{Amount: 200, Currency: "USD"},
+						AverageOrderValue: &pb.Money{Amount: 333, Currency: "USD"},
+						Refunded:          &pb.Money{Currency: "USD"},
+					},
+				},
+				RefreshedUntil: timestamppb.New(refreshedUntil),
+			},
+		},
+		{
+			name:    "err range is required",
+			req:     &pb.ListSalesAnalyticsRequest{From: timestamppb.New(from)},
+			wantErr: status.Errorf(codes.InvalidArgument, "from and to are required"),
+		},
+		{
+			name:    "err from after to",
+			req:     &pb.ListSalesAnalyticsRequest{From: timestamppb.New(to), To: timestamppb.New(from)},
+			wantErr: status.Errorf(codes.InvalidArgument, "from must be before to"),
+		},
+		{
+			name: "err keys without breakdown",
+			req: &pb.ListSalesAnalyticsRequest{
+				From: timestamppb.New(from),
+				To:   timestamppb.New(to),
+				Keys: []string{"7"},
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "keys require a breakdown"),
+		},
+		{
+			name:    "err invalid interval",
+			req:     &pb.ListSalesAnalyticsRequest{From: timestamppb.New(from), To: timestamppb.New(to), Interval: pb.SalesInterval(9)},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid interval"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			salesRollupRepo := &mocks.SalesRollupRepository{}
+			if tt.setup != nil {
+				tt.setup(salesRollupRepo)
+			}
+			s := &productService{
+				salesRollupRepo: salesRollupRepo,
+			}
+			got, err := s.ListSalesAnalytics(adminCtx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	couponpb "trintech/review/dto/coupon-management/coupon"
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/crypto_util"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+)
+
+// cartTokenSize is the number of random bytes of an anonymous cart token.
+const cartTokenSize = 16
+
+// RetrieveCart is a method of the productService that retrieves the cart of the user or of the cart token.
+// A missing cart is returned empty.
+func (s *productService) RetrieveCart(ctx context.Context, req *pb.RetrieveCartRequest) (*pb.RetrieveCartResponse, error) {
+	// Resolve the cart of the request
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Price the cart
+	data, err := s.cartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the priced cart
+	return &pb.RetrieveCartResponse{
+		Data: data,
+	}, nil
+}
+
+// AddCartItem is a method of the productService that adds a quantity of a product to the cart.
+// Without a user nor a cart token, an anonymous cart is created and its token is returned with the cart.
+func (s *productService) AddCartItem(ctx context.Context, req *pb.AddCartItemRequest) (*pb.AddCartItemResponse, error) {
+	// Validate the quantity
+	if req.GetQuantity() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	// Retrieve the product by ID
+	if _, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetProductId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not found, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not found")
+		}
+
+		// If there is an error during product retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+
+	// Resolve the cart of the request, creating it when needed
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the product to the cart
+	if err := s.cartRepo.AddItem(ctx, s.db, cart.ID.Int64, req.GetProductId(), req.GetQuantity()); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to add product to cart: %v", err.Error())
+	}
+
+	// Price the cart
+	data, err := s.cartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the priced cart
+	return &pb.AddCartItemResponse{
+		Data: data,
+	}, nil
+}
+
+// UpdateCartItem is a method of the productService that sets the quantity of a product of the cart.
+// A zero quantity removes the product from the cart.
+func (s *productService) UpdateCartItem(ctx context.Context, req *pb.UpdateCartItemRequest) (*pb.UpdateCartItemResponse, error) {
+	// Validate the quantity
+	if req.GetQuantity() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must not be negative")
+	}
+
+	// Resolve the cart of the request
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, status.Errorf(codes.NotFound, "product not in cart")
+	}
+
+	// Update the quantity of the product, or remove it
+	if req.GetQuantity() == 0 {
+		err = s.cartRepo.RemoveItem(ctx, s.db, cart.ID.Int64, req.GetProductId())
+	} else {
+		err = s.cartRepo.SetItemQuantity(ctx, s.db, cart.ID.Int64, req.GetProductId(), req.GetQuantity())
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not in the cart, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not in cart")
+		}
+
+		// If there is an error during update, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to update cart: %v", err.Error())
+	}
+
+	// Price the cart
+	data, err := s.cartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the priced cart
+	return &pb.UpdateCartItemResponse{
+		Data: data,
+	}, nil
+}
+
+// RemoveCartItem is a method of the productService that removes a product from the cart.
+func (s *productService) RemoveCartItem(ctx context.Context, req *pb.RemoveCartItemRequest) (*pb.RemoveCartItemResponse, error) {
+	// Resolve the cart of the request
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), false)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, status.Errorf(codes.NotFound, "product not in cart")
+	}
+
+	// Remove the product from the cart
+	if err := s.cartRepo.RemoveItem(ctx, s.db, cart.ID.Int64, req.GetProductId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not in the cart, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not in cart")
+		}
+
+		// If there is an error during removal, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to remove product from cart: %v", err.Error())
+	}
+
+	// Price the cart
+	data, err := s.cartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the priced cart
+	return &pb.RemoveCartItemResponse{
+		Data: data,
+	}, nil
+}
+
+// ApplyCartCoupon is a method of the productService that applies a coupon to the cart, or removes it when empty.
+// The coupon must be usable when it is applied, it is checked again every time the cart is priced.
+func (s *productService) ApplyCartCoupon(ctx context.Context, req *pb.ApplyCartCouponRequest) (*pb.ApplyCartCouponResponse, error) {
+	// Resolve the cart of the request, creating it when needed
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Price the cart with the coupon, which must be usable for its items
+	cart.Coupon = pg_util.NullEmptyString(req.GetCoupon())
+	data, coupon, err := s.priceCartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	if cart.Coupon.Valid && coupon == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "unable to apply this coupon: %s", data.GetCouponError())
+	}
+
+	// Store the coupon of the cart
+	if err := s.cartRepo.UpdateCouponByID(ctx, s.db, cart.ID.Int64, cart.Coupon); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to apply coupon to cart: %v", err.Error())
+	}
+
+	// Return the priced cart
+	return &pb.ApplyCartCouponResponse{
+		Data: data,
+	}, nil
+}
+
+// resolveCart returns the cart of the request. For a user, it is the user's cart in which the anonymous cart
+// of the token is merged. Otherwise, it is the anonymous cart of the token.
+// When the cart doesn't exist, it is created with create, or nil is returned.
+func (s *productService) resolveCart(ctx context.Context, cartToken string, create bool) (*entity.Cart, error) {
+	userCtx, loggedIn := http_server.ExtractUserInfoFromCtx(ctx)
+
+	var cart *entity.Cart
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Retrieve the anonymous cart of the token, a user may keep sending a merged token
+		var anonymous *entity.Cart
+		if cartToken != "" {
+			c, err := s.cartRepo.RetrieveByToken(ctx, tx, cartToken)
+			switch {
+			case err == nil:
+				anonymous = c
+			case !errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("unable to retrieve cart: %v", err)
+			case !loggedIn:
+				return status.Errorf(codes.NotFound, "cart not found")
+			}
+		}
+
+		// Use the anonymous cart without a user
+		if !loggedIn {
+			cart = anonymous
+			if cart != nil || !create {
+				return nil
+			}
+
+			token, err := crypto_util.GenerateToken(cartTokenSize)
+			if err != nil {
+				return err
+			}
+			cart = &entity.Cart{Token: pg_util.NullString(token)}
+			cart.ID, err = s.createCart(ctx, tx, cart)
+
+			return err
+		}
+
+		// Retrieve the user's cart, creating it when there is something to put in it
+		c, err := s.cartRepo.RetrieveByUserID(ctx, tx, userCtx.UserID)
+		switch {
+		case err == nil:
+			cart = c
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("unable to retrieve cart: %v", err)
+		case create || anonymous != nil:
+			cart = &entity.Cart{UserID: pg_util.NullInt64(userCtx.UserID)}
+			if cart.ID, err = s.createCart(ctx, tx, cart); err != nil {
+				return err
+			}
+		}
+
+		// Merge the anonymous cart into the user's cart
+		if anonymous != nil {
+			if err := s.cartRepo.MergeInto(ctx, tx, anonymous.ID.Int64, cart.ID.Int64); err != nil {
+				return fmt.Errorf("unable to merge cart: %v", err)
+			}
+			if !cart.Coupon.Valid {
+				cart.Coupon = anonymous.Coupon
+			}
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve cart: %v", err.Error())
+	}
+
+	return cart, nil
+}
+
+// createCart creates a cart and returns its id.
+func (s *productService) createCart(ctx context.Context, db database.Executor, cart *entity.Cart) (sql.NullInt64, error) {
+	id, err := s.cartRepo.Create(ctx, db, cart)
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("unable to create cart: %v", err)
+	}
+
+	return pg_util.NullInt64(id), nil
+}
+
+// retrieveCartCoupon retrieves a coupon with its usability for the owner of the cart and its available items.
+func (s *productService) retrieveCartCoupon(ctx context.Context, cart *entity.Cart, code string, items []*pb.Cart_Item) (*couponpb.RetrieveCouponByCodeResponse, error) {
+	orderValue := money.New(0, s.baseCurrency)
+	var productTypes []string
+	for _, item := range items {
+		if !item.GetAvailable() {
+			continue
+		}
+		orderValue.Amount += item.GetSubtotal().GetAmount()
+		if !slices.Contains(productTypes, item.GetProduct().GetType()) {
+			productTypes = append(productTypes, item.GetProduct().GetType())
+		}
+	}
+
+	req, err := s.couponUseRequest(ctx, code, cart.UserID, orderValue, productTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.couponServiceClient.RetrieveCouponByCode(http_server.InjectIncomingCtxToOutgoingCtx(ctx), req)
+}
+
+// couponUseRequest returns the request checking the use of a coupon for an order of a user, anonymous when the
+// user id is invalid. The order value is the value of the products before discounts.
+func (s *productService) couponUseRequest(ctx context.Context, code string, userID sql.NullInt64, orderValue money.Money, productTypes []string) (*couponpb.RetrieveCouponByCodeRequest, error) {
+	req := &couponpb.RetrieveCouponByCodeRequest{
+		Code:         code,
+		CheckUse:     true,
+		OrderValue:   &couponpb.Money{Amount: orderValue.Amount, Currency: orderValue.Currency},
+		ProductTypes: productTypes,
+	}
+	if userID.Valid {
+		// A user who never purchased is on a first purchase
+		purchases, err := s.purchasedProductRepo.Count(ctx, s.db, &entity.PurchasedProductFilter{UserID: userID})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to count purchases: %v", err.Error())
+		}
+		req.UserId = wrapperspb.Int64(userID.Int64)
+		req.FirstPurchase = purchases == 0
+	}
+
+	return req, nil
+}
+
+// cartView prices a cart with the current prices of its products and its coupon.
+func (s *productService) cartView(ctx context.Context, cart *entity.Cart) (*pb.Cart, error) {
+	data, _, err := s.priceCartView(ctx, cart)
+
+	return data, err
+}
+
+// priceCartView prices a cart like cartView and also returns the coupon of the discount, nil without discount.
+func (s *productService) priceCartView(ctx context.Context, cart *entity.Cart) (*pb.Cart, *couponpb.RetrieveCouponByCodeResponse, error) {
+	data := &pb.Cart{}
+	if cart == nil {
+		priceCart(data, s.baseCurrency, nil)
+
+		return data, nil, nil
+	}
+	if !cart.UserID.Valid {
+		data.CartToken = cart.Token.String
+	}
+
+	// Retrieve the items of the cart with their products
+	items, err := s.cartRepo.ListItems(ctx, s.db, cart.ID.Int64)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "unable to retrieve cart items: %v", err.Error())
+	}
+
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID.Int64)
+	}
+	products, err := s.productRepo.ListByIDs(ctx, s.db, ids)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "unable to retrieve cart products: %v", err.Error())
+	}
+	if err := s.applyScheduledPrices(ctx, products...); err != nil {
+		return nil, nil, err
+	}
+	productByID := make(map[int64]*entity.Product, len(products))
+	for _, product := range products {
+		productByID[product.ID.Int64] = product
+	}
+
+	// Transform the items to the response format, a product which is no longer sold is not priced
+	for _, item := range items {
+		respItem := &pb.Cart_Item{
+			Product:  &pb.Product{Id: item.ProductID.Int64},
+			Quantity: item.Quantity.Int64,
+		}
+		if product, ok := productByID[item.ProductID.Int64]; ok && product.Currency.String == s.baseCurrency {
+			respItem.Product = toPbProduct(product)
+			respItem.Subtotal = toPbMoney(productPrice(product).Mul(item.Quantity.Int64))
+			respItem.Available = true
+		}
+		data.Items = append(data.Items, respItem)
+	}
+
+	// Check the coupon is still usable
+	var coupon *couponpb.RetrieveCouponByCodeResponse
+	if cart.Coupon.Valid {
+		data.Coupon = cart.Coupon.String
+		coupon, err = s.retrieveCartCoupon(ctx, cart, cart.Coupon.String, data.GetItems())
+		switch {
+		case err != nil:
+			slog.Error("unable to retrieve cart coupon", "coupon", cart.Coupon.String, "err", err)
+			coupon = nil
+			data.CouponError = "coupon cannot be checked"
+		case !coupon.GetCanUse():
+			data.CouponError = "coupon cannot be applied"
+			if coupon.GetReasonMessage() != "" {
+				data.CouponError += ": " + coupon.GetReasonMessage()
+			}
+			coupon = nil
+		}
+	}
+
+	// Compute the totals
+	if !priceCart(data, s.baseCurrency, coupon) {
+		coupon = nil
+		data.CouponError = "coupon cannot be applied"
+	}
+
+	return data, coupon, nil
+}
+
+// priceCart sets the subtotal, discount and total of a cart in a currency from its available items and a usable coupon.
+// The discount never exceeds the subtotal, it returns false when the coupon cannot discount the cart.
+func priceCart(cart *pb.Cart, currency string, coupon *couponpb.RetrieveCouponByCodeResponse) bool {
+	subtotal := money.New(0, currency)
+	for _, item := range cart.GetItems() {
+		if item.GetAvailable() {
+			subtotal.Amount += item.GetSubtotal().GetAmount()
+		}
+	}
+
+	discount, ok := money.New(0, currency), true
+	if coupon != nil {
+		discount, ok = couponDiscount(subtotal, coupon)
+	}
+	total, _ := subtotal.Sub(discount)
+
+	cart.Subtotal = toPbMoney(subtotal)
+	cart.Discount = toPbMoney(discount)
+	cart.Total = toPbMoney(total)
+
+	return ok
+}
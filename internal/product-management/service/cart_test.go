@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	couponpb "trintech/review/dto/coupon-management/coupon"
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_priceCart(t *testing.T) {
+	items := func() []*pb.Cart_Item {
+		return []*pb.Cart_Item{
+			{Quantity: 2, Subtotal: &pb.Money{Amount: 4000, Currency: "USD"}, Available: true},
+			{Quantity: 1, Subtotal: &pb.Money{Amount: 6005, Currency: "USD"}, Available: true},
+			{Quantity: 1, Available: false},
+		}
+	}
+	tests := []struct {
+		name   string
+		coupon *couponpb.RetrieveCouponByCodeResponse
+		want   [3]int64
+		wantOK bool
+	}{
+		{
+			name:   "no coupon",
+			want:   [3]int64{10005, 0, 10005},
+			wantOK: true,
+		},
+		{
+			name:   "percent coupon rounds half up",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_PERCENT, PercentBasisPoints: 1050},
+			want:   [3]int64{10005, 1051, 8954},
+			wantOK: true,
+		},
+		{
+			name:   "value coupon",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_VALUE, Value: &couponpb.Money{Amount: 3000, Currency: "USD"}},
+			want:   [3]int64{10005, 3000, 7005},
+			wantOK: true,
+		},
+		{
+			name:   "value coupon above subtotal",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_VALUE, Value: &couponpb.Money{Amount: 15000, Currency: "USD"}},
+			want:   [3]int64{10005, 10005, 0},
+			wantOK: true,
+		},
+		{
+			name:   "value coupon in another currency",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_VALUE, Value: &couponpb.Money{Amount: 3000, Currency: "EUR"}},
+			want:   [3]int64{10005, 0, 10005},
+		},
+		{
+			name: "percent coupon capped by the rules",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_PERCENT, PercentBasisPoints: 5000,
+				Rules: &couponpb.CouponRules{Currency: "USD", MaxDiscount: 2000}},
+			want:   [3]int64{10005, 2000, 8005},
+			wantOK: true,
+		},
+		{
+			name: "cap in another currency",
+			coupon: &couponpb.RetrieveCouponByCodeResponse{CanUse: true, DiscountType: couponpb.DiscountType_DiscountType_PERCENT, PercentBasisPoints: 5000,
+				Rules: &couponpb.CouponRules{Currency: "EUR", MaxDiscount: 2000}},
+			want: [3]int64{10005, 0, 10005},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := &pb.Cart{Items: items()}
+			ok := priceCart(cart, "USD", tt.coupon)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, [3]int64{cart.GetSubtotal().GetAmount(), cart.GetDiscount().GetAmount(), cart.GetTotal().GetAmount()})
+			require.Equal(t, "USD", cart.GetTotal().GetCurrency())
+		})
+	}
+}
+
+func Test_productService_resolveCart(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		cartToken string
+		create    bool
+		wantID    int64
+		wantNil   bool
+		wantErr   error
+		setup     func(cartRepo *mocks.CartRepository)
+	}{
+		{
+			name:      "anonymous cart of the token",
+			ctx:       context.Background(),
+			cartToken: "token",
+			wantID:    5,
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("RetrieveByToken", mock.Anything, mock.Anything, "token").Return(&entity.Cart{
+					ID:    pg_util.NullInt64(5),
+					Token: pg_util.NullString("token"),
+				}, nil)
+			},
+		},
+		{
+			name:      "err unknown anonymous token",
+			ctx:       context.Background(),
+			cartToken: "token",
+			wantErr:   status.Errorf(codes.NotFound, "cart not found"),
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("RetrieveByToken", mock.Anything, mock.Anything, "token").Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "no anonymous cart without create",
+			ctx:     context.Background(),
+			wantNil: true,
+			setup:   func(cartRepo *mocks.CartRepository) {},
+		},
+		{
+			name:   "create anonymous cart",
+			ctx:    context.Background(),
+			create: true,
+			wantID: 6,
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(cart *entity.Cart) bool {
+					return cart.Token.String != "" && !cart.UserID.Valid
+				})).Return(int64(6), nil)
+			},
+		},
+		{
+			name:      "merge anonymous cart on login",
+			ctx:       userCtx,
+			cartToken: "token",
+			wantID:    7,
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("RetrieveByToken", mock.Anything, mock.Anything, "token").Return(&entity.Cart{
+					ID:    pg_util.NullInt64(5),
+					Token: pg_util.NullString("token"),
+				}, nil)
+				cartRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Cart{
+					ID:     pg_util.NullInt64(7),
+					UserID: pg_util.NullInt64(1),
+				}, nil)
+				cartRepo.On("MergeInto", mock.Anything, mock.Anything, int64(5), int64(7)).Return(nil)
+			},
+		},
+		{
+			name:      "merged token is ignored",
+			ctx:       userCtx,
+			cartToken: "token",
+			wantID:    7,
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("RetrieveByToken", mock.Anything, mock.Anything, "token").Return(nil, sql.ErrNoRows)
+				cartRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Cart{
+					ID:     pg_util.NullInt64(7),
+					UserID: pg_util.NullInt64(1),
+				}, nil)
+			},
+		},
+		{
+			name:      "user cart created to merge anonymous cart",
+			ctx:       userCtx,
+			cartToken: "token",
+			wantID:    8,
+			setup: func(cartRepo *mocks.CartRepository) {
+				cartRepo.On("RetrieveByToken", mock.Anything, mock.Anything, "token").Return(&entity.Cart{
+					ID:    pg_util.NullInt64(5),
+					Token: pg_util.NullString("token"),
+				}, nil)
+				cartRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+				cartRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(int64(8), nil)
+				cartRepo.On("MergeInto", mock.Anything, mock.Anything, int64(5), int64(8)).Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			smock.ExpectBegin()
+			if tt.wantErr != nil {
+				smock.ExpectRollback()
+			} else {
+				smock.ExpectCommit()
+			}
+			cartRepo := &mocks.CartRepository{}
+			tt.setup(cartRepo)
+			s := &productService{
+				cartRepo: cartRepo,
+				db:       &postgres_client.PostgresClient{DB: db},
+			}
+			got, err := s.resolveCart(tt.ctx, tt.cartToken, tt.create)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				require.Nil(t, got)
+			} else {
+				require.Equal(t, tt.wantID, got.ID.Int64)
+			}
+			cartRepo.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	couponpb "trintech/review/dto/coupon-management/coupon"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/processor"
+)
+
+// The outbox topics of the coupon redemption saga steps run after the purchase, and of its compensation after a refund.
+const (
+	couponConfirmTopic = "COUPON_CONFIRM"
+	couponReleaseTopic = "COUPON_RELEASE"
+	couponRestoreTopic = "COUPON_RESTORE"
+)
+
+// couponRedemptionEvent is the outbox payload of a coupon redemption saga step.
+// The discount the coupon gave is in the payload of the confirmation.
+type couponRedemptionEvent struct {
+	RedemptionID     string `json:"redemption_id"`
+	Discount         int64  `json:"discount,omitempty"`
+	DiscountCurrency string `json:"discount_currency,omitempty"`
+}
+
+// reserveCoupon reserves a coupon for the purchase of a user, the first step of the coupon redemption saga.
+// It returns the redemption id, which is confirmed in the transaction of the purchase with [productService.confirmCoupon]
+// or released with [productService.releaseCoupon] when the purchase fails.
+func (s *productService) reserveCoupon(ctx context.Context, code string, userID int64) (string, error) {
+	redemptionID := uuid.NewString()
+
+	if _, err := s.couponServiceClient.ReserveCoupon(http_server.InjectIncomingCtxToOutgoingCtx(ctx), &couponpb.ReserveCouponRequest{
+		RedemptionId: redemptionID,
+		Code:         code,
+		UserId:       userID,
+	}); err != nil {
+		stt, _ := status.FromError(err)
+		switch stt.Code() {
+		case codes.NotFound:
+			// If the coupon is not found, return a not found error
+			return "", status.Errorf(codes.NotFound, "coupon not found")
+		case codes.FailedPrecondition:
+			// If there is a failed precondition, return a failed precondition error
+			return "", status.Errorf(codes.FailedPrecondition, "unable to apply this coupon")
+		default:
+			// The reservation may have been made, release it
+			s.releaseCoupon(ctx, redemptionID)
+
+			return "", status.Errorf(codes.Internal, "unable to apply this coupon: %v", err.Error())
+		}
+	}
+
+	return redemptionID, nil
+}
+
+// confirmCoupon enqueues the confirmation of a reserved coupon with the discount it gave in the transaction of its purchase.
+func (s *productService) confirmCoupon(ctx context.Context, tx database.Executor, redemptionID string, discount money.Money) error {
+	return database.EnqueueOutboxEvent(ctx, tx, couponConfirmTopic, &couponRedemptionEvent{
+		RedemptionID:     redemptionID,
+		Discount:         discount.Amount,
+		DiscountCurrency: discount.Currency,
+	})
+}
+
+// releaseCoupon enqueues the release of a reserved coupon whose purchase failed.
+// If the release cannot be enqueued, the reservation expires in the coupon service.
+func (s *productService) releaseCoupon(ctx context.Context, redemptionID string) {
+	if err := s.enqueueCouponRelease(context.WithoutCancel(ctx), s.db, redemptionID); err != nil {
+		slog.Error("unable to release coupon reservation", "redemption_id", redemptionID, "err", err)
+	}
+}
+
+// enqueueCouponRelease enqueues the release of a reserved coupon, in the transaction of the cancellation of its order.
+func (s *productService) enqueueCouponRelease(ctx context.Context, db database.Executor, redemptionID string) error {
+	return database.EnqueueOutboxEvent(ctx, db, couponReleaseTopic, &couponRedemptionEvent{RedemptionID: redemptionID})
+}
+
+// restoreCoupon enqueues the restoration of the confirmed coupon of a purchase in the transaction of its refund.
+func (s *productService) restoreCoupon(ctx context.Context, tx database.Executor, redemptionID string) error {
+	return database.EnqueueOutboxEvent(ctx, tx, couponRestoreTopic, &couponRedemptionEvent{RedemptionID: redemptionID})
+}
+
+// NewCouponSagaRelay returns a processor which relays the confirmations, releases and restorations of the coupon redemption saga to the coupon service.
+func NewCouponSagaRelay(db database.Database, couponServiceClient couponpb.CouponServiceClient) processor.Processor {
+	return database.NewOutboxRelay("coupon-saga-relay", db, time.Second, map[string]database.OutboxHandler{
+		couponConfirmTopic: func(ctx context.Context, event *database.OutboxEvent) error {
+			var data couponRedemptionEvent
+			if err := json.Unmarshal(event.Payload, &data); err != nil {
+				return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+			}
+
+			req := &couponpb.ConfirmCouponRequest{RedemptionId: data.RedemptionID}
+			if data.DiscountCurrency != "" {
+				req.Discount = &couponpb.Money{Amount: data.Discount, Currency: data.DiscountCurrency}
+			}
+			_, err := couponServiceClient.ConfirmCoupon(ctx, req)
+			return couponSagaError(err)
+		},
+		couponReleaseTopic: func(ctx context.Context, event *database.OutboxEvent) error {
+			var data couponRedemptionEvent
+			if err := json.Unmarshal(event.Payload, &data); err != nil {
+				return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+			}
+
+			_, err := couponServiceClient.ReleaseCoupon(ctx, &couponpb.ReleaseCouponRequest{RedemptionId: data.RedemptionID})
+			return couponSagaError(err)
+		},
+		couponRestoreTopic: func(ctx context.Context, event *database.OutboxEvent) error {
+			var data couponRedemptionEvent
+			if err := json.Unmarshal(event.Payload, &data); err != nil {
+				return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+			}
+
+			_, err := couponServiceClient.RestoreCoupon(ctx, &couponpb.RestoreCouponRequest{RedemptionId: data.RedemptionID})
+			return couponSagaError(err)
+		},
+	})
+}
+
+// couponSagaError returns the error of a saga step, the step is discarded when the coupon service rejects it and retried otherwise.
+func couponSagaError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	stt, _ := status.FromError(err)
+	switch stt.Code() {
+	case codes.NotFound, codes.FailedPrecondition, codes.InvalidArgument:
+		return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+	}
+
+	return err
+}
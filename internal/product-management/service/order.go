@@ -0,0 +1,821 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	couponpb "trintech/review/dto/coupon-management/coupon"
+	msgpb "trintech/review/dto/msg/common"
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/shipping"
+	"trintech/review/pkg/tax"
+)
+
+// orderStatusChangedTopic is the topic of the order status transitions.
+const orderStatusChangedTopic = "ORDER_STATUS_CHANGED"
+
+// orderTransitions maps an order status to the statuses it can move to.
+// Cancelled and refunded orders are final.
+var orderTransitions = map[string][]string{
+	entity.OrderStatus_Pending:   {entity.OrderStatus_Paid, entity.OrderStatus_Cancelled},
+	entity.OrderStatus_Paid:      {entity.OrderStatus_Fulfilled, entity.OrderStatus_Refunded},
+	entity.OrderStatus_Fulfilled: {entity.OrderStatus_Delivered, entity.OrderStatus_Refunded},
+	entity.OrderStatus_Delivered: {entity.OrderStatus_Refunded},
+}
+
+// orderStatuses maps the order statuses to the response format.
+var orderStatuses = map[string]pb.OrderStatus{
+	entity.OrderStatus_Pending:   pb.OrderStatus_OrderStatus_PENDING,
+	entity.OrderStatus_Paid:      pb.OrderStatus_OrderStatus_PAID,
+	entity.OrderStatus_Fulfilled: pb.OrderStatus_OrderStatus_FULFILLED,
+	entity.OrderStatus_Delivered: pb.OrderStatus_OrderStatus_DELIVERED,
+	entity.OrderStatus_Cancelled: pb.OrderStatus_OrderStatus_CANCELLED,
+	entity.OrderStatus_Refunded:  pb.OrderStatus_OrderStatus_REFUNDED,
+}
+
+// canTransitOrder reports whether an order can move from a status to another.
+func canTransitOrder(fromStatus, toStatus string) bool {
+	return slices.Contains(orderTransitions[fromStatus], toStatus)
+}
+
+// orderStatusFromPb returns the order status of the request format.
+func orderStatusFromPb(s pb.OrderStatus) (string, bool) {
+	for k, v := range orderStatuses {
+		if v == s {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// toPbOrder transforms an order entity with its rows to the response format.
+func toPbOrder(order *entity.Order, items []*entity.OrderItem, discounts []*entity.OrderDiscount, histories []*entity.OrderHistory) *pb.Order {
+	data := &pb.Order{
+		Id:       order.ID.Int64,
+		UserId:   order.UserID.Int64,
+		Status:   orderStatuses[order.Status.String],
+		Subtotal: toPbMoney(money.New(order.Subtotal.Int64, order.Currency.String)),
+		Discount: toPbMoney(money.New(order.Discount.Int64, order.Currency.String)),
+		Total:    toPbMoney(money.New(order.Total.Int64, order.Currency.String)),
+		Tax:      toPbMoney(money.New(order.Tax.Int64, order.Currency.String)),
+		Shipping: toPbMoney(money.New(order.Shipping.Int64, order.Currency.String)),
+
+		ShippingMethodId: order.ShippingMethodID.Int64,
+		ShippingAddress:  toPbShippingAddress(order.ShippingAddress),
+		PaymentId:        order.PaymentID.String,
+		PaymentStatus:    order.PaymentStatus.String,
+	}
+	if order.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(order.CreatedAt.Time)
+	}
+	for _, item := range items {
+		respItem := &pb.Order_Item{
+			ProductId: item.ProductID.Int64,
+			Name:      item.Name.String,
+			UnitPrice: toPbMoney(money.New(item.UnitPrice.Int64, order.Currency.String)),
+			Quantity:  item.Quantity.Int64,
+			Subtotal:  toPbMoney(money.New(item.Subtotal.Int64, order.Currency.String)),
+			Discount:  toPbMoney(money.New(item.Discount.Int64, order.Currency.String)),
+			Total:     toPbMoney(money.New(item.Total.Int64, order.Currency.String)),
+			Tax:       toPbMoney(money.New(item.Tax.Int64, order.Currency.String)),
+			Shipping:  toPbMoney(money.New(item.Shipping.Int64, order.Currency.String)),
+		}
+		if item.TaxRegion.Valid {
+			respItem.TaxRate = toPbTaxRate(tax.Rate{
+				Region:      item.TaxRegion.String,
+				Category:    item.TaxCategory.String,
+				BasisPoints: item.TaxBasisPoints.Int64,
+				Inclusive:   item.TaxInclusive.Bool,
+			})
+		}
+		data.Items = append(data.Items, respItem)
+	}
+	for _, discount := range discounts {
+		respDiscount := &pb.Order_Discount{
+			Coupon:       discount.Coupon.String,
+			DiscountType: discount.DiscountType.String,
+			Amount:       toPbMoney(money.New(discount.Amount.Int64, order.Currency.String)),
+		}
+		if discount.DiscountType.String == couponpb.DiscountType_DiscountType_PERCENT.String() {
+			respDiscount.PercentBasisPoints = discount.Value.Int64
+		} else {
+			respDiscount.Value = toPbMoney(money.New(discount.Value.Int64, order.Currency.String))
+		}
+		data.Discounts = append(data.Discounts, respDiscount)
+	}
+	for _, history := range histories {
+		respHistory := &pb.Order_History{
+			FromStatus: orderStatuses[history.FromStatus.String],
+			ToStatus:   orderStatuses[history.ToStatus.String],
+			ChangedBy:  history.ChangedBy.Int64,
+			Reason:     history.Reason.String,
+		}
+		if history.CreatedAt.Valid {
+			respHistory.CreatedAt = timestamppb.New(history.CreatedAt.Time)
+		}
+		data.Histories = append(data.Histories, respHistory)
+	}
+
+	return data
+}
+
+// PlaceOrder is a method of the productService that checks out the cart of the user into a pending order.
+// The order keeps the current prices of the cart, the shipping to the address is added, the coupon is applied,
+// the items are taxed in the region of the request and the cart is emptied.
+// The purchases of its items are recorded once the order is paid, its payment is authorized and its coupon is reserved
+// until then.
+func (s *productService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.PlaceOrderResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Validate the tax region, the orders without region are untaxed
+	var region string
+	if req.GetRegion() != "" {
+		var err error
+		if region, err = validRegion(req.GetRegion()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Retrieve the shipping address, the default address of the user without id, it is the tax region by default
+	var address *entity.Address
+	switch {
+	case req.GetShippingMethodId() != 0:
+		var err error
+		if address, err = s.shippingAddress(ctx, userCtx.UserID, req.GetAddressId()); err != nil {
+			return nil, err
+		}
+		if region == "" {
+			region = address.Region.String
+		}
+	case req.GetAddressId() != 0:
+		return nil, status.Errorf(codes.InvalidArgument, "shipping_method_id is required to ship to an address")
+	}
+
+	// Resolve the cart of the user, merging the anonymous cart of the token
+	cart, err := s.resolveCart(ctx, req.GetCartToken(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Price the cart with the current prices and coupon
+	view, coupon, err := s.priceCartView(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	if len(view.GetItems()) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "cart is empty")
+	}
+	for _, item := range view.GetItems() {
+		if !item.GetAvailable() {
+			return nil, status.Errorf(codes.FailedPrecondition, "product %d is no longer available", item.GetProduct().GetId())
+		}
+	}
+	if view.GetCouponError() != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "unable to apply this coupon")
+	}
+
+	// Quote the shipping of the items to the address, the orders without shipping method are not shipped
+	subtotal := money.New(view.GetSubtotal().GetAmount(), view.GetSubtotal().GetCurrency())
+	shippingCost := money.New(0, subtotal.Currency)
+	if address != nil {
+		var weightGrams int64
+		for _, item := range view.GetItems() {
+			weightGrams += item.GetProduct().GetWeightGrams() * item.GetQuantity()
+		}
+		if shippingCost, err = s.quoteShipping(ctx, req.GetShippingMethodId(), shipping.Parcel{
+			Region:      address.Region.String,
+			WeightGrams: weightGrams,
+			OrderValue:  subtotal,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Discount the shipping too for the coupons which apply to the shipping
+	discount := money.New(view.GetDiscount().GetAmount(), subtotal.Currency)
+	if coupon != nil && coupon.GetAppliesToShipping() && shippingCost.Amount > 0 {
+		base, _ := subtotal.Add(shippingCost)
+		if discount, ok = couponDiscount(base, coupon); !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "unable to apply this coupon")
+		}
+	}
+
+	// Build the order from the priced cart
+	order := &entity.Order{
+		UserID:   pg_util.NullInt64(userCtx.UserID),
+		Status:   pg_util.NullString(entity.OrderStatus_Pending),
+		Subtotal: pg_util.NullInt64(subtotal.Amount),
+		Discount: pg_util.NullInt64(discount.Amount),
+		Currency: pg_util.NullString(subtotal.Currency),
+		Tax:      pg_util.NullInt64(0),
+		Shipping: pg_util.NullInt64(shippingCost.Amount),
+	}
+	if address != nil {
+		order.ShippingMethodID = pg_util.NullInt64(req.GetShippingMethodId())
+		order.ShippingAddress = toShippingAddress(address)
+	}
+
+	// Split the shipping of the order between its items by their subtotal, and the discount by the amount it applies to
+	weights := make([]int64, 0, len(view.GetItems()))
+	for _, item := range view.GetItems() {
+		weights = append(weights, item.GetSubtotal().GetAmount())
+	}
+	itemShippings := shippingCost.Allocate(weights)
+	if coupon.GetAppliesToShipping() {
+		for i := range weights {
+			weights[i] += itemShippings[i].Amount
+		}
+	}
+	itemDiscounts := discount.Allocate(weights)
+	items := make([]*entity.OrderItem, 0, len(view.GetItems()))
+	for i, item := range view.GetItems() {
+		items = append(items, &entity.OrderItem{
+			ProductID: pg_util.NullInt64(item.GetProduct().GetId()),
+			Name:      pg_util.NullString(item.GetProduct().GetName()),
+			UnitPrice: pg_util.NullInt64(item.GetProduct().GetPrice().GetAmount()),
+			Quantity:  pg_util.NullInt64(item.GetQuantity()),
+			Subtotal:  pg_util.NullInt64(item.GetSubtotal().GetAmount()),
+			Shipping:  pg_util.NullInt64(itemShippings[i].Amount),
+			Discount:  pg_util.NullInt64(itemDiscounts[i].Amount),
+			Total:     pg_util.NullInt64(item.GetSubtotal().GetAmount() + itemShippings[i].Amount - itemDiscounts[i].Amount),
+			Tax:       pg_util.NullInt64(0),
+		})
+	}
+
+	// Tax each discounted item with its shipping at the rate of its product type in the region,
+	// an exclusive tax is added to its total
+	if region != "" {
+		for i, item := range view.GetItems() {
+			if err := s.taxOrderItem(ctx, items[i], region, item.GetProduct().GetType(), order.Currency.String); err != nil {
+				return nil, err
+			}
+			order.Tax.Int64 += items[i].Tax.Int64
+		}
+	}
+	order.Total = pg_util.NullInt64(0)
+	for _, item := range items {
+		order.Total.Int64 += item.Total.Int64
+	}
+
+	var discounts []*entity.OrderDiscount
+	if coupon != nil {
+		value := coupon.GetValue().GetAmount()
+		if coupon.GetDiscountType() == couponpb.DiscountType_DiscountType_PERCENT {
+			value = coupon.GetPercentBasisPoints()
+		}
+		discounts = append(discounts, &entity.OrderDiscount{
+			Coupon:       pg_util.NullString(view.GetCoupon()),
+			DiscountType: pg_util.NullString(coupon.GetDiscountType().String()),
+			Value:        pg_util.NullInt64(value),
+			Amount:       pg_util.NullInt64(discount.Amount),
+		})
+	}
+
+	// Reserve the coupon for the order, it is confirmed once the order is paid or released when it is cancelled
+	var redemptionID string
+	if coupon != nil {
+		if redemptionID, err = s.reserveCoupon(ctx, view.GetCoupon(), userCtx.UserID); err != nil {
+			return nil, err
+		}
+		order.CouponRedemptionID = pg_util.NullString(redemptionID)
+	}
+
+	// Authorize the payment of the total, it is captured once the order is paid or voided when it is cancelled
+	authorized, err := s.authorizePayment(ctx, money.New(order.Total.Int64, order.Currency.String), req.GetPaymentToken())
+	if err != nil {
+		// Release the reserved coupon of the unpaid order
+		if redemptionID != "" {
+			s.releaseCoupon(ctx, redemptionID)
+		}
+
+		return nil, err
+	}
+	if authorized != nil {
+		order.PaymentID = pg_util.NullString(authorized.ID)
+		order.PaymentStatus = pg_util.NullString(string(authorized.Status))
+	}
+
+	// Create the order and empty the cart in a database transaction
+	var history *entity.OrderHistory
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if history, err = s.createOrder(ctx, tx, order, items, discounts, userCtx.UserID, ""); err != nil {
+			return err
+		}
+
+		if err := s.cartRepo.ClearItems(ctx, tx, cart.ID.Int64); err != nil {
+			return fmt.Errorf("unable to clear cart: %v", err)
+		}
+		if err := s.cartRepo.UpdateCouponByID(ctx, tx, cart.ID.Int64, sql.NullString{}); err != nil {
+			return fmt.Errorf("unable to clear cart coupon: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		// Void the payment and release the reserved coupon of the failed order
+		s.voidPayment(ctx, authorized)
+		if redemptionID != "" {
+			s.releaseCoupon(ctx, redemptionID)
+		}
+
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to place order: %v", err.Error())
+	}
+
+	// Publish the creation of the order
+	s.publishOrderStatusChanged(ctx, order, history)
+
+	// Return the placed order
+	data, err := s.orderView(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PlaceOrderResponse{Data: data}, nil
+}
+
+// RetrieveOrder is a method of the productService that retrieves an order with its items, discounts and history.
+// Users can only retrieve their own orders, admins can retrieve every order.
+func (s *productService) RetrieveOrder(ctx context.Context, req *pb.RetrieveOrderRequest) (*pb.RetrieveOrderResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the order, the orders of other users are hidden to them
+	order, err := s.orderRepo.RetrieveByID(ctx, s.db, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "order not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve order: %v", err.Error())
+	}
+	isAdmin := slices.Contains([]string{userEntity.UserRole_Admin, userEntity.UserRole_SuperAdmin}, userCtx.Role)
+	if !isAdmin && order.UserID.Int64 != userCtx.UserID {
+		return nil, status.Errorf(codes.NotFound, "order not found")
+	}
+
+	// Return the order
+	data, err := s.orderView(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RetrieveOrderResponse{Data: data}, nil
+}
+
+// UpdateOrderStatus is a method of the productService that moves an order to another status.
+// It validates the admin user and the transition of the order state machine.
+func (s *productService) UpdateOrderStatus(ctx context.Context, req *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the requested status
+	toStatus, ok := orderStatusFromPb(req.GetStatus())
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid order status")
+	}
+
+	// Move the order to the status
+	data, err := s.transitOrder(ctx, req.GetId(), toStatus, userCtx.UserID, req.GetReason(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateOrderStatusResponse{Data: data}, nil
+}
+
+// CancelOrder is a method of the productService that cancels a pending order of the user.
+func (s *productService) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Cancel the order, users can only cancel their own orders
+	data, err := s.transitOrder(ctx, req.GetId(), entity.OrderStatus_Cancelled, userCtx.UserID, req.GetReason(), func(order *entity.Order) error {
+		if order.UserID.Int64 != userCtx.UserID {
+			return status.Errorf(codes.NotFound, "order not found")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CancelOrderResponse{Data: data}, nil
+}
+
+// createOrder creates an order with its items and discounts, and records its creation in the order history.
+// It returns the recorded history, the caller publishes it once the transaction is committed.
+func (s *productService) createOrder(
+	ctx context.Context,
+	db database.Executor,
+	order *entity.Order,
+	items []*entity.OrderItem,
+	discounts []*entity.OrderDiscount,
+	changedBy int64,
+	reason string,
+) (*entity.OrderHistory, error) {
+	id, err := s.orderRepo.Create(ctx, db, order)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create order: %v", err)
+	}
+	order.ID = pg_util.NullInt64(id)
+
+	for _, item := range items {
+		item.OrderID = order.ID
+		if err := s.orderRepo.CreateItem(ctx, db, item); err != nil {
+			return nil, fmt.Errorf("unable to create order item: %v", err)
+		}
+	}
+
+	for _, discount := range discounts {
+		discount.OrderID = order.ID
+		if err := s.orderRepo.CreateDiscount(ctx, db, discount); err != nil {
+			return nil, fmt.Errorf("unable to create order discount: %v", err)
+		}
+	}
+
+	history := &entity.OrderHistory{
+		OrderID:   order.ID,
+		ToStatus:  order.Status,
+		ChangedBy: pg_util.NullInt64(changedBy),
+	}
+	if reason != "" {
+		history.Reason = pg_util.NullString(reason)
+	}
+	if err := s.orderRepo.CreateHistory(ctx, db, history); err != nil {
+		return nil, fmt.Errorf("unable to create order history: %v", err)
+	}
+
+	return history, nil
+}
+
+// transitOrder moves an order to a status when the order state machine allows it,
+// records the transition in the order history and publishes it.
+// check runs on the current order before the transition, when not nil.
+func (s *productService) transitOrder(
+	ctx context.Context,
+	id int64,
+	toStatus string,
+	changedBy int64,
+	reason string,
+	check func(order *entity.Order) error,
+) (*pb.Order, error) {
+	var (
+		order    *entity.Order
+		history  *entity.OrderHistory
+		captured *payment.Payment
+	)
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Retrieve the order
+		var err error
+		order, err = s.orderRepo.RetrieveByID(ctx, tx, id)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "order not found")
+		case err != nil:
+			return fmt.Errorf("unable to retrieve order: %v", err)
+		}
+		if check != nil {
+			if err := check(order); err != nil {
+				return err
+			}
+		}
+
+		// Validate the transition
+		fromStatus := order.Status.String
+		if !canTransitOrder(fromStatus, toStatus) {
+			return status.Errorf(codes.FailedPrecondition, "order cannot move from %s to %s", fromStatus, toStatus)
+		}
+
+		// Update the status, unless the order has changed meanwhile
+		err = s.orderRepo.UpdateStatusByID(ctx, tx, id, fromStatus, toStatus)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.Aborted, "order has changed, please retry")
+		case err != nil:
+			return fmt.Errorf("unable to update order status: %v", err)
+		}
+		order.Status = pg_util.NullString(toStatus)
+
+		// Run the payment step of the transition with it, an order is only paid once its payment is captured
+		if captured, err = s.transitOrderPayment(ctx, tx, order, toStatus); err != nil {
+			return err
+		}
+
+		// Record the purchases of the items once the order is paid
+		if toStatus == entity.OrderStatus_Paid {
+			if err := s.recordOrderPurchases(ctx, tx, order); err != nil {
+				return err
+			}
+		}
+
+		// Run the coupon redemption saga step of the transition with it
+		if err := s.transitOrderCoupon(ctx, tx, order, toStatus); err != nil {
+			return err
+		}
+
+		// Record the transition
+		history = &entity.OrderHistory{
+			OrderID:    order.ID,
+			FromStatus: pg_util.NullString(fromStatus),
+			ToStatus:   order.Status,
+			ChangedBy:  pg_util.NullInt64(changedBy),
+		}
+		if reason != "" {
+			history.Reason = pg_util.NullString(reason)
+		}
+		if err := s.orderRepo.CreateHistory(ctx, tx, history); err != nil {
+			return fmt.Errorf("unable to create order history: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		// Refund the payment captured for the failed transition
+		s.refundPayment(ctx, captured)
+
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to update order status: %v", err.Error())
+	}
+
+	// Publish the transition
+	s.publishOrderStatusChanged(ctx, order, history)
+
+	return s.orderView(ctx, order)
+}
+
+// transitOrderPayment runs the payment step of an order transition in its transaction.
+// The payment authorized when the order is placed is captured once the order is paid, voided when it is cancelled
+// and refunded when it is refunded, an order with a total can only be paid with a payment. It returns the captured
+// payment, which the caller refunds when the transaction fails.
+func (s *productService) transitOrderPayment(ctx context.Context, db database.Executor, order *entity.Order, toStatus string) (*payment.Payment, error) {
+	if toStatus == entity.OrderStatus_Refunded {
+		return nil, s.refundOrderPayment(ctx, db, order)
+	}
+
+	if !order.PaymentID.Valid {
+		if toStatus == entity.OrderStatus_Paid && order.Total.Int64 > 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "order has no authorized payment")
+		}
+
+		return nil, nil
+	}
+
+	var (
+		updated  *payment.Payment
+		captured *payment.Payment
+		err      error
+	)
+	switch toStatus {
+	case entity.OrderStatus_Paid:
+		if updated, err = s.paymentProvider.Capture(ctx, order.PaymentID.String); err != nil {
+			return nil, paymentError(err)
+		}
+		captured = updated
+	case entity.OrderStatus_Cancelled:
+		if updated, err = s.paymentProvider.Void(ctx, order.PaymentID.String); err != nil {
+			return nil, paymentError(err)
+		}
+	default:
+		return nil, nil
+	}
+
+	order.PaymentStatus = pg_util.NullString(string(updated.Status))
+	if err := s.orderRepo.UpdatePaymentStatusByID(ctx, db, order.ID.Int64, order.PaymentStatus.String); err != nil {
+		return captured, fmt.Errorf("unable to update order payment status: %v", err)
+	}
+
+	return captured, nil
+}
+
+// refundOrderPayment records the refund of the amounts of the purchases of a refunded order which are not refunded yet,
+// and enqueues the refund of their total on the payment of the order, which is sent to the payment provider once committed.
+func (s *productService) refundOrderPayment(ctx context.Context, db database.Executor, order *entity.Order) error {
+	purchases, err := s.purchasedProductRepo.ListByOrderID(ctx, db, order.ID.Int64)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve order purchases: %v", err)
+	}
+
+	// Record the refund of the remaining amount of each purchase, the returns already refunded a part of them
+	var remaining int64
+	for _, purchase := range purchases {
+		amount := purchase.Total.Int64 - purchase.RefundedAmount.Int64
+		if amount <= 0 {
+			continue
+		}
+
+		_, err := s.purchasedProductRepo.AddRefundByID(ctx, db, purchase.ID.Int64, amount)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.Aborted, "order has changed, please retry")
+		case err != nil:
+			return fmt.Errorf("unable to record refund: %v", err)
+		}
+		remaining += amount
+	}
+
+	// The orders paid without a payment are refunded outside of the payment provider
+	if !order.PaymentID.Valid {
+		return nil
+	}
+
+	// Record the refunded payment on the order and its purchases
+	order.PaymentStatus = pg_util.NullString(string(payment.StatusRefunded))
+	if err := s.orderRepo.UpdatePaymentStatusByID(ctx, db, order.ID.Int64, order.PaymentStatus.String); err != nil {
+		return fmt.Errorf("unable to update order payment status: %v", err)
+	}
+	if err := s.purchasedProductRepo.UpdatePaymentStatusByPaymentID(ctx, db, order.PaymentID.String, order.PaymentStatus.String); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("unable to update payment status: %v", err)
+	}
+
+	// Refund the remaining amount once the refund is committed, the provider refunds the order once for its idempotency key
+	if remaining > 0 {
+		if err := s.enqueuePaymentRefund(ctx, db, order.PaymentID.String, money.New(remaining, order.Currency.String), orderRefundKey(order.ID.Int64)); err != nil {
+			return fmt.Errorf("unable to refund payment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// orderRefundKey returns the idempotency key of the payment refund of a refunded order.
+func orderRefundKey(orderID int64) string {
+	return fmt.Sprintf("order-%d", orderID)
+}
+
+// transitOrderCoupon enqueues the coupon redemption step of an order transition in its transaction.
+// The coupon reserved when the order is placed is confirmed once the order is paid, released when it is cancelled
+// and restored when it is refunded.
+func (s *productService) transitOrderCoupon(ctx context.Context, db database.Executor, order *entity.Order, toStatus string) error {
+	if !order.CouponRedemptionID.Valid {
+		return nil
+	}
+
+	redemptionID := order.CouponRedemptionID.String
+	switch toStatus {
+	case entity.OrderStatus_Paid:
+		if err := s.confirmCoupon(ctx, db, redemptionID, money.New(order.Discount.Int64, order.Currency.String)); err != nil {
+			return fmt.Errorf("unable to confirm coupon: %v", err)
+		}
+	case entity.OrderStatus_Cancelled:
+		if err := s.enqueueCouponRelease(ctx, db, redemptionID); err != nil {
+			return fmt.Errorf("unable to release coupon: %v", err)
+		}
+	case entity.OrderStatus_Refunded:
+		if err := s.restoreCoupon(ctx, db, redemptionID); err != nil {
+			return fmt.Errorf("unable to restore coupon: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// taxOrderItem taxes the total of an order item at the rate of a product category in a region.
+// The item is untaxed when no rate applies.
+func (s *productService) taxOrderItem(ctx context.Context, item *entity.OrderItem, region, category, currency string) error {
+	rate, ok, err := s.taxRate(ctx, region, category)
+	if err != nil || !ok {
+		return err
+	}
+
+	breakdown := tax.Calculate(money.New(item.Total.Int64, currency), rate)
+	item.Total = pg_util.NullInt64(breakdown.Gross.Amount)
+	item.Tax = pg_util.NullInt64(breakdown.Tax.Amount)
+	item.TaxRegion = pg_util.NullString(rate.Region)
+	item.TaxCategory = pg_util.NullString(rate.Category)
+	item.TaxBasisPoints = pg_util.NullInt64(rate.BasisPoints)
+	item.TaxInclusive = sql.NullBool{Bool: rate.Inclusive, Valid: true}
+
+	return nil
+}
+
+// recordOrderPurchases records a purchase of each item of a paid order, with the shares of the order shipping and discount and the tax of the item.
+// The coupon of the order and its redemption are on the purchases, the redemption is restored once all of them are refunded.
+func (s *productService) recordOrderPurchases(ctx context.Context, db database.Executor, order *entity.Order) error {
+	items, err := s.orderRepo.ListItems(ctx, db, order.ID.Int64)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve order items: %v", err)
+	}
+	discounts, err := s.orderRepo.ListDiscounts(ctx, db, order.ID.Int64)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve order discounts: %v", err)
+	}
+
+	for _, item := range items {
+		purchase := &entity.PurchasedProduct{
+			ProductID: item.ProductID,
+			UserID:    order.UserID,
+			Price:     item.UnitPrice,
+			Quantity:  item.Quantity,
+			Discount:  item.Discount,
+			Total:     item.Total,
+			Currency:  order.Currency,
+			OrderID:   order.ID,
+
+			CouponRedemptionID: order.CouponRedemptionID,
+			PaymentID:          order.PaymentID,
+			PaymentStatus:      order.PaymentStatus,
+			ShippingMethodID:   order.ShippingMethodID,
+			Shipping:           item.Shipping,
+			ShippingAddress:    order.ShippingAddress,
+
+			Tax:            item.Tax,
+			TaxRegion:      item.TaxRegion,
+			TaxCategory:    item.TaxCategory,
+			TaxBasisPoints: item.TaxBasisPoints,
+			TaxInclusive:   item.TaxInclusive,
+		}
+		if len(discounts) > 0 {
+			purchase.Coupon = discounts[0].Coupon
+		}
+		if _, err := s.purchasedProductRepo.Create(ctx, db, purchase); err != nil {
+			return fmt.Errorf("unable to create purchase product: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// orderView retrieves the items, discounts and history of an order and transforms them to the response format.
+func (s *productService) orderView(ctx context.Context, order *entity.Order) (*pb.Order, error) {
+	items, err := s.orderRepo.ListItems(ctx, s.db, order.ID.Int64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve order items: %v", err.Error())
+	}
+
+	discounts, err := s.orderRepo.ListDiscounts(ctx, s.db, order.ID.Int64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve order discounts: %v", err.Error())
+	}
+
+	histories, err := s.orderRepo.ListHistories(ctx, s.db, order.ID.Int64)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve order histories: %v", err.Error())
+	}
+
+	return toPbOrder(order, items, discounts, histories), nil
+}
+
+// publishOrderStatusChanged publishes a status transition of an order.
+// The transition is already committed, so a failure is only logged.
+func (s *productService) publishOrderStatusChanged(ctx context.Context, order *entity.Order, history *entity.OrderHistory) {
+	data, err := proto.Marshal(&msgpb.OrderStatusChanged{
+		OrderId:    order.ID.Int64,
+		UserId:     order.UserID.Int64,
+		FromStatus: history.FromStatus.String,
+		ToStatus:   history.ToStatus.String,
+		ChangedBy:  history.ChangedBy.Int64,
+		Reason:     history.Reason.String,
+		ChangedAt:  timestamppb.Now(),
+	})
+	if err != nil {
+		slog.Error("unable to marshal data", "err", err.Error())
+		return
+	}
+
+	key := []byte(strconv.FormatInt(order.ID.Int64, 10))
+	if err := s.publisher.Publish(ctx, orderStatusChangedTopic, key, data); err != nil {
+		slog.Error("unable to publish order status changed message", "order_id", order.ID.Int64, "err", err.Error())
+	}
+}
@@ -0,0 +1,667 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_canTransitOrder(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: entity.OrderStatus_Pending, to: entity.OrderStatus_Paid, want: true},
+		{from: entity.OrderStatus_Pending, to: entity.OrderStatus_Cancelled, want: true},
+		{from: entity.OrderStatus_Pending, to: entity.OrderStatus_Fulfilled, want: false},
+		{from: entity.OrderStatus_Paid, to: entity.OrderStatus_Fulfilled, want: true},
+		{from: entity.OrderStatus_Paid, to: entity.OrderStatus_Refunded, want: true},
+		{from: entity.OrderStatus_Paid, to: entity.OrderStatus_Cancelled, want: false},
+		{from: entity.OrderStatus_Fulfilled, to: entity.OrderStatus_Delivered, want: true},
+		{from: entity.OrderStatus_Fulfilled, to: entity.OrderStatus_Refunded, want: true},
+		{from: entity.OrderStatus_Delivered, to: entity.OrderStatus_Refunded, want: true},
+		{from: entity.OrderStatus_Delivered, to: entity.OrderStatus_Paid, want: false},
+		{from: entity.OrderStatus_Cancelled, to: entity.OrderStatus_Paid, want: false},
+		{from: entity.OrderStatus_Refunded, to: entity.OrderStatus_Paid, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			require.Equal(t, tt.want, canTransitOrder(tt.from, tt.to))
+		})
+	}
+}
+
+func Test_productService_PlaceOrder(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		req          *pb.PlaceOrderRequest
+		wantTotal    int64
+		wantTax      int64
+		wantShipping int64
+		priced       bool
+		wantErr      error
+		setup        func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository)
+	}{
+		{
+			name:      "untaxed without region",
+			ctx:       userCtx,
+			req:       &pb.PlaceOrderRequest{},
+			wantTotal: 2500,
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+				orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.MatchedBy(func(item *entity.OrderItem) bool {
+					return item.Tax.Int64 == 0 && !item.TaxRegion.Valid && item.Total.Int64 == item.Subtotal.Int64
+				})).Return(nil).Twice()
+			},
+		},
+		{
+			name:      "items taxed at the rate of their product type",
+			ctx:       userCtx,
+			req:       &pb.PlaceOrderRequest{Region: "us-ca"},
+			wantTotal: 2725,
+			wantTax:   225,
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+				taxRateRepo.On("ListByRegions", mock.Anything, mock.Anything, []string{"US-CA", "US"}).Return([]*entity.TaxRate{
+					{Region: pg_util.NullString("US-CA"), Category: pg_util.NullString(""), BasisPoints: pg_util.NullInt64(1000)},
+					{Region: pg_util.NullString("US-CA"), Category: pg_util.NullString("BOOK"), BasisPoints: pg_util.NullInt64(500)},
+				}, nil)
+				orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.MatchedBy(func(item *entity.OrderItem) bool {
+					return item.ProductID.Int64 == 1 && item.Subtotal.Int64 == 2000 && item.Tax.Int64 == 200 && item.Total.Int64 == 2200 &&
+						item.TaxCategory.String == "" && item.TaxBasisPoints.Int64 == 1000
+				})).Return(nil).Once()
+				orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.MatchedBy(func(item *entity.OrderItem) bool {
+					return item.ProductID.Int64 == 2 && item.Subtotal.Int64 == 500 && item.Tax.Int64 == 25 && item.Total.Int64 == 525 &&
+						item.TaxCategory.String == "BOOK" && item.TaxBasisPoints.Int64 == 500
+				})).Return(nil).Once()
+			},
+		},
+		{
+			name:         "items shipped and taxed in the region of the address",
+			ctx:          userCtx,
+			req:          &pb.PlaceOrderRequest{ShippingMethodId: 7},
+			wantTotal:    4200,
+			wantTax:      700,
+			wantShipping: 1000,
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+				addressRepo.On("RetrieveDefault", mock.Anything, mock.Anything, int64(1)).Return(&entity.Address{
+					ID:     pg_util.NullInt64(2),
+					City:   pg_util.NullString("Berlin"),
+					Region: pg_util.NullString("DE"),
+				}, nil)
+				shippingMethodRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(7)).Return(&entity.ShippingMethod{
+					ID:       pg_util.NullInt64(7),
+					Currency: pg_util.NullString("USD"),
+				}, nil)
+				shippingMethodRepo.On("ListRates", mock.Anything, mock.Anything, []int64{7}).Return([]*entity.ShippingRate{
+					{Regions: pg_util.StringArray([]string{"DE"}), MaxWeightGrams: pg_util.NullInt64(1000), Amount: pg_util.NullInt64(1000)},
+					{Regions: pg_util.StringArray([]string{"DE"}), Amount: pg_util.NullInt64(2000)},
+				}, nil)
+				taxRateRepo.On("ListByRegions", mock.Anything, mock.Anything, []string{"DE"}).Return([]*entity.TaxRate{
+					{Region: pg_util.NullString("DE"), Category: pg_util.NullString(""), BasisPoints: pg_util.NullInt64(2000)},
+				}, nil)
+				orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.MatchedBy(func(item *entity.OrderItem) bool {
+					return item.ProductID.Int64 == 1 && item.Shipping.Int64 == 800 && item.Tax.Int64 == 560 && item.Total.Int64 == 3360
+				})).Return(nil).Once()
+				orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.MatchedBy(func(item *entity.OrderItem) bool {
+					return item.ProductID.Int64 == 2 && item.Shipping.Int64 == 200 && item.Tax.Int64 == 140 && item.Total.Int64 == 840
+				})).Return(nil).Once()
+			},
+		},
+		{
+			name:    "err payment declined",
+			ctx:     userCtx,
+			req:     &pb.PlaceOrderRequest{PaymentToken: payment.FakeTokenDeclined},
+			priced:  true,
+			wantErr: status.Errorf(codes.FailedPrecondition, "payment declined"),
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+			},
+		},
+		{
+			name:    "err address without shipping method",
+			ctx:     userCtx,
+			req:     &pb.PlaceOrderRequest{AddressId: 2},
+			wantErr: status.Errorf(codes.InvalidArgument, "shipping_method_id is required to ship to an address"),
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+			},
+		},
+		{
+			name:    "err invalid region",
+			ctx:     userCtx,
+			req:     &pb.PlaceOrderRequest{Region: "California"},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid region"),
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+			},
+		},
+		{
+			name:    "err not logged in",
+			ctx:     context.Background(),
+			req:     &pb.PlaceOrderRequest{},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup: func(taxRateRepo *mocks.TaxRateRepository, orderRepo *mocks.OrderRepository, addressRepo *mocks.AddressRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			cartRepo := &mocks.CartRepository{}
+			productRepo := &mocks.ProductRepository{}
+			scheduledPriceRepo := &mocks.ScheduledPriceRepository{}
+			taxRateRepo := &mocks.TaxRateRepository{}
+			orderRepo := &mocks.OrderRepository{}
+			addressRepo := &mocks.AddressRepository{}
+			shippingMethodRepo := &mocks.ShippingMethodRepository{}
+			publisher := &mocks.Publisher{}
+			if tt.priced || tt.wantErr == nil {
+				// The cart of the user is resolved in its own transaction and priced
+				smock.ExpectBegin()
+				smock.ExpectCommit()
+				cartRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Cart{
+					ID:     pg_util.NullInt64(3),
+					UserID: pg_util.NullInt64(1),
+				}, nil)
+				cartRepo.On("ListItems", mock.Anything, mock.Anything, int64(3)).Return([]*entity.CartItem{
+					{ProductID: pg_util.NullInt64(1), Quantity: pg_util.NullInt64(2)},
+					{ProductID: pg_util.NullInt64(2), Quantity: pg_util.NullInt64(1)},
+				}, nil)
+				productRepo.On("ListByIDs", mock.Anything, mock.Anything, []int64{1, 2}).Return([]*entity.Product{
+					{ID: pg_util.NullInt64(1), Type: pg_util.NullString("SHIRT"), Price: pg_util.NullInt64(1000), Currency: pg_util.NullString("USD"), WeightGrams: pg_util.NullInt64(300)},
+					{ID: pg_util.NullInt64(2), Type: pg_util.NullString("BOOK"), Price: pg_util.NullInt64(500), Currency: pg_util.NullString("USD"), WeightGrams: pg_util.NullInt64(200)},
+				}, nil)
+				scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1, 2}, mock.Anything).Return(nil, nil)
+			}
+			if tt.wantErr == nil {
+				// The order is created with its authorized payment and the cart emptied in another transaction
+				smock.ExpectBegin()
+				smock.ExpectCommit()
+				cartRepo.On("ClearItems", mock.Anything, mock.Anything, int64(3)).Return(nil)
+				cartRepo.On("UpdateCouponByID", mock.Anything, mock.Anything, int64(3), sql.NullString{}).Return(nil)
+				orderRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(order *entity.Order) bool {
+					return order.Subtotal.Int64 == 2500 && order.Total.Int64 == tt.wantTotal && order.Tax.Int64 == tt.wantTax &&
+						order.Shipping.Int64 == tt.wantShipping && order.ShippingMethodID.Valid == (tt.wantShipping > 0) &&
+						order.PaymentID.Valid && order.PaymentStatus.String == string(payment.StatusAuthorized)
+				})).Return(int64(4), nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(4)).Return(nil, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(4)).Return(nil, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(4)).Return(nil, nil)
+				publisher.On("Publish", mock.Anything, orderStatusChangedTopic, []byte("4"), mock.Anything).Return(nil)
+			}
+			tt.setup(taxRateRepo, orderRepo, addressRepo, shippingMethodRepo)
+			s := &productService{
+				cartRepo:           cartRepo,
+				productRepo:        productRepo,
+				scheduledPriceRepo: scheduledPriceRepo,
+				taxRateRepo:        taxRateRepo,
+				orderRepo:          orderRepo,
+				addressRepo:        addressRepo,
+				shippingMethodRepo: shippingMethodRepo,
+				publisher:          publisher,
+				paymentProvider:    payment.NewFakeProvider("secret", nil),
+				baseCurrency:       "USD",
+				db:                 &postgres_client.PostgresClient{DB: db},
+			}
+			got, err := s.PlaceOrder(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				orderRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				require.NoError(t, smock.ExpectationsWereMet())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantTotal, got.GetData().GetTotal().GetAmount())
+			require.Equal(t, tt.wantTax, got.GetData().GetTax().GetAmount())
+			require.Equal(t, tt.wantShipping, got.GetData().GetShipping().GetAmount())
+			cartRepo.AssertExpectations(t)
+			taxRateRepo.AssertExpectations(t)
+			orderRepo.AssertExpectations(t)
+			addressRepo.AssertExpectations(t)
+			shippingMethodRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_productService_UpdateOrderStatus(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        *pb.UpdateOrderStatusRequest
+		wantStatus pb.OrderStatus
+		wantOutbox []string
+		// paymentToken authorizes the payment pay_fake_1 of the order when it is set.
+		paymentToken string
+		wantErr      error
+		setup        func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher)
+	}{
+		{
+			name:       "happy case",
+			ctx:        adminCtx,
+			req:        &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_FULFILLED, Reason: "shipped"},
+			wantStatus: pb.OrderStatus_OrderStatus_FULFILLED,
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:     pg_util.NullInt64(1),
+					UserID: pg_util.NullInt64(2),
+					Status: pg_util.NullString(entity.OrderStatus_Paid),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Paid, entity.OrderStatus_Fulfilled).Return(nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.MatchedBy(func(history *entity.OrderHistory) bool {
+					return history.FromStatus.String == entity.OrderStatus_Paid &&
+						history.ToStatus.String == entity.OrderStatus_Fulfilled &&
+						history.ChangedBy.Int64 == 9 &&
+						history.Reason.String == "shipped"
+				})).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:       "happy case paid order records the purchases of its items and confirms its coupon",
+			ctx:        adminCtx,
+			req:        &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantStatus: pb.OrderStatus_OrderStatus_PAID,
+			wantOutbox: []string{"COUPON_CONFIRM"},
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:                 pg_util.NullInt64(1),
+					UserID:             pg_util.NullInt64(2),
+					Status:             pg_util.NullString(entity.OrderStatus_Pending),
+					Discount:           pg_util.NullInt64(300),
+					Currency:           pg_util.NullString("USD"),
+					CouponRedemptionID: pg_util.NullString("redemption-1"),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Pending, entity.OrderStatus_Paid).Return(nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return([]*entity.OrderItem{
+					{ProductID: pg_util.NullInt64(3), UnitPrice: pg_util.NullInt64(1000), Quantity: pg_util.NullInt64(2), Subtotal: pg_util.NullInt64(2000), Discount: pg_util.NullInt64(200), Total: pg_util.NullInt64(1800)},
+					{
+						ProductID: pg_util.NullInt64(4), UnitPrice: pg_util.NullInt64(1000), Quantity: pg_util.NullInt64(1), Subtotal: pg_util.NullInt64(1000), Discount: pg_util.NullInt64(100), Total: pg_util.NullInt64(990),
+						Tax: pg_util.NullInt64(90), TaxRegion: pg_util.NullString("US-CA"), TaxBasisPoints: pg_util.NullInt64(1000), TaxInclusive: sql.NullBool{Valid: true},
+					},
+				}, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return([]*entity.OrderDiscount{
+					{Coupon: pg_util.NullString("SAVE10"), Amount: pg_util.NullInt64(300)},
+				}, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.ProductID.Int64 == 3 && purchase.UserID.Int64 == 2 && purchase.OrderID.Int64 == 1 &&
+						purchase.Price.Int64 == 1000 && purchase.Quantity.Int64 == 2 && purchase.Discount.Int64 == 200 &&
+						purchase.Total.Int64 == 1800 && purchase.Coupon.String == "SAVE10" && purchase.CouponRedemptionID.String == "redemption-1" &&
+						purchase.Tax.Int64 == 0 && !purchase.TaxRegion.Valid
+				})).Return(int64(10), nil).Once()
+				purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.ProductID.Int64 == 4 && purchase.Quantity.Int64 == 1 && purchase.Total.Int64 == 990 &&
+						purchase.Tax.Int64 == 90 && purchase.TaxRegion.String == "US-CA" && purchase.TaxBasisPoints.Int64 == 1000
+				})).Return(int64(11), nil).Once()
+				publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:       "happy case refunded order restores its coupon",
+			ctx:        adminCtx,
+			req:        &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_REFUNDED},
+			wantStatus: pb.OrderStatus_OrderStatus_REFUNDED,
+			wantOutbox: []string{"COUPON_RESTORE"},
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:                 pg_util.NullInt64(1),
+					UserID:             pg_util.NullInt64(2),
+					Status:             pg_util.NullString(entity.OrderStatus_Delivered),
+					CouponRedemptionID: pg_util.NullString("redemption-1"),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Delivered, entity.OrderStatus_Refunded).Return(nil)
+				purchasedProductRepo.On("ListByOrderID", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:       "happy case refunded order refunds the remaining amount of its payment",
+			ctx:        adminCtx,
+			req:        &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_REFUNDED},
+			wantStatus: pb.OrderStatus_OrderStatus_REFUNDED,
+			wantOutbox: []string{"PAYMENT_REFUND", "COUPON_RESTORE"},
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:                 pg_util.NullInt64(1),
+					UserID:             pg_util.NullInt64(2),
+					Status:             pg_util.NullString(entity.OrderStatus_Delivered),
+					Total:              pg_util.NullInt64(1000),
+					Currency:           pg_util.NullString("USD"),
+					PaymentID:          pg_util.NullString("pay_fake_1"),
+					PaymentStatus:      pg_util.NullString(string(payment.StatusPartiallyRefunded)),
+					CouponRedemptionID: pg_util.NullString("redemption-1"),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Delivered, entity.OrderStatus_Refunded).Return(nil)
+				purchasedProductRepo.On("ListByOrderID", mock.Anything, mock.Anything, int64(1)).Return([]*entity.PurchasedProduct{
+					{ID: pg_util.NullInt64(10), Total: pg_util.NullInt64(600), RefundedAmount: pg_util.NullInt64(200)},
+					{ID: pg_util.NullInt64(11), Total: pg_util.NullInt64(400), RefundedAmount: pg_util.NullInt64(0)},
+					{ID: pg_util.NullInt64(12), Total: pg_util.NullInt64(0), RefundedAmount: pg_util.NullInt64(0)},
+				}, nil)
+				purchasedProductRepo.On("AddRefundByID", mock.Anything, mock.Anything, int64(10), int64(400)).Return(int64(600), nil).Once()
+				purchasedProductRepo.On("AddRefundByID", mock.Anything, mock.Anything, int64(11), int64(400)).Return(int64(400), nil).Once()
+				orderRepo.On("UpdatePaymentStatusByID", mock.Anything, mock.Anything, int64(1), string(payment.StatusRefunded)).Return(nil)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", string(payment.StatusRefunded)).Return(nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:    "err refunded order changed",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_REFUNDED},
+			wantErr: status.Errorf(codes.Aborted, "order has changed, please retry"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:        pg_util.NullInt64(1),
+					Status:    pg_util.NullString(entity.OrderStatus_Delivered),
+					PaymentID: pg_util.NullString("pay_fake_1"),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Delivered, entity.OrderStatus_Refunded).Return(nil)
+				purchasedProductRepo.On("ListByOrderID", mock.Anything, mock.Anything, int64(1)).Return([]*entity.PurchasedProduct{
+					{ID: pg_util.NullInt64(10), Total: pg_util.NullInt64(600)},
+				}, nil)
+				purchasedProductRepo.On("AddRefundByID", mock.Anything, mock.Anything, int64(10), int64(600)).Return(int64(0), sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err invalid transition",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantErr: status.Errorf(codes.FailedPrecondition, "order cannot move from DELIVERED to PAID"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:     pg_util.NullInt64(1),
+					Status: pg_util.NullString(entity.OrderStatus_Delivered),
+				}, nil)
+			},
+		},
+		{
+			name:    "err concurrent transition",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantErr: status.Errorf(codes.Aborted, "order has changed, please retry"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:     pg_util.NullInt64(1),
+					Status: pg_util.NullString(entity.OrderStatus_Pending),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Pending, entity.OrderStatus_Paid).Return(sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err order not found",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantErr: status.Errorf(codes.NotFound, "order not found"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:         "paid captures the authorized payment",
+			ctx:          adminCtx,
+			req:          &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantStatus:   pb.OrderStatus_OrderStatus_PAID,
+			paymentToken: payment.FakeTokenSuccess,
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:            pg_util.NullInt64(1),
+					UserID:        pg_util.NullInt64(2),
+					Status:        pg_util.NullString(entity.OrderStatus_Pending),
+					Total:         pg_util.NullInt64(1000),
+					Currency:      pg_util.NullString("USD"),
+					PaymentID:     pg_util.NullString("pay_fake_1"),
+					PaymentStatus: pg_util.NullString(string(payment.StatusAuthorized)),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Pending, entity.OrderStatus_Paid).Return(nil)
+				orderRepo.On("UpdatePaymentStatusByID", mock.Anything, mock.Anything, int64(1), string(payment.StatusCaptured)).Return(nil)
+				orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return([]*entity.OrderItem{
+					{ProductID: pg_util.NullInt64(3), UnitPrice: pg_util.NullInt64(1000), Quantity: pg_util.NullInt64(1), Subtotal: pg_util.NullInt64(1000), Total: pg_util.NullInt64(1000)},
+				}, nil)
+				orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+				purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.ProductID.Int64 == 3 && purchase.PaymentID.String == "pay_fake_1" &&
+						purchase.PaymentStatus.String == string(payment.StatusCaptured)
+				})).Return(int64(10), nil).Once()
+				publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:         "err payment capture declined",
+			ctx:          adminCtx,
+			req:          &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			paymentToken: payment.FakeTokenCaptureDeclined,
+			wantErr:      status.Errorf(codes.FailedPrecondition, "payment declined"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:        pg_util.NullInt64(1),
+					Status:    pg_util.NullString(entity.OrderStatus_Pending),
+					Total:     pg_util.NullInt64(1000),
+					PaymentID: pg_util.NullString("pay_fake_1"),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Pending, entity.OrderStatus_Paid).Return(nil)
+			},
+		},
+		{
+			name:    "err paid without payment",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantErr: status.Errorf(codes.FailedPrecondition, "order has no authorized payment"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+				orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Order{
+					ID:     pg_util.NullInt64(1),
+					Status: pg_util.NullString(entity.OrderStatus_Pending),
+					Total:  pg_util.NullInt64(1000),
+				}, nil)
+				orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), entity.OrderStatus_Pending, entity.OrderStatus_Paid).Return(nil)
+			},
+		},
+		{
+			name:    "err invalid status",
+			ctx:     adminCtx,
+			req:     &pb.UpdateOrderStatusRequest{Id: 1},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid order status"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+			},
+		},
+		{
+			name: "err not admin",
+			ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+				UserID: 2,
+				Role:   userEntity.UserRole_User,
+			})),
+			req:     &pb.UpdateOrderStatusRequest{Id: 1, Status: pb.OrderStatus_OrderStatus_PAID},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository, publisher *mocks.Publisher) {
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			smock.ExpectBegin()
+			for _, topic := range tt.wantOutbox {
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs(topic, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			if tt.wantErr != nil {
+				smock.ExpectRollback()
+			} else {
+				smock.ExpectCommit()
+			}
+			provider := payment.NewFakeProvider("secret", nil)
+			if tt.paymentToken != "" {
+				_, err := provider.Authorize(context.Background(), &payment.AuthorizeRequest{Amount: money.New(1000, "USD"), Token: tt.paymentToken})
+				require.NoError(t, err)
+			}
+			orderRepo := &mocks.OrderRepository{}
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			publisher := &mocks.Publisher{}
+			tt.setup(orderRepo, purchasedProductRepo, publisher)
+			s := &productService{
+				orderRepo:            orderRepo,
+				purchasedProductRepo: purchasedProductRepo,
+				publisher:            publisher,
+				paymentProvider:      provider,
+				db:                   &postgres_client.PostgresClient{DB: db},
+			}
+			got, err := s.UpdateOrderStatus(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStatus, got.GetData().GetStatus())
+			orderRepo.AssertExpectations(t)
+			purchasedProductRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_productService_CancelOrder(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 2,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name         string
+		status       string
+		userID       int64
+		redemptionID string
+		authorized   bool
+		wantErr      error
+	}{
+		{
+			name:   "happy case",
+			status: entity.OrderStatus_Pending,
+			userID: 2,
+		},
+		{
+			name:       "happy case voids the authorized payment",
+			status:     entity.OrderStatus_Pending,
+			userID:     2,
+			authorized: true,
+		},
+		{
+			name:         "happy case releases the reserved coupon",
+			status:       entity.OrderStatus_Pending,
+			userID:       2,
+			redemptionID: "redemption-1",
+		},
+		{
+			name:    "err paid order",
+			status:  entity.OrderStatus_Paid,
+			userID:  2,
+			wantErr: status.Errorf(codes.FailedPrecondition, "order cannot move from PAID to CANCELLED"),
+		},
+		{
+			name:    "err order of another user",
+			status:  entity.OrderStatus_Pending,
+			userID:  3,
+			wantErr: status.Errorf(codes.NotFound, "order not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			smock.ExpectBegin()
+			if tt.redemptionID != "" && tt.wantErr == nil {
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_RELEASE", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+			if tt.wantErr != nil {
+				smock.ExpectRollback()
+			} else {
+				smock.ExpectCommit()
+			}
+			orderRepo := &mocks.OrderRepository{}
+			publisher := &mocks.Publisher{}
+			provider := payment.NewFakeProvider("secret", nil)
+			order := &entity.Order{
+				ID:     pg_util.NullInt64(1),
+				UserID: pg_util.NullInt64(tt.userID),
+				Status: pg_util.NullString(tt.status),
+			}
+			if tt.redemptionID != "" {
+				order.CouponRedemptionID = pg_util.NullString(tt.redemptionID)
+			}
+			if tt.authorized {
+				authorized, err := provider.Authorize(context.Background(), &payment.AuthorizeRequest{Amount: money.New(1000, "USD")})
+				require.NoError(t, err)
+				order.PaymentID = pg_util.NullString(authorized.ID)
+				orderRepo.On("UpdatePaymentStatusByID", mock.Anything, mock.Anything, int64(1), string(payment.StatusVoided)).Return(nil)
+			}
+			orderRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(order, nil)
+			orderRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(1), tt.status, entity.OrderStatus_Cancelled).Return(nil)
+			orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("ListItems", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+			orderRepo.On("ListDiscounts", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+			orderRepo.On("ListHistories", mock.Anything, mock.Anything, int64(1)).Return(nil, nil)
+			publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", mock.Anything, mock.Anything).Return(nil)
+			s := &productService{
+				orderRepo:       orderRepo,
+				publisher:       publisher,
+				paymentProvider: provider,
+				db:              &postgres_client.PostgresClient{DB: db},
+			}
+			got, err := s.CancelOrder(userCtx, &pb.CancelOrderRequest{Id: 1})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, pb.OrderStatus_OrderStatus_CANCELLED, got.GetData().GetStatus())
+			if tt.authorized {
+				voided, err := provider.Retrieve(context.Background(), order.PaymentID.String)
+				require.NoError(t, err)
+				require.Equal(t, payment.StatusVoided, voided.Status)
+			}
+			orderRepo.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
+	"trintech/review/pkg/processor"
+)
+
+// paymentRefundTopic is the outbox topic of the refunds of the captured payments, sent to the payment provider after their commit.
+const paymentRefundTopic = "PAYMENT_REFUND"
+
+// paymentRefundEvent is the outbox payload of a payment refund.
+// The idempotency key identifies the refund at the payment provider, so that a relayed refund is refunded once.
+type paymentRefundEvent struct {
+	PaymentID      string `json:"payment_id"`
+	Amount         int64  `json:"amount"`
+	Currency       string `json:"currency"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// authorizePayment authorizes the payment of an amount with the payment token of the user, the amount is held
+// until the payment is captured or voided. No payment is made for a zero amount, it returns nil.
+func (s *productService) authorizePayment(ctx context.Context, amount money.Money, token string) (*payment.Payment, error) {
+	if amount.Amount == 0 {
+		return nil, nil
+	}
+
+	authorized, err := s.paymentProvider.Authorize(ctx, &payment.AuthorizeRequest{
+		Amount: amount,
+		Token:  token,
+	})
+	if err != nil {
+		return nil, paymentError(err)
+	}
+
+	return authorized, nil
+}
+
+// voidPayment voids an authorized payment whose order failed.
+func (s *productService) voidPayment(ctx context.Context, authorized *payment.Payment) {
+	if authorized == nil {
+		return
+	}
+
+	if _, err := s.paymentProvider.Void(context.WithoutCancel(ctx), authorized.ID); err != nil {
+		slog.Error("unable to void payment", "payment_id", authorized.ID, "err", err)
+	}
+}
+
+// capturePayment authorizes and captures the payment of an amount with the payment token of the user.
+// A payment which cannot be captured is voided. No payment is made for a zero amount, it returns nil.
+func (s *productService) capturePayment(ctx context.Context, amount money.Money, token string) (*payment.Payment, error) {
+	// Authorize the amount on the payment method of the user
+	authorized, err := s.authorizePayment(ctx, amount, token)
+	if err != nil || authorized == nil {
+		return nil, err
+	}
+
+	// Capture the authorized amount, voiding the authorization when it fails
+	captured, err := s.paymentProvider.Capture(ctx, authorized.ID)
+	if err != nil {
+		s.voidPayment(ctx, authorized)
+
+		return nil, paymentError(err)
+	}
+
+	return captured, nil
+}
+
+// refundPayment refunds a captured payment whose purchase failed.
+func (s *productService) refundPayment(ctx context.Context, captured *payment.Payment) {
+	if captured == nil {
+		return
+	}
+
+	if _, err := s.paymentProvider.Refund(context.WithoutCancel(ctx), captured.ID, &payment.RefundRequest{
+		Amount:         captured.Amount,
+		IdempotencyKey: captured.ID,
+	}); err != nil {
+		slog.Error("unable to refund payment", "payment_id", captured.ID, "err", err)
+	}
+}
+
+// enqueuePaymentRefund enqueues the refund of an amount of a captured payment in the transaction which records it.
+// The refund is sent to the payment provider once committed, with the idempotency key.
+func (s *productService) enqueuePaymentRefund(ctx context.Context, tx database.Executor, paymentID string, amount money.Money, idempotencyKey string) error {
+	return database.EnqueueOutboxEvent(ctx, tx, paymentRefundTopic, &paymentRefundEvent{
+		PaymentID:      paymentID,
+		Amount:         amount.Amount,
+		Currency:       amount.Currency,
+		IdempotencyKey: idempotencyKey,
+	})
+}
+
+// NewPaymentRefundRelay returns a processor which relays the committed refunds to the payment provider.
+func NewPaymentRefundRelay(db database.Database, paymentProvider payment.Provider) processor.Processor {
+	return database.NewOutboxRelay("payment-refund-relay", db, time.Second, map[string]database.OutboxHandler{
+		paymentRefundTopic: paymentRefundHandler(paymentProvider),
+	})
+}
+
+// paymentRefundHandler returns the outbox handler sending the refunds to the payment provider.
+func paymentRefundHandler(paymentProvider payment.Provider) database.OutboxHandler {
+	return func(ctx context.Context, event *database.OutboxEvent) error {
+		var data paymentRefundEvent
+		if err := json.Unmarshal(event.Payload, &data); err != nil {
+			return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+		}
+
+		_, err := paymentProvider.Refund(ctx, data.PaymentID, &payment.RefundRequest{
+			Amount:         money.New(data.Amount, data.Currency),
+			IdempotencyKey: data.IdempotencyKey,
+		})
+		return paymentRefundError(err)
+	}
+}
+
+// paymentRefundError returns the error of a relayed refund, the refund is discarded when the payment provider rejects it and retried otherwise.
+func paymentRefundError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, payment.ErrDeclined) || errors.Is(err, payment.ErrNotFound) ||
+		errors.Is(err, payment.ErrInvalidState) || errors.Is(err, payment.ErrInvalidAmount) {
+		return fmt.Errorf("%w: %v", database.ErrOutboxDiscard, err)
+	}
+
+	return err
+}
+
+// paymentError returns the error of a payment which failed at the payment provider.
+func paymentError(err error) error {
+	if errors.Is(err, payment.ErrDeclined) {
+		return status.Errorf(codes.FailedPrecondition, "payment declined")
+	}
+
+	return status.Errorf(codes.Unavailable, "unable to process payment: %v", err.Error())
+}
+
+// HandlePaymentWebhook is a method of the productService that applies a payment status change notified by the payment provider
+// to the order and the purchases of the payment. The webhooks are authenticated by their signature.
+func (s *productService) HandlePaymentWebhook(ctx context.Context, req *pb.HandlePaymentWebhookRequest) (*pb.HandlePaymentWebhookResponse, error) {
+	// Verify the webhook was sent by the payment provider
+	event, err := s.paymentProvider.VerifyWebhook([]byte(req.GetPayload()), req.GetSignature())
+	switch {
+	case errors.Is(err, payment.ErrInvalidSignature):
+		return nil, status.Errorf(codes.Unauthenticated, "invalid webhook signature")
+	case err != nil:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid webhook: %v", err.Error())
+	}
+
+	// Update the payment status of the order of the payment
+	orderErr := s.orderRepo.UpdatePaymentStatusByPaymentID(ctx, s.db, event.PaymentID, string(event.Status))
+	if orderErr != nil && !errors.Is(orderErr, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.Internal, "unable to update payment status: %v", orderErr.Error())
+	}
+
+	// Update the payment status of the purchases of the payment, an order which is not paid has none yet
+	err = s.purchasedProductRepo.UpdatePaymentStatusByPaymentID(ctx, s.db, event.PaymentID, string(event.Status))
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && errors.Is(orderErr, sql.ErrNoRows):
+		// If neither an order nor a purchase has the payment, return a not found error
+		return nil, status.Errorf(codes.NotFound, "payment not found")
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		// If there is an error during the update, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to update payment status: %v", err.Error())
+	}
+
+	// Return an empty response
+	return &pb.HandlePaymentWebhookResponse{}, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/mocks"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
+)
+
+func Test_productService_HandlePaymentWebhook(t *testing.T) {
+	provider := payment.NewFakeProvider("secret", nil)
+	payload, signature, err := provider.SignWebhook(&payment.WebhookEvent{
+		ID:        "evt_1",
+		PaymentID: "pay_fake_1",
+		Status:    payment.StatusRefunded,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		req     *pb.HandlePaymentWebhookRequest
+		wantErr error
+		setup   func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository)
+	}{
+		{
+			name: "happy case",
+			req:  &pb.HandlePaymentWebhookRequest{Payload: string(payload), Signature: signature},
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository) {
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+			},
+		},
+		{
+			name: "happy case order without purchases",
+			req:  &pb.HandlePaymentWebhookRequest{Payload: string(payload), Signature: signature},
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository) {
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err invalid signature",
+			req:     &pb.HandlePaymentWebhookRequest{Payload: string(payload), Signature: "00"},
+			wantErr: status.Errorf(codes.Unauthenticated, "invalid webhook signature"),
+			setup:   func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository) {},
+		},
+		{
+			name:    "err payment not found",
+			req:     &pb.HandlePaymentWebhookRequest{Payload: string(payload), Signature: signature},
+			wantErr: status.Errorf(codes.NotFound, "payment not found"),
+			setup: func(orderRepo *mocks.OrderRepository, purchasedProductRepo *mocks.PurchasedProductRepository) {
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(sql.ErrNoRows)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(sql.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := &mocks.OrderRepository{}
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			tt.setup(orderRepo, purchasedProductRepo)
+			s := &productService{
+				orderRepo:            orderRepo,
+				purchasedProductRepo: purchasedProductRepo,
+				paymentProvider:      provider,
+			}
+			_, err := s.HandlePaymentWebhook(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			orderRepo.AssertExpectations(t)
+			purchasedProductRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_paymentRefundHandler(t *testing.T) {
+	ctx := context.Background()
+	refundEvent := func(paymentID string, amount int64, key string) *database.OutboxEvent {
+		payload, err := json.Marshal(&paymentRefundEvent{PaymentID: paymentID, Amount: amount, Currency: "USD", IdempotencyKey: key})
+		require.NoError(t, err)
+
+		return &database.OutboxEvent{Topic: paymentRefundTopic, Payload: payload}
+	}
+
+	provider := payment.NewFakeProvider("secret", nil)
+	authorized, err := provider.Authorize(ctx, &payment.AuthorizeRequest{Amount: money.New(5000, "USD")})
+	require.NoError(t, err)
+	_, err = provider.Capture(ctx, authorized.ID)
+	require.NoError(t, err)
+	handler := paymentRefundHandler(provider)
+
+	// A relayed refund is refunded once
+	require.NoError(t, handler(ctx, refundEvent(authorized.ID, 2000, "return-7")))
+	require.NoError(t, handler(ctx, refundEvent(authorized.ID, 2000, "return-7")))
+	got, err := provider.Retrieve(ctx, authorized.ID)
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusPartiallyRefunded, got.Status)
+	require.Equal(t, money.New(2000, "USD"), got.Refunded)
+
+	// A refund the provider rejects is discarded
+	require.ErrorIs(t, handler(ctx, refundEvent(authorized.ID, 4000, "return-8")), database.ErrOutboxDiscard)
+	require.ErrorIs(t, handler(ctx, refundEvent("pay_fake_404", 2000, "return-9")), database.ErrOutboxDiscard)
+	require.ErrorIs(t, handler(ctx, &database.OutboxEvent{Topic: paymentRefundTopic, Payload: []byte("{")}), database.ErrOutboxDiscard)
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	couponpb "trintech/review/dto/coupon-management/coupon"
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+)
+
+// toPbMoney transforms an amount to the response format.
+func toPbMoney(m money.Money) *pb.Money {
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// productPrice returns the price of a product in the currency it is stored in.
+func productPrice(product *entity.Product) money.Money {
+	return money.New(product.Price.Int64, product.Currency.String)
+}
+
+// couponDiscount returns the discount of a usable coupon on an amount, it never exceeds the amount nor the maximum
+// discount of the coupon rules. It returns false when the coupon cannot discount the amount, for a value or a
+// maximum discount in another currency.
+func couponDiscount(amount money.Money, coupon *couponpb.RetrieveCouponByCodeResponse) (money.Money, bool) {
+	zero := money.New(0, amount.Currency)
+
+	var discount money.Money
+	switch coupon.GetDiscountType() {
+	case couponpb.DiscountType_DiscountType_PERCENT:
+		discount = amount.Percent(coupon.GetPercentBasisPoints())
+	case couponpb.DiscountType_DiscountType_VALUE:
+		if coupon.GetValue().GetCurrency() != amount.Currency {
+			return zero, false
+		}
+		discount = money.New(coupon.GetValue().GetAmount(), amount.Currency)
+	default:
+		return zero, true
+	}
+
+	// Cap the discount at the maximum discount of the rules, a cap in another currency cannot be applied
+	if maxDiscount := coupon.GetRules().GetMaxDiscount(); maxDiscount > 0 {
+		if coupon.GetRules().GetCurrency() != amount.Currency {
+			return zero, false
+		}
+		discount.Amount = min(discount.Amount, maxDiscount)
+	}
+
+	discount, err := discount.Clamp(zero, amount)
+	if err != nil {
+		return zero, false
+	}
+
+	return discount, true
+}
+
+// validCurrency returns the currency of a request, the base currency when it is empty.
+func (s *productService) validCurrency(currency string) (string, error) {
+	if currency == "" {
+		return s.baseCurrency, nil
+	}
+	if !money.IsCurrency(currency) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid currency")
+	}
+
+	return currency, nil
+}
+
+// validBasePrice returns the price of a product request, which must be in the base currency.
+func (s *productService) validBasePrice(price *pb.Money) (money.Money, error) {
+	currency := price.GetCurrency()
+	if currency == "" {
+		currency = s.baseCurrency
+	}
+	if currency != s.baseCurrency {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "price must be in %s", s.baseCurrency)
+	}
+	if price.GetAmount() < 0 {
+		return money.Money{}, status.Errorf(codes.InvalidArgument, "price must not be negative")
+	}
+
+	return money.New(price.GetAmount(), currency), nil
+}
+
+// assembleProductViews transforms products to the response format, priced in a currency.
+// The scheduled price in effect replaces the regular price, with the "was" price as compare-at price.
+// The price set for the currency wins over the converted base price, unless the product is marked down.
+func (s *productService) assembleProductViews(ctx context.Context, products []*entity.Product, currency string) ([]*pb.Product, error) {
+	ids := make([]int64, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID.Int64)
+	}
+	now := time.Now()
+
+	// Retrieve the scheduled prices in effect
+	scheduledByID, err := s.activeScheduledPrices(ctx, ids, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the highest prices of the compare-at window
+	highest, err := s.priceHistoryRepo.ListHighestSince(ctx, s.db, ids, now.Add(-compareAtWindow))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve price history: %v", err.Error())
+	}
+	highestByID := make(map[int64]int64, len(highest))
+	for _, history := range highest {
+		highestByID[history.ProductID.Int64] = history.Price.Int64
+	}
+
+	// Retrieve the prices set in the other currencies
+	prices, err := s.productPriceRepo.ListByProductIDs(ctx, s.db, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product prices: %v", err.Error())
+	}
+	pricesByID := make(map[int64][]money.Money, len(products))
+	for _, price := range prices {
+		pricesByID[price.ProductID.Int64] = append(pricesByID[price.ProductID.Int64], money.New(price.Amount.Int64, price.Currency.String))
+	}
+
+	// Retrieve the exchange rate when a price has to be converted
+	var rate *entity.ExchangeRate
+	retrieveRate := func() error {
+		if rate != nil {
+			return nil
+		}
+		r, err := s.exchangeRateRepo.RetrieveByCurrency(ctx, s.db, currency)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.InvalidArgument, "unsupported currency %s", currency)
+		case err != nil:
+			return status.Errorf(codes.Internal, "unable to retrieve exchange rate: %v", err.Error())
+		}
+		rate = r
+
+		return nil
+	}
+
+	respData := make([]*pb.Product, 0, len(products))
+	for _, product := range products {
+		data := toPbProduct(product)
+
+		// Resolve the price in effect and the price it is compared to
+		regular := productPrice(product)
+		price, scheduled := scheduledByID[product.ID.Int64]
+		if !scheduled {
+			price = regular
+		}
+		was := highestByID[product.ID.Int64]
+		if scheduled {
+			was = max(was, regular.Amount)
+		}
+		var compareAt *money.Money
+		if was > price.Amount {
+			compareAt = &money.Money{Amount: was, Currency: price.Currency}
+		}
+
+		// List the regular prices of every currency
+		data.Prices = []*pb.Money{toPbMoney(regular)}
+		var explicit *money.Money
+		for _, p := range pricesByID[product.ID.Int64] {
+			data.Prices = append(data.Prices, toPbMoney(p))
+			if p.Currency == currency {
+				explicit = &p
+			}
+		}
+
+		// Price the product in the currency
+		switch {
+		case currency == price.Currency:
+		case explicit != nil && compareAt == nil:
+			price = *explicit
+		default:
+			if err := retrieveRate(); err != nil {
+				return nil, err
+			}
+			price = price.Convert(currency, rate.Rate.Float64)
+			if compareAt != nil {
+				converted := compareAt.Convert(currency, rate.Rate.Float64)
+				compareAt = &converted
+			}
+		}
+		data.Price = toPbMoney(price)
+		if compareAt != nil {
+			data.CompareAtPrice = toPbMoney(*compareAt)
+		}
+
+		respData = append(respData, data)
+	}
+
+	// Attach the images of the products
+	images, err := s.productImages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range respData {
+		data.Images = images[data.GetId()]
+		data.ImageUrls = imageURLs(data.Images)
+	}
+
+	// Translate the products in the preferred locale of the request
+	if err := s.localiseProducts(ctx, respData); err != nil {
+		return nil, err
+	}
+
+	return respData, nil
+}
+
+// SetProductPrice is a method of the productService that sets the price of a product in another currency.
+func (s *productService) SetProductPrice(ctx context.Context, req *pb.SetProductPriceRequest) (*pb.SetProductPriceResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the price, the base price is the price of the product
+	currency, err := s.validCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	if currency == s.baseCurrency {
+		return nil, status.Errorf(codes.InvalidArgument, "the price in %s is the product price", s.baseCurrency)
+	}
+	if req.GetAmount() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "price must not be negative")
+	}
+
+	// Check the product exists
+	_, err = s.productRepo.RetrieveByID(ctx, s.db, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "product not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+
+	// Set the price
+	if err := s.productPriceRepo.Upsert(ctx, s.db, &entity.ProductPrice{
+		ProductID: pg_util.NullInt64(req.GetId()),
+		Currency:  pg_util.NullString(currency),
+		Amount:    pg_util.NullInt64(req.GetAmount()),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to set product price: %v", err.Error())
+	}
+
+	// Publish the change of the price of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetId())
+
+	return &pb.SetProductPriceResponse{}, nil
+}
+
+// RemoveProductPrice is a method of the productService that removes the price of a product in a currency,
+// its price in the currency is then converted from the base price.
+func (s *productService) RemoveProductPrice(ctx context.Context, req *pb.RemoveProductPriceRequest) (*pb.RemoveProductPriceResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Remove the price
+	err := s.productPriceRepo.Delete(ctx, s.db, req.GetId(), req.GetCurrency())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "product price not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to remove product price: %v", err.Error())
+	}
+
+	// Publish the change of the price of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetId())
+
+	return &pb.RemoveProductPriceResponse{}, nil
+}
+
+// SetExchangeRate is a method of the productService that sets the rate of a currency to the base currency.
+func (s *productService) SetExchangeRate(ctx context.Context, req *pb.SetExchangeRateRequest) (*pb.SetExchangeRateResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the rate
+	currency, err := s.validCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+	if currency == s.baseCurrency {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is the base currency", s.baseCurrency)
+	}
+	if req.GetRate() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "rate must be positive")
+	}
+
+	// Set the rate
+	if err := s.exchangeRateRepo.Upsert(ctx, s.db, &entity.ExchangeRate{
+		Currency:  pg_util.NullString(currency),
+		Rate:      pg_util.NullFloat64(req.GetRate()),
+		UpdatedBy: pg_util.NullInt64(userCtx.UserID),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to set exchange rate: %v", err.Error())
+	}
+
+	// Publish the change of the prices of every product in the currency
+	s.publishProductChanged(ctx, productChangedAction_Updated)
+
+	return &pb.SetExchangeRateResponse{}, nil
+}
+
+// ListExchangeRate is a method of the productService that lists the exchange rates of the base currency.
+func (s *productService) ListExchangeRate(ctx context.Context, _ *pb.ListExchangeRateRequest) (*pb.ListExchangeRateResponse, error) {
+	// Retrieve the rates
+	rates, err := s.exchangeRateRepo.List(ctx, s.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve exchange rates: %v", err.Error())
+	}
+
+	// Transform the rates to the response format
+	respData := make([]*pb.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		respRate := &pb.ExchangeRate{
+			Currency: rate.Currency.String,
+			Rate:     rate.Rate.Float64,
+		}
+		if rate.UpdatedAt.Valid {
+			respRate.UpdatedAt = timestamppb.New(rate.UpdatedAt.Time)
+		}
+		respData = append(respData, respRate)
+	}
+
+	return &pb.ListExchangeRateResponse{
+		BaseCurrency: s.baseCurrency,
+		Data:         respData,
+	}, nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+)
+
+// compareAtWindow is how far back the price history is searched for the "was" price of a product.
+const compareAtWindow = 30 * 24 * time.Hour
+
+// activeScheduledPrices returns the scheduled prices in effect at a time by product id.
+func (s *productService) activeScheduledPrices(ctx context.Context, ids []int64, at time.Time) (map[int64]money.Money, error) {
+	scheduled, err := s.scheduledPriceRepo.ListActive(ctx, s.db, ids, at)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve scheduled prices: %v", err.Error())
+	}
+
+	pricesByID := make(map[int64]money.Money, len(scheduled))
+	for _, price := range scheduled {
+		pricesByID[price.ProductID.Int64] = money.New(price.Price.Int64, price.Currency.String)
+	}
+
+	return pricesByID, nil
+}
+
+// applyScheduledPrices replaces the price of the products by their scheduled price in effect, to sell them at this price.
+func (s *productService) applyScheduledPrices(ctx context.Context, products ...*entity.Product) error {
+	ids := make([]int64, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID.Int64)
+	}
+
+	pricesByID, err := s.activeScheduledPrices(ctx, ids, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if price, ok := pricesByID[product.ID.Int64]; ok {
+			product.Price = pg_util.NullInt64(price.Amount)
+			product.Currency = pg_util.NullString(price.Currency)
+		}
+	}
+
+	return nil
+}
+
+// recordPriceChange records the regular price of a product in its price history.
+func (s *productService) recordPriceChange(ctx context.Context, db database.Executor, productID int64, price money.Money, changedBy int64, reason string) error {
+	if err := s.priceHistoryRepo.Create(ctx, db, &entity.PriceHistory{
+		ProductID: pg_util.NullInt64(productID),
+		Price:     pg_util.NullInt64(price.Amount),
+		Currency:  pg_util.NullString(price.Currency),
+		ChangedBy: pg_util.NullInt64(changedBy),
+		Reason:    pg_util.NullString(reason),
+	}); err != nil {
+		return fmt.Errorf("unable to record price change: %w", err)
+	}
+
+	return nil
+}
+
+// toPbScheduledPrice transforms a scheduled price entity to the response format.
+func toPbScheduledPrice(price *entity.ScheduledPrice) *pb.ScheduledPrice {
+	data := &pb.ScheduledPrice{
+		Id:        price.ID.Int64,
+		ProductId: price.ProductID.Int64,
+		Price:     toPbMoney(money.New(price.Price.Int64, price.Currency.String)),
+		CreatedBy: price.CreatedBy.Int64,
+	}
+	if price.StartsAt.Valid {
+		data.StartsAt = timestamppb.New(price.StartsAt.Time)
+	}
+	if price.EndsAt.Valid {
+		data.EndsAt = timestamppb.New(price.EndsAt.Time)
+	}
+	if price.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(price.CreatedAt.Time)
+	}
+
+	return data
+}
+
+// SchedulePrice is a method of the productService that schedules a price of a product between two times.
+// The scheduled price replaces the regular price while it is in effect, the regular price is then shown as compare-at price.
+func (s *productService) SchedulePrice(ctx context.Context, req *pb.SchedulePriceRequest) (*pb.SchedulePriceResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the price, which is in the base currency, and its period
+	price, err := s.validBasePrice(req.GetPrice())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetStartsAt() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "starts_at is required")
+	}
+	startsAt := req.GetStartsAt().AsTime()
+	var endsAt sql.NullTime
+	if req.GetEndsAt() != nil {
+		endsAt = pg_util.NullTime(req.GetEndsAt().AsTime())
+		if !endsAt.Time.After(startsAt) {
+			return nil, status.Errorf(codes.InvalidArgument, "ends_at must be after starts_at")
+		}
+		if !endsAt.Time.After(time.Now()) {
+			return nil, status.Errorf(codes.InvalidArgument, "ends_at must be in the future")
+		}
+	}
+
+	// Check the product exists
+	_, err = s.productRepo.RetrieveByID(ctx, s.db, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "product not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+
+	// Schedule the price
+	id, err := s.scheduledPriceRepo.Create(ctx, s.db, &entity.ScheduledPrice{
+		ProductID: pg_util.NullInt64(req.GetId()),
+		Price:     pg_util.NullInt64(price.Amount),
+		Currency:  pg_util.NullString(price.Currency),
+		StartsAt:  pg_util.NullTime(startsAt),
+		EndsAt:    endsAt,
+		CreatedBy: pg_util.NullInt64(userCtx.UserID),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to schedule price: %v", err.Error())
+	}
+
+	// Publish the change of the prices of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetId())
+
+	return &pb.SchedulePriceResponse{Id: id}, nil
+}
+
+// ListScheduledPrice is a method of the productService that lists the scheduled prices of a product which have not ended.
+func (s *productService) ListScheduledPrice(ctx context.Context, req *pb.ListScheduledPriceRequest) (*pb.ListScheduledPriceResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the scheduled prices
+	list, err := s.scheduledPriceRepo.ListByProductID(ctx, s.db, req.GetId(), time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve scheduled prices: %v", err.Error())
+	}
+
+	// Transform the scheduled prices to the response format
+	respData := make([]*pb.ScheduledPrice, 0, len(list))
+	for _, price := range list {
+		respData = append(respData, toPbScheduledPrice(price))
+	}
+
+	return &pb.ListScheduledPriceResponse{Data: respData}, nil
+}
+
+// CancelScheduledPrice is a method of the productService that removes a scheduled price of a product.
+func (s *productService) CancelScheduledPrice(ctx context.Context, req *pb.CancelScheduledPriceRequest) (*pb.CancelScheduledPriceResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Remove the scheduled price
+	err := s.scheduledPriceRepo.DeleteByID(ctx, s.db, req.GetId(), req.GetScheduledPriceId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "scheduled price not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to cancel scheduled price: %v", err.Error())
+	}
+
+	// Publish the change of the prices of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetId())
+
+	return &pb.CancelScheduledPriceResponse{}, nil
+}
+
+// ListPriceHistory is a method of the productService that lists the changes of the regular price of a product, the latest first.
+func (s *productService) ListPriceHistory(ctx context.Context, req *pb.ListPriceHistoryRequest) (*pb.ListPriceHistoryResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the price changes
+	list, err := s.priceHistoryRepo.ListByProductID(ctx, s.db, req.GetId(), req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve price history: %v", err.Error())
+	}
+
+	// Transform the price changes to the response format
+	respData := make([]*pb.PriceChange, 0, len(list))
+	for _, history := range list {
+		respHistory := &pb.PriceChange{
+			Price:     toPbMoney(money.New(history.Price.Int64, history.Currency.String)),
+			ChangedBy: history.ChangedBy.Int64,
+			Reason:    history.Reason.String,
+		}
+		if history.CreatedAt.Valid {
+			respHistory.CreatedAt = timestamppb.New(history.CreatedAt.Time)
+		}
+		respData = append(respData, respHistory)
+	}
+
+	// Get the total count of price changes
+	total, err := s.priceHistoryRepo.CountByProductID(ctx, s.db, req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count price history: %v", err.Error())
+	}
+
+	return &pb.ListPriceHistoryResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
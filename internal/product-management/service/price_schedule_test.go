@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_SchedulePrice(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	startsAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	endsAt := startsAt.Add(24 * time.Hour)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.SchedulePriceRequest
+		want    int64
+		wantErr error
+		setup   func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req: &pb.SchedulePriceRequest{
+				Id:       5,
+				Price:    &pb.Money{Amount: 800},
+				StartsAt: timestamppb.New(startsAt),
+				EndsAt:   timestamppb.New(endsAt),
+			},
+			want: 7,
+			setup: func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(5)).Return(&entity.Product{ID: pg_util.NullInt64(5)}, nil)
+				scheduledPriceRepo.On("Create", mock.Anything, mock.Anything, &entity.ScheduledPrice{
+					ProductID: pg_util.NullInt64(5),
+					Price:     pg_util.NullInt64(800),
+					Currency:  pg_util.NullString("USD"),
+					StartsAt:  pg_util.NullTime(startsAt.UTC()),
+					EndsAt:    pg_util.NullTime(endsAt.UTC()),
+					CreatedBy: pg_util.NullInt64(9),
+				}).Return(int64(7), nil)
+			},
+		},
+		{
+			name:    "err missing start",
+			ctx:     adminCtx,
+			req:     &pb.SchedulePriceRequest{Id: 5, Price: &pb.Money{Amount: 800}},
+			wantErr: status.Errorf(codes.InvalidArgument, "starts_at is required"),
+			setup:   func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {},
+		},
+		{
+			name: "err end before start",
+			ctx:  adminCtx,
+			req: &pb.SchedulePriceRequest{
+				Id:       5,
+				Price:    &pb.Money{Amount: 800},
+				StartsAt: timestamppb.New(endsAt),
+				EndsAt:   timestamppb.New(startsAt),
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "ends_at must be after starts_at"),
+			setup:   func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {},
+		},
+		{
+			name: "err price in another currency",
+			ctx:  adminCtx,
+			req: &pb.SchedulePriceRequest{
+				Id:       5,
+				Price:    &pb.Money{Amount: 800, Currency: "EUR"},
+				StartsAt: timestamppb.New(startsAt),
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "price must be in USD"),
+			setup:   func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {},
+		},
+		{
+			name: "err product not found",
+			ctx:  adminCtx,
+			req: &pb.SchedulePriceRequest{
+				Id:       5,
+				Price:    &pb.Money{Amount: 800},
+				StartsAt: timestamppb.New(startsAt),
+			},
+			wantErr: status.Errorf(codes.NotFound, "product not found"),
+			setup: func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(5)).Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.SchedulePriceRequest{Id: 5},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(productRepo *mocks.ProductRepository, scheduledPriceRepo *mocks.ScheduledPriceRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &mocks.ProductRepository{}
+			scheduledPriceRepo := &mocks.ScheduledPriceRepository{}
+			publisher := &mocks.Publisher{}
+			publisher.On("Publish", mock.Anything, "PRODUCT_CHANGED", []byte("5"), mock.Anything).Return(nil)
+			tt.setup(productRepo, scheduledPriceRepo)
+			s := &productService{
+				productRepo:        productRepo,
+				scheduledPriceRepo: scheduledPriceRepo,
+				publisher:          publisher,
+				baseCurrency:       "USD",
+			}
+			got, err := s.SchedulePrice(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.GetId())
+			scheduledPriceRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	memcache "trintech/review/internal/product-management/repository/cache"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_RetrieveProductByID(t *testing.T) {
+	product := &entity.Product{
+		ID:       pg_util.NullInt64(5),
+		Price:    pg_util.NullInt64(1050),
+		Currency: pg_util.NullString("USD"),
+	}
+	eurPrice := &entity.ProductPrice{
+		ProductID: pg_util.NullInt64(5),
+		Currency:  pg_util.NullString("EUR"),
+		Amount:    pg_util.NullInt64(999),
+	}
+	markdown := &entity.ScheduledPrice{
+		ProductID: pg_util.NullInt64(5),
+		Price:     pg_util.NullInt64(800),
+		Currency:  pg_util.NullString("USD"),
+	}
+	jpyRate := &entity.ExchangeRate{
+		Currency: pg_util.NullString("JPY"),
+		Rate:     pg_util.NullFloat64(150.5),
+	}
+	type repos struct {
+		productPriceRepo   *mocks.ProductPriceRepository
+		exchangeRateRepo   *mocks.ExchangeRateRepository
+		priceHistoryRepo   *mocks.PriceHistoryRepository
+		scheduledPriceRepo *mocks.ScheduledPriceRepository
+	}
+	tests := []struct {
+		name          string
+		currency      string
+		wantPrice     *pb.Money
+		wantCompareAt *pb.Money
+		wantErr       error
+		setup         func(r repos)
+	}{
+		{
+			name:      "happy case base currency",
+			wantPrice: &pb.Money{Amount: 1050, Currency: "USD"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+			},
+		},
+		{
+			name:      "happy case explicit price",
+			currency:  "EUR",
+			wantPrice: &pb.Money{Amount: 999, Currency: "EUR"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+			},
+		},
+		{
+			name:      "happy case converted price",
+			currency:  "JPY",
+			wantPrice: &pb.Money{Amount: 1580, Currency: "JPY"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+				r.exchangeRateRepo.On("RetrieveByCurrency", mock.Anything, mock.Anything, "JPY").Return(jpyRate, nil)
+			},
+		},
+		{
+			name:          "happy case scheduled price compared to the regular price",
+			wantPrice:     &pb.Money{Amount: 800, Currency: "USD"},
+			wantCompareAt: &pb.Money{Amount: 1050, Currency: "USD"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return([]*entity.ScheduledPrice{markdown}, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+			},
+		},
+		{
+			name:          "happy case scheduled price converted without the explicit price",
+			currency:      "EUR",
+			wantPrice:     &pb.Money{Amount: 736, Currency: "EUR"},
+			wantCompareAt: &pb.Money{Amount: 966, Currency: "EUR"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return([]*entity.ScheduledPrice{markdown}, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+				r.exchangeRateRepo.On("RetrieveByCurrency", mock.Anything, mock.Anything, "EUR").Return(&entity.ExchangeRate{
+					Currency: pg_util.NullString("EUR"),
+					Rate:     pg_util.NullFloat64(0.92),
+				}, nil)
+			},
+		},
+		{
+			name:          "happy case price dropped in the window",
+			wantPrice:     &pb.Money{Amount: 1050, Currency: "USD"},
+			wantCompareAt: &pb.Money{Amount: 1500, Currency: "USD"},
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return([]*entity.PriceHistory{
+					{ProductID: pg_util.NullInt64(5), Price: pg_util.NullInt64(1500), Currency: pg_util.NullString("USD")},
+				}, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return([]*entity.ProductPrice{eurPrice}, nil)
+			},
+		},
+		{
+			name:     "err unsupported currency",
+			currency: "GBP",
+			wantErr:  status.Errorf(codes.InvalidArgument, "unsupported currency GBP"),
+			setup: func(r repos) {
+				r.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(sql.NullTime{}, nil)
+				r.priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{5}, mock.Anything).Return(nil, nil)
+				r.productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return(nil, nil)
+				r.exchangeRateRepo.On("RetrieveByCurrency", mock.Anything, mock.Anything, "GBP").Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:     "err invalid currency",
+			currency: "euro",
+			wantErr:  status.Errorf(codes.InvalidArgument, "invalid currency"),
+			setup:    func(r repos) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &mocks.ProductRepository{}
+			productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(5)).Return(product, nil)
+			r := repos{
+				productPriceRepo:   &mocks.ProductPriceRepository{},
+				exchangeRateRepo:   &mocks.ExchangeRateRepository{},
+				priceHistoryRepo:   &mocks.PriceHistoryRepository{},
+				scheduledPriceRepo: &mocks.ScheduledPriceRepository{},
+			}
+			productImageRepo := &mocks.ProductImageRepository{}
+			productImageRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{5}).Return(nil, nil)
+			tt.setup(r)
+			s := &productService{
+				productRepo:        productRepo,
+				productCacheRepo:   memcache.NewProductCacheRepository(),
+				productImageRepo:   productImageRepo,
+				productPriceRepo:   r.productPriceRepo,
+				exchangeRateRepo:   r.exchangeRateRepo,
+				priceHistoryRepo:   r.priceHistoryRepo,
+				scheduledPriceRepo: r.scheduledPriceRepo,
+				baseCurrency:       "USD",
+			}
+			got, err := s.RetrieveProductByID(context.Background(), &pb.RetrieveProductByIDRequest{Id: 5, Currency: tt.currency})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPrice, got.GetData().GetPrice())
+			require.Equal(t, tt.wantCompareAt, got.GetData().GetCompareAtPrice())
+			require.Equal(t, []*pb.Money{{Amount: 1050, Currency: "USD"}, {Amount: 999, Currency: "EUR"}}, got.GetData().GetPrices())
+		})
+	}
+}
+
+func Test_productService_SetExchangeRate(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.SetExchangeRateRequest
+		wantErr error
+		setup   func(exchangeRateRepo *mocks.ExchangeRateRepository)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req:  &pb.SetExchangeRateRequest{Currency: "EUR", Rate: 0.92},
+			setup: func(exchangeRateRepo *mocks.ExchangeRateRepository) {
+				exchangeRateRepo.On("Upsert", mock.Anything, mock.Anything, &entity.ExchangeRate{
+					Currency:  pg_util.NullString("EUR"),
+					Rate:      pg_util.NullFloat64(0.92),
+					UpdatedBy: pg_util.NullInt64(9),
+				}).Return(nil)
+			},
+		},
+		{
+			name:    "err base currency",
+			ctx:     adminCtx,
+			req:     &pb.SetExchangeRateRequest{Currency: "USD", Rate: 1},
+			wantErr: status.Errorf(codes.InvalidArgument, "USD is the base currency"),
+			setup:   func(exchangeRateRepo *mocks.ExchangeRateRepository) {},
+		},
+		{
+			name:    "err rate not positive",
+			ctx:     adminCtx,
+			req:     &pb.SetExchangeRateRequest{Currency: "EUR"},
+			wantErr: status.Errorf(codes.InvalidArgument, "rate must be positive"),
+			setup:   func(exchangeRateRepo *mocks.ExchangeRateRepository) {},
+		},
+		{
+			name: "err not admin",
+			ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+				UserID: 1,
+				Role:   userEntity.UserRole_User,
+			})),
+			req:     &pb.SetExchangeRateRequest{Currency: "EUR", Rate: 0.92},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(exchangeRateRepo *mocks.ExchangeRateRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchangeRateRepo := &mocks.ExchangeRateRepository{}
+			publisher := &mocks.Publisher{}
+			publisher.On("Publish", mock.Anything, "PRODUCT_CHANGED", []byte(nil), mock.Anything).Return(nil)
+			tt.setup(exchangeRateRepo)
+			s := &productService{
+				exchangeRateRepo: exchangeRateRepo,
+				publisher:        publisher,
+				baseCurrency:     "USD",
+			}
+			_, err := s.SetExchangeRate(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			exchangeRateRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+		})
+	}
+}
@@ -6,58 +6,289 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/wrapperspb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	couponpb "trintech/review/dto/coupon-management/coupon"
 	pb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
 	"trintech/review/internal/product-management/entity"
+	memcache "trintech/review/internal/product-management/repository/cache"
 	"trintech/review/internal/product-management/repository/postgres"
 	userEntity "trintech/review/internal/user-management/entity"
 	"trintech/review/pkg/database"
 	"trintech/review/pkg/http_server"
 	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
 	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/processor"
+	"trintech/review/pkg/pubsub"
+	"trintech/review/pkg/shipping"
+	"trintech/review/pkg/tax"
 )
 
 // productService is representation of
 type productService struct {
 	productRepo interface {
-		List(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Product, error)
+		List(ctx context.Context, db database.Executor, offset, limit int64, order entity.ProductOrder) ([]*entity.Product, error)
 		Count(ctx context.Context, db database.Executor) (int64, error)
 		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Product, error)
 		Create(ctx context.Context, db database.Executor, data *entity.Product) (int64, error)
-		UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product) error
-		DeleteByID(ctx context.Context, db database.Executor, id int64) error
-		DeleteByIDs(ctx context.Context, db database.Executor, ids []int64) error
+		UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.Product, columns []string) error
+		DeleteByID(ctx context.Context, db database.Executor, id, deletedBy int64) error
+		DeleteByIDs(ctx context.Context, db database.Executor, ids []int64, deletedBy int64) error
+		ListDeleted(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.Product, error)
+		CountDeleted(ctx context.Context, db database.Executor) (int64, error)
+		RestoreByID(ctx context.Context, db database.Executor, id int64) error
+		ListByKeys(ctx context.Context, db database.Executor, sku, externalID string) ([]*entity.Product, error)
+		Iterate(ctx context.Context, db database.Executor, fn func(*entity.Product) error) error
+		RefreshRating(ctx context.Context, db database.Executor, id int64) error
+		IncrementVersionByType(ctx context.Context, db database.Executor, productType string) ([]int64, error)
+		ListByIDs(ctx context.Context, db database.Executor, ids []int64) ([]*entity.Product, error)
 	}
 
 	purchasedProductRepo interface {
-		Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) error
+		Create(ctx context.Context, db database.Executor, data *entity.PurchasedProduct) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchasedProduct, error)
+		List(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter, offset, limit int64) ([]*entity.PurchasedProduct, error)
+		ListByOrderID(ctx context.Context, db database.Executor, orderID int64) ([]*entity.PurchasedProduct, error)
+		Count(ctx context.Context, db database.Executor, filter *entity.PurchasedProductFilter) (int64, error)
+		ExistsByUserIDAndProductID(ctx context.Context, db database.Executor, userID, productID int64) (bool, error)
+		UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error
+		AddRefundByID(ctx context.Context, db database.Executor, id, amount int64) (int64, error)
 	}
 
+	reviewRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.Review) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Review, error)
+		ListByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string, offset, limit int64) ([]*entity.Review, error)
+		CountByProductID(ctx context.Context, db database.Executor, productID int64, statuses []string) (int64, error)
+		UpdateStatusByID(ctx context.Context, db database.Executor, id int64, status string, moderatedBy int64) error
+		CreateHelpfulVote(ctx context.Context, db database.Executor, reviewID, userID int64) (bool, error)
+	}
+
+	wishlistRepo interface {
+		AddItem(ctx context.Context, db database.Executor, userID, productID int64) error
+		RemoveItem(ctx context.Context, db database.Executor, userID, productID int64) error
+		ListProducts(ctx context.Context, db database.Executor, userID, offset, limit int64) ([]*entity.Product, error)
+		CountProducts(ctx context.Context, db database.Executor, userID int64) (int64, error)
+		RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Wishlist, error)
+		RetrieveByShareToken(ctx context.Context, db database.Executor, shareToken string) (*entity.Wishlist, error)
+		UpsertShareToken(ctx context.Context, db database.Executor, userID int64, shareToken string) error
+		ListProductCounts(ctx context.Context, db database.Executor, offset, limit int64) ([]*entity.ProductWishlistCount, error)
+		CountWishedProducts(ctx context.Context, db database.Executor) (int64, error)
+	}
+
+	cartRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.Cart) (int64, error)
+		RetrieveByUserID(ctx context.Context, db database.Executor, userID int64) (*entity.Cart, error)
+		RetrieveByToken(ctx context.Context, db database.Executor, token string) (*entity.Cart, error)
+		UpdateCouponByID(ctx context.Context, db database.Executor, id int64, coupon sql.NullString) error
+		MergeInto(ctx context.Context, db database.Executor, fromID, toID int64) error
+		ListItems(ctx context.Context, db database.Executor, cartID int64) ([]*entity.CartItem, error)
+		AddItem(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error
+		SetItemQuantity(ctx context.Context, db database.Executor, cartID, productID, quantity int64) error
+		RemoveItem(ctx context.Context, db database.Executor, cartID, productID int64) error
+		ClearItems(ctx context.Context, db database.Executor, cartID int64) error
+	}
+
+	orderRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.Order) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.Order, error)
+		UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string) error
+		UpdatePaymentStatusByID(ctx context.Context, db database.Executor, id int64, paymentStatus string) error
+		UpdatePaymentStatusByPaymentID(ctx context.Context, db database.Executor, paymentID, paymentStatus string) error
+		CreateItem(ctx context.Context, db database.Executor, data *entity.OrderItem) error
+		ListItems(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderItem, error)
+		CreateDiscount(ctx context.Context, db database.Executor, data *entity.OrderDiscount) error
+		ListDiscounts(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderDiscount, error)
+		CreateHistory(ctx context.Context, db database.Executor, data *entity.OrderHistory) error
+		ListHistories(ctx context.Context, db database.Executor, orderID int64) ([]*entity.OrderHistory, error)
+	}
+
+	productPriceRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.ProductPrice) error
+		Delete(ctx context.Context, db database.Executor, productID int64, currency string) error
+		ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductPrice, error)
+	}
+
+	exchangeRateRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.ExchangeRate) error
+		RetrieveByCurrency(ctx context.Context, db database.Executor, currency string) (*entity.ExchangeRate, error)
+		List(ctx context.Context, db database.Executor) ([]*entity.ExchangeRate, error)
+	}
+
+	priceHistoryRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.PriceHistory) error
+		ListByProductID(ctx context.Context, db database.Executor, productID, offset, limit int64) ([]*entity.PriceHistory, error)
+		CountByProductID(ctx context.Context, db database.Executor, productID int64) (int64, error)
+		ListHighestSince(ctx context.Context, db database.Executor, productIDs []int64, since time.Time) ([]*entity.PriceHistory, error)
+	}
+
+	scheduledPriceRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.ScheduledPrice) (int64, error)
+		DeleteByID(ctx context.Context, db database.Executor, productID, id int64) error
+		ListByProductID(ctx context.Context, db database.Executor, productID int64, at time.Time) ([]*entity.ScheduledPrice, error)
+		ListActive(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) ([]*entity.ScheduledPrice, error)
+		NextChangeAfter(ctx context.Context, db database.Executor, productIDs []int64, at time.Time) (sql.NullTime, error)
+	}
+
+	purchaseReturnRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.PurchaseReturn) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.PurchaseReturn, error)
+		UpdateStatusByID(ctx context.Context, db database.Executor, id int64, fromStatus, toStatus string, approvedBy int64) error
+	}
+
+	taxRateRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.TaxRate) error
+		Delete(ctx context.Context, db database.Executor, region, category string) error
+		ListByRegions(ctx context.Context, db database.Executor, regions []string) ([]*entity.TaxRate, error)
+		List(ctx context.Context, db database.Executor) ([]*entity.TaxRate, error)
+	}
+
+	addressRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.Address) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, userID, id int64) (*entity.Address, error)
+		RetrieveDefault(ctx context.Context, db database.Executor, userID int64) (*entity.Address, error)
+		ListByUserID(ctx context.Context, db database.Executor, userID int64) ([]*entity.Address, error)
+		UpdateByID(ctx context.Context, db database.Executor, userID, id int64, data *entity.Address) error
+		DeleteByID(ctx context.Context, db database.Executor, userID, id int64) error
+		ClearDefault(ctx context.Context, db database.Executor, userID int64) error
+		SetDefault(ctx context.Context, db database.Executor, userID, id int64) error
+	}
+
+	shippingMethodRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.ShippingMethod) (int64, error)
+		RetrieveByID(ctx context.Context, db database.Executor, id int64) (*entity.ShippingMethod, error)
+		List(ctx context.Context, db database.Executor) ([]*entity.ShippingMethod, error)
+		UpdateByID(ctx context.Context, db database.Executor, id int64, data *entity.ShippingMethod) error
+		CreateRate(ctx context.Context, db database.Executor, data *entity.ShippingRate) error
+		DeleteRates(ctx context.Context, db database.Executor, shippingMethodID int64) error
+		ListRates(ctx context.Context, db database.Executor, shippingMethodIDs []int64) ([]*entity.ShippingRate, error)
+	}
+
+	productImageRepo interface {
+		Create(ctx context.Context, db database.Executor, data *entity.ProductImage) error
+		DeleteByProductID(ctx context.Context, db database.Executor, productID int64) error
+		ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64) ([]*entity.ProductImage, error)
+	}
+
+	productTranslationRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.ProductTranslation) error
+		Delete(ctx context.Context, db database.Executor, productID int64, locale string) error
+		ListByProductIDs(ctx context.Context, db database.Executor, productIDs []int64, locales []string) ([]*entity.ProductTranslation, error)
+		ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingProductTranslation, error)
+		CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+	}
+
+	categoryTranslationRepo interface {
+		Upsert(ctx context.Context, db database.Executor, data *entity.CategoryTranslation) error
+		Delete(ctx context.Context, db database.Executor, category, locale string) error
+		ListByCategories(ctx context.Context, db database.Executor, categories []string, locales []string) ([]*entity.CategoryTranslation, error)
+		ListMissing(ctx context.Context, db database.Executor, locales []string, offset, limit int64) ([]*entity.MissingCategoryTranslation, error)
+		CountMissing(ctx context.Context, db database.Executor, locales []string) (int64, error)
+	}
+
+	salesRollupRepo interface {
+		RetrieveWatermark(ctx context.Context, db database.Executor) (time.Time, error)
+		LockWatermark(ctx context.Context, db database.Executor) (time.Time, error)
+		UpdateWatermark(ctx context.Context, db database.Executor, refreshedUntil time.Time) error
+		ListStaleDays(ctx context.Context, db database.Executor, since time.Time) ([]time.Time, error)
+		RefreshDays(ctx context.Context, db database.Executor, days []time.Time) error
+		List(ctx context.Context, db database.Executor, filter *entity.SalesRollupFilter) ([]*entity.SalesRollup, error)
+	}
+
+	productCacheRepo interface {
+		RetrieveByID(ctx context.Context, id int64) (*entity.Product, error)
+		Generation(ctx context.Context) int64
+		StoreByID(ctx context.Context, id int64, product *entity.Product, generation int64) error
+		RemoveByID(ctx context.Context, id int64) error
+		RetrievePage(ctx context.Context, key string) (*entity.ProductPage, error)
+		StorePage(ctx context.Context, key string, page *entity.ProductPage, generation int64) error
+		RemovePages(ctx context.Context) error
+		RetrieveView(ctx context.Context, key string) (*entity.ProductView, error)
+		StoreView(ctx context.Context, key string, view *entity.ProductView, generation int64) error
+		RemoveViews(ctx context.Context) error
+	}
+
+	// productLoader shares the queries of the concurrent cache misses of the storefront reads.
+	productLoader singleflight.Group
+
 	pb.UnimplementedProductServiceServer
 
 	db database.Database
 
 	couponServiceClient couponpb.CouponServiceClient
+
+	// storageServiceClient resolves the files of the product images.
+	storageServiceClient storagepb.UploadServiceClient
+
+	// publisher publishes the changes of products and orders, the changes of products are also subscribed to
+	// through the broker to invalidate the cache.
+	publisher pubsub.Publisher
+
+	// paymentProvider charges the purchases.
+	paymentProvider payment.Provider
+
+	// baseCurrency is the currency of the product prices, the carts and the orders.
+	baseCurrency string
+
+	// taxRates are the default tax rates of the configuration, the rates set by the admins win over them.
+	taxRates []tax.Rate
 }
 
 // NewProductService ...
 func NewProductService(
 	db database.Database,
 	couponServiceClient couponpb.CouponServiceClient,
+	storageServiceClient storagepb.UploadServiceClient,
+	broker pubsub.Broker,
+	paymentProvider payment.Provider,
+	baseCurrency string,
+	taxRates []tax.Rate,
 ) pb.ProductServiceServer {
-	return &productService{
+	s := &productService{
 		db:                   db,
 		couponServiceClient:  couponServiceClient,
+		storageServiceClient: storageServiceClient,
+		publisher:            broker,
+		paymentProvider:      paymentProvider,
+		baseCurrency:         baseCurrency,
+		taxRates:             taxRates,
 		productRepo:          postgres.NewProductRepository(),
 		purchasedProductRepo: postgres.NewPurchasedProductRepository(),
+		reviewRepo:           postgres.NewReviewRepository(),
+		wishlistRepo:         postgres.NewWishlistRepository(),
+		cartRepo:             postgres.NewCartRepository(),
+		orderRepo:            postgres.NewOrderRepository(),
+		productPriceRepo:     postgres.NewProductPriceRepository(),
+		exchangeRateRepo:     postgres.NewExchangeRateRepository(),
+		priceHistoryRepo:     postgres.NewPriceHistoryRepository(),
+		scheduledPriceRepo:   postgres.NewScheduledPriceRepository(),
+		purchaseReturnRepo:   postgres.NewPurchaseReturnRepository(),
+		taxRateRepo:          postgres.NewTaxRateRepository(),
+		addressRepo:          postgres.NewAddressRepository(),
+		shippingMethodRepo:   postgres.NewShippingMethodRepository(),
+		productImageRepo:     postgres.NewProductImageRepository(),
+		productCacheRepo:     memcache.NewProductCacheRepository(),
+
+		productTranslationRepo:  postgres.NewProductTranslationRepository(),
+		categoryTranslationRepo: postgres.NewCategoryTranslationRepository(),
+		salesRollupRepo:         postgres.NewSalesRollupRepository(),
 	}
+
+	// Subscribe to the changes of products of every replica to invalidate the cache
+	broker.Subscribe(productChangedTopic, s.SubscribeProductChanged)
+
+	return s
 }
 
 // validAdmin checks admin validation from context.
@@ -80,6 +311,40 @@ func validAdmin(ctx context.Context) (*xcontext.UserInfo, error) {
 	return userCtx, nil
 }
 
+// productFieldPaths maps the update mask paths of a product to its columns.
+var productFieldPaths = database.FieldPaths{
+	"name":         "name",
+	"type":         "type",
+	"description":  "description",
+	"price":        "price",
+	"sku":          "sku",
+	"external_id":  "external_id",
+	"weight_grams": "weight_grams",
+}
+
+// toPbProduct transforms a product entity to the response format.
+func toPbProduct(product *entity.Product) *pb.Product {
+	return &pb.Product{
+		Id:            product.ID.Int64,
+		Name:          product.Name.String,
+		Type:          product.Type.String,
+		Description:   product.Description.String,
+		Price:         toPbMoney(productPrice(product)),
+		Sku:           product.SKU.String,
+		ExternalId:    product.ExternalID.String,
+		RatingAverage: product.RatingAverage.Float64,
+		RatingCount:   product.RatingCount.Int64,
+		WeightGrams:   product.WeightGrams.Int64,
+		Version:       product.Version.Int64,
+	}
+}
+
+// productOrders maps the sort options of a product listing to the repository orders.
+var productOrders = map[pb.ProductSortBy]entity.ProductOrder{
+	pb.ProductSortBy_ProductSortBy_NONE:   entity.ProductOrder_Default,
+	pb.ProductSortBy_ProductSortBy_RATING: entity.ProductOrder_Rating,
+}
+
 // CreateProduct is a method of the productService that handles the creation of a new product.
 // It validates the admin user, creates a product in the repository, and returns the created product's ID.
 func (s *productService) CreateProduct(ctx context.Context, req *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
@@ -89,21 +354,56 @@ func (s *productService) CreateProduct(ctx context.Context, req *pb.CreateProduc
 		return nil, err
 	}
 
-	// Create a new product in the repository
-	id, err := s.productRepo.Create(ctx, s.db, &entity.Product{
-		Name:        pg_util.NullString(req.GetName()),
-		Type:        pg_util.NullString(req.GetType()),
-		Description: pg_util.NullString(req.GetDescription()),
-		ImageURLs:   pg_util.StringArray(req.GetImageUrls()),
-		CreatedBy:   pg_util.NullInt64(userCtx.UserID),
-		Price:       pg_util.NullFloat64(req.GetPrice()),
-	})
+	// Validate the price, which is in the base currency, and the weight
+	price, err := s.validBasePrice(req.GetPrice())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetWeightGrams() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "weight must not be negative")
+	}
 
+	// Validate the images, which are uploaded image files, the image URLs are only derived from them
+	if len(req.GetImageUrls()) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "image_urls is read-only, use images")
+	}
+	images, err := s.validProductImages(ctx, req.GetImages())
 	if err != nil {
+		return nil, err
+	}
+
+	// Create a new product in the repository with its images and the start of its price history in a database transaction
+	var id int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		id, err = s.productRepo.Create(ctx, tx, &entity.Product{
+			Name:        pg_util.NullString(req.GetName()),
+			Type:        pg_util.NullString(req.GetType()),
+			Description: pg_util.NullString(req.GetDescription()),
+			CreatedBy:   pg_util.NullInt64(userCtx.UserID),
+			Price:       pg_util.NullInt64(price.Amount),
+			Currency:    pg_util.NullString(price.Currency),
+			SKU:         pg_util.NullEmptyString(req.GetSku()),
+			ExternalID:  pg_util.NullEmptyString(req.GetExternalId()),
+			WeightGrams: pg_util.NullInt64(req.GetWeightGrams()),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.replaceProductImages(ctx, tx, id, images); err != nil {
+			return err
+		}
+
+		return s.recordPriceChange(ctx, tx, id, price, userCtx.UserID, entity.PriceChangeReason_Created)
+	}); err != nil {
 		// If there is an error during product creation, return an internal server error
 		return nil, status.Errorf(codes.Internal, "unable to create product: %v", err.Error())
 	}
 
+	// Publish the creation of the product
+	s.publishProductChanged(ctx, productChangedAction_Created, id)
+
 	// Return the created product's ID
 	return &pb.CreateProductResponse{
 		Id: id,
@@ -111,15 +411,16 @@ func (s *productService) CreateProduct(ctx context.Context, req *pb.CreateProduc
 }
 
 // DeleteProductByID is a method of the productService that handles the deletion of a product by ID.
-// It validates the admin user, deletes the product in the repository, and returns an empty response.
+// It validates the admin user, moves the product to the trash, and returns an empty response.
 func (s *productService) DeleteProductByID(ctx context.Context, req *pb.DeleteProductByIDRequest) (*pb.DeleteProductByIDResponse, error) {
 	// Validate admin user
-	if _, err := validAdmin(ctx); err != nil {
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	// Delete the product in the repository by ID
-	if err := s.productRepo.DeleteByID(ctx, s.db, req.GetId()); err != nil {
+	// Move the product to the trash by ID
+	if err := s.productRepo.DeleteByID(ctx, s.db, req.GetId(), userCtx.UserID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// If the product is not found, return a not found error
 			return nil, status.Errorf(codes.NotFound, "product not found")
@@ -129,24 +430,31 @@ func (s *productService) DeleteProductByID(ctx context.Context, req *pb.DeletePr
 		return nil, status.Errorf(codes.Internal, "unable to delete product: %v", err.Error())
 	}
 
+	// Publish the deletion of the product
+	s.publishProductChanged(ctx, productChangedAction_Deleted, req.GetId())
+
 	// Return an empty response indicating successful deletion
 	return &pb.DeleteProductByIDResponse{}, nil
 }
 
 // DeleteProductByIDs is a method of the productService that handles the deletion of products by IDs.
-// It validates the admin user, deletes the products in the repository by IDs, and returns an empty response.
+// It validates the admin user, moves the products to the trash by IDs, and returns an empty response.
 func (s *productService) DeleteProductByIDs(ctx context.Context, req *pb.DeleteProductByIDsRequest) (*pb.DeleteProductByIDsResponse, error) {
 	// Validate admin user
-	if _, err := validAdmin(ctx); err != nil {
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	// Delete the products in the repository by IDs
-	if err := s.productRepo.DeleteByIDs(ctx, s.db, req.GetIds()); err != nil {
+	// Move the products to the trash by IDs
+	if err := s.productRepo.DeleteByIDs(ctx, s.db, req.GetIds(), userCtx.UserID); err != nil {
 		// If there is an error during product deletion, return an internal server error
 		return nil, status.Errorf(codes.Internal, "unable to delete product: %v", err.Error())
 	}
 
+	// Publish the deletion of the products
+	s.publishProductChanged(ctx, productChangedAction_Deleted, req.GetIds()...)
+
 	// Return an empty response indicating successful deletion
 	return &pb.DeleteProductByIDsResponse{}, nil
 }
@@ -154,47 +462,51 @@ func (s *productService) DeleteProductByIDs(ctx context.Context, req *pb.DeleteP
 // ListProduct is a method of the productService that retrieves a list of products.
 // It validates the admin user, retrieves the list of products from the repository, and returns the response.
 func (s *productService) ListProduct(ctx context.Context, req *pb.ListProductRequest) (*pb.ListProductResponse, error) {
-	// Retrieve the list of products from the repository
-	list, err := s.productRepo.List(ctx, s.db, req.GetOffset(), req.GetLimit())
-	if err != nil {
-		// If there is an error during product retrieval, return an internal server error
-		return nil, status.Errorf(codes.Internal, "unable to retrieve list product: %v", err.Error())
+	// Resolve the order of the list
+	order, ok := productOrders[req.GetSortBy()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid sort by")
 	}
 
-	// Transform the list of products to the response format
-	respData := make([]*pb.Product, 0, len(list))
+	// Resolve the currency of the prices
+	currency, err := s.validCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
 
-	for _, product := range list {
-		respData = append(respData, &pb.Product{
-			Name:        product.Name.String,
-			Type:        product.Type.String,
-			ImageUrls:   pg_util.StringArrayValue(product.ImageURLs),
-			Description: product.Description.String,
-			Price:       product.Price.Float64,
-		})
+	// Retrieve the list of products and the total count of products through the cache
+	page, err := s.listProducts(ctx, req.GetOffset(), req.GetLimit(), order)
+	if err != nil {
+		// If there is an error during product retrieval, return an internal server error
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// Get the total count of products
-	total, err := s.productRepo.Count(ctx, s.db)
+	// Transform the list of products to the response format, priced in the currency
+	respData, err := s.productViews(ctx, page.Products, currency)
 	if err != nil {
-		// If there is an error during count retrieval, return an internal server error
-		return nil, status.Errorf(codes.Internal, "unable to count product: %v", err.Error())
+		return nil, err
 	}
 
 	// Return the list of products and total count in the response
 	return &pb.ListProductResponse{
 		Data:  respData,
-		Total: total,
+		Total: page.Total,
 	}, nil
 }
 
 // RetrieveProductByID is a method of the productService that retrieves a product by ID.
 // It validates the admin user, retrieves the product from the repository by ID, and returns the response.
 func (s *productService) RetrieveProductByID(ctx context.Context, req *pb.RetrieveProductByIDRequest) (*pb.RetrieveProductByIDResponse, error) {
-	// Retrieve the product from the repository by ID
-	product, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetId())
+	// Resolve the currency of the price
+	currency, err := s.validCurrency(req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the product by ID through the cache
+	product, err := s.retrieveProduct(ctx, req.GetId())
 	switch {
-	case errors.Is(err, pgx.ErrNoRows):
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, sql.ErrNoRows):
 		// If the product is not found, return a not found error
 		return nil, status.Errorf(codes.NotFound, "product not found")
 	case err != nil:
@@ -202,49 +514,179 @@ func (s *productService) RetrieveProductByID(ctx context.Context, req *pb.Retrie
 		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
 	}
 
-	// Transform the retrieved product to the response format
+	// Transform the retrieved product to the response format, priced in the currency
+	data, err := s.productViews(ctx, []*entity.Product{product}, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the version of the product as its ETag
+	http_server.SetETag(ctx, product.Version.Int64)
 	return &pb.RetrieveProductByIDResponse{
-		Data: &pb.Product{
-			Name:        product.Name.String,
-			Type:        product.Type.String,
-			ImageUrls:   pg_util.StringArrayValue(product.ImageURLs),
-			Description: product.Description.String,
-			Price:       product.Price.Float64,
-		},
+		Data: data[0],
 	}, nil
 }
 
 // UpdateProductByID is a method of the productService that updates a product by ID.
-// It validates the admin user, updates the product in the repository by ID, and returns an empty response.
+// It validates the admin user, updates the masked fields of the product in the repository by ID, only at the expected
+// version when there is one, and returns the new version of the product.
 func (s *productService) UpdateProductByID(ctx context.Context, req *pb.UpdateProductByIDRequest) (*pb.UpdateProductByIDResponse, error) {
 	// Validate admin user
-	if _, err := validAdmin(ctx); err != nil {
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	// Update the product in the repository by ID
-	if err := s.productRepo.UpdateByID(ctx, s.db, req.GetId(), &entity.Product{
-		Name:        pg_util.NullString(req.GetName()),
-		Type:        pg_util.NullString(req.GetType()),
-		Description: pg_util.NullString(req.GetDescription()),
-		ImageURLs:   pg_util.StringArray(req.GetImageUrls()),
+	// Reject the image URLs, they are only derived from the images
+	if len(req.GetImageUrls()) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "image_urls is read-only, use images")
+	}
+
+	// Resolve the fields to update, falling back to the non-empty fields without an update mask
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = populatedProductPaths(req)
+	}
+	updateImages := slices.Contains(paths, "images")
+	paths = slices.DeleteFunc(slices.Clone(paths), func(path string) bool { return path == "images" })
+	columns, err := productFieldPaths.Columns(paths)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid update mask: %v", err.Error())
+	}
+	if len(columns) == 0 && !updateImages {
+		return nil, status.Errorf(codes.InvalidArgument, "nothing to update")
+	}
+
+	// Validate the price, which is in the base currency, and the weight
+	price, err := s.validBasePrice(req.GetPrice())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetWeightGrams() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "weight must not be negative")
+	}
+
+	// Validate the images, which are uploaded image files
+	var images []*entity.ProductImage
+	if updateImages {
+		if images, err = s.validProductImages(ctx, req.GetImages()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the version to update, from the If-Match header without expected version
+	expectedVersion := req.GetExpectedVersion()
+	if expectedVersion == 0 {
+		if expectedVersion, err = http_server.ExtractIfMatchFromCtx(ctx); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid If-Match header")
+		}
+	}
+	if expectedVersion < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "expected_version must not be negative")
+	}
+	var version sql.NullInt64
+	if expectedVersion > 0 {
+		version = pg_util.NullInt64(expectedVersion)
+	}
+
+	// Update the product in the repository by ID, replace its images and record a change of its price in a database transaction
+	var after *entity.Product
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var before *entity.Product
+		if slices.Contains(columns, "price") {
+			var err error
+			if before, err = s.productRepo.RetrieveByID(ctx, tx, req.GetId()); err != nil {
+				return err
+			}
+		}
+
+		if err := s.productRepo.UpdateByID(ctx, tx, req.GetId(), &entity.Product{
+			Name:        pg_util.NullString(req.GetName()),
+			Type:        pg_util.NullString(req.GetType()),
+			Description: pg_util.NullString(req.GetDescription()),
+			Price:       pg_util.NullInt64(price.Amount),
+			SKU:         pg_util.NullEmptyString(req.GetSku()),
+			ExternalID:  pg_util.NullEmptyString(req.GetExternalId()),
+			WeightGrams: pg_util.NullInt64(req.GetWeightGrams()),
+			Version:     version,
+		}, columns); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = s.productRepo.RetrieveByID(ctx, tx, req.GetId()); err != nil {
+			return err
+		}
+
+		if updateImages {
+			if err := s.replaceProductImages(ctx, tx, req.GetId(), images); err != nil {
+				return err
+			}
+		}
+
+		if slices.Contains(columns, "price") && before.Price.Int64 != price.Amount {
+			return s.recordPriceChange(ctx, tx, req.GetId(), price, userCtx.UserID, entity.PriceChangeReason_Updated)
+		}
+
+		return nil
 	}); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// If the product is not found, return a not found error
 			return nil, status.Errorf(codes.NotFound, "product not found")
 		}
+		if errors.Is(err, database.ErrVersionMismatch) {
+			// If the product has been updated since the expected version, return an aborted error
+			return nil, status.Errorf(codes.Aborted, "product has been modified, version mismatch")
+		}
 
 		// If there is an error during product update, return an internal server error
 		return nil, status.Errorf(codes.Internal, "unable to update product: %v", err.Error())
 	}
 
-	// Return an empty response indicating successful update
-	return &pb.UpdateProductByIDResponse{}, nil
+	// Publish the update of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetId())
+
+	// Return the version of the updated product as its ETag
+	http_server.SetETag(ctx, after.Version.Int64)
+	return &pb.UpdateProductByIDResponse{
+		Version: after.Version.Int64,
+	}, nil
+}
+
+// populatedProductPaths returns the field paths of the non-empty fields in an update request.
+func populatedProductPaths(req *pb.UpdateProductByIDRequest) []string {
+	var paths []string
+	if req.GetName() != "" {
+		paths = append(paths, "name")
+	}
+	if req.GetType() != "" {
+		paths = append(paths, "type")
+	}
+	if req.GetDescription() != "" {
+		paths = append(paths, "description")
+	}
+	if req.GetPrice() != nil {
+		paths = append(paths, "price")
+	}
+	if req.GetSku() != "" {
+		paths = append(paths, "sku")
+	}
+	if req.GetExternalId() != "" {
+		paths = append(paths, "external_id")
+	}
+	if req.GetWeightGrams() != 0 {
+		paths = append(paths, "weight_grams")
+	}
+	if len(req.GetImages()) > 0 {
+		paths = append(paths, "images")
+	}
+
+	return paths
 }
 
 // PurchaseProduct is a method of the productService that handles the purchase of a product.
-// It extracts user information, retrieves the product, applies a coupon if provided,
-// creates a purchase record in the repository, and returns an empty response.
+// It extracts user information, retrieves the product, applies a coupon if provided, captures the payment,
+// creates a purchase record in the repository, and returns the created purchase.
 func (s *productService) PurchaseProduct(ctx context.Context, req *pb.PurchaseProductRequest) (*pb.PurchaseProductResponse, error) {
 	// Extract user information from the context
 	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
@@ -253,6 +695,30 @@ func (s *productService) PurchaseProduct(ctx context.Context, req *pb.PurchasePr
 		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
 	}
 
+	// Validate the tax region, the purchases without region are untaxed
+	var region string
+	if req.GetRegion() != "" {
+		var err error
+		if region, err = validRegion(req.GetRegion()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Retrieve the shipping address, the default address of the user without id, it is the tax region by default
+	var address *entity.Address
+	switch {
+	case req.GetShippingMethodId() != 0:
+		var err error
+		if address, err = s.shippingAddress(ctx, userCtx.UserID, req.GetAddressId()); err != nil {
+			return nil, err
+		}
+		if region == "" {
+			region = address.Region.String
+		}
+	case req.GetAddressId() != 0:
+		return nil, status.Errorf(codes.InvalidArgument, "shipping_method_id is required to ship to an address")
+	}
+
 	// Retrieve the product by ID
 	product, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetId())
 	switch {
@@ -264,16 +730,36 @@ func (s *productService) PurchaseProduct(ctx context.Context, req *pb.PurchasePr
 		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
 	}
 
-	// Initialize discountTotal with the original product price
-	var discountTotal float64 = product.Price.Float64
+	// Sell the product at its scheduled price in effect
+	if err := s.applyScheduledPrices(ctx, product); err != nil {
+		return nil, err
+	}
+
+	// Initialize the discount, there is none without coupon
+	price := productPrice(product)
+	discount := money.New(0, price.Currency)
+
+	// Quote the shipping of the product to the address, the purchases without shipping method are not shipped
+	shippingCost := money.New(0, price.Currency)
+	if address != nil {
+		if shippingCost, err = s.quoteShipping(ctx, req.GetShippingMethodId(), shipping.Parcel{
+			Region:      address.Region.String,
+			WeightGrams: product.WeightGrams.Int64,
+			OrderValue:  price,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	subtotal, _ := price.Add(shippingCost)
 
 	// Apply coupon if provided
+	var orderDiscount *entity.OrderDiscount
 	if req.GetCoupon() != nil {
-		coupon, err := s.couponServiceClient.RetrieveCouponByCode(http_server.InjectIncomingCtxToOutgoingCtx(ctx), &couponpb.RetrieveCouponByCodeRequest{
-			Code:     req.GetCoupon().GetValue(),
-			CheckUse: true,
-			UserId:   wrapperspb.Int64(userCtx.UserID),
-		})
+		couponReq, err := s.couponUseRequest(ctx, req.GetCoupon().GetValue(), pg_util.NullInt64(userCtx.UserID), price, []string{product.Type.String})
+		if err != nil {
+			return nil, err
+		}
+		coupon, err := s.couponServiceClient.RetrieveCouponByCode(http_server.InjectIncomingCtxToOutgoingCtx(ctx), couponReq)
 		if err != nil {
 			stt, _ := status.FromError(err)
 			switch stt.Code() {
@@ -291,63 +777,274 @@ func (s *productService) PurchaseProduct(ctx context.Context, req *pb.PurchasePr
 
 		// Check if the user can use this coupon
 		if !coupon.CanUse {
+			if coupon.GetReasonMessage() != "" {
+				return nil, status.Errorf(codes.FailedPrecondition, "user cannot apply this coupon: %s", coupon.GetReasonMessage())
+			}
 			return nil, status.Errorf(codes.FailedPrecondition, "user cannot apply this coupon")
 		}
 
-		// Apply discount based on the coupon type
-		switch coupon.DiscountType {
-		case couponpb.DiscountType_DiscountType_PERCENT:
-			discountTotal = product.Price.Float64 * coupon.Value / 100
-		case couponpb.DiscountType_DiscountType_VALUE:
-			discountTotal = coupon.GetValue()
+		// Apply discount based on the coupon type, it never exceeds the product price, or the price with the shipping
+		// for the coupons which apply to the shipping
+		base := price
+		if coupon.GetAppliesToShipping() {
+			base = subtotal
+		}
+		if discount, ok = couponDiscount(base, coupon); !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "unable to apply this coupon")
+		}
+
+		// Record the coupon discount on the order, with the value of a percent coupon in basis points
+		value := coupon.GetValue().GetAmount()
+		if coupon.GetDiscountType() == couponpb.DiscountType_DiscountType_PERCENT {
+			value = coupon.GetPercentBasisPoints()
+		}
+		orderDiscount = &entity.OrderDiscount{
+			Coupon:       pg_util.NullString(req.GetCoupon().GetValue()),
+			DiscountType: pg_util.NullString(coupon.GetDiscountType().String()),
+			Value:        pg_util.NullInt64(value),
+			Amount:       pg_util.NullInt64(discount.Amount),
+		}
+	}
+	total, _ := subtotal.Sub(discount)
+
+	// Tax the discounted price with the shipping at the rate of the product type in the region, an exclusive tax is added to the total
+	var breakdown *tax.Breakdown
+	if region != "" {
+		rate, ok, err := s.taxRate(ctx, region, product.Type.String)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			b := tax.Calculate(total, rate)
+			breakdown = &b
+			total = b.Gross
+		}
+	}
+
+	// Build the paid order of the purchase
+	order := &entity.Order{
+		UserID:   pg_util.NullInt64(userCtx.UserID),
+		Status:   pg_util.NullString(entity.OrderStatus_Paid),
+		Subtotal: product.Price,
+		Discount: pg_util.NullInt64(discount.Amount),
+		Total:    pg_util.NullInt64(total.Amount),
+		Currency: product.Currency,
+		Tax:      pg_util.NullInt64(0),
+		Shipping: pg_util.NullInt64(shippingCost.Amount),
+	}
+	if address != nil {
+		order.ShippingMethodID = pg_util.NullInt64(req.GetShippingMethodId())
+		order.ShippingAddress = toShippingAddress(address)
+	}
+	item := &entity.OrderItem{
+		ProductID: product.ID,
+		Name:      product.Name,
+		UnitPrice: product.Price,
+		Quantity:  pg_util.NullInt64(1),
+		Subtotal:  product.Price,
+		Shipping:  order.Shipping,
+		Discount:  order.Discount,
+		Total:     order.Total,
+		Tax:       order.Tax,
+	}
+	if breakdown != nil {
+		order.Tax = pg_util.NullInt64(breakdown.Tax.Amount)
+		item.Tax = order.Tax
+		item.TaxRegion = pg_util.NullString(breakdown.Rate.Region)
+		item.TaxCategory = pg_util.NullString(breakdown.Rate.Category)
+		item.TaxBasisPoints = pg_util.NullInt64(breakdown.Rate.BasisPoints)
+		item.TaxInclusive = sql.NullBool{Bool: breakdown.Rate.Inclusive, Valid: true}
+	}
+	items := []*entity.OrderItem{item}
+	var discounts []*entity.OrderDiscount
+	if orderDiscount != nil {
+		discounts = append(discounts, orderDiscount)
+	}
+
+	// Reserve the coupon, it is confirmed with the purchase or released if the purchase fails
+	var redemptionID string
+	if req.GetCoupon() != nil {
+		if redemptionID, err = s.reserveCoupon(ctx, req.GetCoupon().GetValue(), userCtx.UserID); err != nil {
+			return nil, err
+		}
+		order.CouponRedemptionID = pg_util.NullString(redemptionID)
+	}
+
+	// Capture the payment of the total, the purchase is only recorded once it is paid
+	captured, err := s.capturePayment(ctx, total, req.GetPaymentToken())
+	if err != nil {
+		// Release the reserved coupon of the unpaid purchase
+		if redemptionID != "" {
+			s.releaseCoupon(ctx, redemptionID)
 		}
+
+		return nil, err
+	}
+	if captured != nil {
+		order.PaymentID = pg_util.NullString(captured.ID)
+		order.PaymentStatus = pg_util.NullString(string(captured.Status))
 	}
 
 	// Perform the purchase operation in a database transaction
+	var (
+		history         *entity.OrderHistory
+		purchaseProduct *entity.PurchasedProduct
+	)
 	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Create the order of the purchase
+		var err error
+		if history, err = s.createOrder(ctx, tx, order, items, discounts, userCtx.UserID, ""); err != nil {
+			return err
+		}
+
 		// Create a purchased product record
-		purchaseProduct := &entity.PurchasedProduct{
+		purchaseProduct = &entity.PurchasedProduct{
 			ProductID: product.ID,
 			UserID:    pg_util.NullInt64(userCtx.UserID),
 			Price:     product.Price,
-			Discount:  pg_util.NullFloat64(discountTotal),
-			Total:     pg_util.NullFloat64(max(0, discountTotal)),
+			Quantity:  pg_util.NullInt64(1),
+			Discount:  order.Discount,
+			Total:     order.Total,
+			Currency:  order.Currency,
+			OrderID:   order.ID,
+			Tax:       order.Tax,
+			Shipping:  order.Shipping,
+
+			PaymentID:        order.PaymentID,
+			PaymentStatus:    order.PaymentStatus,
+			ShippingMethodID: order.ShippingMethodID,
+			ShippingAddress:  order.ShippingAddress,
+			TaxRegion:        item.TaxRegion,
+			TaxCategory:      item.TaxCategory,
+			TaxBasisPoints:   item.TaxBasisPoints,
+			TaxInclusive:     item.TaxInclusive,
 		}
 		if req.GetCoupon() != nil {
 			purchaseProduct.Coupon = pg_util.NullString(req.GetCoupon().Value)
+			purchaseProduct.CouponRedemptionID = pg_util.NullString(redemptionID)
 		}
 
 		// Create the purchased product record in the repository
-		if err := s.purchasedProductRepo.Create(ctx, tx, purchaseProduct); err != nil {
+		id, err := s.purchasedProductRepo.Create(ctx, tx, purchaseProduct)
+		if err != nil {
 			return fmt.Errorf("unable to create purchase product: %v", err)
 		}
+		purchaseProduct.ID = pg_util.NullInt64(id)
 
-		// Apply the coupon if provided
-		if req.GetCoupon() != nil {
-			if _, err := s.couponServiceClient.ApplyCoupon(http_server.InjectIncomingCtxToOutgoingCtx(ctx), &couponpb.ApplyCouponRequest{
-				Code: req.Coupon.Value,
-			}); err != nil {
-				stt, _ := status.FromError(err)
-				switch stt.Code() {
-				case codes.NotFound:
-					// If the coupon is not found, return a not found error
-					return status.Errorf(codes.NotFound, "coupon not found")
-				case codes.FailedPrecondition:
-					// If there is a failed precondition, return a failed precondition error
-					return status.Errorf(codes.FailedPrecondition, "unable to apply this coupon")
-				default:
-					// If there is an internal error, return an internal server error
-					return status.Errorf(codes.Internal, "unable to apply this coupon: %v", err.Error())
-				}
+		// Confirm the reserved coupon once the purchase is committed
+		if redemptionID != "" {
+			if err := s.confirmCoupon(ctx, tx, redemptionID, money.New(order.Discount.Int64, order.Currency.String)); err != nil {
+				return fmt.Errorf("unable to confirm coupon: %w", err)
 			}
 		}
 
 		return nil
 	}); err != nil {
+		// Refund the payment and release the reserved coupon of the failed purchase
+		s.refundPayment(ctx, captured)
+		if redemptionID != "" {
+			s.releaseCoupon(ctx, redemptionID)
+		}
+
 		// If there is an error during the transaction, return the error
 		return nil, err
 	}
 
-	// Return an empty response indicating successful purchase
-	return &pb.PurchaseProductResponse{}, nil
+	// Publish the creation of the order
+	s.publishOrderStatusChanged(ctx, order, history)
+
+	// Return the created purchase
+	return &pb.PurchaseProductResponse{Data: toPbPurchase(purchaseProduct)}, nil
+}
+
+// ListDeletedProduct is a method of the productService that retrieves the products in the trash.
+// It validates the admin user, retrieves the deleted products from the repository, and returns the response.
+func (s *productService) ListDeletedProduct(ctx context.Context, req *pb.ListDeletedProductRequest) (*pb.ListDeletedProductResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the list of deleted products from the repository
+	list, err := s.productRepo.ListDeleted(ctx, s.db, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		// If there is an error during product retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve deleted products: %v", err.Error())
+	}
+
+	// Transform the list of deleted products to the response format
+	respData := make([]*pb.ListDeletedProductResponse_DeletedProduct, 0, len(list))
+	for _, product := range list {
+		respData = append(respData, &pb.ListDeletedProductResponse_DeletedProduct{
+			Data:      toPbProduct(product),
+			DeletedAt: timestamppb.New(product.DeletedAt.Time),
+			DeletedBy: product.DeletedBy.Int64,
+		})
+	}
+
+	// Get the total count of deleted products
+	total, err := s.productRepo.CountDeleted(ctx, s.db)
+	if err != nil {
+		// If there is an error during count retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to count deleted products: %v", err.Error())
+	}
+
+	// Return the list of deleted products and total count in the response
+	return &pb.ListDeletedProductResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// RestoreProductByID is a method of the productService that restores a product from the trash.
+// It validates the admin user, restores the product in the repository by ID, and returns an empty response.
+func (s *productService) RestoreProductByID(ctx context.Context, req *pb.RestoreProductByIDRequest) (*pb.RestoreProductByIDResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Restore the product in the repository by ID
+	if err := s.productRepo.RestoreByID(ctx, s.db, req.GetId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not in the trash, return a not found error
+			return nil, status.Errorf(codes.NotFound, "deleted product not found")
+		}
+
+		// If there is an error during product restoration, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to restore product: %v", err.Error())
+	}
+
+	// Publish the restoration of the product
+	s.publishProductChanged(ctx, productChangedAction_Restored, req.GetId())
+
+	// Return an empty response indicating successful restoration
+	return &pb.RestoreProductByIDResponse{}, nil
+}
+
+// NewProductPurgeProcessor returns a processor which periodically hard-deletes
+// the products that stayed in the trash longer than the retention period.
+func NewProductPurgeProcessor(db database.Database, retention time.Duration) processor.Processor {
+	productRepo := postgres.NewProductRepository()
+
+	return processor.NewIntervalProcessor("product-purge", time.Hour, func(ctx context.Context) error {
+		return purgeDeletedProducts(ctx, db, productRepo, retention)
+	})
+}
+
+// productPurgeRepository is the repository of the products purged from the trash.
+type productPurgeRepository interface {
+	PurgeDeletedBefore(ctx context.Context, db database.Executor, before time.Time) (int64, error)
+}
+
+// purgeDeletedProducts hard-deletes the products trashed before the retention period.
+func purgeDeletedProducts(ctx context.Context, db database.Executor, productRepo productPurgeRepository, retention time.Duration) error {
+	purged, err := productRepo.PurgeDeletedBefore(ctx, db, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("unable to purge deleted products: %w", err)
+	}
+
+	slog.Info("purged deleted products", "total", purged)
+
+	return nil
 }
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	msgpb "trintech/review/dto/msg/common"
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/http_server"
+)
+
+// productChangedTopic is the topic of the committed changes of products.
+const productChangedTopic = "PRODUCT_CHANGED"
+
+// The actions of a change of products.
+const (
+	productChangedAction_Created  = "CREATED"
+	productChangedAction_Updated  = "UPDATED"
+	productChangedAction_Deleted  = "DELETED"
+	productChangedAction_Restored = "RESTORED"
+)
+
+// retrieveProduct retrieves a product by ID through the cache. The concurrent misses of a product share one query,
+// which is not cancelled with the context of the caller who started it. The cached products are shared, so they
+// must not be modified.
+func (s *productService) retrieveProduct(ctx context.Context, id int64) (*entity.Product, error) {
+	if product, err := s.productCacheRepo.RetrieveByID(ctx, id); err == nil {
+		return product, nil
+	}
+
+	product, err, _ := s.productLoader.Do(fmt.Sprintf("product|%d", id), func() (any, error) {
+		// The product may have been cached by the query of the previous misses
+		ctx := context.WithoutCancel(ctx)
+		if product, err := s.productCacheRepo.RetrieveByID(ctx, id); err == nil {
+			return product, nil
+		}
+
+		// Load the product, it is not cached when it changes meanwhile
+		generation := s.productCacheRepo.Generation(ctx)
+		product, err := s.productRepo.RetrieveByID(ctx, s.db, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.productCacheRepo.StoreByID(ctx, id, product, generation); err != nil {
+			slog.Error("unable to cache product", "product_id", id, "err", err.Error())
+		}
+
+		return product, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product.(*entity.Product), nil
+}
+
+// listProducts lists a page of products with the total number of products through the cache.
+// The concurrent misses of a page share one query, which is not cancelled with the context of the caller who started it.
+func (s *productService) listProducts(ctx context.Context, offset, limit int64, order entity.ProductOrder) (*entity.ProductPage, error) {
+	key := fmt.Sprintf("%d|%d|%s", offset, limit, order)
+	if page, err := s.productCacheRepo.RetrievePage(ctx, key); err == nil {
+		return page, nil
+	}
+
+	page, err, _ := s.productLoader.Do("page|"+key, func() (any, error) {
+		// The page may have been cached by the query of the previous misses
+		ctx := context.WithoutCancel(ctx)
+		if page, err := s.productCacheRepo.RetrievePage(ctx, key); err == nil {
+			return page, nil
+		}
+
+		// Load the page, it is not cached when a product changes meanwhile
+		generation := s.productCacheRepo.Generation(ctx)
+		products, err := s.productRepo.List(ctx, s.db, offset, limit, order)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve list product: %w", err)
+		}
+		total, err := s.productRepo.Count(ctx, s.db)
+		if err != nil {
+			return nil, fmt.Errorf("unable to count product: %w", err)
+		}
+
+		page := &entity.ProductPage{Products: products, Total: total}
+		if err := s.productCacheRepo.StorePage(ctx, key, page, generation); err != nil {
+			slog.Error("unable to cache product page", "key", key, "err", err.Error())
+		}
+
+		return page, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page.(*entity.ProductPage), nil
+}
+
+// productViews transforms products to the response format priced in a currency and localised, through the cache.
+// The views are cached by version of the product, currency and locales of the request until the next start or end
+// of a scheduled price of their products, and dropped with the changes of the products, their prices and the exchange
+// rates. The compare-at prices follow the price history within the lifetime of a cached view.
+func (s *productService) productViews(ctx context.Context, products []*entity.Product, currency string) ([]*pb.Product, error) {
+	// Serve the cached views, only the missing views are assembled and they are not cached when a product changes meanwhile
+	generation := s.productCacheRepo.Generation(ctx)
+	now := time.Now()
+	locales := strings.Join(http_server.ExtractLocalesFromCtx(ctx), ",")
+	keys := make([]string, len(products))
+	views := make([]*pb.Product, len(products))
+	var (
+		missing    []*entity.Product
+		missingIDs []int64
+	)
+	for i, product := range products {
+		keys[i] = fmt.Sprintf("%d|%d|%s|%s", product.ID.Int64, product.Version.Int64, currency, locales)
+		if view, err := s.productCacheRepo.RetrieveView(ctx, keys[i]); err == nil && (view.ExpiresAt.IsZero() || now.Before(view.ExpiresAt)) {
+			data := &pb.Product{}
+			if err := proto.Unmarshal(view.Data, data); err == nil {
+				views[i] = data
+				continue
+			}
+		}
+		missing = append(missing, product)
+		missingIDs = append(missingIDs, product.ID.Int64)
+	}
+	if len(missing) == 0 {
+		return views, nil
+	}
+
+	// Assemble the missing views, they are valid until the price of one of their products changes
+	nextChange, err := s.scheduledPriceRepo.NextChangeAfter(ctx, s.db, missingIDs, now)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve scheduled prices: %v", err.Error())
+	}
+	assembled, err := s.assembleProductViews(ctx, missing, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the assembled views
+	next := 0
+	for i := range views {
+		if views[i] != nil {
+			continue
+		}
+		views[i] = assembled[next]
+		next++
+
+		data, err := proto.Marshal(views[i])
+		if err != nil {
+			slog.Error("unable to marshal product view", "product_id", views[i].GetId(), "err", err.Error())
+			continue
+		}
+		if err := s.productCacheRepo.StoreView(ctx, keys[i], &entity.ProductView{Data: data, ExpiresAt: nextChange.Time}, generation); err != nil {
+			slog.Error("unable to cache product view", "product_id", views[i].GetId(), "err", err.Error())
+		}
+	}
+
+	return views, nil
+}
+
+// publishProductChanged publishes a change of products, so every replica drops them from its cache.
+// A change without product is a change of every product, such as a change of an exchange rate.
+// The change is already committed, so a failure is only logged.
+func (s *productService) publishProductChanged(ctx context.Context, action string, productIDs ...int64) {
+	data, err := proto.Marshal(&msgpb.ProductChanged{
+		ProductIds: productIDs,
+		Action:     action,
+		ChangedAt:  timestamppb.Now(),
+	})
+	if err != nil {
+		slog.Error("unable to marshal data", "err", err.Error())
+		return
+	}
+
+	var key []byte
+	if len(productIDs) == 1 {
+		key = []byte(strconv.FormatInt(productIDs[0], 10))
+	}
+	if err := s.publisher.Publish(ctx, productChangedTopic, key, data); err != nil {
+		slog.Error("unable to publish product changed message", "product_ids", productIDs, "err", err.Error())
+	}
+}
+
+// SubscribeProductChanged drops the changed products, the pages of the product listings and the views of the products from the cache.
+func (s *productService) SubscribeProductChanged(_, value []byte) {
+	var msg msgpb.ProductChanged
+	if err := proto.Unmarshal(value, &msg); err != nil {
+		slog.Error("unable to unmarshal product changed data", "error", err)
+		return
+	}
+
+	s.invalidateProducts(context.Background(), msg.GetProductIds()...)
+}
+
+// invalidateProducts drops products, the pages of the product listings and the views of the products from the cache.
+func (s *productService) invalidateProducts(ctx context.Context, productIDs ...int64) {
+	for _, id := range productIDs {
+		if err := s.productCacheRepo.RemoveByID(ctx, id); err != nil {
+			slog.Error("unable to remove cached product", "product_id", id, "err", err.Error())
+		}
+	}
+	if err := s.productCacheRepo.RemovePages(ctx); err != nil {
+		slog.Error("unable to remove cached product pages", "err", err.Error())
+	}
+	if err := s.productCacheRepo.RemoveViews(ctx); err != nil {
+		slog.Error("unable to remove cached product views", "err", err.Error())
+	}
+}
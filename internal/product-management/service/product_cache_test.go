@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"trintech/review/internal/product-management/entity"
+	memcache "trintech/review/internal/product-management/repository/cache"
+	"trintech/review/mocks"
+	"trintech/review/pkg/pg_util"
+)
+
+// newCachedProductService returns a product service with an empty cache.
+func newCachedProductService(productRepo *mocks.ProductRepository) *productService {
+	return &productService{
+		productRepo:      productRepo,
+		productCacheRepo: memcache.NewProductCacheRepository(),
+	}
+}
+
+func Test_productService_retrieveProduct(t *testing.T) {
+	release := make(chan time.Time)
+	productRepo := &mocks.ProductRepository{}
+	productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).
+		WaitUntil(release).
+		Return(&entity.Product{ID: pg_util.NullInt64(1), Version: pg_util.NullInt64(1)}, nil).Once()
+	s := newCachedProductService(productRepo)
+
+	// The concurrent misses share one query
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product, err := s.retrieveProduct(context.Background(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), product.Version.Int64)
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	// The next reads are served by the cache
+	product, err := s.retrieveProduct(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), product.Version.Int64)
+	productRepo.AssertNumberOfCalls(t, "RetrieveByID", 1)
+
+	// A change of the product drops it from the cache
+	productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).
+		Return(&entity.Product{ID: pg_util.NullInt64(1), Version: pg_util.NullInt64(2)}, nil).Once()
+	s.invalidateProducts(context.Background(), 1)
+	product, err = s.retrieveProduct(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), product.Version.Int64)
+	productRepo.AssertNumberOfCalls(t, "RetrieveByID", 2)
+
+	// A product changed while it is loaded is not cached
+	s.invalidateProducts(context.Background(), 1)
+	productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).
+		Run(func(mock.Arguments) { s.invalidateProducts(context.Background(), 1) }).
+		Return(&entity.Product{ID: pg_util.NullInt64(1), Version: pg_util.NullInt64(2)}, nil).Once()
+	productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).
+		Return(&entity.Product{ID: pg_util.NullInt64(1), Version: pg_util.NullInt64(3)}, nil).Once()
+	product, err = s.retrieveProduct(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), product.Version.Int64)
+	product, err = s.retrieveProduct(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), product.Version.Int64)
+	productRepo.AssertNumberOfCalls(t, "RetrieveByID", 4)
+}
+
+func Test_productService_listProducts(t *testing.T) {
+	productRepo := &mocks.ProductRepository{}
+	productRepo.On("List", mock.Anything, mock.Anything, int64(0), int64(10), entity.ProductOrder_Default).
+		Return([]*entity.Product{{ID: pg_util.NullInt64(1)}}, nil).Once()
+	productRepo.On("Count", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
+	s := newCachedProductService(productRepo)
+
+	// The page is queried once
+	for i := 0; i < 2; i++ {
+		page, err := s.listProducts(context.Background(), 0, 10, entity.ProductOrder_Default)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), page.Total)
+		require.Len(t, page.Products, 1)
+	}
+	productRepo.AssertNumberOfCalls(t, "List", 1)
+
+	// A created product drops every page
+	productRepo.On("List", mock.Anything, mock.Anything, int64(0), int64(10), entity.ProductOrder_Default).
+		Return([]*entity.Product{{ID: pg_util.NullInt64(1)}, {ID: pg_util.NullInt64(2)}}, nil).Once()
+	productRepo.On("Count", mock.Anything, mock.Anything).Return(int64(2), nil).Once()
+	s.invalidateProducts(context.Background(), 2)
+	page, err := s.listProducts(context.Background(), 0, 10, entity.ProductOrder_Default)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), page.Total)
+	require.Len(t, page.Products, 2)
+	productRepo.AssertExpectations(t)
+
+	// A page loaded while a product changes is not cached
+	s.invalidateProducts(context.Background(), 3)
+	productRepo.On("List", mock.Anything, mock.Anything, int64(0), int64(10), entity.ProductOrder_Default).
+		Run(func(mock.Arguments) { s.invalidateProducts(context.Background(), 3) }).
+		Return([]*entity.Product{{ID: pg_util.NullInt64(1)}, {ID: pg_util.NullInt64(2)}}, nil).Once()
+	productRepo.On("List", mock.Anything, mock.Anything, int64(0), int64(10), entity.ProductOrder_Default).
+		Return([]*entity.Product{{ID: pg_util.NullInt64(1)}, {ID: pg_util.NullInt64(2)}, {ID: pg_util.NullInt64(3)}}, nil).Once()
+	productRepo.On("Count", mock.Anything, mock.Anything).Return(int64(3), nil)
+	for _, want := range []int{2, 3} {
+		page, err := s.listProducts(context.Background(), 0, 10, entity.ProductOrder_Default)
+		require.NoError(t, err)
+		require.Len(t, page.Products, want)
+	}
+	productRepo.AssertNumberOfCalls(t, "List", 4)
+}
+
+func Test_productService_productViews(t *testing.T) {
+	scheduledPriceRepo := &mocks.ScheduledPriceRepository{}
+	scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+	scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(sql.NullTime{}, nil)
+	priceHistoryRepo := &mocks.PriceHistoryRepository{}
+	priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+	productPriceRepo := &mocks.ProductPriceRepository{}
+	productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{1}).Return(nil, nil)
+	productImageRepo := &mocks.ProductImageRepository{}
+	productImageRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{1}).Return(nil, nil)
+	s := &productService{
+		scheduledPriceRepo: scheduledPriceRepo,
+		priceHistoryRepo:   priceHistoryRepo,
+		productPriceRepo:   productPriceRepo,
+		productImageRepo:   productImageRepo,
+		productCacheRepo:   memcache.NewProductCacheRepository(),
+		baseCurrency:       "USD",
+	}
+	products := []*entity.Product{{ID: pg_util.NullInt64(1), Version: pg_util.NullInt64(1), Price: pg_util.NullInt64(1000), Currency: pg_util.NullString("USD")}}
+
+	// The view is assembled once
+	views, err := s.productViews(context.Background(), products, "USD")
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	require.Equal(t, int64(1000), views[0].GetPrice().GetAmount())
+	views, err = s.productViews(context.Background(), products, "USD")
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	scheduledPriceRepo.AssertNumberOfCalls(t, "ListActive", 1)
+	productPriceRepo.AssertNumberOfCalls(t, "ListByProductIDs", 1)
+
+	// A change of the prices drops the views
+	s.invalidateProducts(context.Background())
+	_, err = s.productViews(context.Background(), products, "USD")
+	require.NoError(t, err)
+	scheduledPriceRepo.AssertNumberOfCalls(t, "ListActive", 2)
+
+	// A view expires with the next change of a scheduled price
+	scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, []int64{2}, mock.Anything).
+		Return(pg_util.NullTime(time.Now().Add(-time.Second)), nil)
+	scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{2}, mock.Anything).Return(nil, nil)
+	priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, []int64{2}, mock.Anything).Return(nil, nil)
+	productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{2}).Return(nil, nil)
+	productImageRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{2}).Return(nil, nil)
+	expiring := []*entity.Product{{ID: pg_util.NullInt64(2), Version: pg_util.NullInt64(1), Price: pg_util.NullInt64(500), Currency: pg_util.NullString("USD")}}
+	for i := 0; i < 2; i++ {
+		_, err := s.productViews(context.Background(), expiring, "USD")
+		require.NoError(t, err)
+	}
+	scheduledPriceRepo.AssertNumberOfCalls(t, "ListActive", 4)
+}
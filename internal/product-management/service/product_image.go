@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/pg_util"
+)
+
+const (
+	// maxProductImages is the maximum number of images of a product.
+	maxProductImages = 10
+	// maxAltTextLength is the maximum length of the alt text of an image.
+	maxAltTextLength = 250
+	// maxRetrieveFiles is the maximum number of files storage-management retrieves at once.
+	maxRetrieveFiles = 100
+)
+
+// retrieveFiles retrieves the files of storage-management by id, the unknown ids are left out.
+func (s *productService) retrieveFiles(ctx context.Context, ids []string) (map[string]*storagepb.File, error) {
+	files := make(map[string]*storagepb.File, len(ids))
+	for start := 0; start < len(ids); start += maxRetrieveFiles {
+		end := min(start+maxRetrieveFiles, len(ids))
+		resp, err := s.storageServiceClient.RetrieveFiles(http_server.InjectIncomingCtxToOutgoingCtx(ctx), &storagepb.RetrieveFilesRequest{
+			Ids: ids[start:end],
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to retrieve files: %v", err.Error())
+		}
+		for _, file := range resp.GetData() {
+			files[file.GetId()] = file
+		}
+	}
+
+	return files, nil
+}
+
+// validProductImages validates the images of a request against the files of storage-management and returns them
+// in their order without product. The first image is the primary image when none is.
+func (s *productService) validProductImages(ctx context.Context, images []*pb.ProductImage) ([]*entity.ProductImage, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	if len(images) > maxProductImages {
+		return nil, status.Errorf(codes.InvalidArgument, "too many images, at most %d", maxProductImages)
+	}
+
+	// Validate the images
+	ids := make([]string, 0, len(images))
+	seen := make(map[string]bool, len(images))
+	primary, hasPrimary := 0, false
+	for i, image := range images {
+		id := strings.TrimSpace(image.GetFileId())
+		switch {
+		case id == "":
+			return nil, status.Errorf(codes.InvalidArgument, "file_id is required")
+		case seen[id]:
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate image %s", id)
+		case len(image.GetAltText()) > maxAltTextLength:
+			return nil, status.Errorf(codes.InvalidArgument, "alt_text is too long")
+		}
+		if image.GetIsPrimary() {
+			if hasPrimary {
+				return nil, status.Errorf(codes.InvalidArgument, "only one image can be primary")
+			}
+			primary, hasPrimary = i, true
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	// Check that the images are uploaded image files
+	files, err := s.retrieveFiles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*entity.ProductImage, 0, len(images))
+	for i, id := range ids {
+		file, ok := files[id]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "image file %s not found", id)
+		}
+		if !strings.HasPrefix(file.GetMimeType(), "image/") {
+			return nil, status.Errorf(codes.InvalidArgument, "file %s is not an image", id)
+		}
+
+		result = append(result, &entity.ProductImage{
+			FileID:    pg_util.NullString(id),
+			Position:  pg_util.NullInt64(int64(i)),
+			AltText:   pg_util.NullEmptyString(strings.TrimSpace(images[i].GetAltText())),
+			IsPrimary: sql.NullBool{Bool: i == primary, Valid: true},
+		})
+	}
+
+	return result, nil
+}
+
+// replaceProductImages replaces the images of a product.
+func (s *productService) replaceProductImages(ctx context.Context, db database.Executor, productID int64, images []*entity.ProductImage) error {
+	if err := s.productImageRepo.DeleteByProductID(ctx, db, productID); err != nil {
+		return fmt.Errorf("unable to delete product images: %v", err)
+	}
+	for _, image := range images {
+		image.ProductID = pg_util.NullInt64(productID)
+		if err := s.productImageRepo.Create(ctx, db, image); err != nil {
+			return fmt.Errorf("unable to create product image: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// productImages retrieves the images of products in their order by product, with the URLs their files are served at.
+// The images of the files which are gone from storage-management are left out.
+func (s *productService) productImages(ctx context.Context, productIDs []int64) (map[int64][]*pb.ProductImage, error) {
+	// Retrieve the images of the products
+	images, err := s.productImageRepo.ListByProductIDs(ctx, s.db, productIDs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product images: %v", err.Error())
+	}
+	if len(images) == 0 {
+		return nil, nil
+	}
+
+	// Resolve the files of the images
+	ids := make([]string, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.FileID.String)
+	}
+	files, err := s.retrieveFiles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transform the images to the response format
+	result := make(map[int64][]*pb.ProductImage, len(productIDs))
+	for _, image := range images {
+		file, ok := files[image.FileID.String]
+		if !ok {
+			continue
+		}
+		result[image.ProductID.Int64] = append(result[image.ProductID.Int64], &pb.ProductImage{
+			FileId:    image.FileID.String,
+			Url:       file.GetUrl(),
+			AltText:   image.AltText.String,
+			IsPrimary: image.IsPrimary.Bool,
+		})
+	}
+
+	return result, nil
+}
+
+// imageURLs returns the URLs of images, the legacy image_urls of a product are derived from its images.
+func imageURLs(images []*pb.ProductImage) []string {
+	urls := make([]string, 0, len(images))
+	for _, image := range images {
+		urls = append(urls, image.GetUrl())
+	}
+
+	return urls
+}
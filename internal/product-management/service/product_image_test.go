@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_validProductImages(t *testing.T) {
+	files := []*storagepb.File{
+		{Id: "f1", MimeType: "image/png"},
+		{Id: "f2", MimeType: "image/jpeg"},
+		{Id: "doc", MimeType: "application/pdf"},
+	}
+	tests := []struct {
+		name    string
+		images  []*pb.ProductImage
+		want    []*entity.ProductImage
+		wantErr error
+	}{
+		{
+			name:   "happy case first image is primary by default",
+			images: []*pb.ProductImage{{FileId: "f1", AltText: " Front "}, {FileId: "f2"}},
+			want: []*entity.ProductImage{
+				{FileID: pg_util.NullString("f1"), Position: pg_util.NullInt64(0), AltText: pg_util.NullString("Front"), IsPrimary: sql.NullBool{Bool: true, Valid: true}},
+				{FileID: pg_util.NullString("f2"), Position: pg_util.NullInt64(1), IsPrimary: sql.NullBool{Valid: true}},
+			},
+		},
+		{
+			name:   "happy case primary image",
+			images: []*pb.ProductImage{{FileId: "f1"}, {FileId: "f2", IsPrimary: true}},
+			want: []*entity.ProductImage{
+				{FileID: pg_util.NullString("f1"), Position: pg_util.NullInt64(0), IsPrimary: sql.NullBool{Valid: true}},
+				{FileID: pg_util.NullString("f2"), Position: pg_util.NullInt64(1), IsPrimary: sql.NullBool{Bool: true, Valid: true}},
+			},
+		},
+		{
+			name:    "err duplicate image",
+			images:  []*pb.ProductImage{{FileId: "f1"}, {FileId: "f1"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "duplicate image f1"),
+		},
+		{
+			name:    "err several primary images",
+			images:  []*pb.ProductImage{{FileId: "f1", IsPrimary: true}, {FileId: "f2", IsPrimary: true}},
+			wantErr: status.Errorf(codes.InvalidArgument, "only one image can be primary"),
+		},
+		{
+			name:    "err file not uploaded",
+			images:  []*pb.ProductImage{{FileId: "f1"}, {FileId: "missing"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "image file missing not found"),
+		},
+		{
+			name:    "err file not an image",
+			images:  []*pb.ProductImage{{FileId: "doc"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "file doc is not an image"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageServiceClient := &mocks.UploadServiceClient{}
+			storageServiceClient.On("RetrieveFiles", mock.Anything, mock.Anything, mock.Anything).
+				Return(&storagepb.RetrieveFilesResponse{Data: files}, nil)
+			s := &productService{
+				storageServiceClient: storageServiceClient,
+			}
+			got, err := s.validProductImages(context.Background(), tt.images)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/pg_util"
+)
+
+// importColumns are the product columns written by an import, the keys are added when they are set.
+// The image URLs are derived from the images of the products, so they are not imported.
+var importColumns = []string{"name", "type", "description", "price"}
+
+// rowColumns returns the product columns written by an import of a row to an existing product,
+// which are the columns present in the row or every column when the row does not list them.
+func rowColumns(row *pb.ProductRow) []string {
+	if len(row.GetColumns()) == 0 {
+		return slices.Clone(importColumns)
+	}
+
+	var columns []string
+	for _, column := range importColumns {
+		// The currency of a row is part of its price
+		if slices.Contains(row.GetColumns(), column) || (column == "price" && slices.Contains(row.GetColumns(), "currency")) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// ImportProduct is a method of the productService that upserts a stream of catalogue rows.
+// Every row is validated and written on its own, so a bad row is reported without stopping the import.
+// In dry-run mode rows are validated and matched against the catalogue without being written.
+func (s *productService) ImportProduct(stream pb.ProductService_ImportProductServer) error {
+	ctx := stream.Context()
+
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp := &pb.ImportProductResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return status.Errorf(codes.Unknown, "unable to receive product row: %v", err)
+		}
+
+		switch data := req.GetData().(type) {
+		case *pb.ImportProductRequest_Options_:
+			// Options only apply to the whole import
+			if resp.Total > 0 {
+				return status.Errorf(codes.InvalidArgument, "options must be sent before the first row")
+			}
+			resp.DryRun = data.Options.GetDryRun()
+		case *pb.ImportProductRequest_Row:
+			resp.Total++
+			created, err := s.importProductRow(ctx, data.Row, resp.DryRun, userCtx.UserID)
+			switch {
+			case err != nil:
+				resp.Errors = append(resp.Errors, &pb.ImportProductResponse_RowError{
+					Line:       data.Row.GetLine(),
+					Sku:        data.Row.GetSku(),
+					ExternalId: data.Row.GetExternalId(),
+					Message:    err.Error(),
+				})
+			case created:
+				resp.Created++
+			default:
+				resp.Updated++
+			}
+		}
+	}
+
+	// Send the import summary with the per-row errors
+	if err := stream.SendAndClose(resp); err != nil {
+		return status.Errorf(codes.Unknown, "unable to response: %v", err)
+	}
+
+	return nil
+}
+
+// importProductRow validates a catalogue row and creates or updates the product with the same sku or external id.
+// It reports whether the product is new.
+func (s *productService) importProductRow(ctx context.Context, row *pb.ProductRow, dryRun bool, userID int64) (bool, error) {
+	// Validate the row
+	switch {
+	case row.GetSku() == "" && row.GetExternalId() == "":
+		return false, fmt.Errorf("sku or external_id is required")
+	case row.GetPrice().GetAmount() < 0:
+		return false, fmt.Errorf("price must not be negative")
+	case row.GetPrice().GetCurrency() != "" && row.GetPrice().GetCurrency() != s.baseCurrency:
+		return false, fmt.Errorf("price must be in %s", s.baseCurrency)
+	}
+
+	// Find the product to update by its keys
+	products, err := s.productRepo.ListByKeys(ctx, s.db, row.GetSku(), row.GetExternalId())
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve product: %w", err)
+	}
+	if len(products) > 1 {
+		return false, fmt.Errorf("sku and external_id belong to different products")
+	}
+	if len(products) == 1 && products[0].DeletedAt.Valid {
+		return false, fmt.Errorf("product %d is in the trash", products[0].ID.Int64)
+	}
+
+	// A new product needs a name, as well as an existing product whose name is imported
+	created := len(products) == 0
+	columns := rowColumns(row)
+	if row.GetName() == "" && (created || slices.Contains(columns, "name")) {
+		return false, fmt.Errorf("name is required")
+	}
+	if dryRun {
+		return created, nil
+	}
+
+	data := &entity.Product{
+		Name:        pg_util.NullString(row.GetName()),
+		Type:        pg_util.NullString(row.GetType()),
+		Description: pg_util.NullString(row.GetDescription()),
+		Price:       pg_util.NullInt64(row.GetPrice().GetAmount()),
+		Currency:    pg_util.NullString(s.baseCurrency),
+		SKU:         pg_util.NullEmptyString(row.GetSku()),
+		ExternalID:  pg_util.NullEmptyString(row.GetExternalId()),
+	}
+
+	price := productPrice(data)
+
+	// Create the product when no product matches the keys, with the start of its price history
+	if created {
+		data.CreatedBy = pg_util.NullInt64(userID)
+		var id int64
+		if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			if id, err = s.productRepo.Create(ctx, tx, data); err != nil {
+				return fmt.Errorf("unable to create product: %w", err)
+			}
+
+			return s.recordPriceChange(ctx, tx, id, price, userID, entity.PriceChangeReason_Imported)
+		}); err != nil {
+			return false, err
+		}
+		s.publishProductChanged(ctx, productChangedAction_Created, id)
+
+		return true, nil
+	}
+
+	// Otherwise update the columns of the row, without clearing a key which is not part of the row
+	if row.GetSku() != "" {
+		columns = append(columns, "sku")
+	}
+	if row.GetExternalId() != "" {
+		columns = append(columns, "external_id")
+	}
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.productRepo.UpdateByID(ctx, tx, products[0].ID.Int64, data, columns); err != nil {
+			return fmt.Errorf("unable to update product: %w", err)
+		}
+
+		if slices.Contains(columns, "price") && products[0].Price.Int64 != price.Amount {
+			return s.recordPriceChange(ctx, tx, products[0].ID.Int64, price, userID, entity.PriceChangeReason_Imported)
+		}
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	s.publishProductChanged(ctx, productChangedAction_Updated, products[0].ID.Int64)
+
+	return false, nil
+}
+
+// ExportProduct is a method of the productService that streams every product of the catalogue.
+func (s *productService) ExportProduct(_ *pb.ExportProductRequest, stream pb.ProductService_ExportProductServer) error {
+	ctx := stream.Context()
+
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return err
+	}
+
+	// Send the products one by one while they are read from the repository
+	if err := s.productRepo.Iterate(ctx, s.db, func(product *entity.Product) error {
+		return stream.Send(&pb.ExportProductResponse{
+			Data: &pb.ProductRow{
+				Sku:         product.SKU.String,
+				ExternalId:  product.ExternalID.String,
+				Name:        product.Name.String,
+				Type:        product.Type.String,
+				ImageUrls:   pg_util.StringArrayValue(product.ImageURLs),
+				Description: product.Description.String,
+				Price:       toPbMoney(productPrice(product)),
+			},
+		})
+	}); err != nil {
+		return status.Errorf(codes.Internal, "unable to export products: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+// importProductStream is an import stream which receives the given requests.
+type importProductStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	reqs []*pb.ImportProductRequest
+	resp *pb.ImportProductResponse
+}
+
+func (s *importProductStream) Context() context.Context { return s.ctx }
+
+func (s *importProductStream) Recv() (*pb.ImportProductRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+
+	return req, nil
+}
+
+func (s *importProductStream) SendAndClose(resp *pb.ImportProductResponse) error {
+	s.resp = resp
+
+	return nil
+}
+
+// exportProductStream is an export stream which keeps the sent rows.
+type exportProductStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	rows []*pb.ProductRow
+}
+
+func (s *exportProductStream) Context() context.Context { return s.ctx }
+
+func (s *exportProductStream) Send(resp *pb.ExportProductResponse) error {
+	s.rows = append(s.rows, resp.GetData())
+
+	return nil
+}
+
+// importRows returns the import requests of the options followed by the rows.
+func importRows(options *pb.ImportProductRequest_Options, rows ...*pb.ProductRow) []*pb.ImportProductRequest {
+	var reqs []*pb.ImportProductRequest
+	if options != nil {
+		reqs = append(reqs, &pb.ImportProductRequest{Data: &pb.ImportProductRequest_Options_{Options: options}})
+	}
+	for _, row := range rows {
+		reqs = append(reqs, &pb.ImportProductRequest{Data: &pb.ImportProductRequest_Row{Row: row}})
+	}
+
+	return reqs
+}
+
+func Test_productService_ImportProduct(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 2,
+		Role:   userEntity.UserRole_User,
+	}))
+	existing := func(id, price int64) []*entity.Product {
+		return []*entity.Product{{ID: pg_util.NullInt64(id), Price: pg_util.NullInt64(price), Currency: pg_util.NullString("USD")}}
+	}
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		reqs       []*pb.ImportProductRequest
+		want       *pb.ImportProductResponse
+		wantErr    error
+		wantWrites bool
+		setup      func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock)
+	}{
+		{
+			name: "happy case updates the columns of the header",
+			ctx:  adminCtx,
+			reqs: importRows(nil,
+				&pb.ProductRow{Line: 2, Sku: "NEW-1", Name: "Shirt", Price: &pb.Money{Amount: 1000}, Columns: []string{"sku", "name", "price"}},
+				&pb.ProductRow{Line: 3, Sku: "OLD-1", Description: "slim fit", Columns: []string{"sku", "description"}},
+			),
+			want:       &pb.ImportProductResponse{Total: 2, Created: 1, Updated: 1},
+			wantWrites: true,
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "NEW-1", "").Return(nil, nil)
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "").Return(existing(5, 500), nil)
+				smock.ExpectBegin()
+				productRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(product *entity.Product) bool {
+					return product.SKU.String == "NEW-1" && product.Name.String == "Shirt" && product.CreatedBy.Int64 == 9
+				})).Return(int64(4), nil)
+				priceHistoryRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(history *entity.PriceHistory) bool {
+					return history.ProductID.Int64 == 4 && history.Price.Int64 == 1000
+				})).Return(nil).Once()
+				smock.ExpectCommit()
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(5), mock.MatchedBy(func(product *entity.Product) bool {
+					return product.Description.String == "slim fit"
+				}), []string{"description", "sku"}).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case currency imports the price",
+			ctx:  adminCtx,
+			reqs: importRows(nil,
+				&pb.ProductRow{Line: 2, ExternalId: "ext-1", Price: &pb.Money{Amount: 800, Currency: "USD"}, Columns: []string{"external_id", "currency"}},
+			),
+			want:       &pb.ImportProductResponse{Total: 1, Updated: 1},
+			wantWrites: true,
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "", "ext-1").Return(existing(5, 500), nil)
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(5), mock.Anything, []string{"price", "external_id"}).Return(nil)
+				priceHistoryRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(history *entity.PriceHistory) bool {
+					return history.ProductID.Int64 == 5 && history.Price.Int64 == 800
+				})).Return(nil).Once()
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case row without columns updates every column",
+			ctx:  adminCtx,
+			reqs: importRows(nil,
+				&pb.ProductRow{Line: 1, Sku: "OLD-1", Name: "Shirt", Price: &pb.Money{Amount: 500}},
+			),
+			want:       &pb.ImportProductResponse{Total: 1, Updated: 1},
+			wantWrites: true,
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "").Return(existing(5, 500), nil)
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(5), mock.Anything,
+					[]string{"name", "type", "description", "price", "sku"}).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case dry run",
+			ctx:  adminCtx,
+			reqs: importRows(&pb.ImportProductRequest_Options{DryRun: true},
+				&pb.ProductRow{Line: 2, Sku: "NEW-1", Name: "Shirt", Columns: []string{"sku", "name"}},
+				&pb.ProductRow{Line: 3, Sku: "OLD-1", Name: "Jeans", Columns: []string{"sku", "name"}},
+			),
+			want: &pb.ImportProductResponse{Total: 2, Created: 1, Updated: 1, DryRun: true},
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "NEW-1", "").Return(nil, nil)
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "").Return(existing(5, 500), nil)
+			},
+		},
+		{
+			name: "happy case row errors",
+			ctx:  adminCtx,
+			reqs: importRows(nil,
+				&pb.ProductRow{Line: 2, Name: "Shirt"},
+				&pb.ProductRow{Line: 3, Sku: "NEG-1", Name: "Shirt", Price: &pb.Money{Amount: -1}},
+				&pb.ProductRow{Line: 4, Sku: "EUR-1", Name: "Shirt", Price: &pb.Money{Amount: 100, Currency: "EUR"}},
+				&pb.ProductRow{Line: 5, Sku: "NEW-1", Columns: []string{"sku", "price"}},
+				&pb.ProductRow{Line: 6, Sku: "OLD-1", Columns: []string{"sku", "name"}},
+				&pb.ProductRow{Line: 7, Sku: "DEL-1", Name: "Shirt"},
+				&pb.ProductRow{Line: 8, Sku: "OLD-1", ExternalId: "ext-2", Name: "Shirt"},
+			),
+			want: &pb.ImportProductResponse{
+				Total: 7,
+				Errors: []*pb.ImportProductResponse_RowError{
+					{Line: 2, Message: "sku or external_id is required"},
+					{Line: 3, Sku: "NEG-1", Message: "price must not be negative"},
+					{Line: 4, Sku: "EUR-1", Message: "price must be in USD"},
+					{Line: 5, Sku: "NEW-1", Message: "name is required"},
+					{Line: 6, Sku: "OLD-1", Message: "name is required"},
+					{Line: 7, Sku: "DEL-1", Message: "product 6 is in the trash"},
+					{Line: 8, Sku: "OLD-1", ExternalId: "ext-2", Message: "sku and external_id belong to different products"},
+				},
+			},
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "NEW-1", "").Return(nil, nil)
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "").Return(existing(5, 500), nil)
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "DEL-1", "").Return([]*entity.Product{
+					{ID: pg_util.NullInt64(6), DeletedAt: pg_util.NullTime(time.Now())},
+				}, nil)
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "ext-2").Return(append(existing(5, 500), existing(7, 500)...), nil)
+			},
+		},
+		{
+			name: "happy case failed write is a row error",
+			ctx:  adminCtx,
+			reqs: importRows(nil,
+				&pb.ProductRow{Line: 2, Sku: "OLD-1", Name: "Shirt", Columns: []string{"sku", "name"}},
+			),
+			want: &pb.ImportProductResponse{
+				Total: 1,
+				Errors: []*pb.ImportProductResponse_RowError{
+					{Line: 2, Sku: "OLD-1", Message: "unable to update product: conn closed"},
+				},
+			},
+			wantWrites: true,
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+				productRepo.On("ListByKeys", mock.Anything, mock.Anything, "OLD-1", "").Return(existing(5, 500), nil)
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(5), mock.Anything, []string{"name", "sku"}).
+					Return(fmt.Errorf("conn closed"))
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name: "err options after a row",
+			ctx:  adminCtx,
+			reqs: append(
+				importRows(&pb.ImportProductRequest_Options{}, &pb.ProductRow{Line: 2, Name: "Shirt"}),
+				importRows(&pb.ImportProductRequest_Options{DryRun: true})...,
+			),
+			wantErr: status.Errorf(codes.InvalidArgument, "options must be sent before the first row"),
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+			},
+		},
+		{
+			name:    "err permission denied",
+			ctx:     userCtx,
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup: func(productRepo *mocks.ProductRepository, priceHistoryRepo *mocks.PriceHistoryRepository, smock sqlmock.Sqlmock) {
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			productRepo := &mocks.ProductRepository{}
+			priceHistoryRepo := &mocks.PriceHistoryRepository{}
+			publisher := &mocks.Publisher{}
+			publisher.On("Publish", mock.Anything, "PRODUCT_CHANGED", mock.Anything, mock.Anything).Return(nil)
+			tt.setup(productRepo, priceHistoryRepo, smock)
+			s := &productService{
+				productRepo:      productRepo,
+				priceHistoryRepo: priceHistoryRepo,
+				publisher:        publisher,
+				baseCurrency:     "USD",
+				db:               &postgres_client.PostgresClient{DB: db},
+			}
+			stream := &importProductStream{ctx: tt.ctx, reqs: tt.reqs}
+			err := s.ImportProduct(stream)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				productRepo.AssertNotCalled(t, "ListByKeys", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, stream.resp)
+			productRepo.AssertExpectations(t)
+			priceHistoryRepo.AssertExpectations(t)
+			if !tt.wantWrites {
+				productRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				productRepo.AssertNotCalled(t, "UpdateByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_productService_ExportProduct(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	products := []*entity.Product{
+		{
+			SKU:       pg_util.NullString("SHIRT-1"),
+			Name:      pg_util.NullString("Shirt"),
+			ImageURLs: pg_util.StringArray([]string{"a.png"}),
+			Price:     pg_util.NullInt64(1050),
+			Currency:  pg_util.NullString("USD"),
+		},
+		{
+			ExternalID: pg_util.NullString("ext-2"),
+			Name:       pg_util.NullString("Jeans"),
+			Price:      pg_util.NullInt64(2000),
+			Currency:   pg_util.NullString("USD"),
+		},
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    []*pb.ProductRow
+		wantErr error
+		setup   func(productRepo *mocks.ProductRepository)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			want: []*pb.ProductRow{
+				{Sku: "SHIRT-1", Name: "Shirt", ImageUrls: []string{"a.png"}, Price: &pb.Money{Amount: 1050, Currency: "USD"}},
+				{ExternalId: "ext-2", Name: "Jeans", ImageUrls: []string{}, Price: &pb.Money{Amount: 2000, Currency: "USD"}},
+			},
+			setup: func(productRepo *mocks.ProductRepository) {
+				productRepo.On("Iterate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					fn := args.Get(2).(func(*entity.Product) error)
+					for _, product := range products {
+						require.NoError(t, fn(product))
+					}
+				}).Return(nil)
+			},
+		},
+		{
+			name:    "err iterate products",
+			ctx:     adminCtx,
+			wantErr: status.Errorf(codes.Internal, "unable to export products: conn closed"),
+			setup: func(productRepo *mocks.ProductRepository) {
+				productRepo.On("Iterate", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("conn closed"))
+			},
+		},
+		{
+			name:    "err permission denied",
+			ctx:     context.Background(),
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(productRepo *mocks.ProductRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &mocks.ProductRepository{}
+			tt.setup(productRepo)
+			s := &productService{productRepo: productRepo}
+			stream := &exportProductStream{ctx: tt.ctx}
+			err := s.ExportProduct(&pb.ExportProductRequest{}, stream)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, stream.rows)
+			productRepo.AssertExpectations(t)
+		})
+	}
+}
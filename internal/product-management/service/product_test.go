@@ -4,7 +4,10 @@ package service
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/mock"
@@ -12,23 +15,34 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	couponpb "trintech/review/dto/coupon-management/coupon"
 	pb "trintech/review/dto/product-management/product"
 	"trintech/review/internal/product-management/entity"
+	"trintech/review/internal/product-management/repository/postgres"
 	userEntity "trintech/review/internal/user-management/entity"
 	"trintech/review/mocks"
+	"trintech/review/pkg/database"
 	"trintech/review/pkg/http_server"
 	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/payment"
 	"trintech/review/pkg/pg_util"
 	"trintech/review/pkg/postgres_client"
+	"trintech/review/pkg/tax"
 )
 
 func Test_productService_PurchaseProduct(t *testing.T) {
 	type fields struct {
 		productRepo          *mocks.ProductRepository
 		purchasedProductRepo *mocks.PurchasedProductRepository
+		orderRepo            *mocks.OrderRepository
+		scheduledPriceRepo   *mocks.ScheduledPriceRepository
+		taxRateRepo          *mocks.TaxRateRepository
+		addressRepo          *mocks.AddressRepository
+		shippingMethodRepo   *mocks.ShippingMethodRepository
+		publisher            *mocks.Publisher
 
 		db                  *postgres_client.PostgresClient
 		couponServiceClient *mocks.CouponServiceClient
@@ -53,6 +67,9 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			fields: fields{
 				productRepo:          &mocks.ProductRepository{},
 				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
 				db: &postgres_client.PostgresClient{
 					DB: db,
 				},
@@ -68,31 +85,283 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 					Coupon: wrapperspb.String("ABC"),
 				},
 			},
-			want: &pb.PurchaseProductResponse{},
+			want: &pb.PurchaseProductResponse{
+				Data: &pb.Purchase{
+					Id:        3,
+					ProductId: 1,
+					UserId:    1,
+					OrderId:   1,
+					Price:     &pb.Money{Amount: 10000, Currency: "USD"},
+					Quantity:  1,
+					Discount:  &pb.Money{Amount: 5000, Currency: "USD"},
+					Total:     &pb.Money{Amount: 5000, Currency: "USD"},
+					Coupon:    "ABC",
+
+					PaymentId:     "pay_fake_1",
+					PaymentStatus: "CAPTURED",
+					Refunded:      &pb.Money{Amount: 0, Currency: "USD"},
+					Tax:           &pb.Money{Amount: 0, Currency: "USD"},
+					Shipping:      &pb.Money{Amount: 0, Currency: "USD"},
+				},
+			},
 			setup: func(ctx context.Context, fields fields) {
 				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
-					ID:    pg_util.NullInt64(1),
-					Price: pg_util.NullFloat64(100),
+					ID:       pg_util.NullInt64(1),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
 				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
 
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
 				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
 					Return(&couponpb.RetrieveCouponByCodeResponse{
 						CanUse:       true,
-						Value:        50,
+						Value:        &couponpb.Money{Amount: 5000, Currency: "USD"},
 						DiscountType: couponpb.DiscountType_DiscountType_VALUE,
 					}, nil)
 
+				fields.couponServiceClient.On("ReserveCoupon", mock.Anything, mock.MatchedBy(func(req *couponpb.ReserveCouponRequest) bool {
+					return req.GetRedemptionId() != "" && req.GetCode() == "ABC" && req.GetUserId() == 1
+				}), mock.Anything).Return(&couponpb.ReserveCouponResponse{}, nil)
+
 				smock.ExpectBegin()
-				fields.purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				fields.couponServiceClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&couponpb.ApplyCouponResponse{}, nil)
+				fields.orderRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(order *entity.Order) bool {
+					return order.Status.String == entity.OrderStatus_Paid && order.Discount.Int64 == 5000 && order.Total.Int64 == 5000 && order.Currency.String == "USD" &&
+						order.CouponRedemptionID.String != ""
+				})).Return(int64(1), nil)
+				fields.orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.orderRepo.On("CreateDiscount", mock.Anything, mock.Anything, mock.MatchedBy(func(discount *entity.OrderDiscount) bool {
+					return discount.Coupon.String == "ABC" && discount.DiscountType.String == couponpb.DiscountType_DiscountType_VALUE.String() &&
+						discount.Value.Int64 == 5000 && discount.Amount.Int64 == 5000
+				})).Return(nil)
+				fields.orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.OrderID.Int64 == 1 && purchase.Discount.Int64 == 5000 && purchase.Total.Int64 == 5000 &&
+						purchase.PaymentID.String == "pay_fake_1" && purchase.PaymentStatus.String == "CAPTURED"
+				})).Return(int64(3), nil)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_CONFIRM", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 				smock.ExpectCommit()
+				fields.publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "happy case exclusive tax",
+			fields: fields{
+				productRepo:          &mocks.ProductRepository{},
+				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				taxRateRepo:          &mocks.TaxRateRepository{},
+				publisher:            &mocks.Publisher{},
+				db: &postgres_client.PostgresClient{
+					DB: db,
+				},
+				couponServiceClient: &mocks.CouponServiceClient{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:     1,
+					Region: "us-ca",
+				},
+			},
+			want: &pb.PurchaseProductResponse{
+				Data: &pb.Purchase{
+					Id:        3,
+					ProductId: 1,
+					UserId:    1,
+					OrderId:   1,
+					Price:     &pb.Money{Amount: 10000, Currency: "USD"},
+					Quantity:  1,
+					Discount:  &pb.Money{Amount: 0, Currency: "USD"},
+					Total:     &pb.Money{Amount: 10725, Currency: "USD"},
+
+					PaymentId:     "pay_fake_1",
+					PaymentStatus: "CAPTURED",
+					Refunded:      &pb.Money{Amount: 0, Currency: "USD"},
+					TaxRate:       &pb.TaxRate{Region: "US-CA", BasisPoints: 725},
+					Tax:           &pb.Money{Amount: 725, Currency: "USD"},
+					Shipping:      &pb.Money{Amount: 0, Currency: "USD"},
+				},
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
+					ID:       pg_util.NullInt64(1),
+					Type:     pg_util.NullString("BOOK"),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
+				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+				fields.taxRateRepo.On("ListByRegions", mock.Anything, mock.Anything, []string{"US-CA", "US"}).Return([]*entity.TaxRate{{
+					Region:      pg_util.NullString("US-CA"),
+					Category:    pg_util.NullString(""),
+					BasisPoints: pg_util.NullInt64(725),
+				}}, nil)
+
+				smock.ExpectBegin()
+				fields.orderRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(order *entity.Order) bool {
+					return order.Total.Int64 == 10725 && order.Tax.Int64 == 725
+				})).Return(int64(1), nil)
+				fields.orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.Total.Int64 == 10725 && purchase.Tax.Int64 == 725 && purchase.TaxRegion.String == "US-CA" &&
+						purchase.TaxBasisPoints.Int64 == 725 && purchase.TaxInclusive.Valid && !purchase.TaxInclusive.Bool
+				})).Return(int64(3), nil)
+				smock.ExpectCommit()
+				fields.publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "happy case shipping with coupon applying to shipping",
+			fields: fields{
+				productRepo:          &mocks.ProductRepository{},
+				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				taxRateRepo:          &mocks.TaxRateRepository{},
+				addressRepo:          &mocks.AddressRepository{},
+				shippingMethodRepo:   &mocks.ShippingMethodRepository{},
+				publisher:            &mocks.Publisher{},
+				db: &postgres_client.PostgresClient{
+					DB: db,
+				},
+				couponServiceClient: &mocks.CouponServiceClient{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:               1,
+					Coupon:           wrapperspb.String("SHIP10"),
+					ShippingMethodId: 7,
+				},
+			},
+			want: &pb.PurchaseProductResponse{
+				Data: &pb.Purchase{
+					Id:        3,
+					ProductId: 1,
+					UserId:    1,
+					OrderId:   1,
+					Price:     &pb.Money{Amount: 10000, Currency: "USD"},
+					Quantity:  1,
+					Discount:  &pb.Money{Amount: 1100, Currency: "USD"},
+					Total:     &pb.Money{Amount: 9900, Currency: "USD"},
+					Coupon:    "SHIP10",
+
+					PaymentId:     "pay_fake_1",
+					PaymentStatus: "CAPTURED",
+					Refunded:      &pb.Money{Amount: 0, Currency: "USD"},
+					Tax:           &pb.Money{Amount: 0, Currency: "USD"},
+
+					ShippingMethodId: 7,
+					Shipping:         &pb.Money{Amount: 1000, Currency: "USD"},
+					ShippingAddress:  &pb.Address{Recipient: "Jane Doe", Line1: "1 Hauptstr.", City: "Berlin", PostalCode: "10115", Region: "DE"},
+				},
+			},
+			setup: func(ctx context.Context, fields fields) {
+				fields.addressRepo.On("RetrieveDefault", mock.Anything, mock.Anything, int64(1)).Return(&entity.Address{
+					ID:         pg_util.NullInt64(2),
+					Recipient:  pg_util.NullString("Jane Doe"),
+					Line1:      pg_util.NullString("1 Hauptstr."),
+					City:       pg_util.NullString("Berlin"),
+					PostalCode: pg_util.NullString("10115"),
+					Region:     pg_util.NullString("DE"),
+				}, nil)
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
+					ID:          pg_util.NullInt64(1),
+					Price:       pg_util.NullInt64(10000),
+					Currency:    pg_util.NullString("USD"),
+					WeightGrams: pg_util.NullInt64(500),
+				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+				fields.shippingMethodRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(7)).Return(&entity.ShippingMethod{
+					ID:       pg_util.NullInt64(7),
+					Currency: pg_util.NullString("USD"),
+				}, nil)
+				fields.shippingMethodRepo.On("ListRates", mock.Anything, mock.Anything, []int64{7}).Return([]*entity.ShippingRate{
+					{Regions: pg_util.StringArray([]string{"US"}), Amount: pg_util.NullInt64(500)},
+					{Regions: pg_util.StringArray([]string{"DE", "FR"}), MaxWeightGrams: pg_util.NullInt64(1000), Amount: pg_util.NullInt64(1000)},
+				}, nil)
+
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
+				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
+					Return(&couponpb.RetrieveCouponByCodeResponse{
+						CanUse:             true,
+						PercentBasisPoints: 1000,
+						DiscountType:       couponpb.DiscountType_DiscountType_PERCENT,
+						AppliesToShipping:  true,
+					}, nil)
+				fields.couponServiceClient.On("ReserveCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&couponpb.ReserveCouponResponse{}, nil)
+				fields.taxRateRepo.On("ListByRegions", mock.Anything, mock.Anything, []string{"DE"}).Return(nil, nil)
+
+				smock.ExpectBegin()
+				fields.orderRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(order *entity.Order) bool {
+					return order.Shipping.Int64 == 1000 && order.Discount.Int64 == 1100 && order.Total.Int64 == 9900
+				})).Return(int64(1), nil)
+				fields.orderRepo.On("CreateItem", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.orderRepo.On("CreateDiscount", mock.Anything, mock.Anything, mock.MatchedBy(func(discount *entity.OrderDiscount) bool {
+					return discount.DiscountType.String == couponpb.DiscountType_DiscountType_PERCENT.String() &&
+						discount.Value.Int64 == 1000 && discount.Amount.Int64 == 1100
+				})).Return(nil)
+				fields.orderRepo.On("CreateHistory", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				fields.purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(purchase *entity.PurchasedProduct) bool {
+					return purchase.ShippingMethodID.Int64 == 7 && purchase.Shipping.Int64 == 1000 && purchase.ShippingAddress.Valid
+				})).Return(int64(3), nil)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_CONFIRM", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				smock.ExpectCommit()
+				fields.publisher.On("Publish", mock.Anything, "ORDER_STATUS_CHANGED", []byte("1"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "err address without shipping method",
+			fields: fields{
+				productRepo: &mocks.ProductRepository{},
 			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:        1,
+					AddressId: 2,
+				},
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "shipping_method_id is required to ship to an address"),
+			setup:   func(ctx context.Context, fields fields) {},
+		},
+		{
+			name: "err invalid region",
+			fields: fields{
+				productRepo: &mocks.ProductRepository{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:     1,
+					Region: "usa",
+				},
+			},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid region"),
+			setup:   func(ctx context.Context, fields fields) {},
 		},
 		{
 			name: "err invalid user",
 			fields: fields{
 				productRepo:          &mocks.ProductRepository{},
 				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
 				db: &postgres_client.PostgresClient{
 					DB: db,
 				},
@@ -111,6 +380,9 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			fields: fields{
 				productRepo:          &mocks.ProductRepository{},
 				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
 				db: &postgres_client.PostgresClient{
 					DB: db,
 				},
@@ -138,6 +410,9 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			fields: fields{
 				productRepo:          &mocks.ProductRepository{},
 				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
 				db: &postgres_client.PostgresClient{
 					DB: db,
 				},
@@ -157,10 +432,13 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			wantErr: status.Errorf(codes.NotFound, "coupon not found"),
 			setup: func(ctx context.Context, fields fields) {
 				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
-					ID:    pg_util.NullInt64(1),
-					Price: pg_util.NullFloat64(100),
+					ID:       pg_util.NullInt64(1),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
 				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
 
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
 				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
 					Return(nil, status.Errorf(codes.NotFound, "coupon not found"))
 			},
@@ -171,6 +449,9 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			fields: fields{
 				productRepo:          &mocks.ProductRepository{},
 				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
 				db: &postgres_client.PostgresClient{
 					DB: db,
 				},
@@ -190,21 +471,116 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			wantErr: status.Errorf(codes.FailedPrecondition, "unable to apply this coupon"),
 			setup: func(ctx context.Context, fields fields) {
 				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
-					ID:    pg_util.NullInt64(1),
-					Price: pg_util.NullFloat64(100),
+					ID:       pg_util.NullInt64(1),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
 				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
 
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
 				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
 					Return(&couponpb.RetrieveCouponByCodeResponse{
 						CanUse:       true,
-						Value:        50,
+						Value:        &couponpb.Money{Amount: 5000, Currency: "USD"},
 						DiscountType: couponpb.DiscountType_DiscountType_VALUE,
 					}, nil)
 
+				fields.couponServiceClient.On("ReserveCoupon", mock.Anything, mock.Anything, mock.Anything).
+					Return(nil, status.Errorf(codes.FailedPrecondition, "coupon redemption is released"))
+			},
+		},
+		{
+			name: "err payment declined releases coupon",
+			fields: fields{
+				productRepo:          &mocks.ProductRepository{},
+				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
+				db: &postgres_client.PostgresClient{
+					DB: db,
+				},
+				couponServiceClient: &mocks.CouponServiceClient{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:           1,
+					Coupon:       wrapperspb.String("ABC"),
+					PaymentToken: payment.FakeTokenCaptureDeclined,
+				},
+			},
+			want:    &pb.PurchaseProductResponse{},
+			wantErr: status.Errorf(codes.FailedPrecondition, "payment declined"),
+			setup: func(ctx context.Context, fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
+					ID:       pg_util.NullInt64(1),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
+				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
+				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
+					Return(&couponpb.RetrieveCouponByCodeResponse{
+						CanUse:       true,
+						Value:        &couponpb.Money{Amount: 5000, Currency: "USD"},
+						DiscountType: couponpb.DiscountType_DiscountType_VALUE,
+					}, nil)
+				fields.couponServiceClient.On("ReserveCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&couponpb.ReserveCouponResponse{}, nil)
+
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_RELEASE", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "err purchase failed releases coupon",
+			fields: fields{
+				productRepo:          &mocks.ProductRepository{},
+				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				orderRepo:            &mocks.OrderRepository{},
+				scheduledPriceRepo:   &mocks.ScheduledPriceRepository{},
+				publisher:            &mocks.Publisher{},
+				db: &postgres_client.PostgresClient{
+					DB: db,
+				},
+				couponServiceClient: &mocks.CouponServiceClient{},
+			},
+			args: args{
+				ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+					UserID: 1,
+					Role:   userEntity.UserRole_User,
+				})),
+				req: &pb.PurchaseProductRequest{
+					Id:     1,
+					Coupon: wrapperspb.String("ABC"),
+				},
+			},
+			want:    &pb.PurchaseProductResponse{},
+			wantErr: errors.New("unable to create order: connection reset"),
+			setup: func(ctx context.Context, fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, mock.Anything).Return(&entity.Product{
+					ID:       pg_util.NullInt64(1),
+					Price:    pg_util.NullInt64(10000),
+					Currency: pg_util.NullString("USD"),
+				}, nil)
+				fields.scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, []int64{1}, mock.Anything).Return(nil, nil)
+
+				fields.purchasedProductRepo.On("Count", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), nil)
+				fields.couponServiceClient.On("RetrieveCouponByCode", mock.Anything, mock.Anything, mock.Anything).
+					Return(&couponpb.RetrieveCouponByCodeResponse{
+						CanUse:       true,
+						Value:        &couponpb.Money{Amount: 5000, Currency: "USD"},
+						DiscountType: couponpb.DiscountType_DiscountType_VALUE,
+					}, nil)
+				fields.couponServiceClient.On("ReserveCoupon", mock.Anything, mock.Anything, mock.Anything).Return(&couponpb.ReserveCouponResponse{}, nil)
+
 				smock.ExpectBegin()
-				fields.purchasedProductRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				fields.couponServiceClient.On("ApplyCoupon", mock.Anything, mock.Anything, mock.Anything).Return(nil, status.Errorf(codes.FailedPrecondition, "unable to apply coupon"))
-				smock.ExpectCommit()
+				fields.orderRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("connection reset"))
+				smock.ExpectRollback()
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_RELEASE", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
@@ -214,16 +590,359 @@ func Test_productService_PurchaseProduct(t *testing.T) {
 			s := &productService{
 				productRepo:          tt.fields.productRepo,
 				purchasedProductRepo: tt.fields.purchasedProductRepo,
+				orderRepo:            tt.fields.orderRepo,
+				scheduledPriceRepo:   tt.fields.scheduledPriceRepo,
+				taxRateRepo:          tt.fields.taxRateRepo,
+				addressRepo:          tt.fields.addressRepo,
+				shippingMethodRepo:   tt.fields.shippingMethodRepo,
+				publisher:            tt.fields.publisher,
 				db:                   tt.fields.db,
 				couponServiceClient:  tt.fields.couponServiceClient,
+				paymentProvider:      payment.NewFakeProvider("secret", nil),
+				taxRates:             []tax.Rate{{Region: "US", BasisPoints: 500}},
 			}
-			_, err := s.PurchaseProduct(tt.args.ctx, tt.args.req)
+			got, err := s.PurchaseProduct(tt.args.ctx, tt.args.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_productService_UpdateProductByID(t *testing.T) {
+	adminMD := http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	})
+	adminCtx := metadata.NewIncomingContext(context.Background(), adminMD)
+	ifMatchCtx := func(etag string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Join(adminMD, metadata.Pairs(http_server.MDIfMatch, etag)))
+	}
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		req         *pb.UpdateProductByIDRequest
+		wantVersion int64
+		wantErr     error
+		setup       func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository)
+	}{
+		{
+			name:        "happy case expected version",
+			ctx:         adminCtx,
+			req:         &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug", ExpectedVersion: 3},
+			wantVersion: 4,
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(1), mock.MatchedBy(func(product *entity.Product) bool {
+					return product.Name.String == "Mug" && product.Version == pg_util.NullInt64(3)
+				}), []string{"name"}).Return(nil)
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{Version: pg_util.NullInt64(4)}, nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:        "happy case If-Match header",
+			ctx:         ifMatchCtx(`"3"`),
+			req:         &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug"},
+			wantVersion: 4,
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(1), mock.MatchedBy(func(product *entity.Product) bool {
+					return product.Version == pg_util.NullInt64(3)
+				}), []string{"name"}).Return(nil)
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{Version: pg_util.NullInt64(4)}, nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:        "happy case unconditional",
+			ctx:         ifMatchCtx("*"),
+			req:         &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug"},
+			wantVersion: 8,
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(1), mock.MatchedBy(func(product *entity.Product) bool {
+					return !product.Version.Valid
+				}), []string{"name"}).Return(nil)
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{Version: pg_util.NullInt64(8)}, nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err version mismatch",
+			ctx:     adminCtx,
+			req:     &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug", ExpectedVersion: 3},
+			wantErr: status.Errorf(codes.Aborted, "product has been modified, version mismatch"),
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(1), mock.Anything, []string{"name"}).Return(database.ErrVersionMismatch)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err product not found",
+			ctx:     adminCtx,
+			req:     &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug", ExpectedVersion: 3},
+			wantErr: status.Errorf(codes.NotFound, "product not found"),
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {
+				smock.ExpectBegin()
+				productRepo.On("UpdateByID", mock.Anything, mock.Anything, int64(1), mock.Anything, []string{"name"}).Return(sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err image_urls",
+			ctx:     adminCtx,
+			req:     &pb.UpdateProductByIDRequest{Id: 1, ImageUrls: []string{"a.png"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "image_urls is read-only, use images"),
+			setup:   func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {},
+		},
+		{
+			name:    "err image_urls in update mask",
+			ctx:     adminCtx,
+			req:     &pb.UpdateProductByIDRequest{Id: 1, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"image_urls"}}},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid update mask: unknown field path: image_urls"),
+			setup:   func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {},
+		},
+		{
+			name:    "err invalid If-Match header",
+			ctx:     ifMatchCtx("3"),
+			req:     &pb.UpdateProductByIDRequest{Id: 1, Name: "Mug"},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid If-Match header"),
+			setup:   func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			productRepo := &mocks.ProductRepository{}
+			tt.setup(smock, productRepo)
+			publisher := &mocks.Publisher{}
+			publisher.On("Publish", mock.Anything, productChangedTopic, []byte("1"), mock.Anything).Return(nil)
+			s := &productService{
+				productRepo:  productRepo,
+				db:           &postgres_client.PostgresClient{DB: db},
+				publisher:    publisher,
+				baseCurrency: "USD",
+			}
+			got, err := s.UpdateProductByID(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantVersion, got.GetVersion())
+			productRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_productService_ListDeletedProduct(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	deletedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.ListDeletedProductRequest
+		wantIDs []int64
+		want    int64
+		wantErr error
+		setup   func(productRepo *mocks.ProductRepository)
+	}{
+		{
+			name:    "happy case",
+			ctx:     adminCtx,
+			req:     &pb.ListDeletedProductRequest{Offset: 0, Limit: 10},
+			wantIDs: []int64{1, 2},
+			want:    2,
+			setup: func(productRepo *mocks.ProductRepository) {
+				productRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return([]*entity.Product{
+					{ID: pg_util.NullInt64(1), DeletedAt: pg_util.NullTime(deletedAt), DeletedBy: pg_util.NullInt64(9)},
+					{ID: pg_util.NullInt64(2), DeletedAt: pg_util.NullTime(deletedAt), DeletedBy: pg_util.NullInt64(9)},
+				}, nil)
+				productRepo.On("CountDeleted", mock.Anything, mock.Anything).Return(int64(2), nil)
+			},
+		},
+		{
+			name:    "err list deleted products",
+			ctx:     adminCtx,
+			req:     &pb.ListDeletedProductRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.Internal, "unable to retrieve deleted products: conn closed"),
+			setup: func(productRepo *mocks.ProductRepository) {
+				productRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return(nil, errors.New("conn closed"))
+			},
+		},
+		{
+			name:    "err count deleted products",
+			ctx:     adminCtx,
+			req:     &pb.ListDeletedProductRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.Internal, "unable to count deleted products: conn closed"),
+			setup: func(productRepo *mocks.ProductRepository) {
+				productRepo.On("ListDeleted", mock.Anything, mock.Anything, int64(0), int64(10)).Return(nil, nil)
+				productRepo.On("CountDeleted", mock.Anything, mock.Anything).Return(int64(0), errors.New("conn closed"))
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.ListDeletedProductRequest{Offset: 0, Limit: 10},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(productRepo *mocks.ProductRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &mocks.ProductRepository{}
+			tt.setup(productRepo)
+			s := &productService{productRepo: productRepo}
+			got, err := s.ListDeletedProduct(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.GetTotal())
+			require.Len(t, got.GetData(), len(tt.wantIDs))
+			for i, id := range tt.wantIDs {
+				require.Equal(t, id, got.GetData()[i].GetData().GetId())
+				require.Equal(t, deletedAt, got.GetData()[i].GetDeletedAt().AsTime().Local())
+				require.Equal(t, int64(9), got.GetData()[i].GetDeletedBy())
+			}
+			productRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_productService_RestoreProductByID(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.RestoreProductByIDRequest
+		wantErr error
+		setup   func(productRepo *mocks.ProductRepository, publisher *mocks.Publisher)
+	}{
+		{
+			name: "happy case",
+			ctx:  adminCtx,
+			req:  &pb.RestoreProductByIDRequest{Id: 5},
+			setup: func(productRepo *mocks.ProductRepository, publisher *mocks.Publisher) {
+				productRepo.On("RestoreByID", mock.Anything, mock.Anything, int64(5)).Return(nil)
+				publisher.On("Publish", mock.Anything, "PRODUCT_CHANGED", []byte("5"), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:    "err deleted product not found",
+			ctx:     adminCtx,
+			req:     &pb.RestoreProductByIDRequest{Id: 5},
+			wantErr: status.Errorf(codes.NotFound, "deleted product not found"),
+			setup: func(productRepo *mocks.ProductRepository, publisher *mocks.Publisher) {
+				productRepo.On("RestoreByID", mock.Anything, mock.Anything, int64(5)).Return(sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err restore product",
+			ctx:     adminCtx,
+			req:     &pb.RestoreProductByIDRequest{Id: 5},
+			wantErr: status.Errorf(codes.Internal, "unable to restore product: conn closed"),
+			setup: func(productRepo *mocks.ProductRepository, publisher *mocks.Publisher) {
+				productRepo.On("RestoreByID", mock.Anything, mock.Anything, int64(5)).Return(errors.New("conn closed"))
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     context.Background(),
+			req:     &pb.RestoreProductByIDRequest{Id: 5},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(productRepo *mocks.ProductRepository, publisher *mocks.Publisher) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := &mocks.ProductRepository{}
+			publisher := &mocks.Publisher{}
+			tt.setup(productRepo, publisher)
+			s := &productService{
+				productRepo: productRepo,
+				publisher:   publisher,
+			}
+			_, err := s.RestoreProductByID(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				require.NoError(t, err)
+			}
+			productRepo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+		})
+	}
+}
+
+// purgedBefore matches the trash deadline of a purge with a retention.
+type purgedBefore time.Duration
+
+// Match implements sqlmock.Argument.
+func (retention purgedBefore) Match(v driver.Value) bool {
+	before, ok := v.(time.Time)
+	deadline := time.Now().Add(-time.Duration(retention))
+
+	return ok && before.Before(deadline) && before.After(deadline.Add(-time.Minute))
+}
+
+func Test_purgeDeletedProducts(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock)
+	}{
+		{
+			name: "happy case skips the purchased and ordered products",
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(`DELETE FROM products p WHERE p.deleted_at < \$1 ` +
+					`AND NOT EXISTS \( SELECT 1 FROM purchased_products pp WHERE pp.product_id = p.id \) ` +
+					`AND NOT EXISTS \( SELECT 1 FROM order_items oi WHERE oi.product_id = p.id \)`).
+					WithArgs(purgedBefore(30 * 24 * time.Hour)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name:    "err purge deleted products",
+			wantErr: errors.New("unable to purge deleted products: conn closed"),
+			setup: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec(`DELETE FROM products`).WillReturnError(errors.New("conn closed"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			tt.setup(smock)
+			err = purgeDeletedProducts(context.Background(), &postgres_client.PostgresClient{DB: db}, postgres.NewProductRepository(), 30*24*time.Hour)
 			if tt.wantErr != nil {
 				require.Error(t, err)
 				require.Equal(t, tt.wantErr.Error(), err.Error())
 			} else {
 				require.NoError(t, err)
 			}
+			require.NoError(t, smock.ExpectationsWereMet())
 		})
 	}
 }
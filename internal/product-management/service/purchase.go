@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/tax"
+)
+
+// toPbPurchase transforms a purchased product entity to the response format.
+func toPbPurchase(purchase *entity.PurchasedProduct) *pb.Purchase {
+	data := &pb.Purchase{
+		Id:        purchase.ID.Int64,
+		ProductId: purchase.ProductID.Int64,
+		UserId:    purchase.UserID.Int64,
+		OrderId:   purchase.OrderID.Int64,
+		Price:     toPbMoney(money.New(purchase.Price.Int64, purchase.Currency.String)),
+		Discount:  toPbMoney(money.New(purchase.Discount.Int64, purchase.Currency.String)),
+		Total:     toPbMoney(money.New(purchase.Total.Int64, purchase.Currency.String)),
+		Coupon:    purchase.Coupon.String,
+
+		PaymentId:     purchase.PaymentID.String,
+		PaymentStatus: purchase.PaymentStatus.String,
+		Refunded:      toPbMoney(money.New(purchase.RefundedAmount.Int64, purchase.Currency.String)),
+		Tax:           toPbMoney(money.New(purchase.Tax.Int64, purchase.Currency.String)),
+
+		ShippingMethodId: purchase.ShippingMethodID.Int64,
+		Shipping:         toPbMoney(money.New(purchase.Shipping.Int64, purchase.Currency.String)),
+		ShippingAddress:  toPbShippingAddress(purchase.ShippingAddress),
+		Quantity:         purchase.Quantity.Int64,
+	}
+	if purchase.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(purchase.CreatedAt.Time)
+	}
+	if purchase.TaxRegion.Valid {
+		data.TaxRate = toPbTaxRate(tax.Rate{
+			Region:      purchase.TaxRegion.String,
+			Category:    purchase.TaxCategory.String,
+			BasisPoints: purchase.TaxBasisPoints.Int64,
+			Inclusive:   purchase.TaxInclusive.Bool,
+		})
+	}
+
+	return data
+}
+
+// ListMyPurchases is a method of the productService that retrieves the purchases of the user, the latest first.
+func (s *productService) ListMyPurchases(ctx context.Context, req *pb.ListMyPurchasesRequest) (*pb.ListMyPurchasesResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the purchases of the user
+	respData, total, err := s.listPurchases(ctx, &entity.PurchasedProductFilter{
+		UserID: pg_util.NullInt64(userCtx.UserID),
+	}, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the list of purchases and total count in the response
+	return &pb.ListMyPurchasesResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// GetPurchase is a method of the productService that retrieves a purchase.
+// Users can only retrieve their own purchases, admins can retrieve every purchase.
+func (s *productService) GetPurchase(ctx context.Context, req *pb.GetPurchaseRequest) (*pb.GetPurchaseResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the purchase, the purchases of other users are hidden to them
+	purchase, err := s.purchasedProductRepo.RetrieveByID(ctx, s.db, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "purchase not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve purchase: %v", err.Error())
+	}
+	isAdmin := slices.Contains([]string{userEntity.UserRole_Admin, userEntity.UserRole_SuperAdmin}, userCtx.Role)
+	if !isAdmin && purchase.UserID.Int64 != userCtx.UserID {
+		return nil, status.Errorf(codes.NotFound, "purchase not found")
+	}
+
+	// Return the purchase
+	return &pb.GetPurchaseResponse{Data: toPbPurchase(purchase)}, nil
+}
+
+// ListPurchases is a method of the productService that retrieves the purchases of every user for admins.
+// The purchases can be filtered by user, product, coupon and creation time, the latest come first.
+func (s *productService) ListPurchases(ctx context.Context, req *pb.ListPurchasesRequest) (*pb.ListPurchasesResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Build the filter from the set fields
+	filter := &entity.PurchasedProductFilter{}
+	if req.GetUserId() != nil {
+		filter.UserID = pg_util.NullInt64(req.GetUserId().GetValue())
+	}
+	if req.GetProductId() != nil {
+		filter.ProductID = pg_util.NullInt64(req.GetProductId().GetValue())
+	}
+	if req.GetCoupon() != nil {
+		filter.Coupon = pg_util.NullString(req.GetCoupon().GetValue())
+	}
+	if req.GetFrom() != nil {
+		filter.From = pg_util.NullTime(req.GetFrom().AsTime())
+	}
+	if req.GetTo() != nil {
+		filter.To = pg_util.NullTime(req.GetTo().AsTime())
+	}
+	if filter.From.Valid && filter.To.Valid && !filter.From.Time.Before(filter.To.Time) {
+		return nil, status.Errorf(codes.InvalidArgument, "from must be before to")
+	}
+
+	// Retrieve the matching purchases
+	respData, total, err := s.listPurchases(ctx, filter, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the list of purchases and total count in the response
+	return &pb.ListPurchasesResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// listPurchases retrieves a page of the purchases matching a filter with their total count.
+func (s *productService) listPurchases(ctx context.Context, filter *entity.PurchasedProductFilter, offset, limit int64) ([]*pb.Purchase, int64, error) {
+	purchases, err := s.purchasedProductRepo.List(ctx, s.db, filter, offset, limit)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "unable to retrieve purchases: %v", err.Error())
+	}
+
+	total, err := s.purchasedProductRepo.Count(ctx, s.db, filter)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "unable to count purchases: %v", err.Error())
+	}
+
+	respData := make([]*pb.Purchase, 0, len(purchases))
+	for _, purchase := range purchases {
+		respData = append(respData, toPbPurchase(purchase))
+	}
+
+	return respData, total, nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/payment"
+	"trintech/review/pkg/pg_util"
+)
+
+// returnReasons maps the return reasons to the response format.
+var returnReasons = map[string]pb.ReturnReason{
+	entity.PurchaseReturnReason_Damaged:        pb.ReturnReason_ReturnReason_DAMAGED,
+	entity.PurchaseReturnReason_NotAsDescribed: pb.ReturnReason_ReturnReason_NOT_AS_DESCRIBED,
+	entity.PurchaseReturnReason_WrongItem:      pb.ReturnReason_ReturnReason_WRONG_ITEM,
+	entity.PurchaseReturnReason_NoLongerNeeded: pb.ReturnReason_ReturnReason_NO_LONGER_NEEDED,
+	entity.PurchaseReturnReason_Other:          pb.ReturnReason_ReturnReason_OTHER,
+}
+
+// returnStatuses maps the return statuses to the response format.
+var returnStatuses = map[string]pb.ReturnStatus{
+	entity.PurchaseReturnStatus_Requested: pb.ReturnStatus_ReturnStatus_REQUESTED,
+	entity.PurchaseReturnStatus_Refunded:  pb.ReturnStatus_ReturnStatus_REFUNDED,
+}
+
+// returnReasonFromPb returns the return reason of the request format.
+func returnReasonFromPb(r pb.ReturnReason) (string, bool) {
+	for k, v := range returnReasons {
+		if v == r {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// toPbPurchaseReturn transforms a purchase return entity to the response format.
+func toPbPurchaseReturn(purchaseReturn *entity.PurchaseReturn) *pb.PurchaseReturn {
+	data := &pb.PurchaseReturn{
+		Id:         purchaseReturn.ID.Int64,
+		PurchaseId: purchaseReturn.PurchaseID.Int64,
+		UserId:     purchaseReturn.UserID.Int64,
+		Amount:     toPbMoney(money.New(purchaseReturn.Amount.Int64, purchaseReturn.Currency.String)),
+		Reason:     returnReasons[purchaseReturn.Reason.String],
+		Note:       purchaseReturn.Note.String,
+		Status:     returnStatuses[purchaseReturn.Status.String],
+		ApprovedBy: purchaseReturn.ApprovedBy.Int64,
+	}
+	if purchaseReturn.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(purchaseReturn.CreatedAt.Time)
+	}
+
+	return data
+}
+
+// RequestReturn is a method of the productService that requests the return of a purchase of the user.
+// The requested amount is refunded once an admin approves the return, all of the remaining total without amount.
+func (s *productService) RequestReturn(ctx context.Context, req *pb.RequestReturnRequest) (*pb.RequestReturnResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Validate the reason
+	reason, ok := returnReasonFromPb(req.GetReason())
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid return reason")
+	}
+
+	// Retrieve the purchase, the purchases of other users are hidden to them
+	purchase, err := s.purchasedProductRepo.RetrieveByID(ctx, s.db, req.GetPurchaseId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "purchase not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve purchase: %v", err.Error())
+	}
+	if purchase.UserID.Int64 != userCtx.UserID {
+		return nil, status.Errorf(codes.NotFound, "purchase not found")
+	}
+
+	// Validate the amount, it defaults to the refundable remainder of the total
+	refundable := purchase.Total.Int64 - purchase.RefundedAmount.Int64
+	if refundable <= 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "purchase is fully refunded")
+	}
+	amount := money.New(refundable, purchase.Currency.String)
+	if req.GetAmount() != nil {
+		if currency := req.GetAmount().GetCurrency(); currency != "" && currency != amount.Currency {
+			return nil, status.Errorf(codes.InvalidArgument, "amount must be in %s", amount.Currency)
+		}
+		if req.GetAmount().GetAmount() <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "amount must be positive")
+		}
+		if req.GetAmount().GetAmount() > refundable {
+			return nil, status.Errorf(codes.InvalidArgument, "amount exceeds the refundable amount")
+		}
+		amount.Amount = req.GetAmount().GetAmount()
+	}
+
+	// Create the requested return
+	purchaseReturn := &entity.PurchaseReturn{
+		PurchaseID: purchase.ID,
+		UserID:     pg_util.NullInt64(userCtx.UserID),
+		Amount:     pg_util.NullInt64(amount.Amount),
+		Currency:   pg_util.NullString(amount.Currency),
+		Reason:     pg_util.NullString(reason),
+		Status:     pg_util.NullString(entity.PurchaseReturnStatus_Requested),
+	}
+	if req.GetNote() != "" {
+		purchaseReturn.Note = pg_util.NullString(req.GetNote())
+	}
+	id, err := s.purchaseReturnRepo.Create(ctx, s.db, purchaseReturn)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create return: %v", err.Error())
+	}
+	purchaseReturn.ID = pg_util.NullInt64(id)
+
+	// Return the requested return
+	return &pb.RequestReturnResponse{Data: toPbPurchaseReturn(purchaseReturn)}, nil
+}
+
+// ApproveRefund is a method of the productService that approves a requested return and refunds its amount.
+// The refund is recorded against the purchase, and the coupon and payment of a fully refunded order are restored and refunded.
+func (s *productService) ApproveRefund(ctx context.Context, req *pb.ApproveRefundRequest) (*pb.ApproveRefundResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refund the return in a database transaction, the payment is refunded through the outbox once it is committed
+	var (
+		purchaseReturn *entity.PurchaseReturn
+		purchase       *entity.PurchasedProduct
+	)
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Retrieve the requested return and its purchase
+		var err error
+		purchaseReturn, err = s.purchaseReturnRepo.RetrieveByID(ctx, tx, req.GetId())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "return not found")
+		case err != nil:
+			return fmt.Errorf("unable to retrieve return: %v", err)
+		}
+		if purchaseReturn.Status.String != entity.PurchaseReturnStatus_Requested {
+			return status.Errorf(codes.FailedPrecondition, "return is already refunded")
+		}
+		if purchase, err = s.purchasedProductRepo.RetrieveByID(ctx, tx, purchaseReturn.PurchaseID.Int64); err != nil {
+			return fmt.Errorf("unable to retrieve purchase: %v", err)
+		}
+
+		// Approve the return, unless it has changed meanwhile
+		err = s.purchaseReturnRepo.UpdateStatusByID(ctx, tx, purchaseReturn.ID.Int64,
+			entity.PurchaseReturnStatus_Requested, entity.PurchaseReturnStatus_Refunded, userCtx.UserID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.Aborted, "return has changed, please retry")
+		case err != nil:
+			return fmt.Errorf("unable to update return status: %v", err)
+		}
+		purchaseReturn.Status = pg_util.NullString(entity.PurchaseReturnStatus_Refunded)
+		purchaseReturn.ApprovedBy = pg_util.NullInt64(userCtx.UserID)
+
+		// Record the refunded amount against the purchase
+		refunded, err := s.purchasedProductRepo.AddRefundByID(ctx, tx, purchase.ID.Int64, purchaseReturn.Amount.Int64)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.FailedPrecondition, "amount exceeds the refundable amount")
+		case err != nil:
+			return fmt.Errorf("unable to record refund: %v", err)
+		}
+		purchase.RefundedAmount = pg_util.NullInt64(refunded)
+
+		// Check whether the whole order of the purchase is refunded, its purchases share its coupon and its payment
+		fullyRefunded, err := s.orderFullyRefunded(ctx, tx, purchase)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve order purchases: %v", err)
+		}
+
+		// Restore the coupon of the fully refunded order
+		if fullyRefunded && purchase.CouponRedemptionID.Valid {
+			if err := s.restoreCoupon(ctx, tx, purchase.CouponRedemptionID.String); err != nil {
+				return fmt.Errorf("unable to restore coupon: %w", err)
+			}
+		}
+
+		// The purchases recorded before payments were refunded outside of the payment provider
+		if !purchase.PaymentID.Valid {
+			return nil
+		}
+
+		// Record the payment status of the refund on the purchases and the order of the payment
+		paymentStatus := payment.StatusPartiallyRefunded
+		if fullyRefunded {
+			paymentStatus = payment.StatusRefunded
+		}
+		purchase.PaymentStatus = pg_util.NullString(string(paymentStatus))
+		if err := s.purchasedProductRepo.UpdatePaymentStatusByPaymentID(ctx, tx, purchase.PaymentID.String, purchase.PaymentStatus.String); err != nil {
+			return fmt.Errorf("unable to update payment status: %v", err)
+		}
+		if err := s.orderRepo.UpdatePaymentStatusByPaymentID(ctx, tx, purchase.PaymentID.String, purchase.PaymentStatus.String); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unable to update order payment status: %v", err)
+		}
+
+		// Refund the payment once the refund is committed, the provider refunds the return once for its idempotency key
+		if err := s.enqueuePaymentRefund(ctx, tx, purchase.PaymentID.String,
+			money.New(purchaseReturn.Amount.Int64, purchaseReturn.Currency.String), returnRefundKey(purchaseReturn.ID.Int64)); err != nil {
+			return fmt.Errorf("unable to refund payment: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to approve refund: %v", err.Error())
+	}
+
+	// Return the refunded return and its purchase
+	return &pb.ApproveRefundResponse{
+		Data:     toPbPurchaseReturn(purchaseReturn),
+		Purchase: toPbPurchase(purchase),
+	}, nil
+}
+
+// orderFullyRefunded reports whether all the purchases of the order of a purchase are fully refunded,
+// a purchase without an order is its own order.
+func (s *productService) orderFullyRefunded(ctx context.Context, db database.Executor, purchase *entity.PurchasedProduct) (bool, error) {
+	if !purchase.OrderID.Valid {
+		return purchase.RefundedAmount.Int64 == purchase.Total.Int64, nil
+	}
+
+	purchases, err := s.purchasedProductRepo.ListByOrderID(ctx, db, purchase.OrderID.Int64)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range purchases {
+		if p.RefundedAmount.Int64 < p.Total.Int64 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// returnRefundKey returns the idempotency key of the payment refund of a return.
+func returnRefundKey(returnID int64) string {
+	return fmt.Sprintf("return-%d", returnID)
+}
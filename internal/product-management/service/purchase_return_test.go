@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_productService_RequestReturn(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	purchase := &entity.PurchasedProduct{
+		ID:             pg_util.NullInt64(3),
+		UserID:         pg_util.NullInt64(1),
+		Total:          pg_util.NullInt64(10000),
+		RefundedAmount: pg_util.NullInt64(4000),
+		Currency:       pg_util.NullString("USD"),
+	}
+	tests := []struct {
+		name    string
+		req     *pb.RequestReturnRequest
+		wantErr error
+		setup   func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository)
+	}{
+		{
+			name: "happy case remaining total",
+			req:  &pb.RequestReturnRequest{PurchaseId: 3, Reason: pb.ReturnReason_ReturnReason_DAMAGED},
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+				purchaseReturnRepo.On("Create", mock.Anything, mock.Anything, &entity.PurchaseReturn{
+					PurchaseID: pg_util.NullInt64(3),
+					UserID:     pg_util.NullInt64(1),
+					Amount:     pg_util.NullInt64(6000),
+					Currency:   pg_util.NullString("USD"),
+					Reason:     pg_util.NullString(entity.PurchaseReturnReason_Damaged),
+					Status:     pg_util.NullString(entity.PurchaseReturnStatus_Requested),
+				}).Return(int64(7), nil)
+			},
+		},
+		{
+			name: "happy case partial",
+			req: &pb.RequestReturnRequest{
+				PurchaseId: 3,
+				Amount:     &pb.Money{Amount: 2500, Currency: "USD"},
+				Reason:     pb.ReturnReason_ReturnReason_OTHER,
+				Note:       "one part is missing",
+			},
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+				purchaseReturnRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(data *entity.PurchaseReturn) bool {
+					return data.Amount.Int64 == 2500 && data.Note.String == "one part is missing"
+				})).Return(int64(7), nil)
+			},
+		},
+		{
+			name:    "err amount exceeds refundable",
+			req:     &pb.RequestReturnRequest{PurchaseId: 3, Amount: &pb.Money{Amount: 6001}, Reason: pb.ReturnReason_ReturnReason_DAMAGED},
+			wantErr: status.Errorf(codes.InvalidArgument, "amount exceeds the refundable amount"),
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+			},
+		},
+		{
+			name:    "err purchase of another user",
+			req:     &pb.RequestReturnRequest{PurchaseId: 3, Reason: pb.ReturnReason_ReturnReason_DAMAGED},
+			wantErr: status.Errorf(codes.NotFound, "purchase not found"),
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(&entity.PurchasedProduct{
+					UserID: pg_util.NullInt64(2),
+				}, nil)
+			},
+		},
+		{
+			name:    "err missing reason",
+			req:     &pb.RequestReturnRequest{PurchaseId: 3},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid return reason"),
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			purchaseReturnRepo := &mocks.PurchaseReturnRepository{}
+			tt.setup(purchasedProductRepo, purchaseReturnRepo)
+			s := &productService{
+				purchasedProductRepo: purchasedProductRepo,
+				purchaseReturnRepo:   purchaseReturnRepo,
+			}
+			_, err := s.RequestReturn(userCtx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			purchaseReturnRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_productService_ApproveRefund(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	requested := func(amount int64) *entity.PurchaseReturn {
+		return &entity.PurchaseReturn{
+			ID:         pg_util.NullInt64(7),
+			PurchaseID: pg_util.NullInt64(3),
+			Amount:     pg_util.NullInt64(amount),
+			Currency:   pg_util.NullString("USD"),
+			Status:     pg_util.NullString(entity.PurchaseReturnStatus_Requested),
+		}
+	}
+	paid := func() *entity.PurchasedProduct {
+		return &entity.PurchasedProduct{
+			ID:                 pg_util.NullInt64(3),
+			Total:              pg_util.NullInt64(5000),
+			Currency:           pg_util.NullString("USD"),
+			PaymentID:          pg_util.NullString("pay_fake_1"),
+			CouponRedemptionID: pg_util.NullString("r1"),
+		}
+	}
+	ordered := func() *entity.PurchasedProduct {
+		purchase := paid()
+		purchase.OrderID = pg_util.NullInt64(11)
+		return purchase
+	}
+	orderPurchases := func(refunded int64) []*entity.PurchasedProduct {
+		return []*entity.PurchasedProduct{
+			{ID: pg_util.NullInt64(3), OrderID: pg_util.NullInt64(11), Total: pg_util.NullInt64(5000), RefundedAmount: pg_util.NullInt64(5000)},
+			{ID: pg_util.NullInt64(4), OrderID: pg_util.NullInt64(11), Total: pg_util.NullInt64(3000), RefundedAmount: pg_util.NullInt64(refunded)},
+		}
+	}
+	expectApprove := func(purchase *entity.PurchasedProduct, amount int64, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository) {
+		purchaseReturnRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(7)).Return(requested(amount), nil)
+		purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+		purchaseReturnRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(7),
+			entity.PurchaseReturnStatus_Requested, entity.PurchaseReturnStatus_Refunded, int64(9)).Return(nil)
+		purchasedProductRepo.On("AddRefundByID", mock.Anything, mock.Anything, int64(3), amount).Return(amount, nil)
+	}
+	tests := []struct {
+		name    string
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository)
+	}{
+		{
+			name: "happy case full refund restores coupon",
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				expectApprove(paid(), 5000, purchasedProductRepo, purchaseReturnRepo)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_RESTORE", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(sql.ErrNoRows)
+				smock.ExpectExec("INSERT INTO outbox_events").
+					WithArgs("PAYMENT_REFUND", []byte(`{"payment_id":"pay_fake_1","amount":5000,"currency":"USD","idempotency_key":"return-7"}`)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case partial refund",
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				expectApprove(paid(), 2000, purchasedProductRepo, purchaseReturnRepo)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(nil)
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(sql.ErrNoRows)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("PAYMENT_REFUND", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case full refund of an order purchase keeps the order coupon",
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				expectApprove(ordered(), 5000, purchasedProductRepo, purchaseReturnRepo)
+				purchasedProductRepo.On("ListByOrderID", mock.Anything, mock.Anything, int64(11)).Return(orderPurchases(0), nil)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(nil)
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(nil)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("PAYMENT_REFUND", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name: "happy case last refund of an order restores the order coupon",
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				expectApprove(ordered(), 5000, purchasedProductRepo, purchaseReturnRepo)
+				purchasedProductRepo.On("ListByOrderID", mock.Anything, mock.Anything, int64(11)).Return(orderPurchases(3000), nil)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("COUPON_RESTORE", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "REFUNDED").Return(nil)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("PAYMENT_REFUND", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err refund not enqueued",
+			wantErr: status.Errorf(codes.Internal, "unable to approve refund: unable to refund payment: unable to enqueue outbox event: sql: connection is already closed"),
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				expectApprove(paid(), 2000, purchasedProductRepo, purchaseReturnRepo)
+				purchasedProductRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(nil)
+				orderRepo.On("UpdatePaymentStatusByPaymentID", mock.Anything, mock.Anything, "pay_fake_1", "PARTIALLY_REFUNDED").Return(sql.ErrNoRows)
+				smock.ExpectExec("INSERT INTO outbox_events").WithArgs("PAYMENT_REFUND", sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+				smock.ExpectRollback()
+			},
+		},
+		{
+			name:    "err already refunded",
+			wantErr: status.Errorf(codes.FailedPrecondition, "return is already refunded"),
+			setup: func(smock sqlmock.Sqlmock, purchasedProductRepo *mocks.PurchasedProductRepository, purchaseReturnRepo *mocks.PurchaseReturnRepository, orderRepo *mocks.OrderRepository) {
+				smock.ExpectBegin()
+				purchaseReturnRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(7)).Return(&entity.PurchaseReturn{
+					Status: pg_util.NullString(entity.PurchaseReturnStatus_Refunded),
+				}, nil)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			purchaseReturnRepo := &mocks.PurchaseReturnRepository{}
+			orderRepo := &mocks.OrderRepository{}
+			tt.setup(smock, purchasedProductRepo, purchaseReturnRepo, orderRepo)
+			s := &productService{
+				db:                   &postgres_client.PostgresClient{DB: db},
+				purchasedProductRepo: purchasedProductRepo,
+				purchaseReturnRepo:   purchaseReturnRepo,
+				orderRepo:            orderRepo,
+			}
+			_, err = s.ApproveRefund(adminCtx, &pb.ApproveRefundRequest{Id: 7})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+				purchasedProductRepo.AssertExpectations(t)
+				orderRepo.AssertExpectations(t)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_GetPurchase(t *testing.T) {
+	usd := func(amount int64) *pb.Money {
+		return &pb.Money{Amount: amount, Currency: "USD"}
+	}
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	purchase := &entity.PurchasedProduct{
+		ID:        pg_util.NullInt64(3),
+		ProductID: pg_util.NullInt64(5),
+		UserID:    pg_util.NullInt64(1),
+		Price:     pg_util.NullInt64(10000),
+		Discount:  pg_util.NullInt64(0),
+		Total:     pg_util.NullInt64(10000),
+		Currency:  pg_util.NullString("USD"),
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		want    *pb.GetPurchaseResponse
+		wantErr error
+		setup   func(purchasedProductRepo *mocks.PurchasedProductRepository)
+	}{
+		{
+			name: "happy case owner",
+			ctx:  userCtx,
+			want: &pb.GetPurchaseResponse{Data: &pb.Purchase{Id: 3, ProductId: 5, UserId: 1, Price: usd(10000), Discount: usd(0), Total: usd(10000), Refunded: usd(0), Tax: usd(0), Shipping: usd(0)}},
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+			},
+		},
+		{
+			name: "happy case admin",
+			ctx:  adminCtx,
+			want: &pb.GetPurchaseResponse{Data: &pb.Purchase{Id: 3, ProductId: 5, UserId: 1, Price: usd(10000), Discount: usd(0), Total: usd(10000), Refunded: usd(0), Tax: usd(0), Shipping: usd(0)}},
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+			},
+		},
+		{
+			name: "err purchase of another user",
+			ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+				UserID: 2,
+				Role:   userEntity.UserRole_User,
+			})),
+			wantErr: status.Errorf(codes.NotFound, "purchase not found"),
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(purchase, nil)
+			},
+		},
+		{
+			name:    "err purchase not found",
+			ctx:     userCtx,
+			wantErr: status.Errorf(codes.NotFound, "purchase not found"),
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository) {
+				purchasedProductRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(3)).Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err invalid user",
+			ctx:     context.Background(),
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(purchasedProductRepo *mocks.PurchasedProductRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			tt.setup(purchasedProductRepo)
+			s := &productService{
+				purchasedProductRepo: purchasedProductRepo,
+			}
+			got, err := s.GetPurchase(tt.ctx, &pb.GetPurchaseRequest{Id: 3})
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_productService_ListPurchases(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.ListPurchasesRequest
+		want    *pb.ListPurchasesResponse
+		wantErr error
+		setup   func(purchasedProductRepo *mocks.PurchasedProductRepository)
+	}{
+		{
+			name: "happy case filters",
+			ctx:  adminCtx,
+			req: &pb.ListPurchasesRequest{
+				Limit:     10,
+				UserId:    wrapperspb.Int64(1),
+				ProductId: wrapperspb.Int64(5),
+				Coupon:    wrapperspb.String("ABC"),
+				From:      timestamppb.New(from),
+				To:        timestamppb.New(to),
+			},
+			want: &pb.ListPurchasesResponse{
+				Data:  []*pb.Purchase{{Id: 3, ProductId: 5, UserId: 1, Coupon: "ABC", Price: &pb.Money{}, Discount: &pb.Money{}, Total: &pb.Money{}, Refunded: &pb.Money{}, Tax: &pb.Money{}, Shipping: &pb.Money{}}},
+				Total: 1,
+			},
+			setup: func(purchasedProductRepo *mocks.PurchasedProductRepository) {
+				filter := &entity.PurchasedProductFilter{
+					UserID:    pg_util.NullInt64(1),
+					ProductID: pg_util.NullInt64(5),
+					Coupon:    pg_util.NullString("ABC"),
+					From:      pg_util.NullTime(from),
+					To:        pg_util.NullTime(to),
+				}
+				purchasedProductRepo.On("List", mock.Anything, mock.Anything, filter, int64(0), int64(10)).Return([]*entity.PurchasedProduct{
+					{
+						ID:        pg_util.NullInt64(3),
+						ProductID: pg_util.NullInt64(5),
+						UserID:    pg_util.NullInt64(1),
+						Coupon:    pg_util.NullString("ABC"),
+					},
+				}, nil)
+				purchasedProductRepo.On("Count", mock.Anything, mock.Anything, filter).Return(int64(1), nil)
+			},
+		},
+		{
+			name:    "err invalid range",
+			ctx:     adminCtx,
+			req:     &pb.ListPurchasesRequest{From: timestamppb.New(to), To: timestamppb.New(from)},
+			wantErr: status.Errorf(codes.InvalidArgument, "from must be before to"),
+			setup:   func(purchasedProductRepo *mocks.PurchasedProductRepository) {},
+		},
+		{
+			name: "err not admin",
+			ctx: metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+				UserID: 1,
+				Role:   userEntity.UserRole_User,
+			})),
+			req:     &pb.ListPurchasesRequest{},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(purchasedProductRepo *mocks.PurchasedProductRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchasedProductRepo := &mocks.PurchasedProductRepository{}
+			tt.setup(purchasedProductRepo)
+			s := &productService{
+				purchasedProductRepo: purchasedProductRepo,
+			}
+			got, err := s.ListPurchases(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
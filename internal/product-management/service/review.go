@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/pg_util"
+)
+
+// reviewStatuses maps the review statuses of the API to the stored ones.
+var reviewStatuses = map[pb.ReviewStatus]string{
+	pb.ReviewStatus_ReviewStatus_PENDING:  entity.ReviewStatus_Pending,
+	pb.ReviewStatus_ReviewStatus_APPROVED: entity.ReviewStatus_Approved,
+	pb.ReviewStatus_ReviewStatus_HIDDEN:   entity.ReviewStatus_Hidden,
+}
+
+// toPbReview transforms a review entity to the response format.
+func toPbReview(review *entity.Review) *pb.Review {
+	var reviewStatus pb.ReviewStatus
+	for k, v := range reviewStatuses {
+		if v == review.Status.String {
+			reviewStatus = k
+		}
+	}
+
+	return &pb.Review{
+		Id:           review.ID.Int64,
+		ProductId:    review.ProductID.Int64,
+		UserId:       review.UserID.Int64,
+		Rating:       int32(review.Rating.Int64),
+		Content:      review.Content.String,
+		Status:       reviewStatus,
+		HelpfulCount: review.HelpfulCount.Int64,
+		CreatedAt:    timestamppb.New(review.CreatedAt.Time),
+	}
+}
+
+// CreateReview is a method of the productService that handles the review of a purchased product.
+// It checks the user purchased the product and stores the review, which waits for an admin approval.
+func (s *productService) CreateReview(ctx context.Context, req *pb.CreateReviewRequest) (*pb.CreateReviewResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Validate the rating
+	if req.GetRating() < 1 || req.GetRating() > 5 {
+		return nil, status.Errorf(codes.InvalidArgument, "rating must be between 1 and 5")
+	}
+
+	// Retrieve the product by ID
+	if _, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetProductId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not found, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not found")
+		}
+
+		// If there is an error during product retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+
+	// Check the user purchased the product
+	purchased, err := s.purchasedProductRepo.ExistsByUserIDAndProductID(ctx, s.db, userCtx.UserID, req.GetProductId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve purchased product: %v", err.Error())
+	}
+	if !purchased {
+		return nil, status.Errorf(codes.FailedPrecondition, "only users who purchased the product can review it")
+	}
+
+	// Create the review, pending until an admin approves it
+	id, err := s.reviewRepo.Create(ctx, s.db, &entity.Review{
+		ProductID: pg_util.NullInt64(req.GetProductId()),
+		UserID:    pg_util.NullInt64(userCtx.UserID),
+		Rating:    pg_util.NullInt64(int64(req.GetRating())),
+		Content:   pg_util.NullString(req.GetContent()),
+		Status:    pg_util.NullString(entity.ReviewStatus_Pending),
+	})
+	if err != nil {
+		if pg_util.IsUniqueViolation(err) {
+			// If the user already reviewed the product, return an already exists error
+			return nil, status.Errorf(codes.AlreadyExists, "product already reviewed")
+		}
+
+		// If there is an error during review creation, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to create review: %v", err.Error())
+	}
+
+	// Return the created review's ID
+	return &pb.CreateReviewResponse{
+		Id: id,
+	}, nil
+}
+
+// ListReview is a method of the productService that retrieves the reviews of a product.
+// Approved reviews are public, admins can also filter the reviews by status for moderation.
+func (s *productService) ListReview(ctx context.Context, req *pb.ListReviewRequest) (*pb.ListReviewResponse, error) {
+	// Resolve the statuses to list, only admins can see the reviews which are not approved
+	statuses := []string{entity.ReviewStatus_Approved}
+	if req.GetStatus() != pb.ReviewStatus_ReviewStatus_NONE {
+		reviewStatus, ok := reviewStatuses[req.GetStatus()]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid review status")
+		}
+
+		if reviewStatus != entity.ReviewStatus_Approved {
+			userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+			if !ok || !slices.Contains([]string{userEntity.UserRole_Admin, userEntity.UserRole_SuperAdmin}, userCtx.Role) {
+				return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+			}
+		}
+		statuses = []string{reviewStatus}
+	}
+
+	// Retrieve the list of reviews from the repository
+	list, err := s.reviewRepo.ListByProductID(ctx, s.db, req.GetProductId(), statuses, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		// If there is an error during review retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve list review: %v", err.Error())
+	}
+
+	// Transform the list of reviews to the response format
+	respData := make([]*pb.Review, 0, len(list))
+	for _, review := range list {
+		respData = append(respData, toPbReview(review))
+	}
+
+	// Get the total count of reviews
+	total, err := s.reviewRepo.CountByProductID(ctx, s.db, req.GetProductId(), statuses)
+	if err != nil {
+		// If there is an error during count retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to count review: %v", err.Error())
+	}
+
+	// Return the list of reviews and total count in the response
+	return &pb.ListReviewResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// ModerateReview is a method of the productService that approves or hides a review.
+// It validates the admin user, updates the review status and refreshes the rating of the product.
+func (s *productService) ModerateReview(ctx context.Context, req *pb.ModerateReviewRequest) (*pb.ModerateReviewResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the new status
+	reviewStatus, ok := reviewStatuses[req.GetStatus()]
+	if !ok || reviewStatus == entity.ReviewStatus_Pending {
+		return nil, status.Errorf(codes.InvalidArgument, "review can only be approved or hidden")
+	}
+
+	// Update the review and the product rating in a database transaction
+	var productID int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		// Retrieve the review by ID
+		review, err := s.reviewRepo.RetrieveByID(ctx, tx, req.GetId())
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// If the review is not found, return a not found error
+			return status.Errorf(codes.NotFound, "review not found")
+		case err != nil:
+			return fmt.Errorf("unable to retrieve review: %v", err)
+		}
+
+		// Update the review status
+		if err := s.reviewRepo.UpdateStatusByID(ctx, tx, review.ID.Int64, reviewStatus, userCtx.UserID); err != nil {
+			return fmt.Errorf("unable to update review: %v", err)
+		}
+
+		// Refresh the rating of the product from its approved reviews
+		productID = review.ProductID.Int64
+		if err := s.productRepo.RefreshRating(ctx, tx, productID); err != nil {
+			return fmt.Errorf("unable to refresh product rating: %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to moderate review: %v", err.Error())
+	}
+
+	// Publish the update of the rating of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, productID)
+
+	// Return an empty response indicating successful moderation
+	return &pb.ModerateReviewResponse{}, nil
+}
+
+// MarkReviewHelpful is a method of the productService that marks an approved review helpful.
+// A user counts once per review and cannot mark their own review.
+func (s *productService) MarkReviewHelpful(ctx context.Context, req *pb.MarkReviewHelpfulRequest) (*pb.MarkReviewHelpfulResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the review by ID, only approved reviews are visible
+	review, err := s.reviewRepo.RetrieveByID(ctx, s.db, req.GetId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows), err == nil && review.Status.String != entity.ReviewStatus_Approved:
+		// If the review is not found, return a not found error
+		return nil, status.Errorf(codes.NotFound, "review not found")
+	case err != nil:
+		// If there is an error during review retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve review: %v", err.Error())
+	}
+
+	// Check the user doesn't mark their own review
+	if review.UserID.Int64 == userCtx.UserID {
+		return nil, status.Errorf(codes.FailedPrecondition, "user cannot mark their own review")
+	}
+
+	// Record the vote, voting twice keeps the count unchanged
+	voted, err := s.reviewRepo.CreateHelpfulVote(ctx, s.db, review.ID.Int64, userCtx.UserID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to mark review helpful: %v", err.Error())
+	}
+
+	helpfulCount := review.HelpfulCount.Int64
+	if voted {
+		helpfulCount++
+	}
+
+	// Return the helpful count of the review
+	return &pb.MarkReviewHelpfulResponse{
+		HelpfulCount: helpfulCount,
+	}, nil
+}
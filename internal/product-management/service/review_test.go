@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/postgres_client"
+)
+
+func Test_productService_CreateReview(t *testing.T) {
+	type fields struct {
+		productRepo          *mocks.ProductRepository
+		purchasedProductRepo *mocks.PurchasedProductRepository
+		reviewRepo           *mocks.ReviewRepository
+	}
+
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.CreateReviewRequest
+		want    *pb.CreateReviewResponse
+		wantErr error
+		setup   func(fields fields)
+	}{
+		{
+			name: "happy case",
+			ctx:  userCtx,
+			req:  &pb.CreateReviewRequest{ProductId: 1, Rating: 5, Content: "great"},
+			want: &pb.CreateReviewResponse{Id: 10},
+			setup: func(fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{ID: pg_util.NullInt64(1)}, nil)
+				fields.purchasedProductRepo.On("ExistsByUserIDAndProductID", mock.Anything, mock.Anything, int64(1), int64(1)).Return(true, nil)
+				fields.reviewRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(review *entity.Review) bool {
+					return review.Status.String == entity.ReviewStatus_Pending && review.Rating.Int64 == 5
+				})).Return(int64(10), nil)
+			},
+		},
+		{
+			name:    "err invalid user",
+			ctx:     context.Background(),
+			req:     &pb.CreateReviewRequest{ProductId: 1, Rating: 5},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(fields fields) {},
+		},
+		{
+			name:    "err invalid rating",
+			ctx:     userCtx,
+			req:     &pb.CreateReviewRequest{ProductId: 1, Rating: 6},
+			wantErr: status.Errorf(codes.InvalidArgument, "rating must be between 1 and 5"),
+			setup:   func(fields fields) {},
+		},
+		{
+			name:    "err product not found",
+			ctx:     userCtx,
+			req:     &pb.CreateReviewRequest{ProductId: 1, Rating: 4},
+			wantErr: status.Errorf(codes.NotFound, "product not found"),
+			setup: func(fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err product not purchased",
+			ctx:     userCtx,
+			req:     &pb.CreateReviewRequest{ProductId: 1, Rating: 4},
+			wantErr: status.Errorf(codes.FailedPrecondition, "only users who purchased the product can review it"),
+			setup: func(fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{ID: pg_util.NullInt64(1)}, nil)
+				fields.purchasedProductRepo.On("ExistsByUserIDAndProductID", mock.Anything, mock.Anything, int64(1), int64(1)).Return(false, nil)
+			},
+		},
+		{
+			name:    "err product already reviewed",
+			ctx:     userCtx,
+			req:     &pb.CreateReviewRequest{ProductId: 1, Rating: 4},
+			wantErr: status.Errorf(codes.AlreadyExists, "product already reviewed"),
+			setup: func(fields fields) {
+				fields.productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Product{ID: pg_util.NullInt64(1)}, nil)
+				fields.purchasedProductRepo.On("ExistsByUserIDAndProductID", mock.Anything, mock.Anything, int64(1), int64(1)).Return(true, nil)
+				fields.reviewRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), fmt.Errorf("insert review: %w", &pq.Error{Code: "23505"}))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fields{
+				productRepo:          &mocks.ProductRepository{},
+				purchasedProductRepo: &mocks.PurchasedProductRepository{},
+				reviewRepo:           &mocks.ReviewRepository{},
+			}
+			tt.setup(fields)
+			s := &productService{
+				productRepo:          fields.productRepo,
+				purchasedProductRepo: fields.purchasedProductRepo,
+				reviewRepo:           fields.reviewRepo,
+			}
+			got, err := s.CreateReview(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_productService_ModerateReview(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 2,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.ModerateReviewRequest
+		wantErr error
+		setup   func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository, reviewRepo *mocks.ReviewRepository)
+	}{
+		{
+			name: "happy case approve",
+			ctx:  adminCtx,
+			req:  &pb.ModerateReviewRequest{Id: 10, Status: pb.ReviewStatus_ReviewStatus_APPROVED},
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository, reviewRepo *mocks.ReviewRepository) {
+				smock.ExpectBegin()
+				reviewRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(10)).Return(&entity.Review{
+					ID:        pg_util.NullInt64(10),
+					ProductID: pg_util.NullInt64(1),
+				}, nil)
+				reviewRepo.On("UpdateStatusByID", mock.Anything, mock.Anything, int64(10), entity.ReviewStatus_Approved, int64(2)).Return(nil)
+				productRepo.On("RefreshRating", mock.Anything, mock.Anything, int64(1)).Return(nil)
+				smock.ExpectCommit()
+			},
+		},
+		{
+			name:    "err not admin",
+			ctx:     metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: 1, Role: userEntity.UserRole_User})),
+			req:     &pb.ModerateReviewRequest{Id: 10, Status: pb.ReviewStatus_ReviewStatus_APPROVED},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository, reviewRepo *mocks.ReviewRepository) {
+			},
+		},
+		{
+			name:    "err back to pending",
+			ctx:     adminCtx,
+			req:     &pb.ModerateReviewRequest{Id: 10, Status: pb.ReviewStatus_ReviewStatus_PENDING},
+			wantErr: status.Errorf(codes.InvalidArgument, "review can only be approved or hidden"),
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository, reviewRepo *mocks.ReviewRepository) {
+			},
+		},
+		{
+			name:    "err review not found",
+			ctx:     adminCtx,
+			req:     &pb.ModerateReviewRequest{Id: 10, Status: pb.ReviewStatus_ReviewStatus_HIDDEN},
+			wantErr: status.Errorf(codes.NotFound, "review not found"),
+			setup: func(smock sqlmock.Sqlmock, productRepo *mocks.ProductRepository, reviewRepo *mocks.ReviewRepository) {
+				smock.ExpectBegin()
+				reviewRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(10)).Return(nil, sql.ErrNoRows)
+				smock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, _ := sqlmock.New()
+			productRepo := &mocks.ProductRepository{}
+			reviewRepo := &mocks.ReviewRepository{}
+			tt.setup(smock, productRepo, reviewRepo)
+			publisher := &mocks.Publisher{}
+			publisher.On("Publish", mock.Anything, productChangedTopic, mock.Anything, mock.Anything).Return(nil)
+			s := &productService{
+				productRepo: productRepo,
+				reviewRepo:  reviewRepo,
+				db:          &postgres_client.PostgresClient{DB: db},
+				publisher:   publisher,
+			}
+			_, err := s.ModerateReview(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, smock.ExpectationsWereMet())
+			productRepo.AssertExpectations(t)
+			reviewRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/shipping"
+)
+
+// toShippingMethod transforms a shipping method entity with its rates to the method of the shipping engine.
+func toShippingMethod(method *entity.ShippingMethod, rates []*entity.ShippingRate) shipping.Method {
+	result := shipping.Method{
+		Currency:      method.Currency.String,
+		FreeThreshold: method.FreeThreshold.Int64,
+	}
+	for _, rate := range rates {
+		result.Rules = append(result.Rules, shipping.Rule{
+			Regions:        rate.Regions,
+			MinWeightGrams: rate.MinWeightGrams.Int64,
+			MaxWeightGrams: rate.MaxWeightGrams.Int64,
+			MinOrderValue:  rate.MinOrderValue.Int64,
+			MaxOrderValue:  rate.MaxOrderValue.Int64,
+			Amount:         rate.Amount.Int64,
+		})
+	}
+
+	return result
+}
+
+// toPbShippingMethod transforms a shipping method entity with its rates to the response format.
+func toPbShippingMethod(method *entity.ShippingMethod, rates []*entity.ShippingRate) *pb.ShippingMethod {
+	currency := method.Currency.String
+	data := &pb.ShippingMethod{
+		Id:   method.ID.Int64,
+		Name: method.Name.String,
+	}
+	if method.FreeThreshold.Int64 > 0 {
+		data.FreeShippingThreshold = toPbMoney(money.New(method.FreeThreshold.Int64, currency))
+	}
+	if method.CreatedAt.Valid {
+		data.CreatedAt = timestamppb.New(method.CreatedAt.Time)
+	}
+	for _, rate := range rates {
+		rule := &pb.ShippingRule{
+			Regions:        rate.Regions,
+			MinWeightGrams: rate.MinWeightGrams.Int64,
+			MaxWeightGrams: rate.MaxWeightGrams.Int64,
+			Amount:         toPbMoney(money.New(rate.Amount.Int64, currency)),
+		}
+		if rate.MinOrderValue.Int64 > 0 {
+			rule.MinOrderValue = toPbMoney(money.New(rate.MinOrderValue.Int64, currency))
+		}
+		if rate.MaxOrderValue.Int64 > 0 {
+			rule.MaxOrderValue = toPbMoney(money.New(rate.MaxOrderValue.Int64, currency))
+		}
+		data.Rules = append(data.Rules, rule)
+	}
+
+	return data
+}
+
+// shippingMethodRequest is the shipping method of a request creating or updating a shipping method.
+type shippingMethodRequest interface {
+	GetName() string
+	GetFreeShippingThreshold() *pb.Money
+	GetRules() []*pb.ShippingRule
+}
+
+// validShippingMethod validates the shipping method of a request and returns it with its rates.
+func (s *productService) validShippingMethod(req shippingMethodRequest) (*entity.ShippingMethod, []*entity.ShippingRate, error) {
+	// Validate the amount of a request, which must be in the base currency
+	validAmount := func(name string, amount *pb.Money) (int64, error) {
+		if currency := amount.GetCurrency(); currency != "" && currency != s.baseCurrency {
+			return 0, status.Errorf(codes.InvalidArgument, "%s must be in %s", name, s.baseCurrency)
+		}
+		if amount.GetAmount() < 0 {
+			return 0, status.Errorf(codes.InvalidArgument, "%s must not be negative", name)
+		}
+
+		return amount.GetAmount(), nil
+	}
+
+	// Validate the method
+	if strings.TrimSpace(req.GetName()) == "" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "name is required")
+	}
+	freeThreshold, err := validAmount("free_shipping_threshold", req.GetFreeShippingThreshold())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(req.GetRules()) == 0 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "rules are required")
+	}
+	method := &entity.ShippingMethod{
+		Name:          pg_util.NullString(strings.TrimSpace(req.GetName())),
+		Currency:      pg_util.NullString(s.baseCurrency),
+		FreeThreshold: pg_util.NullInt64(freeThreshold),
+	}
+
+	// Validate the rules
+	rates := make([]*entity.ShippingRate, 0, len(req.GetRules()))
+	for _, rule := range req.GetRules() {
+		regions := make([]string, 0, len(rule.GetRegions()))
+		for _, r := range rule.GetRegions() {
+			region, err := validRegion(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			regions = append(regions, region)
+		}
+		if rule.GetMinWeightGrams() < 0 || rule.GetMaxWeightGrams() < 0 ||
+			(rule.GetMaxWeightGrams() > 0 && rule.GetMaxWeightGrams() <= rule.GetMinWeightGrams()) {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid weight bounds")
+		}
+		minOrderValue, err := validAmount("min_order_value", rule.GetMinOrderValue())
+		if err != nil {
+			return nil, nil, err
+		}
+		maxOrderValue, err := validAmount("max_order_value", rule.GetMaxOrderValue())
+		if err != nil {
+			return nil, nil, err
+		}
+		if maxOrderValue > 0 && maxOrderValue <= minOrderValue {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid order value bounds")
+		}
+		if rule.GetAmount() == nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "amount is required")
+		}
+		amount, err := validAmount("amount", rule.GetAmount())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rates = append(rates, &entity.ShippingRate{
+			Regions:        pg_util.StringArray(regions),
+			MinWeightGrams: pg_util.NullInt64(rule.GetMinWeightGrams()),
+			MaxWeightGrams: pg_util.NullInt64(rule.GetMaxWeightGrams()),
+			MinOrderValue:  pg_util.NullInt64(minOrderValue),
+			MaxOrderValue:  pg_util.NullInt64(maxOrderValue),
+			Amount:         pg_util.NullInt64(amount),
+		})
+	}
+
+	return method, rates, nil
+}
+
+// createShippingRates creates the rates of a shipping method.
+func (s *productService) createShippingRates(ctx context.Context, db database.Executor, shippingMethodID int64, rates []*entity.ShippingRate) error {
+	for _, rate := range rates {
+		rate.ShippingMethodID = pg_util.NullInt64(shippingMethodID)
+		if err := s.shippingMethodRepo.CreateRate(ctx, db, rate); err != nil {
+			return fmt.Errorf("unable to create shipping rate: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// quoteShipping returns the shipping cost of a parcel with a shipping method.
+func (s *productService) quoteShipping(ctx context.Context, shippingMethodID int64, parcel shipping.Parcel) (money.Money, error) {
+	// Retrieve the shipping method with its rates
+	method, err := s.shippingMethodRepo.RetrieveByID(ctx, s.db, shippingMethodID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return money.Money{}, status.Errorf(codes.NotFound, "shipping method not found")
+	case err != nil:
+		return money.Money{}, status.Errorf(codes.Internal, "unable to retrieve shipping method: %v", err.Error())
+	}
+	rates, err := s.shippingMethodRepo.ListRates(ctx, s.db, []int64{shippingMethodID})
+	if err != nil {
+		return money.Money{}, status.Errorf(codes.Internal, "unable to retrieve shipping rates: %v", err.Error())
+	}
+
+	// Quote the parcel
+	cost, err := toShippingMethod(method, rates).Quote(parcel)
+	switch {
+	case errors.Is(err, shipping.ErrNotShippable):
+		return money.Money{}, status.Errorf(codes.FailedPrecondition, "shipping method doesn't ship to this address")
+	case errors.Is(err, shipping.ErrCurrencyMismatch):
+		return money.Money{}, status.Errorf(codes.FailedPrecondition, "shipping method doesn't ship orders in %s", parcel.OrderValue.Currency)
+	case err != nil:
+		return money.Money{}, status.Errorf(codes.Internal, "unable to quote shipping: %v", err.Error())
+	}
+
+	return cost, nil
+}
+
+// CreateShippingMethod is a method of the productService that creates a shipping method with its rate rules.
+func (s *productService) CreateShippingMethod(ctx context.Context, req *pb.CreateShippingMethodRequest) (*pb.CreateShippingMethodResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the shipping method
+	method, rates, err := s.validShippingMethod(req)
+	if err != nil {
+		return nil, err
+	}
+	method.CreatedBy = pg_util.NullInt64(userCtx.UserID)
+
+	// Create the shipping method with its rates in a database transaction
+	var id int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if id, err = s.shippingMethodRepo.Create(ctx, tx, method); err != nil {
+			return fmt.Errorf("unable to create shipping method: %v", err)
+		}
+
+		return s.createShippingRates(ctx, tx, id, rates)
+	}); err != nil {
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to create shipping method: %v", err.Error())
+	}
+
+	return &pb.CreateShippingMethodResponse{Id: id}, nil
+}
+
+// UpdateShippingMethod is a method of the productService that replaces a shipping method with its rate rules.
+// The purchases keep the shipping cost they were quoted.
+func (s *productService) UpdateShippingMethod(ctx context.Context, req *pb.UpdateShippingMethodRequest) (*pb.UpdateShippingMethodResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the shipping method
+	method, rates, err := s.validShippingMethod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the shipping method and its rates in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		err := s.shippingMethodRepo.UpdateByID(ctx, tx, req.GetId(), method)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "shipping method not found")
+		case err != nil:
+			return fmt.Errorf("unable to update shipping method: %v", err)
+		}
+
+		if err := s.shippingMethodRepo.DeleteRates(ctx, tx, req.GetId()); err != nil {
+			return fmt.Errorf("unable to delete shipping rates: %v", err)
+		}
+
+		return s.createShippingRates(ctx, tx, req.GetId(), rates)
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		// If there is an error during the transaction, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to update shipping method: %v", err.Error())
+	}
+
+	return &pb.UpdateShippingMethodResponse{}, nil
+}
+
+// listShippingMethods retrieves the shipping methods with their rates by shipping method.
+func (s *productService) listShippingMethods(ctx context.Context) ([]*entity.ShippingMethod, map[int64][]*entity.ShippingRate, error) {
+	methods, err := s.shippingMethodRepo.List(ctx, s.db)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "unable to retrieve shipping methods: %v", err.Error())
+	}
+	ids := make([]int64, 0, len(methods))
+	for _, method := range methods {
+		ids = append(ids, method.ID.Int64)
+	}
+
+	rates, err := s.shippingMethodRepo.ListRates(ctx, s.db, ids)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "unable to retrieve shipping rates: %v", err.Error())
+	}
+	ratesByID := make(map[int64][]*entity.ShippingRate, len(methods))
+	for _, rate := range rates {
+		ratesByID[rate.ShippingMethodID.Int64] = append(ratesByID[rate.ShippingMethodID.Int64], rate)
+	}
+
+	return methods, ratesByID, nil
+}
+
+// ListShippingMethod is a method of the productService that lists the shipping methods with their rate rules.
+func (s *productService) ListShippingMethod(ctx context.Context, _ *pb.ListShippingMethodRequest) (*pb.ListShippingMethodResponse, error) {
+	// Retrieve the shipping methods
+	methods, ratesByID, err := s.listShippingMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transform the shipping methods to the response format
+	respData := make([]*pb.ShippingMethod, 0, len(methods))
+	for _, method := range methods {
+		respData = append(respData, toPbShippingMethod(method, ratesByID[method.ID.Int64]))
+	}
+
+	return &pb.ListShippingMethodResponse{Data: respData}, nil
+}
+
+// QuoteShipping is a method of the productService that quotes the shipping of a product to an address of the user
+// with every shipping method which ships it there.
+func (s *productService) QuoteShipping(ctx context.Context, req *pb.QuoteShippingRequest) (*pb.QuoteShippingResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the address
+	address, err := s.shippingAddress(ctx, userCtx.UserID, req.GetAddressId())
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the product at its scheduled price in effect
+	product, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetProductId())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "product not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+	if err := s.applyScheduledPrices(ctx, product); err != nil {
+		return nil, err
+	}
+
+	// Quote the product with the shipping methods which ship it to the address
+	methods, ratesByID, err := s.listShippingMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parcel := shipping.Parcel{
+		Region:      address.Region.String,
+		WeightGrams: product.WeightGrams.Int64,
+		OrderValue:  productPrice(product),
+	}
+	respData := make([]*pb.QuoteShippingResponse_Quote, 0, len(methods))
+	for _, method := range methods {
+		cost, err := toShippingMethod(method, ratesByID[method.ID.Int64]).Quote(parcel)
+		if err != nil {
+			continue
+		}
+		respData = append(respData, &pb.QuoteShippingResponse_Quote{
+			ShippingMethodId: method.ID.Int64,
+			Name:             method.Name.String,
+			Amount:           toPbMoney(cost),
+		})
+	}
+
+	return &pb.QuoteShippingResponse{Data: respData}, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_QuoteShipping(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.QuoteShippingRequest
+		want    *pb.QuoteShippingResponse
+		wantErr error
+		setup   func(addressRepo *mocks.AddressRepository, productRepo *mocks.ProductRepository, shippingMethodRepo *mocks.ShippingMethodRepository)
+	}{
+		{
+			name: "happy case skips methods not shipping to the address",
+			ctx:  userCtx,
+			req:  &pb.QuoteShippingRequest{ProductId: 5, AddressId: 2},
+			want: &pb.QuoteShippingResponse{Data: []*pb.QuoteShippingResponse_Quote{
+				{ShippingMethodId: 7, Name: "Standard", Amount: &pb.Money{Amount: 0, Currency: "USD"}},
+			}},
+			setup: func(addressRepo *mocks.AddressRepository, productRepo *mocks.ProductRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+				addressRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(1), int64(2)).Return(&entity.Address{
+					ID:     pg_util.NullInt64(2),
+					Region: pg_util.NullString("US-CA"),
+				}, nil)
+				productRepo.On("RetrieveByID", mock.Anything, mock.Anything, int64(5)).Return(&entity.Product{
+					ID:          pg_util.NullInt64(5),
+					Price:       pg_util.NullInt64(10000),
+					Currency:    pg_util.NullString("USD"),
+					WeightGrams: pg_util.NullInt64(2000),
+				}, nil)
+				shippingMethodRepo.On("List", mock.Anything, mock.Anything).Return([]*entity.ShippingMethod{
+					{ID: pg_util.NullInt64(7), Name: pg_util.NullString("Standard"), Currency: pg_util.NullString("USD"), FreeThreshold: pg_util.NullInt64(5000)},
+					{ID: pg_util.NullInt64(8), Name: pg_util.NullString("Europe"), Currency: pg_util.NullString("USD")},
+				}, nil)
+				shippingMethodRepo.On("ListRates", mock.Anything, mock.Anything, []int64{7, 8}).Return([]*entity.ShippingRate{
+					{ShippingMethodID: pg_util.NullInt64(7), Regions: pg_util.StringArray([]string{"US"}), Amount: pg_util.NullInt64(900)},
+					{ShippingMethodID: pg_util.NullInt64(8), Regions: pg_util.StringArray([]string{"DE"}), Amount: pg_util.NullInt64(1500)},
+				}, nil)
+			},
+		},
+		{
+			name:    "err no default address",
+			ctx:     userCtx,
+			req:     &pb.QuoteShippingRequest{ProductId: 5},
+			wantErr: status.Errorf(codes.FailedPrecondition, "shipping address is required"),
+			setup: func(addressRepo *mocks.AddressRepository, productRepo *mocks.ProductRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+				addressRepo.On("RetrieveDefault", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "err invalid user",
+			ctx:     context.Background(),
+			req:     &pb.QuoteShippingRequest{ProductId: 5},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup: func(addressRepo *mocks.AddressRepository, productRepo *mocks.ProductRepository, shippingMethodRepo *mocks.ShippingMethodRepository) {
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addressRepo := &mocks.AddressRepository{}
+			productRepo := &mocks.ProductRepository{}
+			scheduledPriceRepo := &mocks.ScheduledPriceRepository{}
+			shippingMethodRepo := &mocks.ShippingMethodRepository{}
+			scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(sql.NullTime{}, nil)
+			tt.setup(addressRepo, productRepo, shippingMethodRepo)
+			s := &productService{
+				addressRepo:        addressRepo,
+				productRepo:        productRepo,
+				scheduledPriceRepo: scheduledPriceRepo,
+				shippingMethodRepo: shippingMethodRepo,
+			}
+			got, err := s.QuoteShipping(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_productService_CreateShippingMethod(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		req     *pb.CreateShippingMethodRequest
+		wantErr error
+	}{
+		{
+			name: "err invalid weight bounds",
+			req: &pb.CreateShippingMethodRequest{Name: "Standard", Rules: []*pb.ShippingRule{
+				{MinWeightGrams: 1000, MaxWeightGrams: 500, Amount: &pb.Money{Amount: 500, Currency: "USD"}},
+			}},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid weight bounds"),
+		},
+		{
+			name: "err amount in another currency",
+			req: &pb.CreateShippingMethodRequest{Name: "Standard", Rules: []*pb.ShippingRule{
+				{Amount: &pb.Money{Amount: 500, Currency: "EUR"}},
+			}},
+			wantErr: status.Errorf(codes.InvalidArgument, "amount must be in USD"),
+		},
+		{
+			name: "err invalid region",
+			req: &pb.CreateShippingMethodRequest{Name: "Standard", Rules: []*pb.ShippingRule{
+				{Regions: []string{"usa"}, Amount: &pb.Money{Amount: 500, Currency: "USD"}},
+			}},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid region"),
+		},
+		{
+			name:    "err missing rules",
+			req:     &pb.CreateShippingMethodRequest{Name: "Standard"},
+			wantErr: status.Errorf(codes.InvalidArgument, "rules are required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &productService{
+				shippingMethodRepo: &mocks.ShippingMethodRepository{},
+				baseCurrency:       "USD",
+			}
+			_, err := s.CreateShippingMethod(adminCtx, tt.req)
+			require.Error(t, err)
+			require.Equal(t, tt.wantErr.Error(), err.Error())
+		})
+	}
+}
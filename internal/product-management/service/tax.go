@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/tax"
+)
+
+// toTaxRate transforms a tax rate entity to the rate of the tax engine.
+func toTaxRate(rate *entity.TaxRate) tax.Rate {
+	return tax.Rate{
+		Region:      rate.Region.String,
+		Category:    rate.Category.String,
+		BasisPoints: rate.BasisPoints.Int64,
+		Inclusive:   rate.Inclusive.Bool,
+	}
+}
+
+// toPbTaxRate transforms a rate of the tax engine to the response format.
+func toPbTaxRate(rate tax.Rate) *pb.TaxRate {
+	return &pb.TaxRate{
+		Region:      rate.Region,
+		Category:    rate.Category,
+		BasisPoints: rate.BasisPoints,
+		Inclusive:   rate.Inclusive,
+	}
+}
+
+// validRegion returns the region of a request in upper case.
+func validRegion(region string) (string, error) {
+	region = strings.ToUpper(region)
+	if !tax.IsRegion(region) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid region")
+	}
+
+	return region, nil
+}
+
+// taxRate returns the tax rate of a product category in a region, it returns false for an untaxed region.
+// The rates set by the admins for the region win over the default rates of the configuration.
+func (s *productService) taxRate(ctx context.Context, region, category string) (tax.Rate, bool, error) {
+	// Retrieve the rates set for the region and its country
+	regions := tax.Regions(region)
+	set, err := s.taxRateRepo.ListByRegions(ctx, s.db, regions)
+	if err != nil {
+		return tax.Rate{}, false, status.Errorf(codes.Internal, "unable to retrieve tax rates: %v", err.Error())
+	}
+
+	// Complete them with the default rates which are not set
+	type key struct{ region, category string }
+	rates := make([]tax.Rate, 0, len(set))
+	keys := make(map[key]bool, len(set))
+	for _, rate := range set {
+		rates = append(rates, toTaxRate(rate))
+		keys[key{rate.Region.String, rate.Category.String}] = true
+	}
+	for _, rate := range s.taxRates {
+		if !keys[key{rate.Region, rate.Category}] {
+			rates = append(rates, rate)
+		}
+	}
+
+	rate, ok := tax.Lookup(rates, region, category)
+
+	return rate, ok, nil
+}
+
+// SetTaxRate is a method of the productService that sets the tax rate of a region for a product type,
+// the rate without category is the default rate of the region.
+func (s *productService) SetTaxRate(ctx context.Context, req *pb.SetTaxRateRequest) (*pb.SetTaxRateResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the rate
+	region, err := validRegion(req.GetRegion())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetBasisPoints() < 0 || req.GetBasisPoints() > tax.MaxBasisPoints {
+		return nil, status.Errorf(codes.InvalidArgument, "basis_points must be between 0 and %d", tax.MaxBasisPoints)
+	}
+
+	// Set the rate
+	if err := s.taxRateRepo.Upsert(ctx, s.db, &entity.TaxRate{
+		Region:      pg_util.NullString(region),
+		Category:    pg_util.NullString(req.GetCategory()),
+		BasisPoints: pg_util.NullInt64(req.GetBasisPoints()),
+		Inclusive:   sql.NullBool{Bool: req.GetInclusive(), Valid: true},
+		UpdatedBy:   pg_util.NullInt64(userCtx.UserID),
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to set tax rate: %v", err.Error())
+	}
+
+	return &pb.SetTaxRateResponse{}, nil
+}
+
+// RemoveTaxRate is a method of the productService that removes the tax rate set for a region and a product type,
+// the default rate of the configuration applies again.
+func (s *productService) RemoveTaxRate(ctx context.Context, req *pb.RemoveTaxRateRequest) (*pb.RemoveTaxRateResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Remove the rate
+	err := s.taxRateRepo.Delete(ctx, s.db, strings.ToUpper(req.GetRegion()), req.GetCategory())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.NotFound, "tax rate not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to remove tax rate: %v", err.Error())
+	}
+
+	return &pb.RemoveTaxRateResponse{}, nil
+}
+
+// ListTaxRate is a method of the productService that lists the tax rates set by the admins and the default rates.
+func (s *productService) ListTaxRate(ctx context.Context, _ *pb.ListTaxRateRequest) (*pb.ListTaxRateResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the rates
+	rates, err := s.taxRateRepo.List(ctx, s.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve tax rates: %v", err.Error())
+	}
+
+	// Transform the rates to the response format
+	respData := make([]*pb.TaxRate, 0, len(rates))
+	for _, rate := range rates {
+		respRate := toPbTaxRate(toTaxRate(rate))
+		if rate.UpdatedAt.Valid {
+			respRate.UpdatedAt = timestamppb.New(rate.UpdatedAt.Time)
+		}
+		respData = append(respData, respRate)
+	}
+	defaults := make([]*pb.TaxRate, 0, len(s.taxRates))
+	for _, rate := range s.taxRates {
+		defaults = append(defaults, toPbTaxRate(rate))
+	}
+
+	return &pb.ListTaxRateResponse{
+		Data:     respData,
+		Defaults: defaults,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/pg_util"
+	"trintech/review/pkg/tax"
+)
+
+func Test_productService_taxRate(t *testing.T) {
+	defaults := []tax.Rate{
+		{Region: "DE", BasisPoints: 1900, Inclusive: true},
+		{Region: "DE", Category: "BOOK", BasisPoints: 700, Inclusive: true},
+	}
+	tests := []struct {
+		name     string
+		region   string
+		category string
+		set      []*entity.TaxRate
+		want     tax.Rate
+		wantOk   bool
+	}{
+		{
+			name:     "default rate",
+			region:   "DE",
+			category: "BOOK",
+			want:     defaults[1],
+			wantOk:   true,
+		},
+		{
+			name:     "set rate wins over default rate",
+			region:   "DE",
+			category: "BOOK",
+			set: []*entity.TaxRate{{
+				Region:      pg_util.NullString("DE"),
+				Category:    pg_util.NullString("BOOK"),
+				BasisPoints: pg_util.NullInt64(0),
+			}},
+			want:   tax.Rate{Region: "DE", Category: "BOOK"},
+			wantOk: true,
+		},
+		{
+			name:     "untaxed region",
+			region:   "FR",
+			category: "BOOK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taxRateRepo := &mocks.TaxRateRepository{}
+			taxRateRepo.On("ListByRegions", mock.Anything, mock.Anything, []string{tt.region}).Return(tt.set, nil)
+			s := &productService{
+				taxRateRepo: taxRateRepo,
+				taxRates:    defaults,
+			}
+			got, ok, err := s.taxRate(context.Background(), tt.region, tt.category)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
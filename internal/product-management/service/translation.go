@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/locale"
+	"trintech/review/pkg/pg_util"
+)
+
+// validTranslationLocale validates the locale of a translation, the content in the default locale is not a translation.
+func validTranslationLocale(tag string) (string, error) {
+	l, err := locale.Parse(tag)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid locale %s", tag)
+	}
+	if l == locale.Default {
+		return "", status.Errorf(codes.InvalidArgument, "%s is the default locale, it is not translated", l)
+	}
+
+	return l, nil
+}
+
+// localiseProducts replaces the name, the description and the category name of products by their translation
+// in the first locale of the fallback chain of the request they are translated in.
+func (s *productService) localiseProducts(ctx context.Context, products []*pb.Product) error {
+	for _, product := range products {
+		product.Locale = locale.Default
+		product.CategoryName = product.GetType()
+	}
+
+	// The products are in the default locale, the last locale of the chain
+	chain := http_server.ExtractLocalesFromCtx(ctx)
+	locales := chain[:len(chain)-1]
+	if len(locales) == 0 || len(products) == 0 {
+		return nil
+	}
+	rank := func(l string) int {
+		return slices.Index(locales, l)
+	}
+
+	// Retrieve the translations of the products and their categories in the locales of the chain
+	ids := make([]int64, 0, len(products))
+	var categories []string
+	for _, product := range products {
+		ids = append(ids, product.GetId())
+		if product.GetType() != "" && !slices.Contains(categories, product.GetType()) {
+			categories = append(categories, product.GetType())
+		}
+	}
+	productTranslations, err := s.productTranslationRepo.ListByProductIDs(ctx, s.db, ids, locales)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to retrieve product translations: %v", err.Error())
+	}
+	var categoryTranslations []*entity.CategoryTranslation
+	if len(categories) > 0 {
+		if categoryTranslations, err = s.categoryTranslationRepo.ListByCategories(ctx, s.db, categories, locales); err != nil {
+			return status.Errorf(codes.Internal, "unable to retrieve category translations: %v", err.Error())
+		}
+	}
+
+	// Keep the translation of the preferred locale
+	productByID := make(map[int64]*entity.ProductTranslation, len(products))
+	for _, translation := range productTranslations {
+		best, ok := productByID[translation.ProductID.Int64]
+		if !ok || rank(translation.Locale.String) < rank(best.Locale.String) {
+			productByID[translation.ProductID.Int64] = translation
+		}
+	}
+	categoryByName := make(map[string]*entity.CategoryTranslation, len(categories))
+	for _, translation := range categoryTranslations {
+		best, ok := categoryByName[translation.Category.String]
+		if !ok || rank(translation.Locale.String) < rank(best.Locale.String) {
+			categoryByName[translation.Category.String] = translation
+		}
+	}
+
+	for _, product := range products {
+		if translation, ok := productByID[product.GetId()]; ok {
+			product.Locale = translation.Locale.String
+			product.Name = translation.Name.String
+			if translation.Description.Valid {
+				product.Description = translation.Description.String
+			}
+		}
+		if translation, ok := categoryByName[product.GetType()]; ok {
+			product.CategoryName = translation.Name.String
+		}
+	}
+
+	return nil
+}
+
+// SetProductTranslation is a method of the productService that sets the name and description of a product in a locale.
+// The description falls back to the description in the default locale when it is empty.
+func (s *productService) SetProductTranslation(ctx context.Context, req *pb.SetProductTranslationRequest) (*pb.SetProductTranslationResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the translation
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "name is required")
+	}
+
+	// Set the translation and increment the version of the product in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.productRepo.UpdateByID(ctx, tx, req.GetProductId(), &entity.Product{}, nil); err != nil {
+			return err
+		}
+
+		return s.productTranslationRepo.Upsert(ctx, tx, &entity.ProductTranslation{
+			ProductID:   pg_util.NullInt64(req.GetProductId()),
+			Locale:      pg_util.NullString(l),
+			Name:        pg_util.NullString(name),
+			Description: pg_util.NullEmptyString(strings.TrimSpace(req.GetDescription())),
+			UpdatedBy:   pg_util.NullInt64(userCtx.UserID),
+		})
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "product not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to set product translation: %v", err.Error())
+	}
+
+	// Publish the update of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetProductId())
+
+	return &pb.SetProductTranslationResponse{}, nil
+}
+
+// RemoveProductTranslation is a method of the productService that removes the translation of a product in a locale,
+// the product falls back to the next locale of the chain in this locale.
+func (s *productService) RemoveProductTranslation(ctx context.Context, req *pb.RemoveProductTranslationRequest) (*pb.RemoveProductTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the locale
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the translation and increment the version of the product in a database transaction
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		err := s.productTranslationRepo.Delete(ctx, tx, req.GetProductId(), l)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "product translation not found")
+		case err != nil:
+			return err
+		}
+
+		return s.productRepo.UpdateByID(ctx, tx, req.GetProductId(), &entity.Product{}, nil)
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "product not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to remove product translation: %v", err.Error())
+	}
+
+	// Publish the update of the product
+	s.publishProductChanged(ctx, productChangedAction_Updated, req.GetProductId())
+
+	return &pb.RemoveProductTranslationResponse{}, nil
+}
+
+// ListProductTranslation is a method of the productService that lists the translations of a product by locale.
+func (s *productService) ListProductTranslation(ctx context.Context, req *pb.ListProductTranslationRequest) (*pb.ListProductTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// List the translations of the product
+	translations, err := s.productTranslationRepo.ListByProductIDs(ctx, s.db, []int64{req.GetProductId()}, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list product translations: %v", err.Error())
+	}
+
+	data := make([]*pb.ProductTranslation, 0, len(translations))
+	for _, translation := range translations {
+		data = append(data, &pb.ProductTranslation{
+			Locale:      translation.Locale.String,
+			Name:        translation.Name.String,
+			Description: translation.Description.String,
+			UpdatedAt:   timestamppb.New(translation.UpdatedAt.Time),
+		})
+	}
+
+	return &pb.ListProductTranslationResponse{
+		Data: data,
+	}, nil
+}
+
+// SetCategoryTranslation is a method of the productService that sets the name of a category, a product type, in a locale.
+func (s *productService) SetCategoryTranslation(ctx context.Context, req *pb.SetCategoryTranslationRequest) (*pb.SetCategoryTranslationResponse, error) {
+	// Validate admin user
+	userCtx, err := validAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the translation
+	if req.GetCategory() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "category is required")
+	}
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "name is required")
+	}
+
+	// Set the translation and increment the version of the products of the category in a database transaction
+	var productIDs []int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := s.categoryTranslationRepo.Upsert(ctx, tx, &entity.CategoryTranslation{
+			Category:  pg_util.NullString(req.GetCategory()),
+			Locale:    pg_util.NullString(l),
+			Name:      pg_util.NullString(name),
+			UpdatedBy: pg_util.NullInt64(userCtx.UserID),
+		}); err != nil {
+			return err
+		}
+
+		var err error
+		productIDs, err = s.productRepo.IncrementVersionByType(ctx, tx, req.GetCategory())
+		return err
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to set category translation: %v", err.Error())
+	}
+
+	// Publish the update of the products of the category
+	if len(productIDs) > 0 {
+		s.publishProductChanged(ctx, productChangedAction_Updated, productIDs...)
+	}
+
+	return &pb.SetCategoryTranslationResponse{}, nil
+}
+
+// RemoveCategoryTranslation is a method of the productService that removes the translation of a category in a locale.
+func (s *productService) RemoveCategoryTranslation(ctx context.Context, req *pb.RemoveCategoryTranslationRequest) (*pb.RemoveCategoryTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the locale
+	l, err := validTranslationLocale(req.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the translation and increment the version of the products of the category in a database transaction
+	var productIDs []int64
+	if err := database.Transaction(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		err := s.categoryTranslationRepo.Delete(ctx, tx, req.GetCategory(), l)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return status.Errorf(codes.NotFound, "category translation not found")
+		case err != nil:
+			return err
+		}
+
+		productIDs, err = s.productRepo.IncrementVersionByType(ctx, tx, req.GetCategory())
+		return err
+	}); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to remove category translation: %v", err.Error())
+	}
+
+	// Publish the update of the products of the category
+	if len(productIDs) > 0 {
+		s.publishProductChanged(ctx, productChangedAction_Updated, productIDs...)
+	}
+
+	return &pb.RemoveCategoryTranslationResponse{}, nil
+}
+
+// ListCategoryTranslation is a method of the productService that lists the translations of a category by locale.
+func (s *productService) ListCategoryTranslation(ctx context.Context, req *pb.ListCategoryTranslationRequest) (*pb.ListCategoryTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// List the translations of the category
+	translations, err := s.categoryTranslationRepo.ListByCategories(ctx, s.db, []string{req.GetCategory()}, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list category translations: %v", err.Error())
+	}
+
+	data := make([]*pb.CategoryTranslation, 0, len(translations))
+	for _, translation := range translations {
+		data = append(data, &pb.CategoryTranslation{
+			Locale:    translation.Locale.String,
+			Name:      translation.Name.String,
+			UpdatedAt: timestamppb.New(translation.UpdatedAt.Time),
+		})
+	}
+
+	return &pb.ListCategoryTranslationResponse{
+		Data: data,
+	}, nil
+}
+
+// ListMissingTranslation is a method of the productService that reports the products or the categories which are
+// not translated in one of the locales, with the locales they are missing.
+func (s *productService) ListMissingTranslation(ctx context.Context, req *pb.ListMissingTranslationRequest) (*pb.ListMissingTranslationResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Validate the locales
+	if len(req.GetLocales()) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "locales are required")
+	}
+	locales := make([]string, 0, len(req.GetLocales()))
+	for _, tag := range req.GetLocales() {
+		l, err := validTranslationLocale(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(locales, l) {
+			locales = append(locales, l)
+		}
+	}
+
+	// List the missing translations of the kind with their total count
+	var data []*pb.ListMissingTranslationResponse_MissingTranslation
+	var total int64
+	switch req.GetKind() {
+	case pb.TranslationKind_TranslationKind_PRODUCT:
+		missing, err := s.productTranslationRepo.ListMissing(ctx, s.db, locales, req.GetOffset(), req.GetLimit())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to list missing product translations: %v", err.Error())
+		}
+		if total, err = s.productTranslationRepo.CountMissing(ctx, s.db, locales); err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to count missing product translations: %v", err.Error())
+		}
+		for _, m := range missing {
+			data = append(data, &pb.ListMissingTranslationResponse_MissingTranslation{
+				ProductId: m.ProductID.Int64,
+				Name:      m.Name.String,
+				Locales:   m.Locales,
+			})
+		}
+	case pb.TranslationKind_TranslationKind_CATEGORY:
+		missing, err := s.categoryTranslationRepo.ListMissing(ctx, s.db, locales, req.GetOffset(), req.GetLimit())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to list missing category translations: %v", err.Error())
+		}
+		if total, err = s.categoryTranslationRepo.CountMissing(ctx, s.db, locales); err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to count missing category translations: %v", err.Error())
+		}
+		for _, m := range missing {
+			data = append(data, &pb.ListMissingTranslationResponse_MissingTranslation{
+				Category: m.Category.String,
+				Locales:  m.Locales,
+			})
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid kind")
+	}
+
+	return &pb.ListMissingTranslationResponse{
+		Data:  data,
+		Total: total,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/internal/product-management/entity"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_localiseProducts(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{http_server.MDLocale: []string{"vi-VN", "fr"}})
+	productTranslationRepo := &mocks.ProductTranslationRepository{}
+	productTranslationRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{1, 2, 3}, []string{"vi-VN", "vi", "fr"}).
+		Return([]*entity.ProductTranslation{
+			{ProductID: pg_util.NullInt64(1), Locale: pg_util.NullString("fr"), Name: pg_util.NullString("Chaise")},
+			{ProductID: pg_util.NullInt64(1), Locale: pg_util.NullString("vi"), Name: pg_util.NullString("Ghế"), Description: pg_util.NullString("Ghế gỗ")},
+			{ProductID: pg_util.NullInt64(2), Locale: pg_util.NullString("fr"), Name: pg_util.NullString("Table")},
+		}, nil)
+	categoryTranslationRepo := &mocks.CategoryTranslationRepository{}
+	categoryTranslationRepo.On("ListByCategories", mock.Anything, mock.Anything, []string{"furniture", "lamp"}, []string{"vi-VN", "vi", "fr"}).
+		Return([]*entity.CategoryTranslation{
+			{Category: pg_util.NullString("furniture"), Locale: pg_util.NullString("vi-VN"), Name: pg_util.NullString("Nội thất")},
+		}, nil)
+	s := &productService{
+		productTranslationRepo:  productTranslationRepo,
+		categoryTranslationRepo: categoryTranslationRepo,
+	}
+
+	products := []*pb.Product{
+		{Id: 1, Name: "Chair", Description: "Wooden chair", Type: "furniture"},
+		{Id: 2, Name: "Table", Description: "Oak table", Type: "furniture"},
+		{Id: 3, Name: "Lamp", Description: "Desk lamp", Type: "lamp"},
+	}
+	require.NoError(t, s.localiseProducts(ctx, products))
+	require.Equal(t, []*pb.Product{
+		{Id: 1, Name: "Ghế", Description: "Ghế gỗ", Type: "furniture", CategoryName: "Nội thất", Locale: "vi"},
+		{Id: 2, Name: "Table", Description: "Oak table", Type: "furniture", CategoryName: "Nội thất", Locale: "fr"},
+		{Id: 3, Name: "Lamp", Description: "Desk lamp", Type: "lamp", CategoryName: "lamp", Locale: "en"},
+	}, products)
+}
+
+func Test_productService_ListMissingTranslation(t *testing.T) {
+	adminCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 9,
+		Role:   userEntity.UserRole_Admin,
+	}))
+	tests := []struct {
+		name    string
+		req     *pb.ListMissingTranslationRequest
+		want    *pb.ListMissingTranslationResponse
+		wantErr error
+	}{
+		{
+			name: "happy case categories",
+			req:  &pb.ListMissingTranslationRequest{Kind: pb.TranslationKind_TranslationKind_CATEGORY, Locales: []string{"vi", "VI", "fr"}, Limit: 10},
+			want: &pb.ListMissingTranslationResponse{
+				Data: []*pb.ListMissingTranslationResponse_MissingTranslation{
+					{Category: "lamp", Locales: []string{"fr", "vi"}},
+				},
+				Total: 1,
+			},
+		},
+		{
+			name:    "err locales are required",
+			req:     &pb.ListMissingTranslationRequest{},
+			wantErr: status.Errorf(codes.InvalidArgument, "locales are required"),
+		},
+		{
+			name:    "err invalid locale",
+			req:     &pb.ListMissingTranslationRequest{Locales: []string{"vi", "not a locale"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid locale not a locale"),
+		},
+		{
+			name:    "err invalid kind",
+			req:     &pb.ListMissingTranslationRequest{Kind: pb.TranslationKind(9), Locales: []string{"vi"}},
+			wantErr: status.Errorf(codes.InvalidArgument, "invalid kind"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categoryTranslationRepo := &mocks.CategoryTranslationRepository{}
+			categoryTranslationRepo.On("ListMissing", mock.Anything, mock.Anything, []string{"vi", "fr"}, int64(0), int64(10)).
+				Return([]*entity.MissingCategoryTranslation{
+					{Category: pg_util.NullString("lamp"), Locales: []string{"fr", "vi"}},
+				}, nil)
+			categoryTranslationRepo.On("CountMissing", mock.Anything, mock.Anything, []string{"vi", "fr"}).Return(int64(1), nil)
+			s := &productService{
+				categoryTranslationRepo: categoryTranslationRepo,
+			}
+			got, err := s.ListMissingTranslation(adminCtx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	"trintech/review/pkg/crypto_util"
+	"trintech/review/pkg/http_server"
+)
+
+// wishlistShareTokenSize is the number of random bytes of a wishlist share token.
+const wishlistShareTokenSize = 16
+
+// AddToWishlist is a method of the productService that saves a product in the user's wishlist.
+// Adding a product which is already in the wishlist succeeds without change.
+func (s *productService) AddToWishlist(ctx context.Context, req *pb.AddToWishlistRequest) (*pb.AddToWishlistResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the product by ID
+	if _, err := s.productRepo.RetrieveByID(ctx, s.db, req.GetProductId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not found, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not found")
+		}
+
+		// If there is an error during product retrieval, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to retrieve product: %v", err.Error())
+	}
+
+	// Save the product in the wishlist
+	if err := s.wishlistRepo.AddItem(ctx, s.db, userCtx.UserID, req.GetProductId()); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to add product to wishlist: %v", err.Error())
+	}
+
+	// Return an empty response indicating successful addition
+	return &pb.AddToWishlistResponse{}, nil
+}
+
+// RemoveFromWishlist is a method of the productService that removes a product from the user's wishlist.
+func (s *productService) RemoveFromWishlist(ctx context.Context, req *pb.RemoveFromWishlistRequest) (*pb.RemoveFromWishlistResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Remove the product from the wishlist
+	if err := s.wishlistRepo.RemoveItem(ctx, s.db, userCtx.UserID, req.GetProductId()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// If the product is not in the wishlist, return a not found error
+			return nil, status.Errorf(codes.NotFound, "product not in wishlist")
+		}
+
+		// If there is an error during removal, return an internal server error
+		return nil, status.Errorf(codes.Internal, "unable to remove product from wishlist: %v", err.Error())
+	}
+
+	// Return an empty response indicating successful removal
+	return &pb.RemoveFromWishlistResponse{}, nil
+}
+
+// ListWishlist is a method of the productService that retrieves the products of the user's wishlist.
+func (s *productService) ListWishlist(ctx context.Context, req *pb.ListWishlistRequest) (*pb.ListWishlistResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Retrieve the wishlist products
+	respData, total, err := s.listWishlistProducts(ctx, userCtx.UserID, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the share token, a wishlist which was never shared has none
+	var shareToken string
+	wishlist, err := s.wishlistRepo.RetrieveByUserID(ctx, s.db, userCtx.UserID)
+	switch {
+	case err == nil:
+		shareToken = wishlist.ShareToken.String
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, status.Errorf(codes.Internal, "unable to retrieve wishlist: %v", err.Error())
+	}
+
+	// Return the list of products and total count in the response
+	return &pb.ListWishlistResponse{
+		Data:       respData,
+		Total:      total,
+		ShareToken: shareToken,
+	}, nil
+}
+
+// ShareWishlist is a method of the productService that returns the token of the public link of the user's wishlist.
+// The token is generated on the first share and kept until the user regenerates it.
+func (s *productService) ShareWishlist(ctx context.Context, req *pb.ShareWishlistRequest) (*pb.ShareWishlistResponse, error) {
+	// Extract user information from the context
+	userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx)
+	if !ok {
+		// If user information is not found, return a permission denied error
+		return nil, status.Errorf(codes.PermissionDenied, "user doesn't have permission")
+	}
+
+	// Reuse the current share token unless the user asks for a new one
+	if !req.GetRegenerate() {
+		wishlist, err := s.wishlistRepo.RetrieveByUserID(ctx, s.db, userCtx.UserID)
+		switch {
+		case err == nil && wishlist.ShareToken.Valid:
+			return &pb.ShareWishlistResponse{
+				ShareToken: wishlist.ShareToken.String,
+			}, nil
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return nil, status.Errorf(codes.Internal, "unable to retrieve wishlist: %v", err.Error())
+		}
+	}
+
+	// Generate and store a new share token
+	shareToken, err := crypto_util.GenerateToken(wishlistShareTokenSize)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to share wishlist: %v", err.Error())
+	}
+	if err := s.wishlistRepo.UpsertShareToken(ctx, s.db, userCtx.UserID, shareToken); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to share wishlist: %v", err.Error())
+	}
+
+	// Return the share token
+	return &pb.ShareWishlistResponse{
+		ShareToken: shareToken,
+	}, nil
+}
+
+// RetrieveSharedWishlist is a method of the productService that retrieves the products of a shared wishlist.
+// It doesn't require a user, the share token grants the access.
+func (s *productService) RetrieveSharedWishlist(ctx context.Context, req *pb.RetrieveSharedWishlistRequest) (*pb.RetrieveSharedWishlistResponse, error) {
+	// Retrieve the wishlist by its share token
+	if req.GetShareToken() == "" {
+		return nil, status.Errorf(codes.NotFound, "wishlist not found")
+	}
+	wishlist, err := s.wishlistRepo.RetrieveByShareToken(ctx, s.db, req.GetShareToken())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// If the token doesn't match a wishlist, return a not found error
+		return nil, status.Errorf(codes.NotFound, "wishlist not found")
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "unable to retrieve wishlist: %v", err.Error())
+	}
+
+	// Retrieve the wishlist products
+	respData, total, err := s.listWishlistProducts(ctx, wishlist.UserID.Int64, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the list of products and total count in the response
+	return &pb.RetrieveSharedWishlistResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
+
+// listWishlistProducts retrieves a page of the products of a user's wishlist with their total count.
+func (s *productService) listWishlistProducts(ctx context.Context, userID, offset, limit int64) ([]*pb.Product, int64, error) {
+	// Retrieve the list of products from the repository
+	list, err := s.wishlistRepo.ListProducts(ctx, s.db, userID, offset, limit)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "unable to retrieve wishlist: %v", err.Error())
+	}
+
+	// Transform the list of products to the response format
+	respData, err := s.productViews(ctx, list, s.baseCurrency)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get the total count of products
+	total, err := s.wishlistRepo.CountProducts(ctx, s.db, userID)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "unable to count wishlist: %v", err.Error())
+	}
+
+	return respData, total, nil
+}
+
+// ListProductWishlistCount is a method of the productService that lists the products by the number of wishlists containing them.
+// It validates the admin user and returns the most wished products first.
+func (s *productService) ListProductWishlistCount(ctx context.Context, req *pb.ListProductWishlistCountRequest) (*pb.ListProductWishlistCountResponse, error) {
+	// Validate admin user
+	if _, err := validAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Retrieve the wishlist counts from the repository
+	list, err := s.wishlistRepo.ListProductCounts(ctx, s.db, req.GetOffset(), req.GetLimit())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve wishlist counts: %v", err.Error())
+	}
+
+	// Transform the counts to the response format
+	respData := make([]*pb.ListProductWishlistCountResponse_ProductWishlistCount, 0, len(list))
+	for _, count := range list {
+		respData = append(respData, &pb.ListProductWishlistCountResponse_ProductWishlistCount{
+			ProductId: count.ProductID.Int64,
+			Name:      count.Name.String,
+			Total:     count.Total.Int64,
+		})
+	}
+
+	// Get the total count of wished products
+	total, err := s.wishlistRepo.CountWishedProducts(ctx, s.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to count wished products: %v", err.Error())
+	}
+
+	// Return the wishlist counts and total count in the response
+	return &pb.ListProductWishlistCountResponse{
+		Data:  respData,
+		Total: total,
+	}, nil
+}
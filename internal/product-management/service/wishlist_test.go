@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "trintech/review/dto/product-management/product"
+	storagepb "trintech/review/dto/storage-management/upload"
+	"trintech/review/internal/product-management/entity"
+	memcache "trintech/review/internal/product-management/repository/cache"
+	userEntity "trintech/review/internal/user-management/entity"
+	"trintech/review/mocks"
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/pg_util"
+)
+
+func Test_productService_ShareWishlist(t *testing.T) {
+	userCtx := metadata.NewIncomingContext(context.Background(), http_server.ImportUserInfoToMD(&xcontext.UserInfo{
+		UserID: 1,
+		Role:   userEntity.UserRole_User,
+	}))
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		req       *pb.ShareWishlistRequest
+		wantToken string
+		wantErr   error
+		setup     func(wishlistRepo *mocks.WishlistRepository)
+	}{
+		{
+			name:      "happy case reuse token",
+			ctx:       userCtx,
+			req:       &pb.ShareWishlistRequest{},
+			wantToken: "token",
+			setup: func(wishlistRepo *mocks.WishlistRepository) {
+				wishlistRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(&entity.Wishlist{
+					UserID:     pg_util.NullInt64(1),
+					ShareToken: pg_util.NullString("token"),
+				}, nil)
+			},
+		},
+		{
+			name: "happy case first share",
+			ctx:  userCtx,
+			req:  &pb.ShareWishlistRequest{},
+			setup: func(wishlistRepo *mocks.WishlistRepository) {
+				wishlistRepo.On("RetrieveByUserID", mock.Anything, mock.Anything, int64(1)).Return(nil, sql.ErrNoRows)
+				wishlistRepo.On("UpsertShareToken", mock.Anything, mock.Anything, int64(1), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "happy case regenerate",
+			ctx:  userCtx,
+			req:  &pb.ShareWishlistRequest{Regenerate: true},
+			setup: func(wishlistRepo *mocks.WishlistRepository) {
+				wishlistRepo.On("UpsertShareToken", mock.Anything, mock.Anything, int64(1), mock.Anything).Return(nil)
+			},
+		},
+		{
+			name:    "err invalid user",
+			ctx:     context.Background(),
+			req:     &pb.ShareWishlistRequest{},
+			wantErr: status.Errorf(codes.PermissionDenied, "user doesn't have permission"),
+			setup:   func(wishlistRepo *mocks.WishlistRepository) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wishlistRepo := &mocks.WishlistRepository{}
+			tt.setup(wishlistRepo)
+			s := &productService{
+				wishlistRepo: wishlistRepo,
+			}
+			got, err := s.ShareWishlist(tt.ctx, tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantToken != "" {
+				require.Equal(t, tt.wantToken, got.GetShareToken())
+			} else {
+				require.NotEmpty(t, got.GetShareToken())
+			}
+			wishlistRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_productService_RetrieveSharedWishlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *pb.RetrieveSharedWishlistRequest
+		want    *pb.RetrieveSharedWishlistResponse
+		wantErr error
+		setup   func(wishlistRepo *mocks.WishlistRepository)
+	}{
+		{
+			name: "happy case",
+			req:  &pb.RetrieveSharedWishlistRequest{ShareToken: "token", Limit: 10},
+			want: &pb.RetrieveSharedWishlistResponse{
+				Data: []*pb.Product{{
+					Id:        3,
+					Name:      "Shirt",
+					ImageUrls: []string{"http://files/f1.png"},
+					Price:     &pb.Money{Amount: 2500, Currency: "USD"},
+					Prices:    []*pb.Money{{Amount: 2500, Currency: "USD"}},
+					Images: []*pb.ProductImage{
+						{FileId: "f1", Url: "http://files/f1.png", AltText: "Front", IsPrimary: true},
+					},
+					Locale: "en",
+				}},
+				Total: 1,
+			},
+			setup: func(wishlistRepo *mocks.WishlistRepository) {
+				wishlistRepo.On("RetrieveByShareToken", mock.Anything, mock.Anything, "token").Return(&entity.Wishlist{
+					UserID: pg_util.NullInt64(1),
+				}, nil)
+				wishlistRepo.On("ListProducts", mock.Anything, mock.Anything, int64(1), int64(0), int64(10)).Return([]*entity.Product{
+					{ID: pg_util.NullInt64(3), Name: pg_util.NullString("Shirt"), Price: pg_util.NullInt64(2500), Currency: pg_util.NullString("USD")},
+				}, nil)
+				wishlistRepo.On("CountProducts", mock.Anything, mock.Anything, int64(1)).Return(int64(1), nil)
+			},
+		},
+		{
+			name:    "err empty token",
+			req:     &pb.RetrieveSharedWishlistRequest{},
+			wantErr: status.Errorf(codes.NotFound, "wishlist not found"),
+			setup:   func(wishlistRepo *mocks.WishlistRepository) {},
+		},
+		{
+			name:    "err unknown token",
+			req:     &pb.RetrieveSharedWishlistRequest{ShareToken: "revoked"},
+			wantErr: status.Errorf(codes.NotFound, "wishlist not found"),
+			setup: func(wishlistRepo *mocks.WishlistRepository) {
+				wishlistRepo.On("RetrieveByShareToken", mock.Anything, mock.Anything, "revoked").Return(nil, sql.ErrNoRows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wishlistRepo := &mocks.WishlistRepository{}
+			tt.setup(wishlistRepo)
+			scheduledPriceRepo := &mocks.ScheduledPriceRepository{}
+			scheduledPriceRepo.On("ListActive", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			scheduledPriceRepo.On("NextChangeAfter", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(sql.NullTime{}, nil)
+			priceHistoryRepo := &mocks.PriceHistoryRepository{}
+			priceHistoryRepo.On("ListHighestSince", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			productPriceRepo := &mocks.ProductPriceRepository{}
+			productPriceRepo.On("ListByProductIDs", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+			productImageRepo := &mocks.ProductImageRepository{}
+			productImageRepo.On("ListByProductIDs", mock.Anything, mock.Anything, []int64{3}).Return([]*entity.ProductImage{
+				{ProductID: pg_util.NullInt64(3), FileID: pg_util.NullString("f1"), AltText: pg_util.NullString("Front"), IsPrimary: sql.NullBool{Bool: true, Valid: true}},
+				{ProductID: pg_util.NullInt64(3), FileID: pg_util.NullString("deleted")},
+			}, nil)
+			storageServiceClient := &mocks.UploadServiceClient{}
+			storageServiceClient.On("RetrieveFiles", mock.Anything, &storagepb.RetrieveFilesRequest{Ids: []string{"f1", "deleted"}}, mock.Anything).
+				Return(&storagepb.RetrieveFilesResponse{Data: []*storagepb.File{{Id: "f1", MimeType: "image/png", Url: "http://files/f1.png"}}}, nil)
+			s := &productService{
+				wishlistRepo:         wishlistRepo,
+				productImageRepo:     productImageRepo,
+				storageServiceClient: storageServiceClient,
+				scheduledPriceRepo:   scheduledPriceRepo,
+				priceHistoryRepo:     priceHistoryRepo,
+				productPriceRepo:     productPriceRepo,
+				productCacheRepo:     memcache.NewProductCacheRepository(),
+				baseCurrency:         "USD",
+			}
+			got, err := s.RetrieveSharedWishlist(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				require.Error(t, err)
+				require.Equal(t, tt.wantErr.Error(), err.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
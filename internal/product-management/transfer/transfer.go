@@ -0,0 +1,281 @@
+// Package transfer reads and writes product catalogue rows as CSV or JSON Lines.
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Format is the file format of a catalogue.
+type Format string
+
+const (
+	// FormatCSV is a comma separated file with a header line.
+	FormatCSV Format = "csv"
+	// FormatJSONL is a JSON Lines file with one product per line.
+	FormatJSONL Format = "jsonl"
+)
+
+// imageURLSeparator separates the image urls inside a single CSV cell.
+const imageURLSeparator = "|"
+
+// header is the list of CSV columns, in the order they are written.
+var header = []string{"sku", "external_id", "name", "type", "image_urls", "description", "price", "currency"}
+
+// Row is a product line of a catalogue file.
+// Price is a decimal amount of major units of Currency, e.g. "10.50", an empty currency is the base currency.
+// Columns are the columns present in the line, the header of a CSV file or the keys of a JSON line.
+type Row struct {
+	Line        int64       `json:"-"`
+	Columns     []string    `json:"-"`
+	SKU         string      `json:"sku"`
+	ExternalID  string      `json:"external_id"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	ImageURLs   []string    `json:"image_urls"`
+	Description string      `json:"description"`
+	Price       json.Number `json:"price,omitempty"`
+	Currency    string      `json:"currency,omitempty"`
+}
+
+// RowError is returned by [Reader] when a single line cannot be parsed.
+// The reader can still be used to read the next lines.
+type RowError struct {
+	Line int64
+	Err  error
+}
+
+// Error implements error.
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ParseFormat returns the format matching the given name, or the extension of path when name is empty.
+func ParseFormat(name, path string) (Format, error) {
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	switch Format(strings.ToLower(name)) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSONL, "ndjson":
+		return FormatJSONL, nil
+	}
+
+	return "", fmt.Errorf("unsupported format %q", name)
+}
+
+// Reader reads the rows of a catalogue one at a time.
+type Reader interface {
+	// Read returns the next row, a [*RowError] for a malformed line or io.EOF at the end.
+	Read() (*Row, error)
+}
+
+// NewReader returns a [Reader] of the given format.
+func NewReader(format Format, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		columns, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read csv header: %w", err)
+		}
+		for _, column := range columns {
+			if !slices.Contains(header, column) {
+				return nil, fmt.Errorf("unknown csv column %q", column)
+			}
+		}
+
+		return &csvReader{reader: reader, columns: columns}, nil
+	case FormatJSONL:
+		return &jsonlReader{scanner: bufio.NewScanner(r)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// csvReader is a [Reader] of CSV files.
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+// Read implements [Reader].
+func (r *csvReader) Read() (*Row, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: int64(parseErr.Line), Err: parseErr.Err}
+		}
+
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	if len(record) != len(r.columns) {
+		return nil, &RowError{Line: int64(line), Err: fmt.Errorf("expected %d fields, got %d", len(r.columns), len(record))}
+	}
+
+	row := &Row{Line: int64(line), Columns: slices.Clone(r.columns)}
+	for i, column := range r.columns {
+		value := record[i]
+		switch column {
+		case "sku":
+			row.SKU = value
+		case "external_id":
+			row.ExternalID = value
+		case "name":
+			row.Name = value
+		case "type":
+			row.Type = value
+		case "image_urls":
+			if value != "" {
+				row.ImageURLs = strings.Split(value, imageURLSeparator)
+			}
+		case "description":
+			row.Description = value
+		case "price":
+			if value == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, &RowError{Line: row.Line, Err: fmt.Errorf("invalid price %q", value)}
+			}
+			row.Price = json.Number(value)
+		case "currency":
+			row.Currency = value
+		}
+	}
+
+	return row, nil
+}
+
+// jsonlReader is a [Reader] of JSON Lines files.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int64
+}
+
+// Read implements [Reader].
+func (r *jsonlReader) Read() (*Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := strings.TrimSpace(r.scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		row := &Row{Line: r.line}
+		decoder := json.NewDecoder(strings.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(row); err != nil {
+			return nil, &RowError{Line: r.line, Err: err}
+		}
+
+		// Keep the keys of the line, in the order of the CSV columns
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(data), &fields); err != nil {
+			return nil, &RowError{Line: r.line, Err: err}
+		}
+		for _, column := range header {
+			if _, ok := fields[column]; ok {
+				row.Columns = append(row.Columns, column)
+			}
+		}
+
+		return row, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// Writer writes the rows of a catalogue one at a time.
+type Writer interface {
+	// Write writes a single row.
+	Write(row *Row) error
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// NewWriter returns a [Writer] of the given format.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return nil, fmt.Errorf("unable to write csv header: %w", err)
+		}
+
+		return &csvWriter{writer: writer}, nil
+	case FormatJSONL:
+		writer := bufio.NewWriter(w)
+
+		return &jsonlWriter{writer: writer, encoder: json.NewEncoder(writer)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// csvWriter is a [Writer] of CSV files.
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// Write implements [Writer].
+func (w *csvWriter) Write(row *Row) error {
+	return w.writer.Write([]string{
+		row.SKU,
+		row.ExternalID,
+		row.Name,
+		row.Type,
+		strings.Join(row.ImageURLs, imageURLSeparator),
+		row.Description,
+		row.Price.String(),
+		row.Currency,
+	})
+}
+
+// Flush implements [Writer].
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+// jsonlWriter is a [Writer] of JSON Lines files.
+type jsonlWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+// Write implements [Writer].
+func (w *jsonlWriter) Write(row *Row) error {
+	return w.encoder.Encode(row)
+}
+
+// Flush implements [Writer].
+func (w *jsonlWriter) Flush() error {
+	return w.writer.Flush()
+}
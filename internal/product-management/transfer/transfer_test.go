@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, reader Reader) ([]*Row, []*RowError) {
+	var (
+		rows      []*Row
+		rowErrors []*RowError
+	)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, rowErrors
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	columns := []string{"sku", "name", "image_urls", "price", "currency"}
+	tests := []struct {
+		name          string
+		format        Format
+		data          string
+		wantRows      []*Row
+		wantErrLines  []int64
+		wantCreateErr bool
+	}{
+		{
+			name:   "happy case csv",
+			format: FormatCSV,
+			data: "sku,name,image_urls,price,currency\n" +
+				"SHIRT-1,Shirt,a.png|b.png,10.50,USD\n" +
+				"JEANS-1,Jeans,,20,\n",
+			wantRows: []*Row{
+				{Line: 2, Columns: columns, SKU: "SHIRT-1", Name: "Shirt", ImageURLs: []string{"a.png", "b.png"}, Price: "10.50", Currency: "USD"},
+				{Line: 3, Columns: columns, SKU: "JEANS-1", Name: "Jeans", Price: "20"},
+			},
+		},
+		{
+			name:   "csv keeps reading after a bad line",
+			format: FormatCSV,
+			data: "sku,price\n" +
+				"SHIRT-1,abc\n" +
+				"SHIRT-2\n" +
+				"SHIRT-3,3\n",
+			wantRows: []*Row{
+				{Line: 4, Columns: []string{"sku", "price"}, SKU: "SHIRT-3", Price: "3"},
+			},
+			wantErrLines: []int64{2, 3},
+		},
+		{
+			name:          "err csv unknown column",
+			format:        FormatCSV,
+			data:          "sku,color\n",
+			wantCreateErr: true,
+		},
+		{
+			name:   "happy case jsonl",
+			format: FormatJSONL,
+			data: `{"sku":"SHIRT-1","name":"Shirt","price":10,"currency":"USD"}` + "\n" +
+				"\n" +
+				`{"external_id":"ext-2","name":"Jeans","image_urls":["a.png"]}` + "\n" +
+				`{"sku":"SHIRT-3","color":"red"}` + "\n",
+			wantRows: []*Row{
+				{Line: 1, Columns: []string{"sku", "name", "price", "currency"}, SKU: "SHIRT-1", Name: "Shirt", Price: "10", Currency: "USD"},
+				{Line: 3, Columns: []string{"external_id", "name", "image_urls"}, ExternalID: "ext-2", Name: "Jeans", ImageURLs: []string{"a.png"}},
+			},
+			wantErrLines: []int64{4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(tt.format, strings.NewReader(tt.data))
+			if tt.wantCreateErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			rows, rowErrors := readAll(t, reader)
+			require.Equal(t, tt.wantRows, rows)
+
+			var lines []int64
+			for _, rowErr := range rowErrors {
+				lines = append(lines, rowErr.Line)
+			}
+			require.Equal(t, tt.wantErrLines, lines)
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	rows := []*Row{
+		{SKU: "SHIRT-1", Name: "Shirt, blue", ImageURLs: []string{"a.png", "b.png"}, Price: "10.50", Currency: "USD"},
+		{ExternalID: "ext-2", Name: "Jeans", Description: "slim fit", Price: "20"},
+	}
+
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(format, &buf)
+			require.NoError(t, err)
+			for _, row := range rows {
+				require.NoError(t, writer.Write(row))
+			}
+			require.NoError(t, writer.Flush())
+
+			reader, err := NewReader(format, &buf)
+			require.NoError(t, err)
+			got, rowErrors := readAll(t, reader)
+			require.Empty(t, rowErrors)
+			require.Len(t, got, len(rows))
+			for i := range rows {
+				got[i].Line = 0
+				got[i].Columns = nil
+				require.Equal(t, rows[i], got[i])
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("", "catalogue.CSV")
+	require.NoError(t, err)
+	require.Equal(t, FormatCSV, format)
+
+	format, err = ParseFormat("jsonl", "catalogue.txt")
+	require.NoError(t, err)
+	require.Equal(t, FormatJSONL, format)
+
+	_, err = ParseFormat("", "catalogue.xlsx")
+	require.Error(t, err)
+}
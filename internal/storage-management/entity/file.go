@@ -5,13 +5,14 @@ import (
 )
 
 type File struct {
-	ID        sql.NullString
-	FileName  sql.NullString
-	MimeType  sql.NullString
-	Size      sql.NullInt64
-	CreatedBy sql.NullInt64
-	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
+	ID        sql.NullString `db:"id"`
+	FileName  sql.NullString `db:"file_name"`
+	MimeType  sql.NullString `db:"mime_type"`
+	Size      sql.NullInt64  `db:"size"`
+	URL       sql.NullString `db:"url"`
+	CreatedBy sql.NullInt64  `db:"created_by"`
+	CreatedAt sql.NullTime   `db:"created_at"`
+	UpdatedAt sql.NullTime   `db:"updated_at"`
 }
 
 func (t *File) TableName() string {
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"trintech/review/internal/storage-management/entity"
+	"trintech/review/pkg/database"
+)
+
+type FileRepository interface {
+	Create(ctx context.Context, db database.Executor, data *entity.File) error
+	ListByIDs(ctx context.Context, db database.Executor, ids []string) ([]*entity.File, error)
+}
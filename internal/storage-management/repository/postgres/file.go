@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"trintech/review/internal/storage-management/entity"
+	"trintech/review/internal/storage-management/repository"
+	"trintech/review/pkg/database"
+)
+
+// fileCreateColumns are the columns written when a file is created, the others keep their default.
+var fileCreateColumns = []string{"id", "file_name", "mime_type", "size", "url", "created_by"}
+
+type fileRepository struct{}
+
+func NewFileRepository() repository.FileRepository {
+	return &fileRepository{}
+}
+
+func (r *fileRepository) Create(ctx context.Context, db database.Executor, data *entity.File) error {
+	fieldNames, values := database.SelectFieldMap(data, fileCreateColumns)
+	placeHolders := database.GetPlaceholders(len(fieldNames))
+
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s(%s)
+		VALUES(%s)
+	`, data.TableName(), strings.Join(fieldNames, ","), placeHolders)
+
+	if _, err := db.ExecContext(ctx, stmt, values...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListByIDs lists the files of the given ids, the unknown ids are left out.
+func (r *fileRepository) ListByIDs(ctx context.Context, db database.Executor, ids []string) ([]*entity.File, error) {
+	e := &entity.File{}
+	fieldNames, _ := database.FieldMap(e)
+	stmt := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE id = ANY($1)
+	`, strings.Join(fieldNames, ","), e.TableName())
+
+	rows, err := db.QueryContext(ctx, stmt, pq.StringArray(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*entity.File
+	for rows.Next() {
+		var val entity.File
+		_, values := database.FieldMap(&val)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		result = append(result, &val)
+	}
+
+	return result, rows.Err()
+}
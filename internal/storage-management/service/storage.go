@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"path"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
@@ -12,22 +13,46 @@ import (
 
 	pb "trintech/review/dto/storage-management/upload"
 	"trintech/review/internal/storage-management/entity"
+	"trintech/review/internal/storage-management/repository/postgres"
 	"trintech/review/pkg/database"
 	"trintech/review/pkg/http_server/xcontext"
 	"trintech/review/pkg/pg_util"
 	"trintech/review/pkg/storage"
 )
 
+// maxRetrieveFiles is the maximum number of files retrieved at once.
+const maxRetrieveFiles = 100
+
 // storageService handles file upload functionality using gRPC streaming.
 type storageService struct {
 	storage  storage.Storage
 	fileRepo interface {
 		Create(ctx context.Context, db database.Executor, data *entity.File) error
+		ListByIDs(ctx context.Context, db database.Executor, ids []string) ([]*entity.File, error)
 	}
 	db database.Database
 	pb.UnimplementedUploadServiceServer
 }
 
+// NewStorageService ...
+func NewStorageService(db database.Database, storage storage.Storage) pb.UploadServiceServer {
+	return &storageService{
+		db:       db,
+		storage:  storage,
+		fileRepo: postgres.NewFileRepository(),
+	}
+}
+
+// toPbFile transforms a file entity to the response format.
+func toPbFile(file *entity.File) *pb.File {
+	return &pb.File{
+		Id:       file.ID.String,
+		MimeType: file.MimeType.String,
+		Size:     file.Size.Int64,
+		Url:      file.URL.String,
+	}
+}
+
 // Upload handles the file upload gRPC streaming method.
 func (s *storageService) Upload(stream pb.UploadService_UploadServer) error {
 	ctx := stream.Context()
@@ -75,33 +100,57 @@ func (s *storageService) Upload(stream pb.UploadService_UploadServer) error {
 		}
 	}
 
-	// Upload the file to the storage provider.
-	url, err := s.storage.UploadObject(ctx, fileName, &fileData)
+	// Upload the file to the storage provider, named after its id so that the uploads of a same file name are kept.
+	id := uuid.NewString()
+	url, err := s.storage.UploadObject(ctx, id+path.Ext(fileName), &fileData)
 	if err != nil {
 		return status.Errorf(codes.Internal, "unable to write chunk data: %v", err)
 	}
 
 	// Create a file record in the database.
-	if err := s.fileRepo.Create(ctx, s.db, &entity.File{
-		ID:        pg_util.NullString(uuid.NewString()),
+	file := &entity.File{
+		ID:        pg_util.NullString(id),
 		FileName:  pg_util.NullString(fileName),
 		MimeType:  pg_util.NullString(mimeType),
 		Size:      pg_util.NullInt64(int64(fileSize)),
+		URL:       pg_util.NullString(url),
 		CreatedBy: pg_util.NullInt64(userCtx.UserID),
-	}); err != nil {
+	}
+	if err := s.fileRepo.Create(ctx, s.db, file); err != nil {
 		return status.Errorf(codes.Internal, "unable to create file: %v", err)
 	}
 
 	// Send the response to the client with file information.
 	if err := stream.SendAndClose(&pb.UploadResponse{
-		Data: &pb.File{
-			MimeType: mimeType,
-			Size:     int64(fileSize),
-			Url:      url,
-		},
+		Data: toPbFile(file),
 	}); err != nil {
 		return status.Errorf(codes.Unknown, "unable to response: %v", err)
 	}
 
 	return nil
 }
+
+// RetrieveFiles retrieves the uploaded files of the given ids, the unknown ids are left out.
+func (s *storageService) RetrieveFiles(ctx context.Context, req *pb.RetrieveFilesRequest) (*pb.RetrieveFilesResponse, error) {
+	// Validate the number of files.
+	if len(req.GetIds()) > maxRetrieveFiles {
+		return nil, status.Errorf(codes.InvalidArgument, "too many files, at most %d", maxRetrieveFiles)
+	}
+	if len(req.GetIds()) == 0 {
+		return &pb.RetrieveFilesResponse{}, nil
+	}
+
+	// Retrieve the files.
+	files, err := s.fileRepo.ListByIDs(ctx, s.db, req.GetIds())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to retrieve files: %v", err)
+	}
+
+	// Transform the files to the response format.
+	respData := make([]*pb.File, 0, len(files))
+	for _, file := range files {
+		respData = append(respData, toPbFile(file))
+	}
+
+	return &pb.RetrieveFilesResponse{Data: respData}, nil
+}
@@ -0,0 +1,6 @@
+--  soft delete columns for coupon table
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz,
+  ADD COLUMN IF NOT EXISTS "deleted_by" bigint;
+
+CREATE INDEX IF NOT EXISTS coupons_deleted_at_idx ON coupons(deleted_at);
@@ -0,0 +1,25 @@
+--  store the coupon value as an integer: the minor units of its currency for a value discount,
+--  the basis points for a percent discount, 10.5% is 1050.
+--  the existing values are in USD, the default base currency, which has 2 minor unit digits.
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1
+    FROM information_schema.columns
+    WHERE table_schema = current_schema()
+    AND table_name = 'coupons'
+    AND column_name = 'value'
+    AND data_type = 'double precision'
+  ) THEN
+    ALTER TABLE coupons ALTER COLUMN "value" TYPE bigint USING round("value" * 100);
+  END IF;
+END $$;
+
+--  the currency of a value discount, NULL for a percent discount
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "currency" text;
+
+UPDATE coupons
+SET currency = 'USD'
+WHERE discount_type = 'DiscountType_VALUE'
+AND currency IS NULL;
@@ -0,0 +1,12 @@
+--  create coupon redemption table, the steps of the coupon redemption saga of a purchase by redemption id.
+--  the coupon is NULL for a redemption released before it was reserved.
+CREATE TABLE IF NOT EXISTS coupon_redemptions(
+  "id" text PRIMARY KEY,
+  "coupon_id" bigint REFERENCES coupons("id"),
+  "user_id" bigint,
+  "status" text NOT NULL CHECK ("status" IN ('RESERVED', 'CONFIRMED', 'RELEASED', 'EXPIRED')),
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS coupon_redemptions_reserved_idx ON coupon_redemptions(created_at) WHERE status = 'RESERVED';
@@ -0,0 +1,11 @@
+--  a confirmed redemption is restored when its purchase is refunded, giving the coupon usage back.
+ALTER TABLE coupon_redemptions DROP CONSTRAINT IF EXISTS coupon_redemptions_status_check;
+
+ALTER TABLE coupon_redemptions
+  ADD CONSTRAINT coupon_redemptions_status_check CHECK ("status" IN ('RESERVED', 'CONFIRMED', 'RELEASED', 'EXPIRED', 'RESTORED'));
+
+--  the redemption of a used coupon, the coupons used before the redemption saga have none.
+ALTER TABLE used_coupons
+  ADD COLUMN IF NOT EXISTS "redemption_id" text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS used_coupons_redemption_id_idx ON used_coupons(redemption_id);
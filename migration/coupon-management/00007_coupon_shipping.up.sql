@@ -0,0 +1,3 @@
+--  the coupons which discount the shipping cost too, and not only the products
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "applies_to_shipping" boolean DEFAULT false;
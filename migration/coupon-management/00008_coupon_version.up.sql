@@ -0,0 +1,3 @@
+--  the version of a coupon, it is incremented by every update and is the ETag of the coupon
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+--  create coupon translation table, the description of the coupons in the other locales
+CREATE TABLE IF NOT EXISTS coupon_translations(
+  "coupon_id" bigint NOT NULL REFERENCES coupons("id") ON DELETE CASCADE,
+  "locale" text NOT NULL,
+  "description" text NOT NULL,
+  "updated_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("coupon_id", "locale")
+);
@@ -0,0 +1,3 @@
+--  the eligibility rules of a coupon, the JSON of the rules evaluated when its use is checked
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "rules" jsonb;
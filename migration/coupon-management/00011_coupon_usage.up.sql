@@ -0,0 +1,4 @@
+--  the type of the coupon whose usage a reservation took, the usage is given back when the reservation is released
+--  or expires. the redemptions reserved before the usage was taken have none.
+ALTER TABLE coupon_redemptions
+  ADD COLUMN IF NOT EXISTS "coupon_type" coupon_type;
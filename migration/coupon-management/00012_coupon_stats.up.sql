@@ -0,0 +1,7 @@
+--  the discount given by a used coupon in minor units of its currency, the coupons used before have none.
+ALTER TABLE used_coupons
+  ADD COLUMN IF NOT EXISTS "discount" bigint,
+  ADD COLUMN IF NOT EXISTS "currency" text;
+
+--  the coupons by creator, for the admin listing of the coupons.
+CREATE INDEX IF NOT EXISTS coupons_created_by_idx ON coupons(created_by);
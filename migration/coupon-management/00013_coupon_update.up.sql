@@ -0,0 +1,18 @@
+--  a paused coupon cannot be used until it is resumed.
+ALTER TABLE coupons
+  ADD COLUMN IF NOT EXISTS "paused_at" timestamptz,
+  ADD COLUMN IF NOT EXISTS "paused_by" bigint;
+
+--  create coupon audit table, the audit trail of the changes of the coupons by the admins.
+--  changes are the changed columns with their previous and new values.
+CREATE TABLE IF NOT EXISTS coupon_audits(
+  "id" bigserial PRIMARY KEY,
+  "coupon_id" bigint NOT NULL REFERENCES coupons("id") ON DELETE CASCADE,
+  "action" text NOT NULL CHECK ("action" IN ('CREATED', 'UPDATED', 'PAUSED', 'RESUMED', 'DELETED', 'RESTORED')),
+  "changes" jsonb,
+  "version" bigint,
+  "actor_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS coupon_audits_coupon_id_idx ON coupon_audits(coupon_id, id);
@@ -0,0 +1,6 @@
+--  soft delete columns for product table
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz,
+  ADD COLUMN IF NOT EXISTS "deleted_by" bigint;
+
+CREATE INDEX IF NOT EXISTS products_deleted_at_idx ON products(deleted_at);
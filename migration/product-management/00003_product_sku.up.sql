@@ -0,0 +1,4 @@
+--  stable keys used to upsert products from catalogue imports
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "sku" text UNIQUE,
+  ADD COLUMN IF NOT EXISTS "external_id" text UNIQUE;
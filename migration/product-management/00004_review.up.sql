@@ -0,0 +1,32 @@
+--  rating aggregates of the approved reviews of a product
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "rating_average" float8 DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "rating_count" bigint DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS products_rating_idx ON products(rating_average DESC NULLS LAST, rating_count DESC NULLS LAST);
+
+--  create review table, a user reviews a purchased product once
+CREATE TABLE IF NOT EXISTS reviews(
+  "id" serial PRIMARY KEY,
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "user_id" bigint,
+  "rating" smallint CHECK ("rating" BETWEEN 1 AND 5),
+  "content" text,
+  "status" text,
+  "helpful_count" bigint DEFAULT 0,
+  "moderated_by" bigint,
+  "moderated_at" timestamptz,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  UNIQUE ("product_id", "user_id")
+);
+
+CREATE INDEX IF NOT EXISTS reviews_product_id_status_idx ON reviews(product_id, status);
+
+--  a user marks a review helpful once
+CREATE TABLE IF NOT EXISTS review_helpful_votes(
+  "review_id" bigint REFERENCES reviews("id") ON DELETE CASCADE,
+  "user_id" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("review_id", "user_id")
+);
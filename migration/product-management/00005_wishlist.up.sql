@@ -0,0 +1,17 @@
+--  create wishlist table, holding the public share token of a user's wishlist
+CREATE TABLE IF NOT EXISTS wishlists(
+  "user_id" bigint PRIMARY KEY,
+  "share_token" text UNIQUE,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
+
+--  create wishlist item table
+CREATE TABLE IF NOT EXISTS wishlist_items(
+  "user_id" bigint,
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "created_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("user_id", "product_id")
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_items_product_id_idx ON wishlist_items(product_id);
@@ -0,0 +1,19 @@
+--  create cart table, a cart belongs to a user or is anonymous and identified by its token
+CREATE TABLE IF NOT EXISTS carts(
+  "id" serial PRIMARY KEY,
+  "user_id" bigint UNIQUE,
+  "token" text UNIQUE,
+  "coupon" text,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
+
+--  create cart item table
+CREATE TABLE IF NOT EXISTS cart_items(
+  "cart_id" bigint REFERENCES carts("id") ON DELETE CASCADE,
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "quantity" bigint CHECK ("quantity" > 0),
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("cart_id", "product_id")
+);
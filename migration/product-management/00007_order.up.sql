@@ -0,0 +1,96 @@
+--  create order table
+CREATE TABLE IF NOT EXISTS orders(
+  "id" serial PRIMARY KEY,
+  "user_id" bigint,
+  "status" text,
+  "subtotal" float8,
+  "discount" float8,
+  "total" float8,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders(user_id);
+
+--  create order item table, the product name and price are copied when the order is placed
+CREATE TABLE IF NOT EXISTS order_items(
+  "id" serial PRIMARY KEY,
+  "order_id" bigint REFERENCES orders("id") ON DELETE CASCADE,
+  "product_id" bigint REFERENCES products("id"),
+  "name" text,
+  "unit_price" float8,
+  "quantity" bigint,
+  "subtotal" float8,
+  "created_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON order_items(order_id);
+
+--  create order discount table
+CREATE TABLE IF NOT EXISTS order_discounts(
+  "id" serial PRIMARY KEY,
+  "order_id" bigint REFERENCES orders("id") ON DELETE CASCADE,
+  "coupon" text,
+  "discount_type" text,
+  "value" float8,
+  "amount" float8,
+  "created_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_discounts_order_id_idx ON order_discounts(order_id);
+
+--  create order history table, a row per status transition
+CREATE TABLE IF NOT EXISTS order_histories(
+  "id" serial PRIMARY KEY,
+  "order_id" bigint REFERENCES orders("id") ON DELETE CASCADE,
+  "from_status" text,
+  "to_status" text,
+  "changed_by" bigint,
+  "reason" text,
+  "created_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_histories_order_id_idx ON order_histories(order_id);
+
+--  link purchases to their order
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "order_id" bigint REFERENCES orders("id");
+
+--  convert the existing purchases into paid orders of a single item.
+--  purchase_total was written with the discount instead of the paid amount,
+--  so the totals are recomputed from the price and the discount.
+DO $$
+DECLARE
+  pp RECORD;
+  new_order_id bigint;
+  paid_discount float8;
+BEGIN
+  FOR pp IN
+    SELECT p.ctid AS row_id, p.*, pr.name AS product_name
+    FROM purchased_products p
+    LEFT JOIN products pr ON pr.id = p.product_id
+    WHERE p.order_id IS NULL
+  LOOP
+    paid_discount := CASE
+      WHEN pp.apply_coupon IS NULL THEN 0
+      ELSE LEAST(GREATEST(COALESCE(pp.discount, 0), 0), COALESCE(pp.price, 0))
+    END;
+
+    INSERT INTO orders(user_id, status, subtotal, discount, total, created_at, updated_at)
+    VALUES (pp.user_id, 'PAID', pp.price, paid_discount, COALESCE(pp.price, 0) - paid_discount, pp.created_at, pp.updated_at)
+    RETURNING id INTO new_order_id;
+
+    INSERT INTO order_items(order_id, product_id, name, unit_price, quantity, subtotal, created_at)
+    VALUES (new_order_id, pp.product_id, pp.product_name, pp.price, 1, pp.price, pp.created_at);
+
+    IF pp.apply_coupon IS NOT NULL THEN
+      INSERT INTO order_discounts(order_id, coupon, amount, created_at)
+      VALUES (new_order_id, pp.apply_coupon, paid_discount, pp.created_at);
+    END IF;
+
+    INSERT INTO order_histories(order_id, from_status, to_status, reason, created_at)
+    VALUES (new_order_id, NULL, 'PAID', 'converted from purchased product', pp.created_at);
+
+    UPDATE purchased_products SET order_id = new_order_id WHERE ctid = pp.row_id;
+  END LOOP;
+END $$;
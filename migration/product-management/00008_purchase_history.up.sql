@@ -0,0 +1,20 @@
+--  identify purchases, the existing rows are numbered by the sequence
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "id" bigserial PRIMARY KEY;
+
+CREATE INDEX IF NOT EXISTS purchased_products_created_at_idx ON purchased_products(created_at);
+
+CREATE INDEX IF NOT EXISTS purchased_products_apply_coupon_idx ON purchased_products(apply_coupon);
+
+--  discount and purchase_total were written with the coupon discount, or the price without coupon,
+--  recompute them as the paid discount and amount like the orders of the purchases.
+UPDATE purchased_products
+SET
+  discount = CASE
+    WHEN apply_coupon IS NULL THEN 0
+    ELSE LEAST(GREATEST(COALESCE(discount, 0), 0), COALESCE(price, 0))
+  END,
+  purchase_total = COALESCE(price, 0) - CASE
+    WHEN apply_coupon IS NULL THEN 0
+    ELSE LEAST(GREATEST(COALESCE(discount, 0), 0), COALESCE(price, 0))
+  END;
@@ -0,0 +1,58 @@
+--  store the amounts as integer minor units of their currency instead of float8.
+--  the existing amounts are in USD, the default base currency, which has 2 minor unit digits.
+--  order_discounts.value holds the basis points of a percent discount, 10.5% is 1050.
+DO $$
+DECLARE
+  col RECORD;
+BEGIN
+  FOR col IN
+    SELECT table_name, column_name
+    FROM information_schema.columns
+    WHERE table_schema = current_schema()
+    AND data_type = 'double precision'
+    AND (table_name, column_name) IN (
+      ('products', 'price'),
+      ('purchased_products', 'price'),
+      ('purchased_products', 'discount'),
+      ('purchased_products', 'purchase_total'),
+      ('orders', 'subtotal'),
+      ('orders', 'discount'),
+      ('orders', 'total'),
+      ('order_items', 'unit_price'),
+      ('order_items', 'subtotal'),
+      ('order_discounts', 'value'),
+      ('order_discounts', 'amount')
+    )
+  LOOP
+    EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE bigint USING round(%I * 100)', col.table_name, col.column_name, col.column_name);
+  END LOOP;
+END $$;
+
+--  the currency of the amounts of a row
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "currency" text DEFAULT 'USD';
+
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "currency" text DEFAULT 'USD';
+
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "currency" text DEFAULT 'USD';
+
+--  create product price table, the prices of a product in other currencies than the base currency
+CREATE TABLE IF NOT EXISTS product_prices(
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "currency" text,
+  "amount" bigint CHECK ("amount" >= 0),
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("product_id", "currency")
+);
+
+--  create exchange rate table, rate is the amount of the currency for a unit of the base currency
+CREATE TABLE IF NOT EXISTS exchange_rates(
+  "currency" text PRIMARY KEY,
+  "rate" numeric CHECK ("rate" > 0),
+  "updated_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
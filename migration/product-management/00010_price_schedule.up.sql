@@ -0,0 +1,33 @@
+--  create price history table, every change of the regular price of a product
+CREATE TABLE IF NOT EXISTS price_histories(
+  "id" bigserial PRIMARY KEY,
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "price" bigint,
+  "currency" text,
+  "changed_by" bigint,
+  "reason" text,
+  "created_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_histories_product_id_created_at_idx ON price_histories ("product_id", "created_at");
+
+--  the current prices start the history of the existing products
+INSERT INTO price_histories("product_id", "price", "currency", "changed_by", "reason", "created_at")
+SELECT "id", "price", "currency", "created_by", 'CREATED', COALESCE("updated_at", "created_at", now())
+FROM products
+WHERE NOT EXISTS (SELECT 1 FROM price_histories);
+
+--  create scheduled price table, a price which replaces the regular price between its start and end
+CREATE TABLE IF NOT EXISTS scheduled_prices(
+  "id" bigserial PRIMARY KEY,
+  "product_id" bigint REFERENCES products("id") ON DELETE CASCADE,
+  "price" bigint CHECK ("price" >= 0),
+  "currency" text,
+  "starts_at" timestamptz NOT NULL,
+  "ends_at" timestamptz,
+  "created_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  CHECK ("ends_at" IS NULL OR "ends_at" > "starts_at")
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_prices_product_id_starts_at_idx ON scheduled_prices ("product_id", "starts_at");
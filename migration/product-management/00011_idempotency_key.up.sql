@@ -0,0 +1,10 @@
+--  create idempotency key table, the responses of the mutating requests sent with an Idempotency-Key header.
+--  the key is scoped by method and user, the response is NULL while the request is in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+  "key" text PRIMARY KEY,
+  "request_hash" text NOT NULL,
+  "response" bytea,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys(created_at);
@@ -0,0 +1,15 @@
+--  create outbox event table, the events written in the transaction of a change and relayed after its commit.
+CREATE TABLE IF NOT EXISTS outbox_events(
+  "id" bigserial PRIMARY KEY,
+  "topic" text NOT NULL,
+  "payload" jsonb NOT NULL,
+  "attempts" bigint NOT NULL DEFAULT 0,
+  "last_error" text,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT now(),
+  "processed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events(next_attempt_at, id) WHERE processed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS outbox_events_processed_at_idx ON outbox_events(processed_at);
@@ -0,0 +1,6 @@
+--  the payment of a purchase at the payment provider, the purchases recorded before payments have none
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "payment_id" text,
+  ADD COLUMN IF NOT EXISTS "payment_status" text;
+
+CREATE INDEX IF NOT EXISTS purchased_products_payment_id_idx ON purchased_products(payment_id);
@@ -0,0 +1,21 @@
+--  the refunded part of the total of a purchase, and the coupon redemption restored when it is fully refunded
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "refunded_amount" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "coupon_redemption_id" text;
+
+--  create purchase return table, the returns of purchases requested by their users and refunded once approved
+CREATE TABLE IF NOT EXISTS purchase_returns(
+  "id" bigserial PRIMARY KEY,
+  "purchase_id" bigint NOT NULL REFERENCES purchased_products("id"),
+  "user_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" text NOT NULL,
+  "reason" text NOT NULL,
+  "note" text,
+  "status" text NOT NULL CHECK ("status" IN ('REQUESTED', 'REFUNDED')),
+  "approved_by" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS purchase_returns_purchase_id_idx ON purchase_returns(purchase_id);
@@ -0,0 +1,23 @@
+--  create tax rate table, the rates of the regions set by the admins, an empty category is the default rate of the region
+CREATE TABLE IF NOT EXISTS tax_rates(
+  "region" text,
+  "category" text NOT NULL DEFAULT '',
+  "basis_points" bigint NOT NULL CHECK ("basis_points" BETWEEN 0 AND 10000),
+  "inclusive" boolean NOT NULL DEFAULT false,
+  "updated_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("region", "category")
+);
+
+--  the tax breakdown of a purchase, the purchases without region are untaxed
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "tax" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "tax_region" text,
+  ADD COLUMN IF NOT EXISTS "tax_category" text,
+  ADD COLUMN IF NOT EXISTS "tax_basis_points" bigint,
+  ADD COLUMN IF NOT EXISTS "tax_inclusive" boolean;
+
+--  the tax of an order, its total includes the exclusive taxes
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "tax" bigint NOT NULL DEFAULT 0;
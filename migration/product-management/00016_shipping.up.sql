@@ -0,0 +1,58 @@
+--  the weight of a product, the products without weight weigh nothing
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "weight_grams" bigint CHECK ("weight_grams" >= 0);
+
+--  create address table, the address book of the users with at most one default address per user
+CREATE TABLE IF NOT EXISTS addresses(
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "recipient" text NOT NULL,
+  "line1" text NOT NULL,
+  "line2" text,
+  "city" text NOT NULL,
+  "postal_code" text,
+  "region" text NOT NULL,
+  "phone" text,
+  "is_default" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON addresses(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS addresses_user_id_default_idx ON addresses(user_id) WHERE is_default;
+
+--  create shipping method table, free_threshold is the order value from which the shipping is free
+CREATE TABLE IF NOT EXISTS shipping_methods(
+  "id" bigserial PRIMARY KEY,
+  "name" text NOT NULL,
+  "currency" text NOT NULL,
+  "free_threshold" bigint NOT NULL DEFAULT 0 CHECK ("free_threshold" >= 0),
+  "created_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now()
+);
+
+--  create shipping rate table, the rate rules of the shipping methods
+--  an empty regions is everywhere, and a zero upper bound is unbounded
+CREATE TABLE IF NOT EXISTS shipping_rates(
+  "id" bigserial PRIMARY KEY,
+  "shipping_method_id" bigint NOT NULL REFERENCES shipping_methods("id") ON DELETE CASCADE,
+  "regions" text[] NOT NULL DEFAULT '{}',
+  "min_weight_grams" bigint NOT NULL DEFAULT 0,
+  "max_weight_grams" bigint NOT NULL DEFAULT 0,
+  "min_order_value" bigint NOT NULL DEFAULT 0,
+  "max_order_value" bigint NOT NULL DEFAULT 0,
+  "amount" bigint NOT NULL CHECK ("amount" >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS shipping_rates_shipping_method_id_idx ON shipping_rates(shipping_method_id);
+
+--  the shipping of a purchase, shipping_address is the address it was shipped to when it was purchased
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "shipping_method_id" bigint,
+  ADD COLUMN IF NOT EXISTS "shipping" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "shipping_address" jsonb;
+
+--  the shipping of an order, its total includes it
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "shipping" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+--  create product image table, the ordered images of the products referencing the files of storage-management
+--  with at most one primary image per product
+CREATE TABLE IF NOT EXISTS product_images(
+  "product_id" bigint NOT NULL REFERENCES products("id") ON DELETE CASCADE,
+  "file_id" text NOT NULL,
+  "position" int NOT NULL,
+  "alt_text" text,
+  "is_primary" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("product_id", "file_id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_images_product_id_primary_idx ON product_images(product_id) WHERE is_primary;
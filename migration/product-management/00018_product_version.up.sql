@@ -0,0 +1,3 @@
+--  the version of a product, it is incremented by every update and is the ETag of the product
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,22 @@
+--  create product translation table, the name and description of the products in the other locales
+CREATE TABLE IF NOT EXISTS product_translations(
+  "product_id" bigint NOT NULL REFERENCES products("id") ON DELETE CASCADE,
+  "locale" text NOT NULL,
+  "name" text NOT NULL,
+  "description" text,
+  "updated_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("product_id", "locale")
+);
+
+--  create category translation table, the names of the product categories, the product types, in the other locales
+CREATE TABLE IF NOT EXISTS category_translations(
+  "category" text NOT NULL,
+  "locale" text NOT NULL,
+  "name" text NOT NULL,
+  "updated_by" bigint,
+  "created_at" timestamptz DEFAULT now(),
+  "updated_at" timestamptz DEFAULT now(),
+  PRIMARY KEY ("category", "locale")
+);
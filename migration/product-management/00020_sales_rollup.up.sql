@@ -0,0 +1,30 @@
+--  create sales rollup table, the sales of the purchases aggregated by UTC day, currency and dimension,
+--  the TOTAL dimension has an empty key, the PRODUCT, TYPE and COUPON keys are the product id, type and coupon code.
+CREATE TABLE IF NOT EXISTS sales_rollups(
+  "day" date NOT NULL,
+  "dimension" text NOT NULL CHECK ("dimension" IN ('TOTAL', 'PRODUCT', 'TYPE', 'COUPON')),
+  "dimension_key" text NOT NULL DEFAULT '',
+  "currency" text NOT NULL,
+  "units" bigint NOT NULL DEFAULT 0,
+  "orders" bigint NOT NULL DEFAULT 0,
+  "revenue" bigint NOT NULL DEFAULT 0,
+  "discount" bigint NOT NULL DEFAULT 0,
+  "refunded" bigint NOT NULL DEFAULT 0,
+  "refreshed_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("day", "dimension", "dimension_key", "currency")
+);
+
+CREATE INDEX IF NOT EXISTS sales_rollups_dimension_day_idx ON sales_rollups(dimension, day);
+
+--  create sales rollup watermark table, the purchases updated before refreshed_until are in the rollups
+CREATE TABLE IF NOT EXISTS sales_rollup_watermarks(
+  "name" text PRIMARY KEY,
+  "refreshed_until" timestamptz NOT NULL
+);
+
+INSERT INTO sales_rollup_watermarks(name, refreshed_until)
+VALUES ('sales', 'epoch')
+ON CONFLICT (name) DO NOTHING;
+
+--  find the purchases updated since the last refresh
+CREATE INDEX IF NOT EXISTS purchased_products_updated_at_idx ON purchased_products(updated_at);
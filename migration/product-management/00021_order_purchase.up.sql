@@ -0,0 +1,11 @@
+--  the share of the order discount of an order item and the amount paid for it,
+--  the purchases of the items are recorded with them once the order is paid
+ALTER TABLE order_items
+  ADD COLUMN IF NOT EXISTS "discount" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "total" bigint;
+
+UPDATE order_items SET total = subtotal WHERE total IS NULL;
+
+--  the number of units of a purchase, a purchased product is a single unit and an order item any number of them
+ALTER TABLE purchased_products
+  ADD COLUMN IF NOT EXISTS "quantity" bigint NOT NULL DEFAULT 1 CHECK ("quantity" > 0);
@@ -0,0 +1,4 @@
+--  the redemption of the coupon of an order, reserved when it is placed, confirmed once it is paid,
+--  released when it is cancelled and restored when it is refunded
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "coupon_redemption_id" text;
@@ -0,0 +1,8 @@
+--  the tax of an order item at the rate of its product type in the region of the order,
+--  the total of the item includes the exclusive tax
+ALTER TABLE order_items
+  ADD COLUMN IF NOT EXISTS "tax" bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "tax_region" text,
+  ADD COLUMN IF NOT EXISTS "tax_category" text,
+  ADD COLUMN IF NOT EXISTS "tax_basis_points" bigint,
+  ADD COLUMN IF NOT EXISTS "tax_inclusive" boolean;
@@ -0,0 +1,8 @@
+--  the shipping of an order, shipping_address is the address it is shipped to when it was placed
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "shipping_method_id" bigint,
+  ADD COLUMN IF NOT EXISTS "shipping_address" jsonb;
+
+--  the share of the order shipping of an order item, it is included in the total of the item
+ALTER TABLE order_items
+  ADD COLUMN IF NOT EXISTS "shipping" bigint NOT NULL DEFAULT 0;
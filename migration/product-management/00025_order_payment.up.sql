@@ -0,0 +1,6 @@
+--  the payment of an order at the payment provider, authorized when the order is placed and captured once it is paid
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS "payment_id" text,
+  ADD COLUMN IF NOT EXISTS "payment_status" text;
+
+CREATE INDEX IF NOT EXISTS orders_payment_id_idx ON orders(payment_id);
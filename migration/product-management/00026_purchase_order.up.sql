@@ -0,0 +1,2 @@
+--  the purchases of an order are listed to refund it and to decide whether its payment and coupon are fully refunded
+CREATE INDEX IF NOT EXISTS purchased_products_order_id_idx ON purchased_products(order_id);
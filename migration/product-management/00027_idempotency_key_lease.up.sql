@@ -0,0 +1,3 @@
+--  the lease of the key of a request in progress, a retry takes over the key once it expired
+ALTER TABLE idempotency_keys
+  ADD COLUMN IF NOT EXISTS "locked_until" timestamptz NOT NULL DEFAULT now();
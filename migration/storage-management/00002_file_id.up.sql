@@ -0,0 +1,6 @@
+--  the ids of the files are uuids, they are referenced by the other services
+ALTER TABLE files
+  ALTER COLUMN "id" DROP DEFAULT,
+  ALTER COLUMN "id" TYPE text USING "id"::text;
+
+DROP SEQUENCE IF EXISTS files_id_seq;
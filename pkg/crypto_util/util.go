@@ -1,6 +1,8 @@
 package crypto_util
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -91,3 +93,13 @@ func stringWithCharset(length int) string {
 func genStringWithLength(length int) string {
 	return stringWithCharset(length)
 }
+
+// GenerateToken returns a random url-safe token of n random bytes, suitable for links which must not be guessed.
+func GenerateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
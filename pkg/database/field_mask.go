@@ -0,0 +1,65 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownFieldPath is returned when a field mask path has no matching column.
+var ErrUnknownFieldPath = errors.New("unknown field path")
+
+// FieldPaths maps the proto field paths of an update request to the db tags of an entity.
+// Only the paths listed here can be written, which keeps columns like id or created_by out of reach.
+type FieldPaths map[string]string
+
+// Columns returns the db tags of the given proto field paths, without duplicates and in the given order.
+func (f FieldPaths) Columns(paths []string) ([]string, error) {
+	var columns []string
+	for _, path := range paths {
+		column, ok := f[path]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFieldPath, path)
+		}
+
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns, nil
+}
+
+// SelectFieldMap returns the field names and pointer values of an entity restricted to the given columns.
+// The result follows the order of columns, so it can be used with [GetSetClauses].
+func SelectFieldMap[T Entity](e T, columns []string) ([]string, []any) {
+	fieldNames, fieldValues := FieldMap(e)
+
+	var (
+		names  []string
+		values []any
+	)
+	for _, column := range columns {
+		idx := slices.Index(fieldNames, column)
+		if idx < 0 {
+			continue
+		}
+
+		names = append(names, fieldNames[idx])
+		values = append(values, fieldValues[idx])
+	}
+
+	return names, values
+}
+
+// GetSetClauses returns the assignments of an UPDATE statement for the given columns,
+// with placeholders starting right after offset (e.g. `"name" = $2, "price" = $3` for an offset of 1).
+func GetSetClauses(columns []string, offset int) string {
+	result := make([]string, 0, len(columns))
+	for i, column := range columns {
+		result = append(result, fmt.Sprintf(`"%s" = $%d`, column, offset+i+1))
+	}
+
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntity struct {
+	ID    sql.NullInt64   `db:"id"`
+	Name  sql.NullString  `db:"name"`
+	Type  sql.NullString  `db:"coupon_type"`
+	Price sql.NullFloat64 `db:"price"`
+}
+
+func (e *testEntity) TableName() string {
+	return "test_entities"
+}
+
+func TestFieldPaths_Columns(t *testing.T) {
+	fieldPaths := FieldPaths{
+		"name":  "name",
+		"type":  "coupon_type",
+		"price": "price",
+	}
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "happy case",
+			paths: []string{"type", "name"},
+			want:  []string{"coupon_type", "name"},
+		},
+		{
+			name:  "duplicated paths",
+			paths: []string{"name", "name"},
+			want:  []string{"name"},
+		},
+		{
+			name:    "err unknown path",
+			paths:   []string{"id"},
+			wantErr: ErrUnknownFieldPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fieldPaths.Columns(tt.paths)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSelectFieldMap(t *testing.T) {
+	e := &testEntity{
+		Name:  sql.NullString{String: "shirt", Valid: true},
+		Price: sql.NullFloat64{Float64: 0, Valid: true},
+	}
+
+	names, values := SelectFieldMap(e, []string{"price", "name", "unknown"})
+	assert.Equal(t, []string{"price", "name"}, names)
+	assert.Equal(t, []any{&e.Price, &e.Name}, values)
+}
+
+func TestGetSetClauses(t *testing.T) {
+	assert.Equal(t, `"name" = $2, "price" = $3`, GetSetClauses([]string{"name", "price"}, 1))
+	assert.Equal(t, "", GetSetClauses(nil, 1))
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"trintech/review/pkg/processor"
+)
+
+// ErrOutboxDiscard is wrapped by an [OutboxHandler] error to drop an event which can never be handled,
+// instead of retrying it.
+var ErrOutboxDiscard = errors.New("discard outbox event")
+
+const (
+	// outboxBatchSize is the maximum number of events relayed by a run.
+	outboxBatchSize = 100
+	// outboxMaxBackoff is the maximum delay before the retry of a failed event.
+	outboxMaxBackoff = time.Hour
+	// outboxRetention is how long the relayed events are kept.
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// OutboxEvent is an event written in the transaction of a change, and relayed after its commit.
+type OutboxEvent struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int64
+	CreatedAt time.Time
+}
+
+// OutboxHandler handles a relayed event of a topic. An event is relayed at least once,
+// the handler is called again after a failure or a crash, so it must be idempotent.
+type OutboxHandler func(ctx context.Context, event *OutboxEvent) error
+
+// EnqueueOutboxEvent writes an event with the JSON payload in the outbox_events table.
+// It is given the transaction of the change, so that the event is relayed if and only if the change is committed.
+func EnqueueOutboxEvent(ctx context.Context, db Executor, topic string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal outbox event: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO outbox_events(topic, payload)
+		VALUES($1, $2)
+	`, &topic, data); err != nil {
+		return fmt.Errorf("unable to enqueue outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// outboxRelay relays the pending events of the outbox to their handler.
+type outboxRelay struct {
+	db       Database
+	handlers map[string]OutboxHandler
+}
+
+// NewOutboxRelay returns a processor which relays the pending events of the outbox to the handler of their topic every interval.
+// The events are locked while they are relayed, so that several relays can run side by side.
+// A failed event is retried with an exponential backoff, the relayed events are removed after a week.
+func NewOutboxRelay(name string, db Database, interval time.Duration, handlers map[string]OutboxHandler) processor.Processor {
+	relay := &outboxRelay{
+		db:       db,
+		handlers: handlers,
+	}
+
+	return processor.NewIntervalProcessor(name, interval, relay.relay)
+}
+
+// relay relays a batch of the pending events.
+func (r *outboxRelay) relay(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the pending events, skipping the events locked by another relay
+	events, err := r.listPending(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("unable to list outbox events: %w", err)
+	}
+
+	// Relay the events to their handler
+	for _, event := range events {
+		err := fmt.Errorf("no handler of topic %q", event.Topic)
+		if handler, ok := r.handlers[event.Topic]; ok {
+			err = handler(ctx, event)
+		}
+
+		switch {
+		case err == nil:
+			_, err = tx.ExecContext(ctx, `
+				UPDATE outbox_events
+				SET processed_at = now()
+				WHERE id = $1
+			`, &event.ID)
+		case errors.Is(err, ErrOutboxDiscard):
+			slog.Error("discarded outbox event", "id", event.ID, "topic", event.Topic, "err", err)
+			lastError := err.Error()
+			_, err = tx.ExecContext(ctx, `
+				UPDATE outbox_events
+				SET processed_at = now(), last_error = $2
+				WHERE id = $1
+			`, &event.ID, &lastError)
+		default:
+			slog.Warn("unable to relay outbox event", "id", event.ID, "topic", event.Topic, "attempts", event.Attempts+1, "err", err)
+			lastError := err.Error()
+			nextAttemptAt := time.Now().Add(outboxBackoff(event.Attempts + 1))
+			_, err = tx.ExecContext(ctx, `
+				UPDATE outbox_events
+				SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+				WHERE id = $1
+			`, &event.ID, &lastError, &nextAttemptAt)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to update outbox event: %w", err)
+		}
+	}
+
+	// Remove the events relayed before the retention period
+	before := time.Now().Add(-outboxRetention)
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM outbox_events
+		WHERE processed_at < $1
+	`, &before); err != nil {
+		return fmt.Errorf("unable to purge outbox events: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// listPending locks and lists the events which are due, the oldest first.
+func (r *outboxRelay) listPending(ctx context.Context, db Executor) ([]*OutboxEvent, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, topic, payload, attempts, created_at
+		FROM outbox_events
+		WHERE processed_at IS NULL
+		AND next_attempt_at <= now()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*OutboxEvent
+	for rows.Next() {
+		event := &OutboxEvent{}
+		if err := rows.Scan(&event.ID, &event.Topic, &event.Payload, &event.Attempts, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// outboxBackoff returns the delay before the retry of an event failed a number of times, doubling from a second.
+func outboxBackoff(attempts int64) time.Duration {
+	if attempts > 13 {
+		return outboxMaxBackoff
+	}
+
+	return min(time.Second<<(attempts-1), outboxMaxBackoff)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+// sqlDatabase is a [Database] of a sql.DB.
+type sqlDatabase struct {
+	*sql.DB
+}
+
+func (d *sqlDatabase) Connect(context.Context) error { return nil }
+
+func (d *sqlDatabase) Close(context.Context) error { return nil }
+
+func Test_outboxRelay_relay(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect func(smock sqlmock.Sqlmock)
+	}{
+		{
+			name: "happy case",
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("UPDATE outbox_events SET processed_at = now\\(\\) WHERE id").
+					WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "happy case discarded",
+			err:  ErrOutboxDiscard,
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("UPDATE outbox_events SET processed_at = now\\(\\), last_error").
+					WithArgs(7, ErrOutboxDiscard.Error()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "happy case retried",
+			err:  errors.New("unavailable"),
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("UPDATE outbox_events SET attempts = attempts \\+ 1").
+					WithArgs(7, "unavailable", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+
+			smock.ExpectBegin()
+			smock.ExpectQuery("SELECT id, topic, payload, attempts, created_at FROM outbox_events").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "payload", "attempts", "created_at"}).
+					AddRow(7, "COUPON_CONFIRM", []byte(`{"redemption_id":"r1"}`), 2, time.Now()))
+			tt.expect(smock)
+			smock.ExpectExec("DELETE FROM outbox_events").WillReturnResult(sqlmock.NewResult(0, 0))
+			smock.ExpectCommit()
+
+			var got *OutboxEvent
+			relay := &outboxRelay{
+				db: &sqlDatabase{DB: db},
+				handlers: map[string]OutboxHandler{
+					"COUPON_CONFIRM": func(ctx context.Context, event *OutboxEvent) error {
+						got = event
+						return tt.err
+					},
+				},
+			}
+			require.NoError(t, relay.relay(context.Background()))
+			require.Equal(t, int64(7), got.ID)
+			require.Equal(t, int64(2), got.Attempts)
+			require.JSONEq(t, `{"redemption_id":"r1"}`, string(got.Payload))
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_outboxBackoff(t *testing.T) {
+	require.Equal(t, time.Second, outboxBackoff(1))
+	require.Equal(t, 8*time.Second, outboxBackoff(4))
+	require.Equal(t, time.Hour, outboxBackoff(13))
+	require.Equal(t, time.Hour, outboxBackoff(100))
+}
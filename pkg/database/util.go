@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"slices"
@@ -9,6 +10,9 @@ import (
 
 const DB_TAG = "db"
 
+// ErrVersionMismatch is returned by an update of a version of a record which has another version.
+var ErrVersionMismatch = errors.New("database: version mismatch")
+
 // e is a presentation of a entity that must have TableName function inside.
 type Entity interface {
 	TableName() string
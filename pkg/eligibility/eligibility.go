@@ -0,0 +1,154 @@
+// Package eligibility evaluates the eligibility rules of a coupon for an order.
+package eligibility
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	// The time zones of the rules are loaded from the embedded database, the hosts may not have one.
+	_ "time/tzdata"
+
+	"trintech/review/pkg/money"
+)
+
+// ErrInvalidRules is wrapped by the errors of the rules which cannot be evaluated.
+var ErrInvalidRules = errors.New("eligibility: invalid rules")
+
+// Reason is why an order is not eligible to a coupon.
+type Reason string
+
+const (
+	ReasonMinOrderValue     Reason = "MIN_ORDER_VALUE"
+	ReasonProductType       Reason = "PRODUCT_TYPE"
+	ReasonFirstPurchaseOnly Reason = "FIRST_PURCHASE_ONLY"
+	ReasonWeekday           Reason = "WEEKDAY"
+	ReasonHour              Reason = "HOUR"
+	ReasonUserRole          Reason = "USER_ROLE"
+)
+
+// Rules are the eligibility rules of a coupon, the zero value of a rule doesn't restrict the orders.
+// The amounts are minor units of Currency, the weekdays and the hours are in TimeZone, UTC when it is empty.
+type Rules struct {
+	Currency      string `json:"currency,omitempty"`
+	MinOrderValue int64  `json:"min_order_value,omitempty"`
+	// MaxDiscount caps the discount of the coupon, it is applied where the discount is computed.
+	MaxDiscount  int64    `json:"max_discount,omitempty"`
+	ProductTypes []string `json:"product_types,omitempty"`
+	// FirstPurchaseOnly restricts the coupon to the users who never purchased.
+	FirstPurchaseOnly bool           `json:"first_purchase_only,omitempty"`
+	Weekdays          []time.Weekday `json:"weekdays,omitempty"`
+	// FromHour and ToHour bound the hours of the day in [FromHour, ToHour), the hours wrap around midnight when
+	// FromHour is after ToHour and both zero is the whole day.
+	FromHour  int      `json:"from_hour,omitempty"`
+	ToHour    int      `json:"to_hour,omitempty"`
+	TimeZone  string   `json:"time_zone,omitempty"`
+	UserRoles []string `json:"user_roles,omitempty"`
+}
+
+// Order is what a coupon is applied to, the order value is the value of its products before discounts.
+type Order struct {
+	Time          time.Time
+	OrderValue    money.Money
+	ProductTypes  []string
+	FirstPurchase bool
+	UserRole      string
+}
+
+// Failure is the reason an order is not eligible to a coupon with its explanation.
+type Failure struct {
+	Reason  Reason
+	Message string
+}
+
+// Error returns the explanation of the failure.
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+// Validate checks the rules can be evaluated.
+func (r Rules) Validate() error {
+	if r.MinOrderValue < 0 || r.MaxDiscount < 0 {
+		return fmt.Errorf("%w: amounts must not be negative", ErrInvalidRules)
+	}
+	if (r.MinOrderValue > 0 || r.MaxDiscount > 0) && !money.IsCurrency(r.Currency) {
+		return fmt.Errorf("%w: invalid currency %q", ErrInvalidRules, r.Currency)
+	}
+	if slices.Contains(r.ProductTypes, "") {
+		return fmt.Errorf("%w: product types must not be empty", ErrInvalidRules)
+	}
+	for _, weekday := range r.Weekdays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			return fmt.Errorf("%w: invalid weekday %d", ErrInvalidRules, weekday)
+		}
+	}
+	if r.FromHour < 0 || r.FromHour > 23 || r.ToHour < 0 || r.ToHour > 24 {
+		return fmt.Errorf("%w: hours must be between 0 and 24", ErrInvalidRules)
+	}
+	if r.FromHour == r.ToHour && r.FromHour != 0 {
+		return fmt.Errorf("%w: from hour and to hour must differ", ErrInvalidRules)
+	}
+	if _, err := time.LoadLocation(r.TimeZone); err != nil {
+		return fmt.Errorf("%w: invalid time zone %q", ErrInvalidRules, r.TimeZone)
+	}
+	if slices.Contains(r.UserRoles, "") {
+		return fmt.Errorf("%w: user roles must not be empty", ErrInvalidRules)
+	}
+
+	return nil
+}
+
+// Evaluate returns the first rule the order fails, nil when the order is eligible.
+func (r Rules) Evaluate(order Order) *Failure {
+	if r.MinOrderValue > 0 {
+		minOrderValue := money.New(r.MinOrderValue, r.Currency)
+		if order.OrderValue.Currency != r.Currency || order.OrderValue.Amount < r.MinOrderValue {
+			return &Failure{ReasonMinOrderValue, fmt.Sprintf("order value must be at least %s", minOrderValue)}
+		}
+	}
+
+	if len(r.ProductTypes) > 0 && (len(order.ProductTypes) == 0 || slices.ContainsFunc(order.ProductTypes, func(productType string) bool {
+		return !slices.Contains(r.ProductTypes, productType)
+	})) {
+		return &Failure{ReasonProductType, fmt.Sprintf("coupon only applies to products of type %s", strings.Join(r.ProductTypes, ", "))}
+	}
+
+	if r.FirstPurchaseOnly && !order.FirstPurchase {
+		return &Failure{ReasonFirstPurchaseOnly, "coupon only applies to a first purchase"}
+	}
+
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := order.Time.In(loc)
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, local.Weekday()) {
+		days := make([]string, 0, len(r.Weekdays))
+		for _, weekday := range r.Weekdays {
+			days = append(days, weekday.String())
+		}
+		return &Failure{ReasonWeekday, fmt.Sprintf("coupon only applies on %s", strings.Join(days, ", "))}
+	}
+	if !r.withinHours(local.Hour()) {
+		return &Failure{ReasonHour, fmt.Sprintf("coupon only applies from %02d:00 to %02d:00 %s", r.FromHour, r.ToHour, loc)}
+	}
+
+	if len(r.UserRoles) > 0 && !slices.Contains(r.UserRoles, order.UserRole) {
+		return &Failure{ReasonUserRole, "coupon doesn't apply to the role of the user"}
+	}
+
+	return nil
+}
+
+// withinHours reports whether an hour of the day is in the hours of the rules.
+func (r Rules) withinHours(hour int) bool {
+	switch {
+	case r.FromHour == 0 && r.ToHour == 0:
+		return true
+	case r.FromHour < r.ToHour:
+		return hour >= r.FromHour && hour < r.ToHour
+	default:
+		return hour >= r.FromHour || hour < r.ToHour
+	}
+}
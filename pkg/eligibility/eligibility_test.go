@@ -0,0 +1,87 @@
+package eligibility
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"trintech/review/pkg/money"
+)
+
+func TestRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+	}{
+		{name: "no rule", rules: Rules{}},
+		{name: "every rule", rules: Rules{
+			Currency: "USD", MinOrderValue: 5000, MaxDiscount: 1000, ProductTypes: []string{"book"}, FirstPurchaseOnly: true,
+			Weekdays: []time.Weekday{time.Monday}, FromHour: 22, ToHour: 2, TimeZone: "Asia/Ho_Chi_Minh", UserRoles: []string{"USER"},
+		}},
+		{name: "amount without currency", rules: Rules{MinOrderValue: 5000}, wantErr: true},
+		{name: "negative amount", rules: Rules{Currency: "USD", MaxDiscount: -1}, wantErr: true},
+		{name: "empty product type", rules: Rules{ProductTypes: []string{""}}, wantErr: true},
+		{name: "invalid weekday", rules: Rules{Weekdays: []time.Weekday{7}}, wantErr: true},
+		{name: "invalid hour", rules: Rules{FromHour: 9, ToHour: 25}, wantErr: true},
+		{name: "empty hours", rules: Rules{FromHour: 9, ToHour: 9}, wantErr: true},
+		{name: "invalid time zone", rules: Rules{TimeZone: "Mars/Olympus"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidRules))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRules_Evaluate(t *testing.T) {
+	// Monday 2026-10-19 23:30 in UTC, Tuesday 06:30 in Asia/Ho_Chi_Minh
+	now := time.Date(2026, 10, 19, 23, 30, 0, 0, time.UTC)
+	order := Order{
+		Time:          now,
+		OrderValue:    money.New(6000, "USD"),
+		ProductTypes:  []string{"book", "pen"},
+		FirstPurchase: false,
+		UserRole:      "USER",
+	}
+	tests := []struct {
+		name  string
+		rules Rules
+		want  Reason
+	}{
+		{name: "no rule", rules: Rules{}},
+		{name: "min order value", rules: Rules{Currency: "USD", MinOrderValue: 6000}},
+		{name: "err min order value", rules: Rules{Currency: "USD", MinOrderValue: 6001}, want: ReasonMinOrderValue},
+		{name: "err min order value in another currency", rules: Rules{Currency: "EUR", MinOrderValue: 1}, want: ReasonMinOrderValue},
+		{name: "product types", rules: Rules{ProductTypes: []string{"pen", "book"}}},
+		{name: "err product types", rules: Rules{ProductTypes: []string{"book"}}, want: ReasonProductType},
+		{name: "err first purchase only", rules: Rules{FirstPurchaseOnly: true}, want: ReasonFirstPurchaseOnly},
+		{name: "weekday", rules: Rules{Weekdays: []time.Weekday{time.Monday}}},
+		{name: "err weekday in time zone", rules: Rules{Weekdays: []time.Weekday{time.Monday}, TimeZone: "Asia/Ho_Chi_Minh"}, want: ReasonWeekday},
+		{name: "hours around midnight", rules: Rules{FromHour: 22, ToHour: 2}},
+		{name: "err hours", rules: Rules{FromHour: 9, ToHour: 17}, want: ReasonHour},
+		{name: "hours in time zone", rules: Rules{FromHour: 6, ToHour: 7, TimeZone: "Asia/Ho_Chi_Minh"}},
+		{name: "user role", rules: Rules{UserRoles: []string{"ADMIN", "USER"}}},
+		{name: "err user role", rules: Rules{UserRoles: []string{"ADMIN"}}, want: ReasonUserRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := tt.rules.Evaluate(order)
+			if tt.want == "" {
+				assert.Nil(t, failure)
+				return
+			}
+			if assert.NotNil(t, failure) {
+				assert.Equal(t, tt.want, failure.Reason)
+				assert.NotEmpty(t, failure.Message)
+			}
+		})
+	}
+}
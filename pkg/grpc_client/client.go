@@ -50,5 +50,5 @@ func (c *GrpcClient) Connect(ctx context.Context) error {
 }
 
 func (c *GrpcClient) Close(ctx context.Context) error {
-	return c.Close(ctx)
+	return c.ClientConn.Close()
 }
@@ -18,11 +18,13 @@ type GrpcServer struct {
 	Server   *grpc.Server
 }
 
-func NewGrpcServer(endpoint *config.Endpoint) *GrpcServer {
+// NewGrpcServer returns a server listening on the endpoint port, the unary requests go through the interceptors in order.
+func NewGrpcServer(endpoint *config.Endpoint, interceptors ...grpc.UnaryServerInterceptor) *GrpcServer {
 	srv := grpc.NewServer(
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_validator.StreamServerInterceptor(),
 		)),
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...)),
 	)
 	return &GrpcServer{
 		endpoint: endpoint,
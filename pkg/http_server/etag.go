@@ -0,0 +1,165 @@
+package http_server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	stringutil "trintech/review/pkg/string_util"
+)
+
+const (
+	ETAG          = "ETag"
+	IF_MATCH      = "If-Match"
+	IF_NONE_MATCH = "If-None-Match"
+)
+
+const (
+	// MDETag is the ETag of the record of a response, the version of the record.
+	MDETag = "etag"
+	// MDIfMatch is the ETag a mutating request expects the record to have.
+	MDIfMatch = "if-match"
+)
+
+// ErrInvalidETag is returned when an ETag is not the version of a record.
+var ErrInvalidETag = errors.New("invalid etag")
+
+// ETag returns the ETag of a version of a record.
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag returns the version of an ETag, the weak ETags are compared as the strong ones.
+func ParseETag(etag string) (int64, error) {
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(strings.TrimSpace(etag), "W/"))
+	if err != nil {
+		return 0, ErrInvalidETag
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidETag
+	}
+
+	return version, nil
+}
+
+// ExtractIfMatchFromCtx returns the version the If-Match header of a request expects, zero without header or
+// for any version.
+func ExtractIfMatchFromCtx(ctx context.Context) (int64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	etag := stringutil.Coalesce(md.Get(MDIfMatch)...)
+	if etag == "" || etag == "*" {
+		return 0, nil
+	}
+
+	return ParseETag(etag)
+}
+
+// SetETag sends the version of the record of a response as its ETag header, it is a no-op outside a gRPC call.
+func SetETag(ctx context.Context, version int64) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MDETag, ETag(version)))
+}
+
+// outgoingHeaderMatcher forwards the ETag of the responses as the ETag header, and the other metadata
+// with the default prefix.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == MDETag {
+		return ETAG, true
+	}
+
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
+
+// errorHandler responds 412 Precondition Failed to the conditional requests whose version does not match.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if status.Code(err) == codes.Aborted && r.Header.Get(IF_MATCH) != "" {
+		w = &statusResponseWriter{ResponseWriter: w, status: http.StatusPreconditionFailed}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
+
+// statusResponseWriter writes its status instead of the status of the response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(int) {
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// notModified responds 304 Not Modified without body to the reads whose If-None-Match header matches the ETag
+// of the response.
+func notModified(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch := r.Header.Get(IF_NONE_MATCH)
+		if ifNoneMatch == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		h.ServeHTTP(&notModifiedResponseWriter{ResponseWriter: w, ifNoneMatch: ifNoneMatch}, r)
+	})
+}
+
+// notModifiedResponseWriter drops the body of a successful response whose ETag is in ifNoneMatch.
+type notModifiedResponseWriter struct {
+	http.ResponseWriter
+	ifNoneMatch string
+	dropBody    bool
+	wroteHeader bool
+}
+
+func (w *notModifiedResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if etag := w.Header().Get(ETAG); code == http.StatusOK && etag != "" && matchETag(w.ifNoneMatch, etag) {
+		w.dropBody = true
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		code = http.StatusNotModified
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *notModifiedResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.dropBody {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// matchETag reports whether an ETag is in the list of ETags of an If-None-Match header.
+func matchETag(header, etag string) bool {
+	version, err := ParseETag(etag)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == "*" {
+			return true
+		}
+		if v, err := ParseETag(candidate); err == nil && v == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package http_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		etag    string
+		want    int64
+		wantErr bool
+	}{
+		{etag: `"5"`, want: 5},
+		{etag: ` W/"12" `, want: 12},
+		{etag: `5`, wantErr: true},
+		{etag: `"0"`, wantErr: true},
+		{etag: `"abc"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.etag, func(t *testing.T) {
+			got, err := ParseETag(tt.etag)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidETag)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+
+			roundTrip, err := ParseETag(ETag(got))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, roundTrip)
+		})
+	}
+}
+
+func Test_notModified(t *testing.T) {
+	handler := notModified(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ETAG, ETag(3))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"3"}`))
+	}))
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "without header", method: http.MethodGet, wantStatus: http.StatusOK, wantBody: `{"version":"3"}`},
+		{name: "same version", method: http.MethodGet, ifNoneMatch: `"3"`, wantStatus: http.StatusNotModified},
+		{name: "one of the versions", method: http.MethodGet, ifNoneMatch: `"1", W/"3"`, wantStatus: http.StatusNotModified},
+		{name: "other version", method: http.MethodGet, ifNoneMatch: `"2"`, wantStatus: http.StatusOK, wantBody: `{"version":"3"}`},
+		{name: "not a read", method: http.MethodPatch, ifNoneMatch: `"3"`, wantStatus: http.StatusOK, wantBody: `{"version":"3"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/v1/products/1", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set(IF_NONE_MATCH, tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			require.Equal(t, tt.wantStatus, w.Code)
+			require.Equal(t, tt.wantBody, w.Body.String())
+			require.Equal(t, ETag(3), w.Header().Get(ETAG))
+		})
+	}
+}
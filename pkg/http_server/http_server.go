@@ -32,6 +32,8 @@ func NewHttpServer(
 			UnmarshalOptions: protojson.UnmarshalOptions{AllowPartial: true},
 		}),
 		runtime.WithMetadata(MapMetaDataWithBearerToken(authenticator)),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithErrorHandler(errorHandler),
 		// runtime.WithErrorHandler(forwardErrorResponse),
 	)
 	handler(mux)
@@ -41,7 +43,8 @@ func NewHttpServer(
 
 	slices.Reverse(middlewares)
 
-	var handleR http.Handler = mux
+	// The reads whose ETag is unchanged are not modified
+	var handleR http.Handler = notModified(mux)
 	// for _, handle := range middlewares {
 	// 	handleR = handle(handleR)
 	// }
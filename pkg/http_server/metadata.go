@@ -12,13 +12,16 @@ import (
 	"google.golang.org/grpc/metadata"
 
 	"trintech/review/pkg/http_server/xcontext"
+	"trintech/review/pkg/locale"
 	stringutil "trintech/review/pkg/string_util"
 	"trintech/review/pkg/token_util"
 )
 
 const (
-	AUTHORIZATION = "Authorization"
-	BEARER        = "Bearer"
+	AUTHORIZATION   = "Authorization"
+	BEARER          = "Bearer"
+	IDEMPOTENCY_KEY = "Idempotency-Key"
+	ACCEPT_LANGUAGE = "Accept-Language"
 )
 
 const (
@@ -27,6 +30,10 @@ const (
 	MDUserAgent     = "user-agent"
 	MDRoleKey       = "role"
 	MDXForwardedFor = "x-forwarded-for"
+	// MDIdempotencyKey is the idempotency key of a mutating request, see [trintech/review/pkg/idempotency].
+	MDIdempotencyKey = "idempotency-key"
+	// MDLocale lists the locales of the Accept-Language header of a request in order of preference.
+	MDLocale = "locale"
 )
 
 // DataResponse ...
@@ -54,6 +61,21 @@ func MapMetaDataWithBearerToken(authenticator token_util.JWTAuthenticator) mapMe
 			UserAgent: stringutil.Coalesce(md.Get(MDUserAgent)...),
 		}))
 
+		// Forward the idempotency key of the mutating requests
+		if key := r.Header.Get(IDEMPOTENCY_KEY); key != "" && r.Method != http.MethodGet {
+			md = metadata.Join(md, metadata.Pairs(MDIdempotencyKey, key))
+		}
+
+		// Forward the preferred locales of the localised content
+		if locales := locale.ParseAcceptLanguage(r.Header.Get(ACCEPT_LANGUAGE)); len(locales) > 0 {
+			md = metadata.Join(md, metadata.MD{MDLocale: locales})
+		}
+
+		// Forward the version the conditional mutating requests expect
+		if etag := r.Header.Get(IF_MATCH); etag != "" && r.Method != http.MethodGet {
+			md = metadata.Join(md, metadata.Pairs(MDIfMatch, etag))
+		}
+
 		authorization := r.Header.Get(AUTHORIZATION)
 
 		if authorization != "" {
@@ -114,7 +136,29 @@ func ExtractSessionFromCtx(ctx context.Context) *xcontext.Session {
 	}
 }
 
+// InjectIncomingCtxToOutgoingCtx forwards the metadata of an incoming request to the internal calls it makes.
+// The idempotency key and the expected version belong to the incoming request and are not forwarded,
+// so that the internal calls of a retried request are not rejected as a reuse of its key for another request.
 func InjectIncomingCtxToOutgoingCtx(ctx context.Context) context.Context {
 	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Delete(MDIdempotencyKey)
+	md.Delete(MDIfMatch)
+
 	return metadata.NewOutgoingContext(ctx, md)
 }
+
+// ExtractLocalesFromCtx returns the fallback chain of the preferred locales of a request, the default locale
+// without preferred locale.
+func ExtractLocalesFromCtx(ctx context.Context) []string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var locales []string
+	for _, tag := range md.Get(MDLocale) {
+		if l, err := locale.Parse(tag); err == nil {
+			locales = append(locales, l)
+		}
+	}
+
+	return locale.Chain(locales...)
+}
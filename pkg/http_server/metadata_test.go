@@ -0,0 +1,29 @@
+package http_server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestInjectIncomingCtxToOutgoingCtx(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		MDUserIDKey, "1",
+		MDRoleKey, "USER",
+		MDIdempotencyKey, "key-1",
+		MDIfMatch, `"3"`,
+	))
+
+	md, ok := metadata.FromOutgoingContext(InjectIncomingCtxToOutgoingCtx(ctx))
+	require.True(t, ok)
+	require.Equal(t, []string{"1"}, md.Get(MDUserIDKey))
+	require.Equal(t, []string{"USER"}, md.Get(MDRoleKey))
+	require.Empty(t, md.Get(MDIdempotencyKey))
+	require.Empty(t, md.Get(MDIfMatch))
+
+	// The incoming metadata is kept as it is
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	require.Equal(t, []string{"key-1"}, incoming.Get(MDIdempotencyKey))
+}
@@ -0,0 +1,158 @@
+// Package idempotency makes the mutating RPCs safe to retry.
+// A request sent with an idempotency key is executed once, the retries with the same key
+// get the response of the first request.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/processor"
+	stringutil "trintech/review/pkg/string_util"
+)
+
+// maxKeyLength is the maximum length of an idempotency key.
+const maxKeyLength = 255
+
+// Retention is how long the response of a key is kept for the retries.
+const Retention = 24 * time.Hour
+
+// UnaryServerInterceptor returns a server interceptor which executes the requests sent with
+// an idempotency key once. The key is scoped by method and user.
+//
+// A retry with the same key and request gets the recorded response, a retry with another request
+// is rejected with codes.AlreadyExists and a retry while the first request runs with codes.Aborted.
+// The key of a failed request is released, so that the request can be retried, and the key of a request
+// which never completed is taken over by a retry once its [Lease] expired.
+func UnaryServerInterceptor(store Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := stringutil.Coalesce(md.Get(http_server.MDIdempotencyKey)...)
+		reqMsg, ok := req.(proto.Message)
+		if key == "" || !ok {
+			return handler(ctx, req)
+		}
+		if len(key) > maxKeyLength {
+			return nil, status.Errorf(codes.InvalidArgument, "idempotency key must not exceed %d characters", maxKeyLength)
+		}
+
+		// Reserve the key of the user for the method
+		requestHash, err := hashRequest(info.FullMethod, reqMsg)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to hash request: %v", err.Error())
+		}
+		var userID int64
+		if userCtx, ok := http_server.ExtractUserInfoFromCtx(ctx); ok {
+			userID = userCtx.UserID
+		}
+		scopedKey := fmt.Sprintf("%s:%d:%s", info.FullMethod, userID, key)
+		record, reserved, err := store.Reserve(ctx, scopedKey, requestHash)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to reserve idempotency key: %v", err.Error())
+		}
+
+		// Replay the recorded response of the key
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				return nil, status.Errorf(codes.AlreadyExists, "idempotency key is already used by another request")
+			case record.Response == nil:
+				return nil, status.Errorf(codes.Aborted, "request with this idempotency key is in progress")
+			}
+
+			resp, err := unmarshalResponse(record.Response)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "unable to read recorded response: %v", err.Error())
+			}
+
+			return resp, nil
+		}
+
+		// Execute the request, the key is released or completed even if the client is gone
+		resp, err := handler(ctx, req)
+		storeCtx := context.WithoutCancel(ctx)
+		if err != nil {
+			if releaseErr := store.Release(storeCtx, scopedKey); releaseErr != nil {
+				slog.Error("unable to release idempotency key", "method", info.FullMethod, "err", releaseErr)
+			}
+
+			return nil, err
+		}
+
+		// Record the response of the key
+		data, err := marshalResponse(resp)
+		if err == nil {
+			err = store.Complete(storeCtx, scopedKey, data)
+		}
+		if err != nil {
+			slog.Error("unable to record idempotency key response", "method", info.FullMethod, "err", err)
+		}
+
+		return resp, nil
+	}
+}
+
+// NewPurgeProcessor returns a processor which periodically removes the keys older than the retention.
+func NewPurgeProcessor(store Store, retention time.Duration) processor.Processor {
+	return processor.NewIntervalProcessor("idempotency-key-purge", time.Hour, func(ctx context.Context) error {
+		// Remove the keys recorded before the retention period
+		purged, err := store.PurgeBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return fmt.Errorf("unable to purge idempotency keys: %w", err)
+		}
+
+		slog.Info("purged idempotency keys", "total", purged)
+
+		return nil
+	})
+}
+
+// hashRequest returns the hash of the method and the content of a request.
+func hashRequest(method string, req proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// marshalResponse marshals a response with its type, so that it can be unmarshalled without knowing it.
+func marshalResponse(resp any) ([]byte, error) {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("response %T is not a proto message", resp)
+	}
+	wrapped, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(wrapped)
+}
+
+// unmarshalResponse unmarshals a response marshalled by [marshalResponse].
+func unmarshalResponse(data []byte) (proto.Message, error) {
+	wrapped := &anypb.Any{}
+	if err := proto.Unmarshal(data, wrapped); err != nil {
+		return nil, err
+	}
+
+	return wrapped.UnmarshalNew()
+}
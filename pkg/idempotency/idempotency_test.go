@@ -0,0 +1,175 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"trintech/review/pkg/http_server"
+	"trintech/review/pkg/http_server/xcontext"
+)
+
+// memoryStore is a [Store] kept in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+func (s *memoryStore) Reserve(_ context.Context, key, requestHash string) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok {
+		return record, false, nil
+	}
+	s.records[key] = &Record{Key: key, RequestHash: requestHash, CreatedAt: time.Now()}
+
+	return nil, true, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key].Response = response
+
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+
+	return nil
+}
+
+func (s *memoryStore) PurgeBefore(_ context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func Test_UnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/product.ProductService/PurchaseProduct"}
+	withKey := func(userID int64, key string) context.Context {
+		md := metadata.Join(
+			http_server.ImportUserInfoToMD(&xcontext.UserInfo{UserID: userID}),
+			metadata.Pairs(http_server.MDIdempotencyKey, key),
+		)
+
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+	type call struct {
+		ctx     context.Context
+		req     *wrapperspb.Int64Value
+		want    int64
+		wantErr error
+	}
+	tests := []struct {
+		name      string
+		calls     []call
+		wantCalls int
+		failFirst bool
+	}{
+		{
+			name: "happy case replay",
+			calls: []call{
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), want: 1},
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), want: 1},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "happy case without key",
+			calls: []call{
+				{ctx: context.Background(), req: wrapperspb.Int64(5), want: 1},
+				{ctx: context.Background(), req: wrapperspb.Int64(5), want: 2},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "happy case key of another user",
+			calls: []call{
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), want: 1},
+				{ctx: withKey(2, "key"), req: wrapperspb.Int64(5), want: 2},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "happy case retry after failure",
+			calls: []call{
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), wantErr: status.Errorf(codes.Unavailable, "unavailable")},
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), want: 2},
+			},
+			wantCalls: 2,
+			failFirst: true,
+		},
+		{
+			name: "err key reused by another request",
+			calls: []call{
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(5), want: 1},
+				{ctx: withKey(1, "key"), req: wrapperspb.Int64(6), wantErr: status.Errorf(codes.AlreadyExists, "idempotency key is already used by another request")},
+			},
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := func(ctx context.Context, req any) (any, error) {
+				calls++
+				if tt.failFirst && calls == 1 {
+					return nil, status.Errorf(codes.Unavailable, "unavailable")
+				}
+
+				return wrapperspb.Int64(int64(calls)), nil
+			}
+			interceptor := UnaryServerInterceptor(&memoryStore{records: map[string]*Record{}})
+			for _, c := range tt.calls {
+				got, err := interceptor(c.ctx, c.req, info, handler)
+				if c.wantErr != nil {
+					require.Error(t, err)
+					require.Equal(t, c.wantErr.Error(), err.Error())
+					continue
+				}
+				require.NoError(t, err)
+				require.True(t, proto.Equal(wrapperspb.Int64(c.want), got.(proto.Message)))
+			}
+			require.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func Test_UnaryServerInterceptor_inProgress(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/product.ProductService/PurchaseProduct"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(http_server.MDIdempotencyKey, "key"))
+	interceptor := UnaryServerInterceptor(&memoryStore{records: map[string]*Record{}})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := interceptor(ctx, wrapperspb.Int64(5), info, func(ctx context.Context, req any) (any, error) {
+			close(started)
+			<-release
+
+			return wrapperspb.Int64(1), nil
+		})
+		done <- err
+	}()
+	<-started
+
+	_, err := interceptor(ctx, wrapperspb.Int64(5), info, func(ctx context.Context, req any) (any, error) {
+		return nil, errors.New("must not be called")
+	})
+	require.Equal(t, status.Errorf(codes.Aborted, "request with this idempotency key is in progress").Error(), err.Error())
+
+	close(release)
+	require.NoError(t, <-done)
+}
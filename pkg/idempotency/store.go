@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"trintech/review/pkg/database"
+)
+
+// Lease is how long a request in progress holds its key. The key of a request which did not complete nor release it
+// in time, e.g. whose server crashed, is taken over by a retry of the same request.
+const Lease = time.Minute
+
+// Record is a request made with an idempotency key.
+type Record struct {
+	Key         string
+	RequestHash string
+	// Response is the marshalled response of the request, nil while the request is in progress.
+	Response  []byte
+	CreatedAt time.Time
+}
+
+// Store records the idempotency keys and the responses of their request.
+type Store interface {
+	// Reserve records the key of a request in progress for the [Lease], or takes over the key of the same request
+	// whose lease expired. It returns false and the existing record when the key is already recorded.
+	Reserve(ctx context.Context, key, requestHash string) (*Record, bool, error)
+	// Complete records the response of the request of a key.
+	Complete(ctx context.Context, key string, response []byte) error
+	// Release removes the key of a failed request, so that it can be retried.
+	Release(ctx context.Context, key string) error
+	// PurgeBefore removes the keys recorded before a time and returns the number of removed keys.
+	PurgeBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// postgresStore is a [Store] backed by the idempotency_keys table.
+type postgresStore struct {
+	db database.Executor
+}
+
+// NewPostgresStore returns a [Store] backed by the idempotency_keys table of db.
+func NewPostgresStore(db database.Executor) Store {
+	return &postgresStore{db: db}
+}
+
+// Reserve implements [Store].
+func (s *postgresStore) Reserve(ctx context.Context, key, requestHash string) (*Record, bool, error) {
+	lease := Lease.Seconds()
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys(key, request_hash, locked_until)
+		VALUES($1, $2, now() + make_interval(secs => $3))
+		ON CONFLICT (key) DO UPDATE
+		SET locked_until = EXCLUDED.locked_until, created_at = now()
+		WHERE idempotency_keys.response IS NULL
+		AND idempotency_keys.request_hash = EXCLUDED.request_hash
+		AND idempotency_keys.locked_until < now()
+	`, &key, &requestHash, &lease)
+	if err != nil {
+		return nil, false, err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if rowEffected == 1 {
+		return nil, true, nil
+	}
+
+	record := &Record{}
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT key, request_hash, response, created_at
+		FROM idempotency_keys
+		WHERE key = $1
+	`, &key).Scan(&record.Key, &record.RequestHash, &record.Response, &record.CreatedAt); err != nil {
+		return nil, false, err
+	}
+
+	return record, false, nil
+}
+
+// Complete implements [Store].
+func (s *postgresStore) Complete(ctx context.Context, key string, response []byte) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET response = $2
+		WHERE key = $1
+	`, &key, response)
+	if err != nil {
+		return err
+	}
+	rowEffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowEffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Release implements [Store].
+func (s *postgresStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE key = $1
+		AND response IS NULL
+	`, &key)
+
+	return err
+}
+
+// PurgeBefore implements [Store].
+func (s *postgresStore) PurgeBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE created_at < $1
+	`, &before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_postgresStore_Reserve(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		expect       func(smock sqlmock.Sqlmock)
+		wantReserved bool
+		wantRecord   *Record
+	}{
+		{
+			name: "happy case new key",
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("INSERT INTO idempotency_keys").WithArgs("key", "hash", Lease.Seconds()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantReserved: true,
+		},
+		{
+			name: "happy case key of an expired lease taken over",
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("ON CONFLICT \\(key\\) DO UPDATE SET locked_until = EXCLUDED.locked_until, created_at = now\\(\\) "+
+					"WHERE idempotency_keys.response IS NULL AND idempotency_keys.request_hash = EXCLUDED.request_hash "+
+					"AND idempotency_keys.locked_until < now\\(\\)").
+					WithArgs("key", "hash", Lease.Seconds()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantReserved: true,
+		},
+		{
+			name: "happy case key in progress",
+			expect: func(smock sqlmock.Sqlmock) {
+				smock.ExpectExec("INSERT INTO idempotency_keys").WithArgs("key", "hash", Lease.Seconds()).WillReturnResult(sqlmock.NewResult(0, 0))
+				smock.ExpectQuery("SELECT key, request_hash, response, created_at FROM idempotency_keys WHERE key = \\$1").WithArgs("key").
+					WillReturnRows(sqlmock.NewRows([]string{"key", "request_hash", "response", "created_at"}).AddRow("key", "hash", nil, createdAt))
+			},
+			wantRecord: &Record{Key: "key", RequestHash: "hash", CreatedAt: createdAt},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, smock, err := sqlmock.New()
+			require.NoError(t, err)
+			tt.expect(smock)
+
+			record, reserved, err := NewPostgresStore(db).Reserve(context.Background(), "key", "hash")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantReserved, reserved)
+			require.Equal(t, tt.wantRecord, record)
+			require.NoError(t, smock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package locale resolves the locales of the localised content, BCP 47 tags made of a language, an optional
+// script and an optional region, such as vi, vi-VN or zh-Hant-TW.
+package locale
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the locale of the content which is not translated, the last locale of every fallback chain.
+const Default = "en"
+
+// ErrInvalidLocale is returned for a tag which is not a locale.
+var ErrInvalidLocale = errors.New("invalid locale")
+
+// localeRegexp matches a locale with its subtags separated by a hyphen or an underscore, in any case.
+var localeRegexp = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{4}))?(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// Parse returns the canonical form of a locale: a lower case language, a title case script and an upper case region.
+func Parse(tag string) (string, error) {
+	m := localeRegexp.FindStringSubmatch(strings.TrimSpace(tag))
+	if m == nil {
+		return "", ErrInvalidLocale
+	}
+
+	result := strings.ToLower(m[1])
+	if m[2] != "" {
+		result += "-" + strings.ToUpper(m[2][:1]) + strings.ToLower(m[2][1:])
+	}
+	if m[3] != "" {
+		result += "-" + strings.ToUpper(m[3])
+	}
+
+	return result, nil
+}
+
+// Parent returns the locale a locale falls back to by dropping its last subtag, and false for a language.
+func Parent(locale string) (string, bool) {
+	i := strings.LastIndex(locale, "-")
+	if i < 0 {
+		return "", false
+	}
+
+	return locale[:i], true
+}
+
+// Chain returns the fallback chain of the preferred locales, in order of preference: every locale is followed
+// by its parents, and the chain ends with [Default]. The locales must be canonical.
+func Chain(locales ...string) []string {
+	var chain []string
+	for _, locale := range locales {
+		for ok := true; ok; locale, ok = Parent(locale) {
+			if !slices.Contains(chain, locale) {
+				chain = append(chain, locale)
+			}
+		}
+	}
+	if !slices.Contains(chain, Default) {
+		chain = append(chain, Default)
+	}
+
+	return chain
+}
+
+// ParseAcceptLanguage returns the canonical locales of an Accept-Language header in order of preference.
+// The wildcard, the invalid tags and the tags with a zero quality are left out.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		locale, err := Parse(tag)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{locale: locale, quality: quality})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !slices.Contains(result, tag.locale) {
+			result = append(result, tag.locale)
+		}
+	}
+
+	return result
+}
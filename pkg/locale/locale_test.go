@@ -0,0 +1,70 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "vi", want: "vi"},
+		{tag: "vi-vn", want: "vi-VN"},
+		{tag: " VI_VN ", want: "vi-VN"},
+		{tag: "zh-hant-tw", want: "zh-Hant-TW"},
+		{tag: "es-419", want: "es-419"},
+		{tag: "*", wantErr: true},
+		{tag: "english", wantErr: true},
+		{tag: "vi-VN-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := Parse(tt.tag)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidLocale)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		locales []string
+		want    []string
+	}{
+		{name: "default", want: []string{"en"}},
+		{name: "region", locales: []string{"vi-VN"}, want: []string{"vi-VN", "vi", "en"}},
+		{name: "script and region", locales: []string{"zh-Hant-TW"}, want: []string{"zh-Hant-TW", "zh-Hant", "zh", "en"}},
+		{name: "several locales", locales: []string{"fr-CA", "en-GB", "fr"}, want: []string{"fr-CA", "fr", "en-GB", "en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Chain(tt.locales...))
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: []string{}},
+		{header: "vi-VN,vi;q=0.9,en-US;q=0.8,en;q=0.7", want: []string{"vi-VN", "vi", "en-US", "en"}},
+		{header: "en;q=0.5, fr-CA", want: []string{"fr-CA", "en"}},
+		{header: "*, de;q=0, it;q=abc, ja", want: []string{"ja"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			require.Equal(t, tt.want, ParseAcceptLanguage(tt.header))
+		})
+	}
+}
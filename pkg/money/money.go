@@ -0,0 +1,217 @@
+// Package money represents amounts of money as integer minor units of an ISO 4217 currency,
+// so that prices and discounts are computed without floating point drift.
+package money
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when an operation mixes different currencies.
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	// ErrInvalidCurrency is returned for a currency which is not an ISO 4217 code.
+	ErrInvalidCurrency = errors.New("money: invalid currency")
+	// ErrInvalidAmount is returned when an amount cannot be parsed in a currency.
+	ErrInvalidAmount = errors.New("money: invalid amount")
+)
+
+// exponents are the number of minor unit digits of the currencies which don't use 2 digits.
+var exponents = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3,
+	"LYD": 3, "OMR": 3, "PYG": 0, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// Money is an amount of minor units of a currency, e.g. {Amount: 1050, Currency: "USD"} is 10.50 USD.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns an amount of minor units of a currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Exponent returns the number of minor unit digits of a currency.
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+
+	return 2
+}
+
+// IsCurrency reports whether code has the shape of an ISO 4217 currency code.
+func IsCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Parse parses a decimal amount of major units, e.g. "10.5" USD is 1050 minor units.
+// It rejects the amounts with more digits than the minor units of the currency.
+func Parse(s, currency string) (Money, error) {
+	if !IsCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	major, minor, _ := strings.Cut(s, ".")
+	exp := Exponent(currency)
+	if major == "" || len(minor) > exp || strings.ContainsAny(major+minor, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	amount, err := strconv.ParseInt(major+minor+strings.Repeat("0", exp-len(minor)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if neg {
+		amount = -amount
+	}
+
+	return New(amount, currency), nil
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return New(m.Amount+o.Amount, m.Currency), nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return New(m.Amount-o.Amount, m.Currency), nil
+}
+
+// Mul returns m multiplied by a quantity.
+func (m Money) Mul(n int64) Money {
+	return New(m.Amount*n, m.Currency)
+}
+
+// Percent returns a percentage of m given in basis points, 1050 is 10.5%.
+// The result is rounded half away from zero to the minor unit.
+func (m Money) Percent(basisPoints int64) Money {
+	p := m.Amount * basisPoints
+	q, r := p/10000, p%10000
+	switch {
+	case r >= 5000:
+		q++
+	case r <= -5000:
+		q--
+	}
+
+	return New(q, m.Currency)
+}
+
+// Clamp returns m bounded to [lo, hi], the bounds must be in the currency of m.
+func (m Money) Clamp(lo, hi Money) (Money, error) {
+	if m.Currency != lo.Currency || m.Currency != hi.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	return New(min(max(m.Amount, lo.Amount), hi.Amount), m.Currency), nil
+}
+
+// Allocate splits m into parts proportional to the non-negative weights, the parts sum to m.
+// The minor units left by the rounding down go to the parts with the largest remainders, the first ones first,
+// and m is split evenly when the weights sum to zero. A negative m is split as its absolute value, negated.
+func (m Money) Allocate(weights []int64) []Money {
+	if m.Amount < 0 {
+		parts := New(-m.Amount, m.Currency).Allocate(weights)
+		for i := range parts {
+			parts[i].Amount = -parts[i].Amount
+		}
+
+		return parts
+	}
+
+	parts := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return parts
+	}
+
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 {
+		weights = make([]int64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		sum = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(weights))
+	order := make([]int, len(weights))
+	left := m.Amount
+	for i, w := range weights {
+		parts[i] = New(m.Amount*w/sum, m.Currency)
+		remainders[i] = m.Amount * w % sum
+		order[i] = i
+		left -= parts[i].Amount
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(remainders[b], remainders[a]) })
+	for _, i := range order[:left] {
+		parts[i].Amount++
+	}
+
+	return parts
+}
+
+// Convert converts m to another currency with the rate of a major unit of m in the currency.
+// The result is rounded half away from zero and is meant for display, not for accounting.
+func (m Money) Convert(currency string, rate float64) Money {
+	scale := math.Pow10(Exponent(currency) - Exponent(m.Currency))
+
+	return New(int64(math.Round(float64(m.Amount)*rate*scale)), currency)
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Decimal returns the amount in major units, e.g. "10.50".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if exp == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	digits := fmt.Sprintf("%0*d", exp+1, amount)
+
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String returns the amount in major units followed by the currency, e.g. "10.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
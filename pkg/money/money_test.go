@@ -0,0 +1,127 @@
+package money
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{name: "happy case", s: "10.5", currency: "USD", want: New(1050, "USD")},
+		{name: "integer", s: "12", currency: "USD", want: New(1200, "USD")},
+		{name: "negative", s: "-0.05", currency: "USD", want: New(-5, "USD")},
+		{name: "zero exponent", s: "1500", currency: "JPY", want: New(1500, "JPY")},
+		{name: "three digits exponent", s: "1.234", currency: "KWD", want: New(1234, "KWD")},
+		{name: "err too many digits", s: "1.234", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "err not a number", s: "abc", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "err empty", s: "", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "err invalid currency", s: "1", currency: "usd", wantErr: ErrInvalidCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s, tt.currency)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMoney_Percent(t *testing.T) {
+	tests := []struct {
+		name        string
+		m           Money
+		basisPoints int64
+		want        Money
+	}{
+		{name: "exact", m: New(10000, "USD"), basisPoints: 1000, want: New(1000, "USD")},
+		{name: "round half up", m: New(1005, "USD"), basisPoints: 5000, want: New(503, "USD")},
+		{name: "round down", m: New(999, "USD"), basisPoints: 333, want: New(33, "USD")},
+		{name: "negative round half away from zero", m: New(-1005, "USD"), basisPoints: 5000, want: New(-503, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.m.Percent(tt.basisPoints))
+		})
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	got, err := New(150, "USD").Add(New(250, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, New(400, "USD"), got)
+
+	_, err = New(150, "USD").Add(New(250, "EUR"))
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		weights []int64
+		want    []Money
+	}{
+		{name: "proportional", m: New(300, "USD"), weights: []int64{100, 200}, want: []Money{New(100, "USD"), New(200, "USD")}},
+		{name: "largest remainder", m: New(100, "USD"), weights: []int64{1, 1, 1}, want: []Money{New(34, "USD"), New(33, "USD"), New(33, "USD")}},
+		{name: "remainder to the largest fraction", m: New(10, "USD"), weights: []int64{12, 38, 50}, want: []Money{New(1, "USD"), New(4, "USD"), New(5, "USD")}},
+		{name: "tie to the first", m: New(10, "USD"), weights: []int64{15, 35, 50}, want: []Money{New(2, "USD"), New(3, "USD"), New(5, "USD")}},
+		{name: "zero weights split evenly", m: New(5, "USD"), weights: []int64{0, 0}, want: []Money{New(3, "USD"), New(2, "USD")}},
+		{name: "no weights", m: New(5, "USD"), weights: nil, want: []Money{}},
+		{name: "negative amount", m: New(-100, "USD"), weights: []int64{1, 1, 1}, want: []Money{New(-34, "USD"), New(-33, "USD"), New(-33, "USD")}},
+		{name: "negative amount zero weights", m: New(-5, "USD"), weights: []int64{0, 0}, want: []Money{New(-3, "USD"), New(-2, "USD")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.m.Allocate(tt.weights))
+		})
+	}
+}
+
+func TestMoney_Convert(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		currency string
+		rate     float64
+		want     Money
+	}{
+		{name: "same exponent", m: New(1000, "USD"), currency: "EUR", rate: 0.9234, want: New(923, "EUR")},
+		{name: "to zero exponent", m: New(1050, "USD"), currency: "JPY", rate: 150.5, want: New(1580, "JPY")},
+		{name: "from zero exponent", m: New(1500, "JPY"), currency: "USD", rate: 0.0066, want: New(990, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.m.Convert(tt.currency, tt.rate))
+		})
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{m: New(1050, "USD"), want: "10.50 USD"},
+		{m: New(5, "USD"), want: "0.05 USD"},
+		{m: New(-5, "USD"), want: "-0.05 USD"},
+		{m: New(1500, "JPY"), want: "1500 JPY"},
+		{m: New(1234, "KWD"), want: "1.234 KWD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.m.String())
+		})
+	}
+}
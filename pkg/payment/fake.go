@@ -0,0 +1,246 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"trintech/review/pkg/money"
+)
+
+// The payment tokens of the scenarios of [DefaultFakeScenarios].
+const (
+	FakeTokenSuccess         = "tok_success"
+	FakeTokenDeclined        = "tok_declined"
+	FakeTokenCaptureDeclined = "tok_capture_declined"
+	FakeTokenUnavailable     = "tok_unavailable"
+)
+
+// ErrFakeUnavailable is the transient error of the unavailable scenario of the fake provider.
+var ErrFakeUnavailable = errors.New("fake payment provider unavailable")
+
+// FakeScenario is the outcome of the operations of the payments authorized with a token of the fake provider,
+// a nil error is a successful operation.
+type FakeScenario struct {
+	AuthorizeErr error
+	CaptureErr   error
+	VoidErr      error
+	RefundErr    error
+}
+
+// DefaultFakeScenarios returns the scenarios of the fake provider:
+//   - [FakeTokenSuccess]: every operation succeeds
+//   - [FakeTokenDeclined]: the authorization is declined
+//   - [FakeTokenCaptureDeclined]: the authorization succeeds, the capture is declined
+//   - [FakeTokenUnavailable]: the authorization fails with a transient error
+func DefaultFakeScenarios() map[string]FakeScenario {
+	return map[string]FakeScenario{
+		FakeTokenSuccess:         {},
+		FakeTokenDeclined:        {AuthorizeErr: ErrDeclined},
+		FakeTokenCaptureDeclined: {CaptureErr: ErrDeclined},
+		FakeTokenUnavailable:     {AuthorizeErr: ErrFakeUnavailable},
+	}
+}
+
+// FakeProvider is a deterministic in-memory [Provider] to run the payment flow offline.
+// The outcome of a payment is the scenario of its token, an empty token is the success scenario
+// and an unknown token is declined. The payment ids are sequential.
+type FakeProvider struct {
+	scenarios map[string]FakeScenario
+	secret    []byte
+
+	mu       sync.Mutex
+	seq      int64
+	payments map[string]*fakePayment
+}
+
+// fakePayment is a payment of the fake provider.
+type fakePayment struct {
+	Payment
+	scenario FakeScenario
+	// refunds are the idempotency keys of the refunds of the payment.
+	refunds map[string]bool
+}
+
+// NewFakeProvider returns a fake provider signing its webhooks with the secret.
+// When no scenarios are given, it uses [DefaultFakeScenarios].
+func NewFakeProvider(secret string, scenarios map[string]FakeScenario) *FakeProvider {
+	if scenarios == nil {
+		scenarios = DefaultFakeScenarios()
+	}
+
+	return &FakeProvider{
+		scenarios: scenarios,
+		secret:    []byte(secret),
+		payments:  make(map[string]*fakePayment),
+	}
+}
+
+// Authorize implements [Provider].
+func (p *FakeProvider) Authorize(_ context.Context, req *AuthorizeRequest) (*Payment, error) {
+	if req.Amount.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	token := req.Token
+	if token == "" {
+		token = FakeTokenSuccess
+	}
+	scenario, ok := p.scenarios[token]
+	if !ok {
+		return nil, ErrDeclined
+	}
+	if scenario.AuthorizeErr != nil {
+		return nil, scenario.AuthorizeErr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	payment := &fakePayment{
+		Payment: Payment{
+			ID:       fmt.Sprintf("pay_fake_%d", p.seq),
+			Amount:   req.Amount,
+			Refunded: money.New(0, req.Amount.Currency),
+			Status:   StatusAuthorized,
+		},
+		scenario: scenario,
+		refunds:  make(map[string]bool),
+	}
+	p.payments[payment.ID] = payment
+
+	return p.copy(payment), nil
+}
+
+// Capture implements [Provider].
+func (p *FakeProvider) Capture(_ context.Context, paymentID string) (*Payment, error) {
+	return p.transition(paymentID, func(payment *fakePayment) error {
+		if payment.Status == StatusCaptured {
+			return nil
+		}
+		if payment.Status != StatusAuthorized {
+			return ErrInvalidState
+		}
+		if payment.scenario.CaptureErr != nil {
+			if errors.Is(payment.scenario.CaptureErr, ErrDeclined) {
+				payment.Status = StatusFailed
+			}
+			return payment.scenario.CaptureErr
+		}
+
+		payment.Status = StatusCaptured
+		return nil
+	})
+}
+
+// Void implements [Provider].
+func (p *FakeProvider) Void(_ context.Context, paymentID string) (*Payment, error) {
+	return p.transition(paymentID, func(payment *fakePayment) error {
+		switch payment.Status {
+		case StatusVoided, StatusFailed:
+			return nil
+		case StatusAuthorized:
+		default:
+			return ErrInvalidState
+		}
+		if payment.scenario.VoidErr != nil {
+			return payment.scenario.VoidErr
+		}
+
+		payment.Status = StatusVoided
+		return nil
+	})
+}
+
+// Refund implements [Provider], a refund whose idempotency key was refunded returns the payment as is.
+func (p *FakeProvider) Refund(_ context.Context, paymentID string, req *RefundRequest) (*Payment, error) {
+	amount := req.Amount
+	return p.transition(paymentID, func(payment *fakePayment) error {
+		if req.IdempotencyKey != "" && payment.refunds[req.IdempotencyKey] {
+			return nil
+		}
+		if payment.Status != StatusCaptured && payment.Status != StatusPartiallyRefunded {
+			return ErrInvalidState
+		}
+		if amount.Currency != payment.Amount.Currency || amount.Amount <= 0 ||
+			payment.Refunded.Amount+amount.Amount > payment.Amount.Amount {
+			return ErrInvalidAmount
+		}
+		if payment.scenario.RefundErr != nil {
+			return payment.scenario.RefundErr
+		}
+
+		payment.Refunded.Amount += amount.Amount
+		if req.IdempotencyKey != "" {
+			payment.refunds[req.IdempotencyKey] = true
+		}
+		payment.Status = StatusPartiallyRefunded
+		if payment.Refunded.Amount == payment.Amount.Amount {
+			payment.Status = StatusRefunded
+		}
+		return nil
+	})
+}
+
+// Retrieve implements [Provider].
+func (p *FakeProvider) Retrieve(_ context.Context, paymentID string) (*Payment, error) {
+	return p.transition(paymentID, func(*fakePayment) error { return nil })
+}
+
+// VerifyWebhook implements [Provider], the signature is the hex HMAC-SHA256 of the payload with the secret.
+func (p *FakeProvider) VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("unable to decode webhook: %w", err)
+	}
+
+	return &event, nil
+}
+
+// SignWebhook returns the payload and signature of a webhook of the event, as the fake provider sends it.
+func (p *FakeProvider) SignWebhook(event *WebhookEvent) ([]byte, string, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, hex.EncodeToString(p.sign(payload)), nil
+}
+
+func (p *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// transition applies the operation to a payment and returns it, the payment is updated even if the operation fails.
+func (p *FakeProvider) transition(paymentID string, operation func(payment *fakePayment) error) (*Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if err := operation(payment); err != nil {
+		return nil, err
+	}
+
+	return p.copy(payment), nil
+}
+
+func (p *FakeProvider) copy(payment *fakePayment) *Payment {
+	result := payment.Payment
+	return &result
+}
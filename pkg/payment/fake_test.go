@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"trintech/review/pkg/money"
+)
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	amount := money.New(1000, "USD")
+
+	t.Run("capture and refund", func(t *testing.T) {
+		p := NewFakeProvider("secret", nil)
+
+		authorized, err := p.Authorize(ctx, &AuthorizeRequest{Amount: amount, Token: FakeTokenSuccess})
+		require.NoError(t, err)
+		require.Equal(t, "pay_fake_1", authorized.ID)
+		require.Equal(t, StatusAuthorized, authorized.Status)
+
+		captured, err := p.Capture(ctx, authorized.ID)
+		require.NoError(t, err)
+		require.Equal(t, StatusCaptured, captured.Status)
+
+		_, err = p.Void(ctx, authorized.ID)
+		require.ErrorIs(t, err, ErrInvalidState)
+
+		refunded, err := p.Refund(ctx, authorized.ID, &RefundRequest{Amount: money.New(400, "USD"), IdempotencyKey: "refund_1"})
+		require.NoError(t, err)
+		require.Equal(t, StatusPartiallyRefunded, refunded.Status)
+
+		retried, err := p.Refund(ctx, authorized.ID, &RefundRequest{Amount: money.New(400, "USD"), IdempotencyKey: "refund_1"})
+		require.NoError(t, err)
+		require.Equal(t, refunded, retried)
+
+		_, err = p.Refund(ctx, authorized.ID, &RefundRequest{Amount: money.New(700, "USD")})
+		require.ErrorIs(t, err, ErrInvalidAmount)
+
+		refunded, err = p.Refund(ctx, authorized.ID, &RefundRequest{Amount: money.New(600, "USD"), IdempotencyKey: "refund_2"})
+		require.NoError(t, err)
+		require.Equal(t, StatusRefunded, refunded.Status)
+		require.Equal(t, amount, refunded.Refunded)
+
+		retrieved, err := p.Retrieve(ctx, authorized.ID)
+		require.NoError(t, err)
+		require.Equal(t, refunded, retrieved)
+	})
+
+	t.Run("void", func(t *testing.T) {
+		p := NewFakeProvider("secret", nil)
+
+		authorized, err := p.Authorize(ctx, &AuthorizeRequest{Amount: amount})
+		require.NoError(t, err)
+
+		voided, err := p.Void(ctx, authorized.ID)
+		require.NoError(t, err)
+		require.Equal(t, StatusVoided, voided.Status)
+
+		_, err = p.Capture(ctx, authorized.ID)
+		require.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("scenarios", func(t *testing.T) {
+		p := NewFakeProvider("secret", nil)
+
+		_, err := p.Authorize(ctx, &AuthorizeRequest{Amount: amount, Token: FakeTokenDeclined})
+		require.ErrorIs(t, err, ErrDeclined)
+
+		_, err = p.Authorize(ctx, &AuthorizeRequest{Amount: amount, Token: FakeTokenUnavailable})
+		require.ErrorIs(t, err, ErrFakeUnavailable)
+
+		_, err = p.Authorize(ctx, &AuthorizeRequest{Amount: amount, Token: "tok_unknown"})
+		require.ErrorIs(t, err, ErrDeclined)
+
+		authorized, err := p.Authorize(ctx, &AuthorizeRequest{Amount: amount, Token: FakeTokenCaptureDeclined})
+		require.NoError(t, err)
+		_, err = p.Capture(ctx, authorized.ID)
+		require.ErrorIs(t, err, ErrDeclined)
+
+		_, err = p.Capture(ctx, "pay_fake_404")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		p := NewFakeProvider("secret", nil)
+		event := &WebhookEvent{ID: "evt_1", PaymentID: "pay_fake_1", Status: StatusRefunded}
+
+		payload, signature, err := p.SignWebhook(event)
+		require.NoError(t, err)
+
+		got, err := p.VerifyWebhook(payload, signature)
+		require.NoError(t, err)
+		require.Equal(t, event, got)
+
+		_, err = NewFakeProvider("other", nil).VerifyWebhook(payload, signature)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+
+		_, err = p.VerifyWebhook(payload, "not-hex")
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
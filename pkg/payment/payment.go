@@ -0,0 +1,87 @@
+// Package payment abstracts the payment providers which charge the purchases.
+// A payment is authorized, then captured or voided, and a captured payment can be refunded.
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"trintech/review/pkg/money"
+)
+
+// Status is the status of a payment at the provider.
+type Status string
+
+const (
+	// StatusAuthorized is a payment whose amount is held, it is not charged yet.
+	StatusAuthorized Status = "AUTHORIZED"
+	// StatusCaptured is a charged payment.
+	StatusCaptured Status = "CAPTURED"
+	// StatusVoided is an authorized payment which was cancelled, it was never charged.
+	StatusVoided Status = "VOIDED"
+	// StatusPartiallyRefunded is a captured payment of which a part is refunded.
+	StatusPartiallyRefunded Status = "PARTIALLY_REFUNDED"
+	// StatusRefunded is a captured payment which is fully refunded.
+	StatusRefunded Status = "REFUNDED"
+	// StatusFailed is a payment which the provider failed after its authorization.
+	StatusFailed Status = "FAILED"
+)
+
+var (
+	// ErrDeclined is returned when the provider declines the payment, retrying it does not help.
+	ErrDeclined = errors.New("payment declined")
+	// ErrNotFound is returned for an unknown payment.
+	ErrNotFound = errors.New("payment not found")
+	// ErrInvalidState is returned when the payment is not in a status allowing the operation, e.g. capturing a voided payment.
+	ErrInvalidState = errors.New("invalid payment state")
+	// ErrInvalidAmount is returned for an amount the payment cannot be charged or refunded.
+	ErrInvalidAmount = errors.New("invalid payment amount")
+	// ErrInvalidSignature is returned by [Provider.VerifyWebhook] for a webhook which was not sent by the provider.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Payment is a payment at the provider.
+type Payment struct {
+	ID     string
+	Amount money.Money
+	// Refunded is the refunded part of the captured amount.
+	Refunded money.Money
+	Status   Status
+}
+
+// AuthorizeRequest is the request of a payment authorization.
+type AuthorizeRequest struct {
+	Amount money.Money
+	// Token is the payment method of the customer, tokenized by the provider on the client.
+	Token string
+}
+
+// RefundRequest is the request of a payment refund.
+type RefundRequest struct {
+	Amount money.Money
+	// IdempotencyKey identifies the refund at the provider, a refund retried with the same key is refunded once.
+	IdempotencyKey string
+}
+
+// WebhookEvent is a payment status change notified by the provider.
+type WebhookEvent struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Status    Status `json:"status"`
+}
+
+// Provider is a payment provider.
+type Provider interface {
+	// Authorize holds the amount of a payment on the payment method of the customer.
+	Authorize(ctx context.Context, req *AuthorizeRequest) (*Payment, error)
+	// Capture charges the authorized amount of a payment.
+	Capture(ctx context.Context, paymentID string) (*Payment, error)
+	// Void cancels an authorized payment which is not captured.
+	Void(ctx context.Context, paymentID string) (*Payment, error)
+	// Refund refunds a part or all of the captured amount of a payment.
+	Refund(ctx context.Context, paymentID string, req *RefundRequest) (*Payment, error)
+	// Retrieve returns a payment.
+	Retrieve(ctx context.Context, paymentID string) (*Payment, error)
+	// VerifyWebhook checks a webhook was sent by the provider and returns its event.
+	VerifyWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
@@ -2,6 +2,7 @@ package pg_util
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/lib/pq"
@@ -15,6 +16,11 @@ func NullString(str string) sql.NullString {
 	return result
 }
 
+// NullEmptyString help to transform string to [database/sql.NullString], an empty string becomes NULL.
+func NullEmptyString(str string) sql.NullString {
+	return sql.NullString{String: str, Valid: str != ""}
+}
+
 // NullInt64 help to transform int64 to [database/sql.NullInt64]
 func NullInt64(val int64) sql.NullInt64 {
 	var result sql.NullInt64
@@ -39,12 +45,12 @@ func NullTime(val time.Time) sql.NullTime {
 	return result
 }
 
-// StringArray ...
+// StringArray help to transform []string to [github.com/lib/pq.StringArray].
+// An empty slice is kept as an empty array instead of NULL.
 func StringArray(val []string) pq.StringArray {
-	var result pq.StringArray
-	result.Scan(val)
+	result := make(pq.StringArray, 0, len(val))
 
-	return result
+	return append(result, val...)
 }
 
 // StringArrayValue ...
@@ -57,3 +63,10 @@ func StringArrayValue(val pq.StringArray) []string {
 
 	return result
 }
+
+// IsUniqueViolation reports whether err is a postgres unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// intervalProcessor is a [Processor] that runs a job on a fixed interval until it is stopped.
+type intervalProcessor struct {
+	name     string
+	interval time.Duration
+	job      func(ctx context.Context) error
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewIntervalProcessor returns a [Processor] that calls job every interval.
+// Errors returned by job are logged and do not stop the processor.
+func NewIntervalProcessor(name string, interval time.Duration, job func(ctx context.Context) error) Processor {
+	return &intervalProcessor{
+		name:     name,
+		interval: interval,
+		job:      job,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start implements [Processor] by running the job until ctx is done or [intervalProcessor.Stop] is called.
+func (p *intervalProcessor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	slog.Info("interval processor started", "name", p.name, "interval", p.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.done:
+			return nil
+		case <-ticker.C:
+			if err := p.job(ctx); err != nil {
+				slog.Error("unable to run interval job", "name", p.name, "err", err)
+			}
+		}
+	}
+}
+
+// Stop implements [Processor] by signalling the running loop to exit.
+func (p *intervalProcessor) Stop(_ context.Context) error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Broker publishes messages and delivers them to the subscribers of their topic.
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
+// localBroker is a [Broker] which delivers the messages to the subscribers of the process, for environments
+// without a broker.
+type localBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string][]func(key, value []byte)
+}
+
+// NewLocalBroker returns a [Broker] which logs the topic and key of every message and delivers it synchronously
+// to the subscribers of the process.
+func NewLocalBroker() Broker {
+	return &localBroker{
+		subscribers: make(map[string][]func(key, value []byte)),
+	}
+}
+
+// Publish implements [Publisher].
+func (b *localBroker) Publish(ctx context.Context, topic string, key, value []byte) error {
+	slog.InfoContext(ctx, "publish message", "topic", topic, "key", string(key), "size", len(value))
+
+	b.mu.RLock()
+	subscribers := b.subscribers[topic]
+	b.mu.RUnlock()
+	for _, subscribeFn := range subscribers {
+		subscribeFn(key, value)
+	}
+
+	return nil
+}
+
+// Subscribe implements [Subscriber].
+func (b *localBroker) Subscribe(topic string, subscribeFn func(key, value []byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[topic] = append(b.subscribers[topic], subscribeFn)
+}
@@ -0,0 +1,21 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logPublisher is a [Publisher] which only logs the messages, for environments without a broker.
+type logPublisher struct{}
+
+// NewLogPublisher returns a [Publisher] which logs the topic and key of every message instead of sending it.
+func NewLogPublisher() Publisher {
+	return &logPublisher{}
+}
+
+// Publish implements [Publisher].
+func (p *logPublisher) Publish(ctx context.Context, topic string, key, value []byte) error {
+	slog.InfoContext(ctx, "publish message", "topic", topic, "key", string(key), "size", len(value))
+
+	return nil
+}
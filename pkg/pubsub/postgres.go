@@ -0,0 +1,161 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"trintech/review/pkg/database"
+	"trintech/review/pkg/processor"
+)
+
+// ListeningBroker is a [Broker] which receives the messages of the other processes while it is started.
+type ListeningBroker interface {
+	Broker
+	processor.Processor
+}
+
+// postgresBroker is a [ListeningBroker] which delivers the messages to the subscribers of every process of a
+// Postgres database with NOTIFY and LISTEN, the topics are the channels. A message is delivered once to the processes
+// listening when it is published, the messages published while a listener reconnects are lost.
+type postgresBroker struct {
+	db         database.Executor
+	connString string
+	// origin identifies the messages of the process, they are delivered to its subscribers when they are published
+	// and skipped when they are notified.
+	origin string
+
+	mu          sync.RWMutex
+	subscribers map[string][]func(key, value []byte)
+	listener    *pq.Listener
+
+	done chan struct{}
+	once sync.Once
+}
+
+// postgresMessage is the payload of the notification of a message.
+type postgresMessage struct {
+	Origin string `json:"origin"`
+	Key    []byte `json:"key,omitempty"`
+	Value  []byte `json:"value"`
+}
+
+// NewPostgresBroker returns a [ListeningBroker] which notifies the messages through db and listens to them with a
+// dedicated connection of the connection string. The payload of a notification is limited to 8000 bytes by Postgres,
+// so the broker is meant for small messages such as the invalidations of a cache.
+func NewPostgresBroker(db database.Executor, connString string) ListeningBroker {
+	return &postgresBroker{
+		db:          db,
+		connString:  connString,
+		origin:      uuid.NewString(),
+		subscribers: make(map[string][]func(key, value []byte)),
+		done:        make(chan struct{}),
+	}
+}
+
+// Publish implements [Publisher] by delivering the message to the subscribers of the process and notifying it to the
+// other processes.
+func (b *postgresBroker) Publish(ctx context.Context, topic string, key, value []byte) error {
+	slog.InfoContext(ctx, "publish message", "topic", topic, "key", string(key), "size", len(value))
+
+	b.deliver(topic, key, value)
+
+	payload, err := json.Marshal(&postgresMessage{Origin: b.origin, Key: key, Value: value})
+	if err != nil {
+		return fmt.Errorf("unable to marshal message: %w", err)
+	}
+	if _, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, topic, string(payload)); err != nil {
+		return fmt.Errorf("unable to notify message: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe implements [Subscriber], the topic is listened to once the broker is started.
+func (b *postgresBroker) Subscribe(topic string, subscribeFn func(key, value []byte)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[topic]; !ok && b.listener != nil {
+		if err := b.listener.Listen(topic); err != nil {
+			slog.Error("unable to listen to topic", "topic", topic, "err", err)
+		}
+	}
+	b.subscribers[topic] = append(b.subscribers[topic], subscribeFn)
+}
+
+// Start implements [processor.Processor] by listening to the subscribed topics and delivering the messages of the
+// other processes until ctx is done or [postgresBroker.Stop] is called.
+func (b *postgresBroker) Start(ctx context.Context) error {
+	listener := pq.NewListener(b.connString, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("postgres broker listener failed", "event", event, "err", err)
+		}
+	})
+	defer listener.Close()
+
+	b.mu.Lock()
+	for topic := range b.subscribers {
+		if err := listener.Listen(topic); err != nil {
+			b.mu.Unlock()
+			return fmt.Errorf("unable to listen to topic %q: %w", topic, err)
+		}
+	}
+	b.listener = listener
+	b.mu.Unlock()
+
+	slog.Info("postgres broker started")
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-b.done:
+			return nil
+		case <-ticker.C:
+			// Check the connection, which is re-established when it is lost
+			go listener.Ping()
+		case n := <-listener.Notify:
+			if n == nil {
+				slog.Warn("postgres broker reconnected, the messages published meanwhile are lost")
+				continue
+			}
+
+			var msg postgresMessage
+			if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil {
+				slog.Error("unable to unmarshal message", "topic", n.Channel, "err", err)
+				continue
+			}
+			if msg.Origin == b.origin {
+				continue
+			}
+			b.deliver(n.Channel, msg.Key, msg.Value)
+		}
+	}
+}
+
+// Stop implements [processor.Processor] by signalling the listening loop to exit.
+func (b *postgresBroker) Stop(_ context.Context) error {
+	b.once.Do(func() {
+		close(b.done)
+	})
+
+	return nil
+}
+
+// deliver delivers a message synchronously to the subscribers of its topic.
+func (b *postgresBroker) deliver(topic string, key, value []byte) {
+	b.mu.RLock()
+	subscribers := b.subscribers[topic]
+	b.mu.RUnlock()
+	for _, subscribeFn := range subscribers {
+		subscribeFn(key, value)
+	}
+}
@@ -0,0 +1,87 @@
+// Package shipping computes the shipping cost of a parcel with the rate rules of a shipping method.
+package shipping
+
+import (
+	"errors"
+	"slices"
+
+	"trintech/review/pkg/money"
+	"trintech/review/pkg/tax"
+)
+
+var (
+	// ErrNotShippable is returned when no rule of a method applies to a parcel.
+	ErrNotShippable = errors.New("shipping: no rate for the parcel")
+	// ErrCurrencyMismatch is returned when the order value is not in the currency of the method.
+	ErrCurrencyMismatch = errors.New("shipping: currency mismatch")
+)
+
+// Rule is the rate of a shipping method for the parcels of a zone within bounds of weight and order value.
+// The zone is a list of regions, a country includes its subdivisions and an empty zone is everywhere.
+// The lower bounds are inclusive, the upper bounds are exclusive and zero upper bounds are unbounded.
+type Rule struct {
+	Regions        []string
+	MinWeightGrams int64
+	MaxWeightGrams int64
+	MinOrderValue  int64
+	MaxOrderValue  int64
+	Amount         int64
+}
+
+// Method is a shipping method, its amounts are minor units of its currency.
+type Method struct {
+	Currency string
+	Rules    []Rule
+	// FreeThreshold is the order value from which the shipping is free, zero when it is never free.
+	FreeThreshold int64
+}
+
+// Parcel is what is shipped, the order value is the value of its products before discounts.
+type Parcel struct {
+	Region      string
+	WeightGrams int64
+	OrderValue  money.Money
+}
+
+// Quote returns the shipping cost of a parcel, free from the threshold of the method and otherwise the
+// cheapest rule which applies to the parcel.
+func (m Method) Quote(parcel Parcel) (money.Money, error) {
+	if parcel.OrderValue.Currency != m.Currency {
+		return money.Money{}, ErrCurrencyMismatch
+	}
+
+	var (
+		cost  int64
+		found bool
+	)
+	for _, rule := range m.Rules {
+		if rule.applies(parcel) && (!found || rule.Amount < cost) {
+			cost, found = rule.Amount, true
+		}
+	}
+	if !found {
+		return money.Money{}, ErrNotShippable
+	}
+	if m.FreeThreshold > 0 && parcel.OrderValue.Amount >= m.FreeThreshold {
+		cost = 0
+	}
+
+	return money.New(cost, m.Currency), nil
+}
+
+// applies reports whether the rule applies to a parcel.
+func (r Rule) applies(parcel Parcel) bool {
+	if len(r.Regions) > 0 && !slices.ContainsFunc(tax.Regions(parcel.Region), func(region string) bool {
+		return slices.Contains(r.Regions, region)
+	}) {
+		return false
+	}
+
+	return within(parcel.WeightGrams, r.MinWeightGrams, r.MaxWeightGrams) &&
+		within(parcel.OrderValue.Amount, r.MinOrderValue, r.MaxOrderValue)
+}
+
+// within reports whether v is in [lo, hi), hi is unbounded when it is zero.
+func within(v, lo, hi int64) bool {
+	return v >= lo && (hi == 0 || v < hi)
+}
@@ -0,0 +1,72 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"trintech/review/pkg/money"
+)
+
+func TestMethod_Quote(t *testing.T) {
+	method := Method{
+		Currency: "USD",
+		Rules: []Rule{
+			{Regions: []string{"US"}, MaxWeightGrams: 1000, Amount: 500},
+			{Regions: []string{"US"}, MinWeightGrams: 1000, Amount: 1200},
+			{Regions: []string{"US-CA"}, MaxWeightGrams: 1000, Amount: 400},
+			{Regions: []string{"DE", "FR"}, MaxOrderValue: 5000, Amount: 2000},
+			{Regions: []string{"DE", "FR"}, MinOrderValue: 5000, Amount: 1500},
+		},
+		FreeThreshold: 10000,
+	}
+	tests := []struct {
+		name    string
+		parcel  Parcel
+		want    money.Money
+		wantErr error
+	}{
+		{
+			name:   "weight rule",
+			parcel: Parcel{Region: "US-NY", WeightGrams: 2500, OrderValue: money.New(3000, "USD")},
+			want:   money.New(1200, "USD"),
+		},
+		{
+			name:   "cheapest rule of the subdivision",
+			parcel: Parcel{Region: "US-CA", WeightGrams: 500, OrderValue: money.New(3000, "USD")},
+			want:   money.New(400, "USD"),
+		},
+		{
+			name:   "order value rule",
+			parcel: Parcel{Region: "FR", WeightGrams: 500, OrderValue: money.New(5000, "USD")},
+			want:   money.New(1500, "USD"),
+		},
+		{
+			name:   "free from the threshold",
+			parcel: Parcel{Region: "DE", WeightGrams: 500, OrderValue: money.New(10000, "USD")},
+			want:   money.New(0, "USD"),
+		},
+		{
+			name:    "err zone not shipped",
+			parcel:  Parcel{Region: "JP", WeightGrams: 500, OrderValue: money.New(10000, "USD")},
+			wantErr: ErrNotShippable,
+		},
+		{
+			name:    "err currency mismatch",
+			parcel:  Parcel{Region: "US", WeightGrams: 500, OrderValue: money.New(3000, "EUR")},
+			wantErr: ErrCurrencyMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := method.Quote(tt.parcel)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidName is returned when an object name is not a plain file name.
+var ErrInvalidName = errors.New("storage: invalid object name")
+
+// localStorage stores the objects as files of a directory which is served at a base URL.
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage returns a storage writing the objects in dir, their URLs are under baseURL.
+func NewLocalStorage(dir, baseURL string) Storage {
+	return &localStorage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// UploadObject writes an object to the directory and returns its URL, it replaces an object of the same name.
+func (s *localStorage) UploadObject(_ context.Context, name string, data io.Reader) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", ErrInvalidName
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", fmt.Errorf("unable to create storage directory: %w", err)
+	}
+	f, err := os.Create(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", fmt.Errorf("unable to create object: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, data); err != nil {
+		return "", fmt.Errorf("unable to write object: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("unable to write object: %w", err)
+	}
+
+	return s.baseURL + "/" + url.PathEscape(name), nil
+}
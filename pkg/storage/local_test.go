@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_UploadObject(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "http://localhost:8084/files/")
+
+	url, err := s.UploadObject(context.Background(), "a b.png", strings.NewReader("image"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8084/files/a%20b.png", url)
+	data, err := os.ReadFile(filepath.Join(dir, "a b.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+
+	for _, name := range []string{"", "..", "../a.png", "sub/a.png"} {
+		_, err := s.UploadObject(context.Background(), name, strings.NewReader("image"))
+		assert.True(t, errors.Is(err, ErrInvalidName), name)
+	}
+}
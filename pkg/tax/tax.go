@@ -0,0 +1,171 @@
+// Package tax computes the taxes of amounts of money with the rates of regions and product categories.
+// A rate is either included in the prices, as a VAT, or added on top of them, as a sales tax.
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"trintech/review/pkg/money"
+)
+
+var (
+	// ErrInvalidRegion is returned for a region which is not an ISO 3166 code.
+	ErrInvalidRegion = errors.New("tax: invalid region")
+	// ErrInvalidRate is returned for a rate which cannot be parsed or is out of bounds.
+	ErrInvalidRate = errors.New("tax: invalid rate")
+)
+
+// MaxBasisPoints is the highest rate, 100%.
+const MaxBasisPoints = 10000
+
+// Rate is the tax rate of a region for a product category, 1050 basis points is 10.5%.
+// The rate without category is the default rate of the region.
+type Rate struct {
+	Region      string
+	Category    string
+	BasisPoints int64
+	// Inclusive rates are included in the prices, the others are added on top of them.
+	Inclusive bool
+}
+
+// Breakdown is the tax of an amount, Gross is Net plus Tax.
+type Breakdown struct {
+	Rate  Rate
+	Net   money.Money
+	Tax   money.Money
+	Gross money.Money
+}
+
+// IsRegion reports whether code has the shape of an ISO 3166-1 country code, optionally followed by an ISO 3166-2
+// subdivision, e.g. "DE" or "US-CA".
+func IsRegion(code string) bool {
+	country, subdivision, found := strings.Cut(code, "-")
+	if len(country) != 2 || !isAlnum(country, false) {
+		return false
+	}
+	if found && (len(subdivision) == 0 || len(subdivision) > 3 || !isAlnum(subdivision, true)) {
+		return false
+	}
+
+	return true
+}
+
+func isAlnum(s string, digits bool) bool {
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (!digits || c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Regions returns the regions whose rates apply to a region, the region first and then its country.
+func Regions(region string) []string {
+	country, _, found := strings.Cut(region, "-")
+	if !found {
+		return []string{region}
+	}
+
+	return []string{region, country}
+}
+
+// Lookup returns the rate of a product category in a region. The rate of the category wins over the default rate,
+// and the rates of the subdivision of a country win over the rates of the country.
+// It returns false when no rate applies, the amounts are then untaxed.
+func Lookup(rates []Rate, region, category string) (Rate, bool) {
+	for _, r := range Regions(region) {
+		var (
+			fallback Rate
+			found    bool
+		)
+		for _, rate := range rates {
+			if rate.Region != r {
+				continue
+			}
+			if rate.Category == category {
+				return rate, true
+			}
+			if rate.Category == "" {
+				fallback, found = rate, true
+			}
+		}
+		if found {
+			return fallback, true
+		}
+	}
+
+	return Rate{}, false
+}
+
+// Calculate returns the tax of the amount of a line at a rate, rounded half away from zero to the minor unit.
+// The amount is the gross amount of an inclusive rate and the net amount of an exclusive rate.
+// The lines are rounded one by one, the tax of an order is the sum of the tax of its lines.
+func Calculate(amount money.Money, rate Rate) Breakdown {
+	b := Breakdown{Rate: rate, Net: amount, Gross: amount}
+	if rate.Inclusive {
+		b.Tax = money.New(divRound(amount.Amount*rate.BasisPoints, MaxBasisPoints+rate.BasisPoints), amount.Currency)
+		b.Net = money.New(amount.Amount-b.Tax.Amount, amount.Currency)
+	} else {
+		b.Tax = amount.Percent(rate.BasisPoints)
+		b.Gross = money.New(amount.Amount+b.Tax.Amount, amount.Currency)
+	}
+
+	return b
+}
+
+// divRound returns n / d rounded half away from zero, d must be positive.
+func divRound(n, d int64) int64 {
+	q, r := n/d, n%d
+	switch {
+	case 2*r >= d:
+		q++
+	case 2*r <= -d:
+		q--
+	}
+
+	return q
+}
+
+// ParseRates parses a comma separated list of rates, each written REGION[/CATEGORY]=BASIS_POINTS[:inclusive],
+// e.g. "US-CA=725,DE=1900:inclusive,DE/BOOK=700:inclusive".
+func ParseRates(s string) ([]Rate, error) {
+	var rates []Rate
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRate, entry)
+		}
+		var rate Rate
+		rate.Region, rate.Category, _ = strings.Cut(key, "/")
+		if !IsRegion(rate.Region) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRegion, rate.Region)
+		}
+
+		value, mode, _ := strings.Cut(value, ":")
+		switch mode {
+		case "", "exclusive":
+		case "inclusive":
+			rate.Inclusive = true
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRate, entry)
+		}
+		basisPoints, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || basisPoints < 0 || basisPoints > MaxBasisPoints {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRate, entry)
+		}
+		rate.BasisPoints = basisPoints
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
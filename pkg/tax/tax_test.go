@@ -0,0 +1,134 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"trintech/review/pkg/money"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount money.Money
+		rate   Rate
+		want   Breakdown
+	}{
+		{
+			name:   "exclusive",
+			amount: money.New(999, "USD"),
+			rate:   Rate{Region: "US-CA", BasisPoints: 725},
+			want: Breakdown{
+				Rate:  Rate{Region: "US-CA", BasisPoints: 725},
+				Net:   money.New(999, "USD"),
+				Tax:   money.New(72, "USD"),
+				Gross: money.New(1071, "USD"),
+			},
+		},
+		{
+			name:   "inclusive",
+			amount: money.New(1000, "EUR"),
+			rate:   Rate{Region: "DE", BasisPoints: 1900, Inclusive: true},
+			want: Breakdown{
+				Rate:  Rate{Region: "DE", BasisPoints: 1900, Inclusive: true},
+				Net:   money.New(840, "EUR"),
+				Tax:   money.New(160, "EUR"),
+				Gross: money.New(1000, "EUR"),
+			},
+		},
+		{
+			name:   "rounds half away from zero",
+			amount: money.New(5, "USD"),
+			rate:   Rate{Region: "US", BasisPoints: 1000},
+			want: Breakdown{
+				Rate:  Rate{Region: "US", BasisPoints: 1000},
+				Net:   money.New(5, "USD"),
+				Tax:   money.New(1, "USD"),
+				Gross: money.New(6, "USD"),
+			},
+		},
+		{
+			name:   "zero rate",
+			amount: money.New(1000, "USD"),
+			rate:   Rate{Region: "US"},
+			want: Breakdown{
+				Rate:  Rate{Region: "US"},
+				Net:   money.New(1000, "USD"),
+				Tax:   money.New(0, "USD"),
+				Gross: money.New(1000, "USD"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Calculate(tt.amount, tt.rate))
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	rates := []Rate{
+		{Region: "US", BasisPoints: 500},
+		{Region: "US-CA", BasisPoints: 725},
+		{Region: "DE", BasisPoints: 1900, Inclusive: true},
+		{Region: "DE", Category: "BOOK", BasisPoints: 700, Inclusive: true},
+	}
+	tests := []struct {
+		name     string
+		region   string
+		category string
+		want     Rate
+		wantOk   bool
+	}{
+		{name: "category rate", region: "DE", category: "BOOK", want: rates[3], wantOk: true},
+		{name: "default rate", region: "DE", category: "FOOD", want: rates[2], wantOk: true},
+		{name: "subdivision rate", region: "US-CA", category: "BOOK", want: rates[1], wantOk: true},
+		{name: "country rate of subdivision", region: "US-NY", category: "BOOK", want: rates[0], wantOk: true},
+		{name: "untaxed region", region: "FR", category: "BOOK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(rates, tt.region, tt.category)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []Rate
+		wantErr error
+	}{
+		{
+			name: "happy case",
+			s:    "US-CA=725, DE=1900:inclusive,DE/BOOK=700:inclusive,US=0:exclusive",
+			want: []Rate{
+				{Region: "US-CA", BasisPoints: 725},
+				{Region: "DE", BasisPoints: 1900, Inclusive: true},
+				{Region: "DE", Category: "BOOK", BasisPoints: 700, Inclusive: true},
+				{Region: "US"},
+			},
+		},
+		{name: "empty", s: ""},
+		{name: "err invalid region", s: "usa=725", wantErr: ErrInvalidRegion},
+		{name: "err missing rate", s: "US", wantErr: ErrInvalidRate},
+		{name: "err rate out of bounds", s: "US=10001", wantErr: ErrInvalidRate},
+		{name: "err invalid mode", s: "US=725:included", wantErr: ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRates(tt.s)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}